@@ -1,32 +1,30 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/shard-cache/internal/cache"
 	"github.com/shard-cache/internal/server"
 )
 
 func main() {
-	var (
-		grpcPort      = flag.Int("grpc-port", 8080, "gRPC server port")
-		httpPort      = flag.Int("http-port", 8081, "HTTP server port")
-		cacheCapacity = flag.Int("cache-capacity", 10000, "Cache capacity")
-		maxConcurrent = flag.Int64("max-concurrent", 1000, "Maximum concurrent requests")
-		cpuThreshold  = flag.Float64("cpu-threshold", 0.9, "CPU threshold for load shedding")
-		cpuWindow     = flag.Duration("cpu-window", 10*time.Second, "CPU monitoring window")
-	)
-	flag.Parse()
+	config, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 	
-	config := &server.Config{
-		GRPCPort:      *grpcPort,
-		HTTPPort:      *httpPort,
-		CacheCapacity: *cacheCapacity,
-		MaxConcurrent: *maxConcurrent,
-		CPUThreshold:  *cpuThreshold,
-		CPUWindow:     *cpuWindow,
+	// SIGHUP re-reads the command line and config file
+	config.ReloadConfig = func() (*server.Config, error) {
+		return loadConfig(os.Args[1:])
 	}
 	
 	srv, err := server.NewServer(config)
@@ -34,9 +32,138 @@ func main() {
 		log.Fatalf("Failed to create server: %v", err)
 	}
 	
-	fmt.Printf("Starting cache server on gRPC port %d, HTTP port %d\n", *grpcPort, *httpPort)
+	fmt.Printf("Starting cache server on gRPC %s, HTTP %s\n", config.GRPCListenAddr(), config.HTTPListenAddr())
 	
 	if err := srv.Start(); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
-} 
\ No newline at end of file
+}
+
+// loadConfig parses the flags in args followed by those in the file named by
+// -config-file, one per line, so that settings in the file take precedence. Blank
+// lines and lines starting with # are skipped.
+func loadConfig(args []string) (*server.Config, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	var (
+		grpcPort      = fs.Int("grpc-port", 8080, "gRPC server port")
+		httpPort      = fs.Int("http-port", 8081, "HTTP server port")
+		grpcAddr      = fs.String("grpc-addr", "", "host:port the gRPC server listens on, overriding -grpc-port")
+		httpAddr      = fs.String("http-addr", "", "host:port the HTTP server listens on, overriding -http-port")
+		cacheCapacity = fs.Int("cache-capacity", 10000, "Cache capacity")
+		maxConcurrent = fs.Int64("max-concurrent", 1000, "Maximum concurrent requests")
+		cpuThreshold  = fs.Float64("cpu-threshold", 0.9, "CPU threshold for load shedding")
+		cpuWindow     = fs.Duration("cpu-window", 10*time.Second, "CPU monitoring window")
+		cleanup       = fs.Duration("cleanup-interval", 5*time.Minute, "Interval between expired entry cleanups")
+		tombstoneTTL  = fs.Duration("tombstone-ttl", 10*time.Minute, "How long deletes are remembered to prevent resurrection")
+		enableAdmin   = fs.Bool("enable-admin", false, "Expose the admin gRPC service")
+		warmupFile    = fs.String("warmup-file", "", "Snapshot or JSON lines file to preload the cache from on startup")
+		snapshotFile  = fs.String("snapshot-file", "", "File the cache is saved to on graceful shutdown")
+		snapshotFmt   = fs.String("snapshot-format", "binary", "Snapshot record encoding: binary or json")
+		evictBatch    = fs.Int("evict-batch", 1, "Entries evicted at a time once the cache is over capacity")
+		maxKeyBytes   = fs.Int("max-key-bytes", server.DefaultMaxKeyBytes, "Longest key accepted, in bytes")
+		maxRecvMsg    = fs.Int("max-recv-msg-size", server.DefaultMaxMsgSize, "Largest gRPC message accepted, in bytes")
+		maxSendMsg    = fs.Int("max-send-msg-size", server.DefaultMaxMsgSize, "Largest gRPC message sent, in bytes")
+		ttlJitter     = fs.Float64("ttl-jitter", 0, "Fraction by which entry TTLs are randomly spread, e.g. 0.1 for ±10%")
+		emaAlpha      = fs.Float64("ema-alpha", 0.2, "Smoothing factor for load averages reported in stats")
+		evictWarnRate = fs.Float64("eviction-warn-rate", 0, "Evictions per second above which a warning is logged (0 to disable)")
+		evictWarnIntv = fs.Duration("eviction-warn-interval", time.Minute, "Least time between eviction rate warnings")
+		historySize   = fs.Int("stats-history-size", 60, "Per-second cache stats samples kept for /metrics/history")
+		eventSinkURL  = fs.String("event-sink-url", "", "Webhook URL that expiry and eviction events are posted to")
+		policy        = fs.String("eviction-policy", "lru", "Cache admission policy: lru or tinylfu")
+		eviction      = fs.String("eviction", "lru", "Cache eviction strategy: lru, fifo or random")
+		lockTiming    = fs.Bool("lock-timing", false, "Record cache lock wait times in /metrics")
+		bloomFilter   = fs.Bool("bloom-filter", false, "Answer reads of keys never set from a bloom filter")
+		bloomRebuild  = fs.Duration("bloom-rebuild-interval", time.Minute, "How often the bloom filter is rebuilt from the live keys")
+		offHeap       = fs.Bool("off-heap", false, "Store cached values in memory-mapped regions outside the Go heap")
+		tenantDelim   = fs.String("tenant-delimiter", "", "Break down /metrics by the key prefix before this delimiter")
+		maxTenants    = fs.Int("max-tenants", 100, "Tenant labels tracked before others are counted as \"other\"")
+		maxDuration   = fs.Duration("max-request-duration", 0, "Longest a request may run before it is canceled (0 for no limit)")
+		queueTimeout  = fs.Duration("queue-timeout", 0, "Longest a request waits for a concurrency slot before it is rejected (0 to reject at once)")
+		maxQueued     = fs.Int("max-queued", 0, "Requests that may wait for a concurrency slot at once before others are rejected (0 for no limit)")
+		slowThreshold = fs.Duration("slow-threshold", 0, "Log a warning for requests whose handler runs longer than this (0 to disable)")
+		prefixTTLs    = fs.String("prefix-ttls", "", "Default TTLs for writes without one, as prefix=ttl pairs such as session:=30m,=1h")
+		configFile    = fs.String("config-file", "", "File of additional flags, one per line, re-read on SIGHUP")
+	)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		
+		var fileArgs []string
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				fileArgs = append(fileArgs, line)
+			}
+		}
+		if err := fs.Parse(fileArgs); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", *configFile, err)
+		}
+	}
+	
+	evictionPolicy, err := cache.ParsePolicy(*policy)
+	if err != nil {
+		return nil, err
+	}
+	evictionStrategy, err := cache.ParseEviction(*eviction)
+	if err != nil {
+		return nil, err
+	}
+	snapshotFormat, err := server.ParseSnapshotFormat(*snapshotFmt)
+	if err != nil {
+		return nil, err
+	}
+	defaultTTLs, err := server.ParsePrefixTTLs(*prefixTTLs)
+	if err != nil {
+		return nil, err
+	}
+	
+	config := &server.Config{
+		GRPCPort:             *grpcPort,
+		HTTPPort:             *httpPort,
+		GRPCAddr:             *grpcAddr,
+		HTTPAddr:             *httpAddr,
+		CacheCapacity:        *cacheCapacity,
+		MaxConcurrent:        *maxConcurrent,
+		CPUThreshold:         *cpuThreshold,
+		CPUWindow:            *cpuWindow,
+		CleanupInterval:      *cleanup,
+		TombstoneTTL:         *tombstoneTTL,
+		EnableAdmin:          *enableAdmin,
+		WarmupFile:           *warmupFile,
+		SnapshotFile:         *snapshotFile,
+		SnapshotFormat:       snapshotFormat,
+		EvictBatch:           *evictBatch,
+		MaxKeyBytes:          *maxKeyBytes,
+		MaxRecvMsgSize:       *maxRecvMsg,
+		MaxSendMsgSize:       *maxSendMsg,
+		TTLJitter:            *ttlJitter,
+		EMAAlpha:             *emaAlpha,
+		EvictionWarnRate:     *evictWarnRate,
+		EvictionWarnInterval: *evictWarnIntv,
+		StatsHistorySize:     *historySize,
+		EventSinkURL:         *eventSinkURL,
+		EvictionPolicy:       evictionPolicy,
+		Eviction:             evictionStrategy,
+		LockTiming:           *lockTiming,
+		BloomFilter:          *bloomFilter,
+		BloomRebuildInterval: *bloomRebuild,
+		OffHeapValues:        *offHeap,
+		MaxTenants:           *maxTenants,
+		MaxRequestDuration:   *maxDuration,
+		QueueTimeout:         *queueTimeout,
+		MaxQueued:            *maxQueued,
+		SlowThreshold:        *slowThreshold,
+		PrefixTTLs:           defaultTTLs,
+	}
+	if *tenantDelim != "" {
+		config.TenantLabel = server.TenantPrefix(*tenantDelim)
+	}
+	
+	return config, nil
+}
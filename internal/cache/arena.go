@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"runtime"
+)
+
+const (
+	// arenaMinShift is the log2 of the smallest slot; shorter values are rounded up to it
+	arenaMinShift = 6
+
+	// arenaChunkShift is the log2 of the size of each region values are carved from;
+	// longer values get a region of their own
+	arenaChunkShift = 22
+
+	// arenaClasses is the number of slot sizes, one per power of two from the smallest
+	// slot to a whole chunk
+	arenaClasses = arenaChunkShift - arenaMinShift + 1
+)
+
+// arenaRef locates a value held by a valueArena
+type arenaRef struct {
+	chunk  uint32 // Index into chunks plus one; zero if the value is not in the arena
+	offset uint32
+	length uint32
+}
+
+// valueArena stores value bytes outside the Go heap, in memory-mapped regions where
+// the platform supports it, so that a large cache leaves the garbage collector only
+// its small entries to track. Each region is split into slots of one power-of-two
+// size, and values are copied into a free slot of the smallest size that fits them:
+// up to half of a slot can go unused. Freed slots are reused by later values of the
+// same size class but their regions stay mapped until the arena is released, except
+// for values longer than a region, which get a region of their own that is unmapped
+// when they are freed. The arena is not safe for concurrent use; the cache's lock
+// guards it.
+type valueArena struct {
+	chunks    [][]byte                 // Mapped regions; nil once a dedicated region is unmapped
+	classes   []int                    // Slot size class of each region; -1 for a dedicated region
+	freeSlots [arenaClasses][]arenaRef // Freed slots of each class, reused first
+	filling   [arenaClasses]uint32     // Region of each class with unused slots, as in arenaRef
+	next      [arenaClasses]uint32     // Offset of the first unused slot in that region
+	unmapped  []uint32                 // Indexes of unmapped dedicated regions, reused first
+	mapped    uint64                   // Bytes mapped
+	used      uint64                   // Bytes of the values stored
+}
+
+// newValueArena creates an empty arena whose regions are unmapped once it becomes
+// unreachable, so a discarded cache does not leak them
+func newValueArena() *valueArena {
+	a := &valueArena{}
+	runtime.SetFinalizer(a, (*valueArena).release)
+	return a
+}
+
+// sizeClass returns the class of the smallest slot holding n bytes, or -1 if n is
+// longer than a region
+func sizeClass(n int) int {
+	if n > 1<<arenaChunkShift {
+		return -1
+	}
+	if n <= 1<<arenaMinShift {
+		return 0
+	}
+	return bits.Len(uint(n-1)) - arenaMinShift
+}
+
+// alloc copies a non-empty value into the arena
+func (a *valueArena) alloc(value []byte) (arenaRef, error) {
+	if uint64(len(value)) > math.MaxUint32 {
+		return arenaRef{}, fmt.Errorf("value of %d bytes is too long for the arena", len(value))
+	}
+	
+	var ref arenaRef
+	class := sizeClass(len(value))
+	switch {
+	case class < 0:
+		chunk, err := a.mapChunk(len(value), -1)
+		if err != nil {
+			return arenaRef{}, err
+		}
+		ref = arenaRef{chunk: chunk}
+	case len(a.freeSlots[class]) > 0:
+		last := len(a.freeSlots[class]) - 1
+		ref = a.freeSlots[class][last]
+		a.freeSlots[class] = a.freeSlots[class][:last]
+	default:
+		slot := uint32(1) << (arenaMinShift + class)
+		if a.filling[class] == 0 || a.next[class]+slot > 1<<arenaChunkShift {
+			chunk, err := a.mapChunk(1<<arenaChunkShift, class)
+			if err != nil {
+				return arenaRef{}, err
+			}
+			a.filling[class], a.next[class] = chunk, 0
+		}
+		ref = arenaRef{chunk: a.filling[class], offset: a.next[class]}
+		a.next[class] += slot
+	}
+	
+	ref.length = uint32(len(value))
+	copy(a.chunks[ref.chunk-1][ref.offset:], value)
+	a.used += uint64(len(value))
+	return ref, nil
+}
+
+// mapChunk maps a region of size bytes for a slot size class, returning its index as
+// in arenaRef
+func (a *valueArena) mapChunk(size int, class int) (uint32, error) {
+	chunk, err := mapMemory(size)
+	if err != nil {
+		return 0, err
+	}
+	a.mapped += uint64(len(chunk))
+	
+	if n := len(a.unmapped); n > 0 {
+		index := a.unmapped[n-1]
+		a.unmapped = a.unmapped[:n-1]
+		a.chunks[index], a.classes[index] = chunk, class
+		return index + 1, nil
+	}
+	a.chunks = append(a.chunks, chunk)
+	a.classes = append(a.classes, class)
+	return uint32(len(a.chunks)), nil
+}
+
+// read returns the bytes of a value. The slice aliases the arena: it must be copied
+// before the lock is released, since the slot may be reused once the value is freed.
+func (a *valueArena) read(ref arenaRef) []byte {
+	return a.chunks[ref.chunk-1][ref.offset : ref.offset+ref.length]
+}
+
+// free releases the slot of a value, unmapping it if it had a region of its own
+func (a *valueArena) free(ref arenaRef) {
+	a.used -= uint64(ref.length)
+	
+	index := ref.chunk - 1
+	class := a.classes[index]
+	if class >= 0 {
+		a.freeSlots[class] = append(a.freeSlots[class], arenaRef{chunk: ref.chunk, offset: ref.offset})
+		return
+	}
+	
+	a.mapped -= uint64(len(a.chunks[index]))
+	unmapMemory(a.chunks[index])
+	a.chunks[index] = nil
+	a.unmapped = append(a.unmapped, index)
+}
+
+// release unmaps every region. The arena is left empty and may be reused.
+func (a *valueArena) release() {
+	for _, chunk := range a.chunks {
+		if chunk != nil {
+			unmapMemory(chunk)
+		}
+	}
+	*a = valueArena{}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package cache
+
+// mapMemory allocates a region of size bytes. Without mmap support the region is on
+// the Go heap, but as one object it still spares the collector tracking every value.
+func mapMemory(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+// unmapMemory leaves a region from mapMemory to the garbage collector
+func unmapMemory(region []byte) {}
@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package cache
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// mapMemory maps an anonymous private region of size bytes outside the Go heap
+func mapMemory(size int) ([]byte, error) {
+	region, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map %d bytes: %w", size, err)
+	}
+	return region, nil
+}
+
+// unmapMemory returns a region from mapMemory to the operating system
+func unmapMemory(region []byte) {
+	syscall.Munmap(region)
+}
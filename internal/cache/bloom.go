@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// bloomFalsePositiveRate is the false positive rate the filter is sized for when the
+// cache is full and the filter has just been rebuilt
+const bloomFalsePositiveRate = 0.01
+
+// bloomFilter records every key inserted into the cache. It can report a key as
+// possibly present when it is not, but never the reverse, so a negative answer lets
+// reads skip the cache. Keys are never removed: deleted and evicted keys stay set
+// until the filter is rebuilt from the live keys. Bits are set and tested atomically
+// so that lookups need no lock.
+type bloomFilter struct {
+	words  []uint64
+	size   uint64 // Number of bits
+	hashes int
+}
+
+// newBloomFilter creates an empty filter sized for a cache holding capacity entries
+func newBloomFilter(capacity int) *bloomFilter {
+	n := math.Max(float64(capacity), 1)
+	size := uint64(math.Ceil(-n * math.Log(bloomFalsePositiveRate) / (math.Ln2 * math.Ln2)))
+	words := (size + 63) / 64
+	
+	return &bloomFilter{
+		words:  make([]uint64, words),
+		size:   words * 64,
+		hashes: int(math.Max(math.Round(float64(words*64)/n*math.Ln2), 1)),
+	}
+}
+
+// positions calls fn with each bit position of a key until it returns false, deriving
+// the positions from two halves of one 64-bit hash
+func (f *bloomFilter) positions(key string, fn func(pos uint64) bool) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+	for i := 0; i < f.hashes; i++ {
+		if !fn((h1 + uint64(i)*h2) % f.size) {
+			return
+		}
+	}
+}
+
+// add sets the bits of a key
+func (f *bloomFilter) add(key string) {
+	f.positions(key, func(pos uint64) bool {
+		word, bit := &f.words[pos/64], uint64(1)<<(pos%64)
+		for {
+			old := atomic.LoadUint64(word)
+			if old&bit != 0 || atomic.CompareAndSwapUint64(word, old, old|bit) {
+				return true
+			}
+		}
+	})
+}
+
+// mayContain reports whether a key may have been added; false means it never was
+func (f *bloomFilter) mayContain(key string) bool {
+	found := true
+	f.positions(key, func(pos uint64) bool {
+		found = atomic.LoadUint64(&f.words[pos/64])&(uint64(1)<<(pos%64)) != 0
+		return found
+	})
+	return found
+}
+
+// falsePositiveRate estimates the chance that an absent key is reported as possibly
+// present, from the fraction of bits set
+func (f *bloomFilter) falsePositiveRate() float64 {
+	set := 0
+	for i := range f.words {
+		set += bits.OnesCount64(atomic.LoadUint64(&f.words[i]))
+	}
+	return math.Pow(float64(set)/float64(f.size), float64(f.hashes))
+}
@@ -1,103 +1,937 @@
 package cache
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// DefaultTombstoneTTL is how long a tombstone is retained after a versioned delete
+const DefaultTombstoneTTL = 10 * time.Minute
+
+// evictionSample is how many entries from the LRU tail are considered for eviction
+const evictionSample = 5
+
+// forEachChunk is how many entries ForEach reads per lock acquisition
+const forEachChunk = 1000
+
+// promoteDivisor sets the share of the capacity, counted from the front of the LRU
+// list, within which entries that are read stay in place: with the default of 4 a
+// read only moves an entry that has fallen out of the most recently used quarter
+const promoteDivisor = 4
+
+// CostFunc computes the eviction cost of an entry; higher cost entries are kept longer
+type CostFunc func(key string, value []byte) int
+
+// Clock tells the cache the current time, against which writes are timestamped and
+// TTLs are set and checked. Tests can supply one that is advanced by hand instead of
+// sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// wallClock is the default Clock, reading the system time
+type wallClock struct{}
+
+// Now returns the system time
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
 // Entry represents a cache entry
 type Entry struct {
 	Key       string
 	Value     []byte
+	CreatedAt time.Time // When the current value or tombstone was written
 	ExpiresAt time.Time
+	Version   uint64
+	Tombstone bool // Deleted at Version; retained until ExpiresAt
+	Cost      int
+	priority  int64  // Inflation at last access plus Cost; lowest is evicted first
+	promoted  uint64 // The cache's promotion count when last moved to the front
+	pinned    bool   // Exempt from eviction
 	Prev      *Entry
 	Next      *Entry
+	ref       arenaRef // Location of the value while it is stored off-heap, when Value is nil
+}
+
+// EvictionReason says why an entry left the cache without being deleted
+type EvictionReason int
+
+const (
+	// EvictionExpired means the entry's TTL lapsed
+	EvictionExpired EvictionReason = iota
+
+	// EvictionCapacity means the entry was evicted to make room
+	EvictionCapacity
+)
+
+// String returns the reason's name
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionExpired:
+		return "expired"
+	case EvictionCapacity:
+		return "evicted"
+	default:
+		return fmt.Sprintf("EvictionReason(%d)", int(r))
+	}
+}
+
+// Policy decides which new keys the cache admits once it is full
+type Policy int
+
+const (
+	// PolicyLRU admits every new key, evicting an entry to make room
+	PolicyLRU Policy = iota
+
+	// PolicyTinyLFU keeps an approximate, decaying count of recent reads of every key,
+	// including misses, and admits a new key into a full cache only if it has been read
+	// more often than the entry that would be evicted for it. A scan of keys read once
+	// then cannot flush out a frequently read working set. Writes are not counted; a
+	// key that was never read is admitted only in place of an entry not read recently.
+	PolicyTinyLFU
+)
+
+// String returns the policy's name
+func (p Policy) String() string {
+	switch p {
+	case PolicyLRU:
+		return "lru"
+	case PolicyTinyLFU:
+		return "tinylfu"
+	default:
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+}
+
+// ParsePolicy returns the policy with the given name, "lru" or "tinylfu"
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "lru":
+		return PolicyLRU, nil
+	case "tinylfu":
+		return PolicyTinyLFU, nil
+	default:
+		return 0, fmt.Errorf("unknown cache policy %q", name)
+	}
+}
+
+// Eviction decides which entry is evicted to make room
+type Eviction int
+
+const (
+	// EvictLRU evicts among the least recently used entries, preferring those with
+	// the lowest cost (GreedyDual); with uniform costs this is plain LRU
+	EvictLRU Eviction = iota
+
+	// EvictFIFO evicts the oldest inserted entry, ignoring reads and costs. Updating a
+	// key keeps its place, and Keys and Snapshot list the newest insertions first.
+	EvictFIFO
+
+	// EvictRandom evicts an arbitrary entry, avoiding any bookkeeping on reads
+	EvictRandom
+)
+
+// String returns the eviction strategy's name
+func (e Eviction) String() string {
+	switch e {
+	case EvictLRU:
+		return "lru"
+	case EvictFIFO:
+		return "fifo"
+	case EvictRandom:
+		return "random"
+	default:
+		return fmt.Sprintf("Eviction(%d)", int(e))
+	}
+}
+
+// ParseEviction returns the eviction strategy with the given name: "lru", "fifo" or "random"
+func ParseEviction(name string) (Eviction, error) {
+	switch name {
+	case "lru":
+		return EvictLRU, nil
+	case "fifo":
+		return EvictFIFO, nil
+	case "random":
+		return EvictRandom, nil
+	default:
+		return 0, fmt.Errorf("unknown eviction strategy %q", name)
+	}
+}
+
+// EvictionListener is notified when a live entry expires or is evicted. Tombstones
+// are not reported.
+type EvictionListener func(key string, value []byte, reason EvictionReason)
+
+// KV is a single item for SetMany and Replace, or returned by Snapshot and Range
+type KV struct {
+	Key   string
+	Value []byte
+	TTL   time.Duration
 }
 
 // Cache implements an LRU cache with TTL support
 type Cache struct {
-	mu       sync.RWMutex
-	entries  map[string]*Entry
-	head     *Entry // Most recently used
-	tail     *Entry // Least recently used
-	capacity int
-	size     int
+	mu           sync.RWMutex
+	entries      map[string]*Entry
+	head         *Entry // Most recently used
+	tail         *Entry // Least recently used
+	capacity     int
+	size         int
+	tombstoneTTL time.Duration
+	costFunc     CostFunc
+	inflation    int64 // Priority of the last evicted entry
+	evictBatch   int   // Entries the cache may exceed capacity by before evicting down to it
+	ttlJitter    float64 // Fraction by which TTLs are randomly lengthened or shortened
+	hits         uint64
+	misses       uint64 // Updated atomically with hits, as reads count under the read lock
+	promotions   uint64 // Entries moved or added to the front of the list
+	evictions    uint64 // Entries evicted to make room; updated atomically
+	pinned       int    // Entries exempt from eviction
+	listeners    []EvictionListener
+	eviction     Eviction
+	sketch       *frequencySketch            // Access frequencies; nil unless the policy is TinyLFU
+	rejected     uint64                      // New keys refused by the admission policy
+	lockTiming   int32                       // Non-zero while lock waits are timed; accessed atomically
+	lockWaits    uint64                      // Lock acquisitions timed
+	lockWaitNs   uint64                      // Total time spent waiting for those acquisitions
+	bloom        atomic.Pointer[bloomFilter] // Keys inserted since the last rebuild; nil unless enabled
+	bloomRejects uint64                      // Reads answered by the bloom filter alone
+	arena        *valueArena                 // Holds value bytes off-heap; nil unless enabled
+	cipher       *valueCipher                // Encrypts stored values; nil unless enabled
+	decryptFails uint64                      // Reads of values that failed to decrypt; updated atomically
+	clock        Clock                       // Source of the current time for timestamps and TTLs
 }
 
-// NewCache creates a new cache with the specified capacity
+// NewCache creates a new LRU cache with the specified capacity
 func NewCache(capacity int) *Cache {
+	return NewCacheWithPolicy(capacity, EvictLRU)
+}
+
+// NewCacheWithPolicy creates a new cache with the specified capacity that evicts
+// according to eviction
+func NewCacheWithPolicy(capacity int, eviction Eviction) *Cache {
 	cache := &Cache{
-		entries:  make(map[string]*Entry),
-		capacity: capacity,
+		entries:      make(map[string]*Entry),
+		capacity:     capacity,
+		tombstoneTTL: DefaultTombstoneTTL,
+		evictBatch:   1,
+		eviction:     eviction,
+		clock:        wallClock{},
 	}
 	return cache
 }
 
-// Get retrieves a value from the cache
-func (c *Cache) Get(key string) ([]byte, bool) {
+// NewCacheWithClock creates a new LRU cache with the specified capacity that reads
+// the current time from clock, or the system clock if it is nil
+func NewCacheWithClock(capacity int, clock Clock) *Cache {
+	cache := NewCache(capacity)
+	if clock != nil {
+		cache.clock = clock
+	}
+	return cache
+}
+
+// NewCacheWithEncryption creates a new LRU cache with the specified capacity that
+// encrypts every value it stores with AES-GCM under key, which must be 16, 24 or 32
+// bytes long, choosing AES-128, AES-192 or AES-256. Keys stay in plaintext for lookup.
+//
+// Values are sealed on every write and opened on every read, including Snapshot,
+// ForEach and the eviction listeners, so callers only ever see plaintext. This costs
+// a random nonce and an encryption per write, a decryption into a new copy per read,
+// and 28 bytes of nonce and tag per value. With hardware AES and 256-byte values,
+// BenchmarkCacheEncryptedSet and BenchmarkCacheEncryptedGetHit take about 0.4µs more
+// per write and 0.5µs more per read, a read taking about four times as long, with
+// two allocations each; the gap grows with value size.
+func NewCacheWithEncryption(capacity int, key []byte) (*Cache, error) {
+	cipher, err := newValueCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	cache := NewCache(capacity)
+	cache.cipher = cipher
+	return cache, nil
+}
+
+// SetEvictBatch sets how many entries are evicted at a time. Once the cache holds
+// capacity+batch-1 entries the next insert evicts back down to capacity in one pass,
+// so with a batch above 1 the size may exceed capacity by up to batch-1 entries.
+// A batch of 1, the default, keeps size at or below capacity after every Set.
+func (c *Cache) SetEvictBatch(batch int) {
+	if batch < 1 {
+		batch = 1
+	}
+	
+	c.lock()
+	defer c.mu.Unlock()
+	c.evictBatch = batch
+}
+
+// SetLockTiming turns timing of the cache's lock on or off. While on, every operation
+// records how long it waited to acquire the lock, reported by GetStats as lock_waits
+// and lock_wait; this costs two clock reads per operation.
+func (c *Cache) SetLockTiming(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&c.lockTiming, value)
+}
+
+// lock acquires the write lock, timing the wait if lock timing is on
+func (c *Cache) lock() {
+	if atomic.LoadInt32(&c.lockTiming) == 0 {
+		c.mu.Lock()
+		return
+	}
+	
+	start := time.Now()
 	c.mu.Lock()
+	c.recordLockWait(start)
+}
+
+// rlock acquires the read lock, timing the wait if lock timing is on
+func (c *Cache) rlock() {
+	if atomic.LoadInt32(&c.lockTiming) == 0 {
+		c.mu.RLock()
+		return
+	}
+	
+	start := time.Now()
+	c.mu.RLock()
+	c.recordLockWait(start)
+}
+
+// recordLockWait adds the time since start to the lock wait totals
+func (c *Cache) recordLockWait(start time.Time) {
+	atomic.AddUint64(&c.lockWaitNs, uint64(time.Since(start)))
+	atomic.AddUint64(&c.lockWaits, 1)
+}
+
+// SetBloomFilter turns the bloom filter on or off. While on, reads of keys the filter
+// has never seen return without looking them up, and Exists, Peek and PeekEntry answer
+// for such keys without taking the lock. Deleted and evicted keys stay in the filter,
+// raising its false positive rate, until RebuildBloomFilter is called.
+func (c *Cache) SetBloomFilter(enabled bool) {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	if !enabled {
+		c.bloom.Store(nil)
+		return
+	}
+	c.rebuildBloomFilter()
+}
+
+// RebuildBloomFilter replaces the bloom filter with one holding only the keys now in
+// the cache, dropping those deleted or evicted since the last rebuild. It does nothing
+// while the filter is off.
+func (c *Cache) RebuildBloomFilter() {
+	c.rlock()
+	defer c.mu.RUnlock()
+	
+	if c.bloom.Load() != nil {
+		c.rebuildBloomFilter()
+	}
+}
+
+// rebuildBloomFilter builds a filter sized for the capacity from the keys in the map,
+// tombstones included; the caller must hold the lock so that no insert is missed
+func (c *Cache) rebuildBloomFilter() {
+	filter := newBloomFilter(c.capacity)
+	for key := range c.entries {
+		filter.add(key)
+	}
+	c.bloom.Store(filter)
+}
+
+// definitelyAbsent reports whether the bloom filter rules out a key being in the cache
+func (c *Cache) definitelyAbsent(key string) bool {
+	filter := c.bloom.Load()
+	if filter == nil || filter.mayContain(key) {
+		return false
+	}
+	atomic.AddUint64(&c.bloomRejects, 1)
+	return true
+}
+
+// bloomFalsePositiveRate estimates the bloom filter's false positive rate, or returns
+// zero while it is off
+func (c *Cache) bloomFalsePositiveRate() float64 {
+	filter := c.bloom.Load()
+	if filter == nil {
+		return 0
+	}
+	return filter.falsePositiveRate()
+}
+
+// SetOffHeap moves value bytes out of the Go heap into memory-mapped regions managed
+// by the cache, or back onto the heap. With many large values this shrinks the heap
+// the garbage collector has to track to the entries themselves. Off-heap values are
+// copied on every read, so Get, Peek, ForEach and the other reads return copies rather
+// than sharing storage with the cache. Storage is allocated in power-of-two slots, so
+// up to half of each slot may go unused, and it is only returned to the operating
+// system by Clear, Replace or turning the option off. A value that cannot be mapped is
+// kept on the heap.
+func (c *Cache) SetOffHeap(enabled bool) {
+	c.lock()
 	defer c.mu.Unlock()
+	c.setOffHeap(enabled)
+}
+
+// setOffHeap moves every value into a new arena or out of the current one; the caller
+// must hold the lock
+func (c *Cache) setOffHeap(enabled bool) {
+	switch {
+	case enabled && c.arena == nil:
+		c.arena = newValueArena()
+		for _, entry := range c.entries {
+			c.placeValue(entry, entry.Value)
+		}
+	case !enabled && c.arena != nil:
+		for _, entry := range c.entries {
+			if entry.ref.chunk != 0 {
+				entry.Value = append([]byte(nil), c.arena.read(entry.ref)...)
+				entry.ref = arenaRef{}
+			}
+		}
+		c.arena.release()
+		c.arena = nil
+	}
+}
+
+// storeValue sets the value of an entry, encrypting it if encryption is on; the
+// caller must hold the lock
+func (c *Cache) storeValue(entry *Entry, value []byte) {
+	if c.cipher != nil {
+		value = c.cipher.seal(entry.Key, value)
+	}
+	c.placeValue(entry, value)
+}
+
+// placeValue sets the stored bytes of an entry, copying them off-heap if the arena is
+// on; the caller must hold the lock
+func (c *Cache) placeValue(entry *Entry, value []byte) {
+	c.releaseValue(entry)
+	if c.arena != nil && len(value) > 0 {
+		if ref, err := c.arena.alloc(value); err == nil {
+			entry.Value, entry.ref = nil, ref
+			return
+		}
+	}
+	entry.Value = value
+}
+
+// releaseValue frees the off-heap storage of an entry's value, if it has any; the
+// caller must hold the lock
+func (c *Cache) releaseValue(entry *Entry) {
+	if entry.ref.chunk != 0 {
+		c.arena.free(entry.ref)
+		entry.ref = arenaRef{}
+	}
+}
+
+// value returns the value of an entry, decrypting it if encryption is on and copying
+// it if it is stored off-heap so that the result stays valid once the lock is
+// released. It reports false, counting a decryption failure, for a value that fails
+// to decrypt, which callers treat as absent. The caller must hold the lock.
+func (c *Cache) value(entry *Entry) ([]byte, bool) {
+	stored := entry.Value
+	if entry.ref.chunk != 0 {
+		stored = c.arena.read(entry.ref)
+	}
+	if c.cipher != nil {
+		value, err := c.cipher.open(entry.Key, stored)
+		if err != nil {
+			atomic.AddUint64(&c.decryptFails, 1)
+			return nil, false
+		}
+		return value, true
+	}
+	if entry.ref.chunk == 0 {
+		return stored, true
+	}
+	return append([]byte(nil), stored...), true
+}
+
+// SetTTLJitter spreads expiry times by randomly scaling each TTL within
+// ±jitter of its value, so keys written together with the same TTL do not all
+// expire at once. The jitter is clamped to [0, 1); zero disables it.
+func (c *Cache) SetTTLJitter(jitter float64) {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter >= 1 {
+		jitter = 0.99
+	}
 	
+	c.lock()
+	defer c.mu.Unlock()
+	c.ttlJitter = jitter
+}
+
+// jitteredTTL applies the configured jitter to a positive TTL, never returning
+// less than a nanosecond; the caller must hold the lock
+func (c *Cache) jitteredTTL(ttl time.Duration) time.Duration {
+	if c.ttlJitter == 0 || ttl <= 0 {
+		return ttl
+	}
+	
+	factor := 1 + c.ttlJitter*(2*rand.Float64()-1)
+	jittered := time.Duration(float64(ttl) * factor)
+	if jittered <= 0 {
+		return time.Nanosecond
+	}
+	return jittered
+}
+
+// OnEvict registers a listener for expired and evicted entries. Listeners run
+// synchronously while the cache is locked, so they must be quick and must not
+// call back into the cache.
+func (c *Cache) OnEvict(listener EvictionListener) {
+	c.lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// SetPolicy selects the admission policy; the default is PolicyLRU. Switching to
+// PolicyTinyLFU starts with no access history.
+func (c *Cache) SetPolicy(policy Policy) {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.sketch = nil
+	if policy == PolicyTinyLFU {
+		c.sketch = newFrequencySketch(c.capacity)
+	}
+}
+
+// SetTombstoneTTL sets how long tombstones are retained after a versioned delete
+func (c *Cache) SetTombstoneTTL(ttl time.Duration) {
+	c.lock()
+	defer c.mu.Unlock()
+	c.tombstoneTTL = ttl
+}
+
+// Get retrieves a value from the cache. Misses, and hits on entries that need not be
+// moved to the front, are served under the read lock so that concurrent reads do not
+// serialize; only expiring or promoting an entry takes the write lock.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.rlock()
+	if entry, ok := c.lookupShared(key); ok {
+		defer c.mu.RUnlock()
+		if entry == nil || entry.Tombstone {
+			atomic.AddUint64(&c.misses, 1)
+			return nil, false
+		}
+		value, ok := c.value(entry)
+		if !ok {
+			atomic.AddUint64(&c.misses, 1)
+			return nil, false
+		}
+		atomic.AddUint64(&c.hits, 1)
+		return value, true
+	}
+	c.mu.RUnlock()
+	
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.recordAccess(key)
+	if c.definitelyAbsent(key) {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
 	entry, exists := c.entries[key]
 	if !exists {
+		atomic.AddUint64(&c.misses, 1)
 		return nil, false
 	}
 	
 	// Check if expired
-	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
-		c.removeEntry(entry)
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		c.expire(entry)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+	
+	value, ok := c.value(entry)
+	if entry.Tombstone || !ok {
+		atomic.AddUint64(&c.misses, 1)
 		return nil, false
 	}
 	
 	// Move to front (most recently used)
-	c.moveToFront(entry)
+	c.touch(entry)
+	atomic.AddUint64(&c.hits, 1)
 	
-	return entry.Value, true
+	return value, true
+}
+
+// GetAndTouch returns the values of the live keys among keys and resets their expiry
+// to ttl from now, atomically with the read. A ttl of zero removes the expiry. Missing,
+// expired and deleted keys are left out of the result and no entry is created for them.
+func (c *Cache) GetAndTouch(keys []string, ttl time.Duration) map[string][]byte {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		c.recordAccess(key)
+		entry := c.liveEntry(key)
+		if entry == nil || entry.Tombstone {
+			atomic.AddUint64(&c.misses, 1)
+			continue
+		}
+		value, ok := c.value(entry)
+		if !ok {
+			atomic.AddUint64(&c.misses, 1)
+			continue
+		}
+		
+		if ttl > 0 {
+			entry.ExpiresAt = c.clock.Now().Add(c.jitteredTTL(ttl))
+		} else {
+			entry.ExpiresAt = time.Time{}
+		}
+		c.touch(entry)
+		atomic.AddUint64(&c.hits, 1)
+		values[key] = value
+	}
+	return values
+}
+
+// Touch resets the expiry of a live key to ttl from now without rewriting its value,
+// reporting whether the key was present. A ttl of zero removes the expiry. Unlike
+// GetAndTouch it does not count as a read or change the entry's place in the
+// eviction order.
+func (c *Cache) Touch(key string, ttl time.Duration) bool {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	entry := c.liveEntry(key)
+	if entry == nil || entry.Tombstone {
+		return false
+	}
+	
+	if ttl > 0 {
+		entry.ExpiresAt = c.clock.Now().Add(c.jitteredTTL(ttl))
+	} else {
+		entry.ExpiresAt = time.Time{}
+	}
+	return true
+}
+
+// SetCostFunc sets the function used to compute the cost of entries stored with Set.
+// Without one every entry costs 1 and eviction is plain LRU.
+func (c *Cache) SetCostFunc(fn CostFunc) {
+	c.lock()
+	defer c.mu.Unlock()
+	c.costFunc = fn
+}
+
+// Lookup returns a copy of the entry for a key, including tombstones.
+// Live entries are marked as recently used. Like Get, it takes the write lock only
+// to expire or promote the entry.
+func (c *Cache) Lookup(key string) (Entry, bool) {
+	c.rlock()
+	if entry, ok := c.lookupShared(key); ok {
+		defer c.mu.RUnlock()
+		if entry == nil {
+			atomic.AddUint64(&c.misses, 1)
+			return Entry{}, false
+		}
+		copied, ok := c.entryCopy(entry)
+		if !ok || entry.Tombstone {
+			atomic.AddUint64(&c.misses, 1)
+		} else {
+			atomic.AddUint64(&c.hits, 1)
+		}
+		return copied, ok
+	}
+	c.mu.RUnlock()
+	
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.recordAccess(key)
+	if c.definitelyAbsent(key) {
+		atomic.AddUint64(&c.misses, 1)
+		return Entry{}, false
+	}
+	entry, exists := c.entries[key]
+	if !exists {
+		atomic.AddUint64(&c.misses, 1)
+		return Entry{}, false
+	}
+	
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		c.expire(entry)
+		atomic.AddUint64(&c.misses, 1)
+		return Entry{}, false
+	}
+	
+	copied, ok := c.entryCopy(entry)
+	if !ok || entry.Tombstone {
+		atomic.AddUint64(&c.misses, 1)
+	} else {
+		c.touch(entry)
+		atomic.AddUint64(&c.hits, 1)
+	}
+	
+	return copied, ok
+}
+
+// lookupShared finds a key for a read made under the read lock. It returns the live
+// entry or tombstone, or nil if the key is absent, and reports false if the read needs
+// the write lock instead: the entry has expired, the admission policy counts the
+// access, or the entry is due to be moved to the front. The entry may only be used
+// until the lock is released.
+func (c *Cache) lookupShared(key string) (*Entry, bool) {
+	if c.sketch != nil {
+		return nil, false
+	}
+	if c.definitelyAbsent(key) {
+		return nil, true
+	}
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, true
+	}
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		return nil, false
+	}
+	if !entry.Tombstone && c.needsPromotion(entry) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// needsPromotion reports whether a read of an entry should move it to the front of
+// the list. Under LRU an entry is left in place while fewer than capacity/promoteDivisor
+// entries have been moved in front of it since it was, so it is at most that far from
+// where exact LRU would keep it, and caches smaller than promoteDivisor are exact.
+func (c *Cache) needsPromotion(entry *Entry) bool {
+	return c.eviction == EvictLRU && c.promotions-entry.promoted >= uint64(c.capacity/promoteDivisor)
+}
+
+// entryCopy returns a copy of an entry detached from the list and the arena, reporting
+// false if its value fails to decrypt
+func (c *Cache) entryCopy(entry *Entry) (Entry, bool) {
+	result := *entry
+	var ok bool
+	if result.Value, ok = c.value(entry); !ok {
+		return Entry{}, false
+	}
+	result.Prev = nil
+	result.Next = nil
+	result.ref = arenaRef{}
+	return result, true
 }
 
 // Set stores a value in the cache
 func (c *Cache) Set(key string, value []byte, ttl time.Duration) {
-	c.mu.Lock()
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.set(key, value, ttl, 0, c.entryCost(key, value), true)
+}
+
+// SetQuiet stores a value like Set but leaves an existing entry where it is in the
+// eviction order instead of marking it as recently used, so that bulk loads such as
+// warming do not disturb the order that reads have established. New keys are
+// inserted as by Set.
+func (c *Cache) SetQuiet(key string, value []byte, ttl time.Duration) {
+	c.lock()
 	defer c.mu.Unlock()
 	
+	c.set(key, value, ttl, 0, c.entryCost(key, value), false)
+}
+
+// SetMany stores a batch of items under a single lock acquisition, evicting once
+// after the whole batch is inserted rather than per item. Later items win when a
+// key appears more than once, and if the batch is larger than the capacity only
+// its most recent items are kept.
+func (c *Cache) SetMany(items []KV) {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	for _, item := range items {
+		c.put(item.Key, item.Value, item.TTL, 0, c.entryCost(item.Key, item.Value), true)
+	}
+	c.evictOverflow()
+}
+
+// SetWithCost stores a value with an explicit eviction cost. Among the least recently
+// used entries, lower cost entries are evicted first.
+func (c *Cache) SetWithCost(key string, value []byte, ttl time.Duration, cost int) {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.set(key, value, ttl, 0, cost, true)
+}
+
+// SetVersioned stores a value only if version is newer than the current entry or tombstone.
+// It reports whether the write was applied.
+func (c *Cache) SetVersioned(key string, value []byte, ttl time.Duration, version uint64) bool {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	if current := c.liveEntry(key); current != nil && current.Version >= version {
+		return false
+	}
+	
+	c.set(key, value, ttl, version, c.entryCost(key, value), true)
+	return true
+}
+
+// Version returns the version of the unexpired entry or tombstone for a key, without
+// marking it as recently used
+func (c *Cache) Version(key string) (uint64, bool) {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	entry := c.liveEntry(key)
+	if entry == nil {
+		return 0, false
+	}
+	return entry.Version, true
+}
+
+// entryCost returns the cost of an entry using the configured cost function; the caller must hold the lock
+func (c *Cache) entryCost(key string, value []byte) int {
+	if c.costFunc == nil {
+		return 1
+	}
+	return c.costFunc(key, value)
+}
+
+// set stores a value at the given version and cost, evicting if over capacity;
+// the caller must hold the lock
+func (c *Cache) set(key string, value []byte, ttl time.Duration, version uint64, cost int, promote bool) {
+	c.put(key, value, ttl, version, cost, promote)
+	c.evictOverflow()
+}
+
+// put stores a value at the given version and cost without evicting, marking an
+// existing entry as recently used if promote is set; the caller must hold the lock
+func (c *Cache) put(key string, value []byte, ttl time.Duration, version uint64, cost int, promote bool) {
+	if cost < 0 {
+		cost = 0
+	}
+	ttl = c.jitteredTTL(ttl)
+	now := c.clock.Now()
+	
 	// Check if key already exists
 	if existing, exists := c.entries[key]; exists {
 		// Update existing entry
-		existing.Value = value
+		c.storeValue(existing, value)
+		existing.CreatedAt = now
+		existing.Version = version
+		existing.Tombstone = false
+		existing.Cost = cost
 		if ttl > 0 {
-			existing.ExpiresAt = time.Now().Add(ttl)
+			existing.ExpiresAt = now.Add(ttl)
 		} else {
 			existing.ExpiresAt = time.Time{}
 		}
-		c.moveToFront(existing)
+		if promote {
+			c.touch(existing)
+		}
+		return
+	}
+	
+	if !c.admit(key) {
+		c.rejected++
 		return
 	}
 	
 	// Create new entry
 	entry := &Entry{
-		Key:   key,
-		Value: value,
+		Key:       key,
+		CreatedAt: now,
+		Version:   version,
+		Cost:      cost,
 	}
 	if ttl > 0 {
-		entry.ExpiresAt = time.Now().Add(ttl)
+		entry.ExpiresAt = now.Add(ttl)
 	}
+	c.storeValue(entry, value)
+	
+	c.insert(entry)
+}
+
+// insert adds a new entry at the front of the list
+func (c *Cache) insert(entry *Entry) {
+	entry.priority = c.inflation + int64(entry.Cost)
 	
 	// Add to map
-	c.entries[key] = entry
+	c.entries[entry.Key] = entry
+	if filter := c.bloom.Load(); filter != nil {
+		filter.add(entry.Key)
+	}
 	
 	// Add to front of list
 	c.addToFront(entry)
 	c.size++
-	
-	// Evict if necessary
-	if c.size > c.capacity {
-		c.evictLRU()
+}
+
+// recordAccess counts a read of a key for the admission policy; the caller must hold the lock
+func (c *Cache) recordAccess(key string) {
+	if c.sketch != nil {
+		c.sketch.increment(key)
+	}
+}
+
+// admit reports whether a new key may be inserted. Under TinyLFU a full cache only
+// admits a key read more often than the entry it would evict, or any key in place of
+// an entry that has not been read recently, so unread entries never block writes.
+func (c *Cache) admit(key string) bool {
+	if c.sketch == nil || c.size < c.capacity {
+		return true
+	}
+	victim := c.victim()
+	if victim == nil || victim.Tombstone {
+		return true
+	}
+	victimReads := c.sketch.estimate(victim.Key)
+	return victimReads == 0 || c.sketch.estimate(key) > victimReads
+}
+
+// evictOverflow evicts down to capacity once the cache is past the high-water mark
+func (c *Cache) evictOverflow() {
+	if c.size > c.capacity+c.evictBatch-1 {
+		for c.size > c.capacity {
+			if !c.evict() {
+				break
+			}
+		}
+	}
+}
+
+// liveEntry returns the unexpired entry or tombstone for a key; the caller must hold the lock
+func (c *Cache) liveEntry(key string) *Entry {
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil
+	}
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		c.expire(entry)
+		return nil
 	}
+	return entry
 }
 
 // Delete removes a key from the cache
 func (c *Cache) Delete(key string) bool {
-	c.mu.Lock()
+	c.lock()
 	defer c.mu.Unlock()
 	
 	entry, exists := c.entries[key]
@@ -106,53 +940,496 @@ func (c *Cache) Delete(key string) bool {
 	}
 	
 	c.removeEntry(entry)
+	return !entry.Tombstone
+}
+
+// GetAndDelete replaces a key with a tombstone at the given version, as DeleteVersioned
+// does, and returns the entry it held before, atomically, so that of several concurrent
+// calls for one key only the first receives the value and the others the tombstone it
+// left. The tombstone also rejects an older write of the key arriving later, so that a
+// value still being replicated cannot be taken twice. A key already at the version or
+// a newer one is left alone, and a live value held then is not returned. It reports
+// whether it took a live value, counting a hit or miss as Get does.
+func (c *Cache) GetAndDelete(key string, version uint64) (Entry, bool) {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	var held Entry
+	if entry := c.liveEntry(key); entry != nil {
+		held, _ = c.entryCopy(entry)
+	}
+	applied := c.deleteVersioned(key, version)
+	if !applied && !held.Tombstone {
+		held = Entry{}
+	}
+	if !applied || held.Key == "" || held.Tombstone {
+		atomic.AddUint64(&c.misses, 1)
+		return held, false
+	}
+	
+	atomic.AddUint64(&c.hits, 1)
+	return held, true
+}
+
+// DeleteVersioned replaces a key with a tombstone at the given version so that older
+// writes arriving later are rejected instead of resurrecting the value. The tombstone
+// expires after the tombstone TTL. It reports whether the delete was applied.
+func (c *Cache) DeleteVersioned(key string, version uint64) bool {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	return c.deleteVersioned(key, version)
+}
+
+// deleteVersioned applies a versioned delete; the caller must hold the lock
+func (c *Cache) deleteVersioned(key string, version uint64) bool {
+	now := c.clock.Now()
+	expiresAt := now.Add(c.tombstoneTTL)
+	
+	if current := c.liveEntry(key); current != nil {
+		if current.Version >= version {
+			return false
+		}
+		c.releaseValue(current)
+		c.unpin(current)
+		current.Value = nil
+		current.CreatedAt = now
+		current.Version = version
+		current.Tombstone = true
+		current.ExpiresAt = expiresAt
+		current.Cost = 1
+		current.priority = c.inflation + 1
+		return true
+	}
+	
+	c.insert(&Entry{
+		Key:       key,
+		CreatedAt: now,
+		Version:   version,
+		Tombstone: true,
+		ExpiresAt: expiresAt,
+		Cost:      1,
+	})
+	c.evictOverflow()
 	return true
 }
 
-// Size returns the current number of entries
+// DeleteIf replaces a key with a tombstone only if it holds a live value whose version
+// equals expectedVersion, so a value written since it was read is not lost. The
+// tombstone keeps the deleted version, rejecting older writes that arrive later, and
+// expires after the tombstone TTL. It reports whether the delete was applied.
+func (c *Cache) DeleteIf(key string, expectedVersion uint64) bool {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	current := c.liveEntry(key)
+	if current == nil || current.Tombstone || current.Version != expectedVersion {
+		return false
+	}
+	
+	c.releaseValue(current)
+	c.unpin(current)
+	current.Value = nil
+	current.CreatedAt = c.clock.Now()
+	current.Tombstone = true
+	current.ExpiresAt = current.CreatedAt.Add(c.tombstoneTTL)
+	current.Cost = 1
+	current.priority = c.inflation + 1
+	return true
+}
+
+// Size returns the current number of entries, including tombstones
 func (c *Cache) Size() int {
-	c.mu.RLock()
+	c.rlock()
 	defer c.mu.RUnlock()
 	return c.size
 }
 
 // Capacity returns the cache capacity
 func (c *Cache) Capacity() int {
+	c.rlock()
+	defer c.mu.RUnlock()
 	return c.capacity
 }
 
+// Resize changes the cache capacity, evicting least recently used entries until the
+// cache fits when shrinking. It returns the number of entries evicted. Under TinyLFU
+// the access history is resized, and so starts over.
+func (c *Cache) Resize(capacity int) (int, error) {
+	if capacity <= 0 {
+		return 0, fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+	
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.capacity = capacity
+	if c.sketch != nil {
+		c.sketch = newFrequencySketch(capacity)
+	}
+	if c.bloom.Load() != nil {
+		c.rebuildBloomFilter()
+	}
+	
+	evicted := 0
+	for c.size > c.capacity {
+		if !c.evict() {
+			break
+		}
+		evicted++
+	}
+	
+	return evicted, nil
+}
+
+// Keys returns the keys of all live entries, most recently used first. Tombstones
+// and expired entries are skipped.
+func (c *Cache) Keys() []string {
+	c.rlock()
+	defer c.mu.RUnlock()
+	
+	keys := make([]string, 0, c.size)
+	now := c.clock.Now()
+	for entry := c.head; entry != nil; entry = entry.Next {
+		if entry.Tombstone || (!entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt)) {
+			continue
+		}
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+// ForEach calls fn with the key and value of every live entry, most recently used
+// first, until fn returns false or ctx is done. It takes the list of keys up front
+// and then reads their entries forEachChunk at a time, releasing the lock between
+// chunks and while fn runs, so a long scan does not block other operations and fn may
+// call back into the cache. Entries added during the iteration are not visited, and
+// entries removed or expired before their chunk is read are skipped. Values share
+// storage with the cache and must not be modified.
+func (c *Cache) ForEach(ctx context.Context, fn func(key string, value []byte) bool) {
+	keys := c.Keys()
+	
+	chunk := make([]KV, 0, forEachChunk)
+	for start := 0; start < len(keys); start += forEachChunk {
+		if ctx.Err() != nil {
+			return
+		}
+		
+		end := start + forEachChunk
+		if end > len(keys) {
+			end = len(keys)
+		}
+		
+		chunk = chunk[:0]
+		now := c.clock.Now()
+		c.rlock()
+		for _, key := range keys[start:end] {
+			entry, exists := c.entries[key]
+			if !exists || entry.Tombstone || (!entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt)) {
+				continue
+			}
+			if value, ok := c.value(entry); ok {
+				chunk = append(chunk, KV{Key: key, Value: value})
+			}
+		}
+		c.mu.RUnlock()
+		
+		for _, item := range chunk {
+			if ctx.Err() != nil || !fn(item.Key, item.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Snapshot copies the key, value and remaining TTL of every live entry, most recently
+// used first, holding the lock only while copying so callers can iterate the result
+// at leisure. Tombstones and expired entries are skipped, and entries without an expiry
+// have a zero TTL. Values share storage with the cache and must not be modified.
+func (c *Cache) Snapshot() []KV {
+	c.rlock()
+	defer c.mu.RUnlock()
+	
+	items := make([]KV, 0, c.size)
+	now := c.clock.Now()
+	for entry := c.head; entry != nil; entry = entry.Next {
+		if entry.Tombstone {
+			continue
+		}
+		
+		var ttl time.Duration
+		if !entry.ExpiresAt.IsZero() {
+			ttl = entry.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		if value, ok := c.value(entry); ok {
+			items = append(items, KV{Key: entry.Key, Value: value, TTL: ttl})
+		}
+	}
+	return items
+}
+
+// Range copies the key, value and remaining TTL of every live entry whose key sorts
+// within [startKey, endKey), in key order; an empty endKey leaves the range unbounded
+// above. Entries are not promoted in the LRU order. There is no sorted index, so every
+// call scans the whole keyspace under the read lock, O(n) in the number of entries.
+// Values share storage with the cache and must not be modified.
+func (c *Cache) Range(startKey, endKey string) []KV {
+	c.rlock()
+	defer c.mu.RUnlock()
+	
+	var items []KV
+	now := c.clock.Now()
+	for key, entry := range c.entries {
+		if key < startKey || (endKey != "" && key >= endKey) || entry.Tombstone {
+			continue
+		}
+		
+		var ttl time.Duration
+		if !entry.ExpiresAt.IsZero() {
+			ttl = entry.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		if value, ok := c.value(entry); ok {
+			items = append(items, KV{Key: key, Value: value, TTL: ttl})
+		}
+	}
+	
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+	return items
+}
+
+// Scan copies every live entry whose key starts with prefix, in key order, with its
+// version; an empty prefix selects every entry. Like Range it scans the whole
+// keyspace under the read lock without promoting entries. The copies are detached
+// from the list, but values share storage with the cache and must not be modified.
+func (c *Cache) Scan(prefix string) []Entry {
+	entries, _ := c.scan(prefix, "", 0, false)
+	return entries
+}
+
+// ScanPage copies up to limit entries whose key starts with prefix and sorts after
+// after, in key order, tombstones included so that callers merging replicas can tell a
+// deleted key from one a replica missed. It reports whether more entries follow, to be
+// read with the last key returned as after. A limit of zero or less returns them all.
+func (c *Cache) ScanPage(prefix, after string, limit int) ([]Entry, bool) {
+	return c.scan(prefix, after, limit, true)
+}
+
+// scan implements Scan and ScanPage
+func (c *Cache) scan(prefix, after string, limit int, tombstones bool) ([]Entry, bool) {
+	c.rlock()
+	defer c.mu.RUnlock()
+	
+	var matched []*Entry
+	now := c.clock.Now()
+	for key, entry := range c.entries {
+		if !strings.HasPrefix(key, prefix) || (after != "" && key <= after) {
+			continue
+		}
+		if entry.Tombstone && !tombstones {
+			continue
+		}
+		if !entry.ExpiresAt.IsZero() && !now.Before(entry.ExpiresAt) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Key < matched[j].Key
+	})
+	more := limit > 0 && len(matched) > limit
+	if more {
+		matched = matched[:limit]
+	}
+	
+	entries := make([]Entry, 0, len(matched))
+	for _, entry := range matched {
+		if copied, ok := c.entryCopy(entry); ok {
+			entries = append(entries, copied)
+		}
+	}
+	return entries, more
+}
+
+// Exists reports whether a key holds a live value, without promoting it in the LRU
+// order or counting a hit or miss. Expired entries and tombstones do not exist.
+func (c *Cache) Exists(key string) bool {
+	_, exists := c.PeekEntry(key)
+	return exists
+}
+
+// Peek returns a live value without promoting it in the LRU order, so reads for
+// inspection or metrics do not affect eviction. Expired entries are not returned.
+func (c *Cache) Peek(key string) ([]byte, bool) {
+	entry, exists := c.PeekEntry(key)
+	return entry.Value, exists
+}
+
+// PeekEntry returns a copy of a live entry without promoting it in the LRU order
+func (c *Cache) PeekEntry(key string) (Entry, bool) {
+	if c.definitelyAbsent(key) {
+		return Entry{}, false
+	}
+	
+	c.rlock()
+	defer c.mu.RUnlock()
+	
+	entry, exists := c.entries[key]
+	if !exists || entry.Tombstone {
+		return Entry{}, false
+	}
+	if !entry.ExpiresAt.IsZero() && c.clock.Now().After(entry.ExpiresAt) {
+		return Entry{}, false
+	}
+	
+	return c.entryCopy(entry)
+}
+
 // Clear removes all entries from the cache
 func (c *Cache) Clear() {
-	c.mu.Lock()
+	c.lock()
 	defer c.mu.Unlock()
 	
 	c.entries = make(map[string]*Entry)
 	c.head = nil
 	c.tail = nil
 	c.size = 0
+	c.pinned = 0
+	if c.arena != nil {
+		c.arena.release()
+	}
+	if c.bloom.Load() != nil {
+		c.rebuildBloomFilter()
+	}
+}
+
+// Replace atomically swaps the whole contents of the cache for items. The new entries
+// are built without holding the lock and then swapped in at once, so readers see
+// either the old contents or the new ones, never a mix. As with SetMany, later items
+// win when a key appears more than once and only the most recent items are kept if
+// there are more than the capacity. Like Clear, Replace drops tombstones and does not
+// notify eviction listeners, neither for the old entries nor for items over capacity.
+// Every item is admitted regardless of the admission policy.
+func (c *Cache) Replace(items []KV) {
+	c.rlock()
+	next := &Cache{
+		entries:      make(map[string]*Entry, len(items)),
+		capacity:     c.capacity,
+		tombstoneTTL: c.tombstoneTTL,
+		costFunc:     c.costFunc,
+		inflation:    c.inflation,
+		evictBatch:   1,
+		ttlJitter:    c.ttlJitter,
+		eviction:     c.eviction,
+		clock:        c.clock,
+		cipher:       c.cipher,
+	}
+	if c.arena != nil {
+		next.arena = newValueArena()
+	}
+	c.mu.RUnlock()
+	
+	for _, item := range items {
+		next.put(item.Key, item.Value, item.TTL, 0, next.entryCost(item.Key, item.Value), true)
+	}
+	next.evictOverflow()
+	
+	c.lock()
+	defer c.mu.Unlock()
+	
+	c.entries = next.entries
+	c.head = next.head
+	c.tail = next.tail
+	c.size = next.size
+	c.pinned = 0
+	c.promotions = next.promotions
+	
+	// Values stay where they were built unless SetOffHeap was called in the meantime
+	current := c.arena
+	c.arena = next.arena
+	if current != nil {
+		current.release()
+	}
+	c.setOffHeap(current != nil)
+	if next.inflation > c.inflation {
+		c.inflation = next.inflation
+	}
+	if c.bloom.Load() != nil {
+		c.rebuildBloomFilter()
+	}
+	
+	// The capacity may have shrunk while the new contents were being built
+	c.evictOverflow()
 }
 
-// Cleanup removes expired entries
+// Cleanup removes expired entries and tombstones and returns how many it removed
 func (c *Cache) Cleanup() int {
-	c.mu.Lock()
+	c.lock()
 	defer c.mu.Unlock()
 	
-	removed := 0
-	now := time.Now()
+	removed, _ := c.removeExpired(0)
+	return removed
+}
+
+// CleanupWithKeys removes expired entries and tombstones as Cleanup does and returns
+// the keys of the expired entries, so that caches derived from them can be
+// invalidated. Tombstones are removed but not reported. At most limit keys are
+// returned, or all of them if limit is not positive; the rest are removed all the same.
+func (c *Cache) CleanupWithKeys(limit int) []string {
+	c.lock()
+	defer c.mu.Unlock()
 	
-	// Iterate through entries and remove expired ones
-	for key, entry := range c.entries {
-		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
-			c.removeEntry(entry)
-			removed++
+	if limit <= 0 {
+		limit = -1
+	}
+	_, keys := c.removeExpired(limit)
+	return keys
+}
+
+// removeExpired removes every expired entry and tombstone, returning how many it
+// removed and the keys of up to limit of the expired entries, every one if limit is
+// negative; the caller must hold the lock. Keys beyond the limit are not collected.
+// The expired entries are collected first and removed after, so the map is not
+// changed while it is ranged over.
+func (c *Cache) removeExpired(limit int) (int, []string) {
+	now := c.clock.Now()
+	var expired []*Entry
+	var keys []string
+	for _, entry := range c.entries {
+		if entry.ExpiresAt.IsZero() || !now.After(entry.ExpiresAt) {
+			continue
+		}
+		expired = append(expired, entry)
+		if !entry.Tombstone && (limit < 0 || len(keys) < limit) {
+			keys = append(keys, entry.Key)
 		}
 	}
 	
-	return removed
+	for _, entry := range expired {
+		c.expire(entry)
+	}
+	return len(expired), keys
+}
+
+// touch records a use of an entry for the eviction policy
+func (c *Cache) touch(entry *Entry) {
+	if c.eviction == EvictLRU {
+		c.moveToFront(entry)
+	}
 }
 
 // moveToFront moves an entry to the front of the LRU list
 func (c *Cache) moveToFront(entry *Entry) {
+	entry.priority = c.inflation + int64(entry.Cost)
+	
 	if entry == c.head {
 		return // Already at front
 	}
@@ -181,16 +1458,20 @@ func (c *Cache) addToFront(entry *Entry) {
 		c.head.Prev = entry
 	}
 	c.head = entry
+	c.promotions++
+	entry.promoted = c.promotions
 	
 	if c.tail == nil {
 		c.tail = entry
 	}
 }
 
-// removeEntry removes an entry from the cache
+// removeEntry removes an entry from the cache, freeing its off-heap value
 func (c *Cache) removeEntry(entry *Entry) {
 	// Remove from map
 	delete(c.entries, entry.Key)
+	c.releaseValue(entry)
+	c.unpin(entry)
 	
 	// Remove from list
 	if entry.Prev != nil {
@@ -208,21 +1489,263 @@ func (c *Cache) removeEntry(entry *Entry) {
 	c.size--
 }
 
-// evictLRU removes the least recently used entry
-func (c *Cache) evictLRU() {
-	if c.tail != nil {
-		c.removeEntry(c.tail)
+// evict removes the entry chosen by the eviction policy to make room, reporting
+// false if every entry is pinned
+func (c *Cache) evict() bool {
+	victim := c.victim()
+	if victim == nil {
+		return false
+	}
+	
+	if victim.priority > c.inflation {
+		c.inflation = victim.priority
 	}
+	atomic.AddUint64(&c.evictions, 1)
+	c.notifyEvicted(victim, EvictionCapacity)
+	c.removeEntry(victim)
+	return true
 }
 
-// GetStats returns cache statistics
-func (c *Cache) GetStats() map[string]interface{} {
-	c.mu.RLock()
+// Evictions returns how many entries have been evicted to make room for others
+func (c *Cache) Evictions() uint64 {
+	return atomic.LoadUint64(&c.evictions)
+}
+
+// victim returns the entry evict would remove next, or nil if the cache is empty or
+// every entry is pinned. Pinned entries are passed over by every policy.
+//
+// Under EvictLRU this is the lowest priority entry among the least recently used few
+// that are not pinned, preferring the oldest on ties. An entry's priority is its cost plus the priority of
+// the last victim at the time it was last used, so expensive entries outlive cheap ones
+// but still age out as the cache turns over (GreedyDual). With uniform costs this is
+// plain LRU. Under EvictFIFO reads never reorder the list, so the tail is the oldest
+// insertion; under EvictRandom any entry may be picked. Pinned entries met on the way
+// to a victim are moved to the front of the list, so that later walks do not pass over
+// them again until the rest of the list has turned over.
+func (c *Cache) victim() *Entry {
+	if c.tail == nil || c.pinned >= c.size {
+		return nil
+	}
+	
+	switch c.eviction {
+	case EvictFIFO:
+		for entry := c.tail; entry != nil; {
+			if !entry.pinned {
+				return entry
+			}
+			prev := entry.Prev
+			c.moveToFront(entry)
+			entry = prev
+		}
+		return nil
+	case EvictRandom:
+		for _, entry := range c.entries {
+			if !entry.pinned {
+				return entry
+			}
+		}
+		return nil
+	}
+	
+	// The walk ends at the first entry it moved, with the list searched
+	var victim, moved *Entry
+	for entry, n := c.tail, 0; entry != nil && entry != moved && n < evictionSample; {
+		prev := entry.Prev
+		if entry.pinned {
+			if moved == nil {
+				moved = entry
+			}
+			c.moveToFront(entry)
+		} else {
+			if victim == nil || entry.priority < victim.priority {
+				victim = entry
+			}
+			n++
+		}
+		entry = prev
+	}
+	return victim
+}
+
+// Pin exempts a key's entry from eviction, so that other entries are evicted in its
+// place. A pinned entry still expires at its TTL and can be deleted, either of which
+// ends the pin; updating its value keeps it. Clear and Replace drop every pin. Once
+// pinned entries fill the capacity, new keys are evicted as soon as they are written,
+// and a cache resized below its pinned entries stays over capacity until some are
+// unpinned. Pin reports whether the key was present.
+func (c *Cache) Pin(key string) bool {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	entry := c.liveEntry(key)
+	if entry == nil || entry.Tombstone {
+		return false
+	}
+	if !entry.pinned {
+		entry.pinned = true
+		c.pinned++
+	}
+	return true
+}
+
+// Unpin makes a pinned key evictable again, evicting down to capacity if pins had
+// held the cache above it. It reports whether the key was pinned.
+func (c *Cache) Unpin(key string) bool {
+	c.lock()
+	defer c.mu.Unlock()
+	
+	entry, exists := c.entries[key]
+	if !exists || !entry.pinned {
+		return false
+	}
+	c.unpin(entry)
+	c.evictOverflow()
+	return true
+}
+
+// Pinned returns the number of entries exempt from eviction
+func (c *Cache) Pinned() int {
+	c.rlock()
+	defer c.mu.RUnlock()
+	return c.pinned
+}
+
+// unpin ends an entry's pin, if it has one; the caller must hold the lock
+func (c *Cache) unpin(entry *Entry) {
+	if entry.pinned {
+		entry.pinned = false
+		c.pinned--
+	}
+}
+
+// expire removes an entry whose TTL has lapsed
+func (c *Cache) expire(entry *Entry) {
+	c.notifyEvicted(entry, EvictionExpired)
+	c.removeEntry(entry)
+}
+
+// notifyEvicted calls the eviction listeners for a live entry; the caller must hold the lock
+func (c *Cache) notifyEvicted(entry *Entry, reason EvictionReason) {
+	if entry.Tombstone {
+		return
+	}
+	value, _ := c.value(entry)
+	for _, listener := range c.listeners {
+		listener(entry.Key, value, reason)
+	}
+}
+
+// Stats is a snapshot of cache statistics, returned by StatsSnapshot
+type Stats struct {
+	Size         int
+	Capacity     int
+	Load         float64 // Size as a fraction of capacity
+	Hits         uint64
+	Misses       uint64
+	Evictions    uint64        // Entries evicted to make room
+	Pinned       int           // Entries exempt from eviction
+	Rejected     uint64        // New keys refused by the admission policy
+	LockWaits    uint64        // Lock acquisitions timed while lock timing is enabled
+	LockWait     time.Duration // Total time spent waiting for those acquisitions
+	BloomFPRate  float64       // Estimated false positive rate of the bloom filter
+	BloomRejects uint64        // Reads answered by the bloom filter alone
+	OffHeapBytes uint64        // Bytes mapped for off-heap values
+	DecryptFails uint64        // Reads of encrypted values that failed to decrypt
+}
+
+// StatsSnapshot returns the cache statistics as of one moment
+func (c *Cache) StatsSnapshot() Stats {
+	c.rlock()
 	defer c.mu.RUnlock()
 	
+	var offHeap uint64
+	if c.arena != nil {
+		offHeap = c.arena.mapped
+	}
+	
+	return Stats{
+		Size:         c.size,
+		Capacity:     c.capacity,
+		Load:         float64(c.size) / float64(c.capacity),
+		Hits:         atomic.LoadUint64(&c.hits),
+		Misses:       atomic.LoadUint64(&c.misses),
+		Evictions:    atomic.LoadUint64(&c.evictions),
+		Pinned:       c.pinned,
+		Rejected:     c.rejected,
+		LockWaits:    atomic.LoadUint64(&c.lockWaits),
+		LockWait:     time.Duration(atomic.LoadUint64(&c.lockWaitNs)),
+		BloomFPRate:  c.bloomFalsePositiveRate(),
+		BloomRejects: atomic.LoadUint64(&c.bloomRejects),
+		OffHeapBytes: offHeap,
+		DecryptFails: atomic.LoadUint64(&c.decryptFails),
+	}
+}
+
+// GetStats returns cache statistics keyed by name; StatsSnapshot returns the same
+// values typed
+func (c *Cache) GetStats() map[string]interface{} {
+	stats := c.StatsSnapshot()
 	return map[string]interface{}{
-		"size":     c.size,
-		"capacity": c.capacity,
-		"load":     float64(c.size) / float64(c.capacity),
+		"size":          stats.Size,
+		"capacity":      stats.Capacity,
+		"load":          stats.Load,
+		"hits":          stats.Hits,
+		"misses":        stats.Misses,
+		"evictions":     stats.Evictions,
+		"pinned":        stats.Pinned,
+		"rejected":      stats.Rejected,
+		"lock_waits":    stats.LockWaits,
+		"lock_wait":     stats.LockWait,
+		"bloom_fp_rate": stats.BloomFPRate,
+		"bloom_rejects": stats.BloomRejects,
+		"offheap_bytes": stats.OffHeapBytes,
+		"decrypt_fails": stats.DecryptFails,
+	}
+} 
+
+// ResetStats zeroes the hit, miss, eviction, rejection, lock wait and bloom filter
+// counters without touching the entries, so statistics can be measured over an
+// interval such as a benchmark run
+func (c *Cache) ResetStats() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	atomic.StoreUint64(&c.hits, 0)
+	atomic.StoreUint64(&c.misses, 0)
+	atomic.StoreUint64(&c.evictions, 0)
+	c.rejected = 0
+	atomic.StoreUint64(&c.lockWaits, 0)
+	atomic.StoreUint64(&c.lockWaitNs, 0)
+	atomic.StoreUint64(&c.bloomRejects, 0)
+}
+
+// AgeStats reports how long ago the oldest, newest and median live entries were
+// written, from their CreatedAt, for tuning TTLs and capacity. Tombstones and expired
+// entries are skipped, and an empty cache reports zeros. It sorts the ages of every
+// entry under the read lock, so it is meant for occasional monitoring.
+func (c *Cache) AgeStats() (oldest, newest, median time.Duration) {
+	now := c.clock.Now()
+	c.rlock()
+	ages := make([]time.Duration, 0, c.size)
+	for _, entry := range c.entries {
+		if entry.Tombstone || (!entry.ExpiresAt.IsZero() && !now.Before(entry.ExpiresAt)) {
+			continue
+		}
+		ages = append(ages, now.Sub(entry.CreatedAt))
+	}
+	c.mu.RUnlock()
+	
+	if len(ages) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(ages, func(i, j int) bool {
+		return ages[i] < ages[j]
+	})
+	
+	mid := len(ages) / 2
+	median = ages[mid]
+	if len(ages)%2 == 0 {
+		median = (ages[mid-1] + ages[mid]) / 2
 	}
-} 
\ No newline at end of file
+	return ages[len(ages)-1], ages[0], median
+}
\ No newline at end of file
@@ -1,8 +1,14 @@
 package cache
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -32,7 +38,8 @@ func TestCacheBasicOperations(t *testing.T) {
 }
 
 func TestCacheTTLExpiry(t *testing.T) {
-	cache := NewCache(100)
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
 	
 	key := "ttl-test"
 	value := []byte("ttl-value")
@@ -47,7 +54,7 @@ func TestCacheTTLExpiry(t *testing.T) {
 	}
 	
 	// Wait for expiry
-	time.Sleep(20 * time.Millisecond)
+	clock.Advance(20 * time.Millisecond)
 	
 	// Should not exist after expiry
 	_, exists = cache.Get(key)
@@ -86,6 +93,10 @@ func TestCacheLRUEviction(t *testing.T) {
 	if !exists {
 		t.Error("Expected key4 to exist")
 	}
+	
+	if evictions := cache.Evictions(); evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", evictions)
+	}
 }
 
 func TestCacheLRUOrder(t *testing.T) {
@@ -125,6 +136,355 @@ func TestCacheLRUOrder(t *testing.T) {
 	}
 }
 
+func TestCacheGetPromotionWindow(t *testing.T) {
+	cache := NewCache(100)
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	
+	// Reads near the front are served without promotion; key95 stays where it is
+	before := cache.promotions
+	for i := 0; i < 10; i++ {
+		if _, found := cache.Get("key95"); !found {
+			t.Fatal("Expected key95 to be found")
+		}
+	}
+	if cache.promotions != before {
+		t.Errorf("Expected reads of a recent entry not to promote it, got %d promotions", cache.promotions-before)
+	}
+	
+	// A read of the tail still moves it to the front, protecting it from eviction
+	cache.Get("key0")
+	if cache.head.Key != "key0" {
+		t.Errorf("Expected key0 at the front after being read, got %s", cache.head.Key)
+	}
+	cache.Set("new", []byte("value"), 0)
+	if _, found := cache.Peek("key0"); !found {
+		t.Error("Expected key0 to survive eviction after being read")
+	}
+	if _, found := cache.Peek("key1"); found {
+		t.Error("Expected key1, the least recently used, to be evicted")
+	}
+	
+	stats := cache.GetStats()
+	if stats["hits"] != uint64(11) {
+		t.Errorf("Expected 11 hits, got %v", stats["hits"])
+	}
+}
+
+func TestCacheConcurrentGet(t *testing.T) {
+	cache := NewCache(100)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := fmt.Sprintf("key%d", (i*7+w)%150)
+				if w%2 == 0 {
+					cache.Set(key, []byte(key), time.Millisecond*time.Duration(i%3))
+				} else if value, found := cache.Get(key); found && string(value) != key {
+					t.Errorf("Get %s returned %s", key, value)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	
+	if cache.Size() > cache.Capacity() {
+		t.Errorf("Size %d exceeded capacity %d", cache.Size(), cache.Capacity())
+	}
+	
+	// The list still holds every entry exactly once
+	count := 0
+	for entry := cache.head; entry != nil; entry = entry.Next {
+		count++
+	}
+	if count != cache.Size() {
+		t.Errorf("Expected %d entries in the LRU list, got %d", cache.Size(), count)
+	}
+}
+
+// BenchmarkCacheParallelGet reads a working set smaller than the promotion window
+// from many goroutines, the case served under the read lock
+func BenchmarkCacheParallelGet(b *testing.B) {
+	cache := NewCache(10000)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		cache.Set(keys[i], []byte("value"), 0)
+	}
+	
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cache.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+// fakeClock is a Clock that stands still until it is advanced
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// newFakeClock returns a fakeClock set to the current time
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+// Now returns the clock's time
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// benchmarkKeys returns n keys shaped like typical application keys
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%08d:profile", i)
+	}
+	return keys
+}
+
+// benchmarkCache returns a cache of the given capacity filled with 256 byte values
+// for keys, with its statistics reset
+func benchmarkCache(capacity int, keys []string) *Cache {
+	cache := NewCache(capacity)
+	value := make([]byte, 256)
+	for _, key := range keys {
+		cache.Set(key, value, 0)
+	}
+	cache.ResetStats()
+	return cache
+}
+
+// reportHitRatio reports the share of reads since the last ResetStats that hit
+func reportHitRatio(b *testing.B, cache *Cache) {
+	stats := cache.StatsSnapshot()
+	if reads := stats.Hits + stats.Misses; reads > 0 {
+		b.ReportMetric(float64(stats.Hits)/float64(reads), "hit-ratio")
+	}
+}
+
+// BenchmarkCacheSet writes a key set twice the capacity, so that writes are a mix of
+// updates and inserts that evict
+func BenchmarkCacheSet(b *testing.B) {
+	keys := benchmarkKeys(200000)
+	cache := benchmarkCache(100000, keys[:100000])
+	value := make([]byte, 256)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Set(keys[i%len(keys)], value, 0)
+	}
+}
+
+// BenchmarkCacheGetHit reads keys that are all cached
+func BenchmarkCacheGetHit(b *testing.B) {
+	keys := benchmarkKeys(100000)
+	cache := benchmarkCache(100000, keys)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(keys[i%len(keys)])
+	}
+	b.StopTimer()
+	reportHitRatio(b, cache)
+}
+
+// BenchmarkCacheGetMiss reads keys that were never set
+func BenchmarkCacheGetMiss(b *testing.B) {
+	keys := benchmarkKeys(200000)
+	cache := benchmarkCache(100000, keys[:100000])
+	missing := keys[100000:]
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(missing[i%len(missing)])
+	}
+}
+
+// BenchmarkCacheEncryptedSet is BenchmarkCacheSet with values encrypted at rest
+func BenchmarkCacheEncryptedSet(b *testing.B) {
+	keys := benchmarkKeys(200000)
+	cache := benchmarkEncryptedCache(b, 100000, keys[:100000])
+	value := make([]byte, 256)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Set(keys[i%len(keys)], value, 0)
+	}
+}
+
+// BenchmarkCacheEncryptedGetHit is BenchmarkCacheGetHit with values encrypted at rest
+func BenchmarkCacheEncryptedGetHit(b *testing.B) {
+	keys := benchmarkKeys(100000)
+	cache := benchmarkEncryptedCache(b, 100000, keys)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(keys[i%len(keys)])
+	}
+}
+
+// benchmarkEncryptedCache is benchmarkCache for a cache created with an AES-256 key
+func benchmarkEncryptedCache(b *testing.B, capacity int, keys []string) *Cache {
+	cache, err := NewCacheWithEncryption(capacity, make([]byte, 32))
+	if err != nil {
+		b.Fatalf("NewCacheWithEncryption failed: %v", err)
+	}
+	value := make([]byte, 256)
+	for _, key := range keys {
+		cache.Set(key, value, 0)
+	}
+	cache.ResetStats()
+	return cache
+}
+
+// BenchmarkCacheParallel mixes reads and writes from many goroutines over a key set
+// larger than the capacity, with keys drawn from a Zipf distribution so that a few
+// are hot, at several read percentages
+func BenchmarkCacheParallel(b *testing.B) {
+	keys := benchmarkKeys(200000)
+	for _, reads := range []int{50, 90, 99} {
+		b.Run(fmt.Sprintf("reads=%d%%", reads), func(b *testing.B) {
+			cache := benchmarkCache(100000, keys[:100000])
+			value := make([]byte, 256)
+			var seed int64
+			
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+				zipf := rand.NewZipf(r, 1.1, 1, uint64(len(keys)-1))
+				for pb.Next() {
+					key := keys[zipf.Uint64()]
+					if r.Intn(100) < reads {
+						cache.Get(key)
+					} else {
+						cache.Set(key, value, 0)
+					}
+				}
+			})
+			b.StopTimer()
+			reportHitRatio(b, cache)
+		})
+	}
+}
+
+func TestCacheTouch(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	cache.Set("short", []byte("value"), 50*time.Millisecond)
+	cache.Set("long", []byte("value"), time.Hour)
+	cache.Set("deleted", []byte("value"), 0)
+	cache.DeleteVersioned("deleted", 1)
+	
+	if !cache.Touch("short", time.Hour) {
+		t.Error("Expected Touch of a live key to report it present")
+	}
+	if !cache.Touch("long", 0) {
+		t.Error("Expected Touch of a live key to report it present")
+	}
+	if cache.Touch("missing", time.Hour) || cache.Touch("deleted", time.Hour) {
+		t.Error("Expected Touch of missing and deleted keys to report them absent")
+	}
+	if _, exists := cache.Peek("missing"); exists {
+		t.Error("Expected Touch not to create missing keys")
+	}
+	
+	clock.Advance(100 * time.Millisecond)
+	for _, key := range []string{"short", "long"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("Expected %s to outlive its original TTL", key)
+		}
+	}
+	if entry, _ := cache.PeekEntry("long"); !entry.ExpiresAt.IsZero() {
+		t.Errorf("Expected a zero ttl to remove the expiry, got %v", entry.ExpiresAt)
+	}
+}
+
+func TestCacheGetAndDelete(t *testing.T) {
+	cache := NewCache(100)
+	cache.SetVersioned("job", []byte("payload"), 0, 1)
+	cache.DeleteVersioned("deleted", 1)
+	
+	held, ok := cache.GetAndDelete("job", 2)
+	if !ok || string(held.Value) != "payload" || held.Version != 1 {
+		t.Errorf("Expected the popped value at version 1, got %q at %d, %v", held.Value, held.Version, ok)
+	}
+	if cache.Exists("job") || cache.Size() != 2 {
+		t.Errorf("Expected the key to be replaced with a tombstone, size %d", cache.Size())
+	}
+	held, ok = cache.GetAndDelete("job", 3)
+	if ok || !held.Tombstone || held.Version != 2 {
+		t.Errorf("Expected a second pop to get the first pop's tombstone, got %+v, %v", held, ok)
+	}
+	if _, ok := cache.GetAndDelete("deleted", 2); ok {
+		t.Error("Expected a tombstone not to be popped")
+	}
+	
+	// A copy of the value replicated late is rejected rather than popped again
+	if cache.SetVersioned("job", []byte("payload"), 0, 1) {
+		t.Error("Expected the pop's tombstone to reject an older write")
+	}
+	
+	// A pop older than the value leaves it alone
+	cache.SetVersioned("newer", []byte("payload"), 0, 5)
+	if held, ok := cache.GetAndDelete("newer", 4); ok || held.Key != "" || !cache.Exists("newer") {
+		t.Errorf("Expected an older pop to leave the value, got %+v, %v", held, ok)
+	}
+	
+	// A pop of a missing key leaves a tombstone for a write still on its way
+	if _, ok := cache.GetAndDelete("pending", 2); ok {
+		t.Error("Expected a pop of a missing key to miss")
+	}
+	if cache.SetVersioned("pending", []byte("payload"), 0, 1) {
+		t.Error("Expected the tombstone of a missed pop to reject an older write")
+	}
+	
+	// Of many concurrent pops of each key, exactly one gets its value
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("job:%d", i), []byte("payload"), 0)
+	}
+	var popped int64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, ok := cache.GetAndDelete(fmt.Sprintf("job:%d", i), uint64(g+1)); ok {
+					atomic.AddInt64(&popped, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if popped != 100 {
+		t.Errorf("Expected each of the 100 keys to be popped once, got %d pops", popped)
+	}
+}
+
 func TestCacheDelete(t *testing.T) {
 	cache := NewCache(100)
 	
@@ -227,7 +587,8 @@ func TestCacheConcurrency(t *testing.T) {
 }
 
 func TestCacheCleanup(t *testing.T) {
-	cache := NewCache(100)
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
 	
 	// Add some entries with TTL
 	cache.Set("expired1", []byte("value1"), 1*time.Millisecond)
@@ -235,7 +596,7 @@ func TestCacheCleanup(t *testing.T) {
 	cache.Set("valid", []byte("value3"), 0) // No TTL
 	
 	// Wait for expiry
-	time.Sleep(10 * time.Millisecond)
+	clock.Advance(10 * time.Millisecond)
 	
 	// Cleanup should remove expired entries
 	removed := cache.Cleanup()
@@ -261,6 +622,107 @@ func TestCacheCleanup(t *testing.T) {
 	}
 }
 
+func TestCacheCleanupWithKeys(t *testing.T) {
+	fill := func() (*Cache, map[string]bool) {
+		clock := newFakeClock()
+		cache := NewCacheWithClock(100, clock)
+		cache.SetTombstoneTTL(time.Millisecond)
+		expired := make(map[string]bool)
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("expiring%d", i)
+			cache.Set(key, []byte("value"), time.Millisecond)
+			expired[key] = true
+			cache.Set(fmt.Sprintf("live%d", i), []byte("value"), time.Hour)
+		}
+		cache.DeleteVersioned("deleted", 1)
+		clock.Advance(10 * time.Millisecond)
+		return cache, expired
+	}
+	
+	// Every expired key is reported, and the tombstone is removed without being reported
+	cache, expired := fill()
+	keys := cache.CleanupWithKeys(0)
+	sort.Strings(keys)
+	var want []string
+	for key := range expired {
+		want = append(want, key)
+	}
+	sort.Strings(want)
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected expired keys %v, got %v", want, keys)
+	}
+	if size := cache.Size(); size != 10 {
+		t.Errorf("Expected the expired entries and tombstone to be removed, got size %d", size)
+	}
+	
+	// A cap limits the keys returned but not the entries removed
+	cache, expired = fill()
+	keys = cache.CleanupWithKeys(3)
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 keys, got %v", keys)
+	}
+	for _, key := range keys {
+		if !expired[key] {
+			t.Errorf("Expected only expired keys, got %s", key)
+		}
+	}
+	if size := cache.Size(); size != 10 {
+		t.Errorf("Expected every expired entry to be removed under the cap, got size %d", size)
+	}
+	if removed := cache.Cleanup(); removed != 0 {
+		t.Errorf("Expected nothing left to clean up, got %d", removed)
+	}
+}
+
+func TestCacheCleanupInterleaved(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(1000, clock)
+	
+	// Every third entry expires; the rest have no TTL or a long one
+	var live []string
+	for i := 0; i < 900; i++ {
+		key := fmt.Sprintf("key%d", i)
+		switch i % 3 {
+		case 0:
+			cache.Set(key, []byte("value"), time.Millisecond)
+		case 1:
+			cache.Set(key, []byte("value"), 0)
+			live = append(live, key)
+		default:
+			cache.Set(key, []byte("value"), time.Hour)
+			live = append(live, key)
+		}
+	}
+	clock.Advance(10 * time.Millisecond)
+	
+	if removed := cache.Cleanup(); removed != 300 {
+		t.Errorf("Expected 300 expired entries to be removed, got %d", removed)
+	}
+	if removed := cache.Cleanup(); removed != 0 {
+		t.Errorf("Expected nothing left to remove, got %d", removed)
+	}
+	if cache.Size() != len(live) || len(cache.entries) != len(live) {
+		t.Errorf("Expected size %d, got %d with %d entries", len(live), cache.Size(), len(cache.entries))
+	}
+	
+	// The list still holds every live entry, most recently set first, linked both ways
+	var prev *Entry
+	i := len(live) - 1
+	for entry := cache.head; entry != nil; entry = entry.Next {
+		if entry.Prev != prev {
+			t.Fatalf("Broken back link at %s", entry.Key)
+		}
+		if i < 0 || entry.Key != live[i] {
+			t.Fatalf("Expected %s at position %d, got %s", live[max(i, 0)], len(live)-1-i, entry.Key)
+		}
+		prev = entry
+		i--
+	}
+	if i != -1 || cache.tail != prev {
+		t.Errorf("Expected %d entries ending at the tail, walked %d", len(live), len(live)-1-i)
+	}
+}
+
 func TestCacheClear(t *testing.T) {
 	cache := NewCache(100)
 	
@@ -313,4 +775,1544 @@ func TestCacheStats(t *testing.T) {
 	if load != expectedLoad {
 		t.Errorf("Expected load %f, got %f", expectedLoad, load)
 	}
-} 
\ No newline at end of file
+	
+	// Hits and misses are counted by Get and Lookup
+	cache.Get("key1")
+	cache.Lookup("key2")
+	cache.Get("missing")
+	
+	stats = cache.GetStats()
+	if stats["hits"] != uint64(2) || stats["misses"] != uint64(1) {
+		t.Errorf("Expected 2 hits and 1 miss, got %v and %v", stats["hits"], stats["misses"])
+	}
+}
+
+func TestCachePin(t *testing.T) {
+	for _, eviction := range []Eviction{EvictLRU, EvictFIFO, EvictRandom} {
+		cache := NewCacheWithPolicy(10, eviction)
+		cache.Set("flag", []byte("on"), 0)
+		cache.Set("config", []byte("v1"), 0)
+		if !cache.Pin("flag") || !cache.Pin("config") {
+			t.Fatalf("eviction %v: expected pinning present keys to succeed", eviction)
+		}
+		if cache.Pin("missing") {
+			t.Errorf("eviction %v: expected pinning a missing key to fail", eviction)
+		}
+		
+		// Pinned keys survive eviction pressure, and updates keep them pinned
+		for i := 0; i < 100; i++ {
+			cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+		}
+		cache.Set("config", []byte("v2"), 0)
+		for i := 100; i < 200; i++ {
+			cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+		}
+		if _, found := cache.Get("flag"); !found {
+			t.Errorf("eviction %v: expected pinned flag to survive eviction", eviction)
+		}
+		if value, _ := cache.Get("config"); string(value) != "v2" {
+			t.Errorf("eviction %v: expected updated pinned config to survive eviction, got %q", eviction, value)
+		}
+		if size := cache.Size(); size != 10 {
+			t.Errorf("eviction %v: expected the cache to stay at capacity, got %d", eviction, size)
+		}
+		if pinned := cache.StatsSnapshot().Pinned; pinned != 2 {
+			t.Errorf("eviction %v: expected 2 pinned entries, got %d", eviction, pinned)
+		}
+	}
+	
+	// Pinned entries still expire and can be deleted, which ends the pin
+	clock := newFakeClock()
+	cache := NewCacheWithClock(10, clock)
+	cache.Set("short", []byte("value"), 10*time.Millisecond)
+	cache.Set("deleted", []byte("value"), 0)
+	cache.Pin("short")
+	cache.Pin("deleted")
+	cache.Delete("deleted")
+	clock.Advance(20 * time.Millisecond)
+	if _, found := cache.Get("short"); found {
+		t.Error("Expected a pinned entry to expire")
+	}
+	if pinned := cache.Pinned(); pinned != 0 {
+		t.Errorf("Expected expiry and delete to end the pins, got %d pinned", pinned)
+	}
+	
+	// Once pins fill the capacity only new keys can be evicted
+	cache = NewCache(2)
+	cache.Set("a", []byte("a"), 0)
+	cache.Set("b", []byte("b"), 0)
+	cache.Pin("a")
+	cache.Pin("b")
+	cache.Set("c", []byte("c"), 0)
+	if _, found := cache.Get("c"); found || cache.Size() != 2 {
+		t.Errorf("Expected a new key to be evicted from a fully pinned cache, got size %d", cache.Size())
+	}
+	
+	// Shrinking below the pins leaves the cache over capacity until one is unpinned
+	if evicted, err := cache.Resize(1); err != nil || evicted != 0 || cache.Size() != 2 {
+		t.Errorf("Expected resize to keep the pinned entries, got %d evicted, size %d, err %v", evicted, cache.Size(), err)
+	}
+	if !cache.Unpin("a") || cache.Unpin("a") {
+		t.Error("Expected Unpin to report whether the key was pinned")
+	}
+	if _, found := cache.Get("b"); !found || cache.Size() != 1 {
+		t.Errorf("Expected unpinning to evict back to capacity keeping b, got size %d", cache.Size())
+	}
+	
+	// Pinned entries passed over by an eviction are moved off the tail
+	for _, eviction := range []Eviction{EvictLRU, EvictFIFO} {
+		cache := NewCacheWithPolicy(100, eviction)
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			cache.Set(key, []byte("value"), 0)
+			if i < 90 {
+				cache.Pin(key)
+			}
+		}
+		cache.Set("new", []byte("value"), 0)
+		if cache.tail.pinned {
+			t.Errorf("eviction %v: expected the pinned entries to leave the tail", eviction)
+		}
+		if cache.Pinned() != 90 || cache.Size() != 100 {
+			t.Errorf("eviction %v: expected the 90 pins to be kept at capacity, got %d pinned, size %d", eviction, cache.Pinned(), cache.Size())
+		}
+	}
+}
+
+func TestCacheSetQuiet(t *testing.T) {
+	order := func(cache *Cache) string {
+		var keys []string
+		for _, item := range cache.Snapshot() {
+			keys = append(keys, item.Key)
+		}
+		return strings.Join(keys, ",")
+	}
+	
+	cache := NewCache(3)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, []byte(key), 0)
+	}
+	
+	// A quiet update changes the value but keeps the entry's place
+	cache.SetQuiet("a", []byte("quiet"), 0)
+	if got := order(cache); got != "c,b,a" {
+		t.Errorf("Expected quiet set to keep order c,b,a, got %s", got)
+	}
+	if value, _ := cache.Get("a"); string(value) != "quiet" {
+		t.Errorf("Expected quiet set to update the value, got %q", value)
+	}
+	
+	// A normal set of the same key moves it to the front
+	cache.Set("b", []byte("loud"), 0)
+	if got := order(cache); got != "b,a,c" {
+		t.Errorf("Expected normal set to move b to the front, got %s", got)
+	}
+	
+	// The least recently used entry is still evicted first after a quiet set
+	cache.SetQuiet("c", []byte("quiet"), 0)
+	cache.SetQuiet("d", []byte("d"), 0)
+	if _, found := cache.Get("c"); found {
+		t.Error("Expected the quietly updated c to be evicted first")
+	}
+	if got := order(cache); got != "d,b,a" {
+		t.Errorf("Expected new quiet keys to be inserted at the front, got %s", got)
+	}
+}
+
+func TestCacheStatsSnapshot(t *testing.T) {
+	cache := NewCache(4)
+	for i := 0; i < 5; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	cache.Get("key4")
+	cache.Get("key0")
+	cache.Get("missing")
+	
+	stats := cache.StatsSnapshot()
+	want := Stats{Size: 4, Capacity: 4, Load: 1, Hits: 1, Misses: 2, Evictions: 1}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+	
+	// The map form reports the same values
+	if m := cache.GetStats(); m["size"] != stats.Size || m["misses"] != stats.Misses || m["evictions"] != stats.Evictions {
+		t.Errorf("Expected GetStats to match the snapshot, got %v", m)
+	}
+}
+
+func TestCacheAgeStats(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	if oldest, newest, median := cache.AgeStats(); oldest != 0 || newest != 0 || median != 0 {
+		t.Errorf("Expected zero ages for an empty cache, got %v, %v, %v", oldest, newest, median)
+	}
+	
+	// Entries written 40, 30, 20 and 10 minutes before the measurement, plus an expired
+	// entry and a tombstone that do not count
+	cache.Set("expired", []byte("value"), 5*time.Minute)
+	cache.DeleteVersioned("deleted", 1)
+	for i := 0; i < 4; i++ {
+		clock.Advance(10 * time.Minute)
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	clock.Advance(10 * time.Minute)
+	
+	oldest, newest, median := cache.AgeStats()
+	if oldest != 40*time.Minute || newest != 10*time.Minute || median != 25*time.Minute {
+		t.Errorf("Expected ages 40m, 10m and 25m, got %v, %v and %v", oldest, newest, median)
+	}
+	
+	// With an odd count the median is the middle age
+	cache.Delete("key0")
+	if _, _, median := cache.AgeStats(); median != 20*time.Minute {
+		t.Errorf("Expected a median of 20m, got %v", median)
+	}
+	
+	// A rewrite makes an entry new again
+	cache.Set("key2", []byte("new value"), 0)
+	if oldest, newest, _ := cache.AgeStats(); oldest != 30*time.Minute || newest != 0 {
+		t.Errorf("Expected the rewritten entry to be the newest, got oldest %v and newest %v", oldest, newest)
+	}
+}
+
+func TestCacheResetStats(t *testing.T) {
+	cache := NewCache(2)
+	for i := 0; i < 3; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	cache.Get("key2")
+	cache.Get("missing")
+	
+	cache.ResetStats()
+	stats := cache.StatsSnapshot()
+	if stats.Hits != 0 || stats.Misses != 0 || stats.Evictions != 0 {
+		t.Errorf("Expected counters to be zero after reset, got %+v", stats)
+	}
+	if stats.Size != 2 {
+		t.Errorf("Expected reset to keep the entries, got size %d", stats.Size)
+	}
+	
+	// Counting resumes from zero
+	cache.Get("key2")
+	if hits := cache.StatsSnapshot().Hits; hits != 1 {
+		t.Errorf("Expected 1 hit after reset, got %d", hits)
+	}
+}
+
+func TestCacheTombstones(t *testing.T) {
+	cache := NewCache(100)
+	
+	key := "tombstone-test"
+	if !cache.SetVersioned(key, []byte("v1"), 0, 1) {
+		t.Fatal("Expected initial versioned set to apply")
+	}
+	
+	// Delete at a newer version leaves a tombstone
+	if !cache.DeleteVersioned(key, 2) {
+		t.Fatal("Expected versioned delete to apply")
+	}
+	if _, exists := cache.Get(key); exists {
+		t.Error("Expected tombstoned key to be absent")
+	}
+	
+	entry, exists := cache.Lookup(key)
+	if !exists || !entry.Tombstone || entry.Version != 2 {
+		t.Errorf("Expected tombstone at version 2, got %+v (exists=%v)", entry, exists)
+	}
+	
+	// An older write arriving late must not resurrect the value
+	if cache.SetVersioned(key, []byte("v1"), 0, 1) {
+		t.Error("Expected stale write to be rejected by tombstone")
+	}
+	if _, exists := cache.Get(key); exists {
+		t.Error("Expected key to stay deleted after stale write")
+	}
+	
+	// Stale deletes are rejected too
+	if cache.DeleteVersioned(key, 1) {
+		t.Error("Expected stale delete to be rejected")
+	}
+	
+	// A newer write replaces the tombstone
+	if !cache.SetVersioned(key, []byte("v3"), 0, 3) {
+		t.Fatal("Expected newer write to replace tombstone")
+	}
+	value, exists := cache.Get(key)
+	if !exists || string(value) != "v3" {
+		t.Errorf("Expected v3, got %s (exists=%v)", string(value), exists)
+	}
+	
+	// Deleting a key this node never saw still records the tombstone
+	if !cache.DeleteVersioned("never-set", 5) {
+		t.Error("Expected tombstone for unknown key")
+	}
+	if cache.SetVersioned("never-set", []byte("old"), 0, 4) {
+		t.Error("Expected write older than tombstone to be rejected")
+	}
+	
+	// Plain deletes remove tombstones but don't report them as deleted
+	if cache.Delete("never-set") {
+		t.Error("Expected deleting a tombstone to report false")
+	}
+}
+
+func TestCacheDeleteIf(t *testing.T) {
+	cache := NewCache(100)
+	cache.SetVersioned("key", []byte("v2"), 0, 2)
+	
+	// A delete based on an older read is rejected
+	if cache.DeleteIf("key", 1) {
+		t.Error("Expected delete with a stale version to be rejected")
+	}
+	if value, exists := cache.Get("key"); !exists || string(value) != "v2" {
+		t.Errorf("Expected v2 to survive, got %s (exists=%v)", string(value), exists)
+	}
+	
+	if !cache.DeleteIf("key", 2) {
+		t.Fatal("Expected delete with the current version to apply")
+	}
+	if _, exists := cache.Get("key"); exists {
+		t.Error("Expected key to be deleted")
+	}
+	
+	// The tombstone keeps older writes out but not newer ones
+	if cache.SetVersioned("key", []byte("v1"), 0, 1) {
+		t.Error("Expected stale write to be rejected by tombstone")
+	}
+	if cache.DeleteIf("key", 2) {
+		t.Error("Expected a second delete to report false")
+	}
+	if !cache.SetVersioned("key", []byte("v3"), 0, 3) {
+		t.Error("Expected newer write to replace tombstone")
+	}
+	
+	if cache.DeleteIf("missing", 0) {
+		t.Error("Expected deleting a missing key to report false")
+	}
+}
+
+func TestCacheTombstoneExpiry(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	cache.SetTombstoneTTL(10 * time.Millisecond)
+	
+	key := "tombstone-expiry"
+	cache.SetVersioned(key, []byte("v1"), 0, 1)
+	cache.DeleteVersioned(key, 2)
+	
+	// Wait for the grace period to lapse
+	clock.Advance(20 * time.Millisecond)
+	
+	if removed := cache.Cleanup(); removed != 1 {
+		t.Errorf("Expected cleanup to remove 1 tombstone, got %d", removed)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected empty cache after tombstone expiry, got size %d", cache.Size())
+	}
+	
+	// Once the tombstone is gone, any version may be written again
+	if !cache.SetVersioned(key, []byte("v1"), 0, 1) {
+		t.Error("Expected write to apply after tombstone expiry")
+	}
+}
+
+func TestCacheResize(t *testing.T) {
+	cache := NewCache(5)
+	
+	for i := 1; i <= 5; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	
+	// Touch key1 so key2 and key3 are the least recently used
+	cache.Get("key1")
+	
+	evicted, err := cache.Resize(3)
+	if err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if evicted != 2 {
+		t.Errorf("Expected 2 evictions, got %d", evicted)
+	}
+	if cache.Size() != 3 || cache.Capacity() != 3 {
+		t.Errorf("Expected size and capacity 3, got %d and %d", cache.Size(), cache.Capacity())
+	}
+	
+	for _, key := range []string{"key2", "key3"} {
+		if _, exists := cache.Get(key); exists {
+			t.Errorf("Expected %s to be evicted", key)
+		}
+	}
+	for _, key := range []string{"key1", "key4", "key5"} {
+		if _, exists := cache.Get(key); !exists {
+			t.Errorf("Expected %s to survive resize", key)
+		}
+	}
+	
+	// Growing evicts nothing and makes room for more entries
+	evicted, err = cache.Resize(10)
+	if err != nil || evicted != 0 {
+		t.Errorf("Expected grow without evictions, got %d (%v)", evicted, err)
+	}
+	cache.Set("key6", []byte("value"), 0)
+	if cache.Size() != 4 {
+		t.Errorf("Expected size 4 after growing, got %d", cache.Size())
+	}
+	
+	for _, capacity := range []int{0, -1} {
+		if _, err := cache.Resize(capacity); err == nil {
+			t.Errorf("Expected resize to %d to fail", capacity)
+		}
+	}
+	if cache.Capacity() != 10 {
+		t.Errorf("Expected failed resize to leave capacity unchanged, got %d", cache.Capacity())
+	}
+}
+
+func TestCacheCostAwareEviction(t *testing.T) {
+	cache := NewCache(10)
+	
+	// The expensive and cheap entries are the two least recently used
+	cache.SetWithCost("expensive", []byte("value"), 0, 20)
+	cache.SetWithCost("cheap", []byte("value"), 0, 1)
+	for i := 0; i < 8; i++ {
+		cache.Set(fmt.Sprintf("filler-%d", i), []byte("value"), 0)
+	}
+	
+	// Under pressure the cheap entry goes first even though expensive is older
+	for i := 0; i < 50; i++ {
+		cache.Set(fmt.Sprintf("pressure-%d", i), []byte("value"), 0)
+	}
+	if _, exists := cache.Get("cheap"); exists {
+		t.Error("Expected cheap entry to be evicted")
+	}
+	
+	entry, exists := cache.Lookup("expensive")
+	if !exists || entry.Cost != 20 {
+		t.Fatalf("Expected expensive entry to survive pressure, got %+v (exists=%v)", entry, exists)
+	}
+	
+	// Lookup refreshed it; the expensive entry still ages out eventually
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("churn-%d", i), []byte("value"), 0)
+	}
+	if _, exists := cache.Get("expensive"); exists {
+		t.Error("Expected expensive entry to eventually be evicted")
+	}
+	if cache.Size() != 10 {
+		t.Errorf("Expected size to stay at capacity, got %d", cache.Size())
+	}
+}
+
+func TestCacheCostFunc(t *testing.T) {
+	cache := NewCache(4)
+	cache.SetCostFunc(func(key string, value []byte) int {
+		return len(value)
+	})
+	
+	cache.Set("big", make([]byte, 100), 0)
+	cache.Set("small1", []byte("a"), 0)
+	cache.Set("small2", []byte("b"), 0)
+	cache.Set("small3", []byte("c"), 0)
+	cache.Set("small4", []byte("d"), 0)
+	
+	if _, exists := cache.Get("big"); !exists {
+		t.Error("Expected high cost entry to survive eviction")
+	}
+	if _, exists := cache.Get("small1"); exists {
+		t.Error("Expected low cost entry to be evicted instead")
+	}
+}
+
+func TestCacheKeysAndPeek(t *testing.T) {
+	cache := NewCache(3)
+	cache.Set("key1", []byte("value1"), 0)
+	cache.Set("key2", []byte("value2"), 0)
+	cache.DeleteVersioned("key3", 1)
+	
+	keys := cache.Keys()
+	if len(keys) != 2 || keys[0] != "key2" || keys[1] != "key1" {
+		t.Errorf("Expected [key2 key1], got %v", keys)
+	}
+	
+	// Peek does not promote, so key1 is still evicted first
+	entry, exists := cache.PeekEntry("key1")
+	if !exists || string(entry.Value) != "value1" {
+		t.Errorf("Expected to peek value1, got %q", entry.Value)
+	}
+	if _, exists := cache.PeekEntry("key3"); exists {
+		t.Error("Expected tombstone to be hidden from Peek")
+	}
+	
+	cache.Set("key4", []byte("value4"), 0)
+	if _, exists := cache.Get("key1"); exists {
+		t.Error("Expected key1 to be evicted after Peek")
+	}
+}
+
+func TestCacheEvictBatch(t *testing.T) {
+	cache := NewCache(10)
+	cache.SetEvictBatch(4)
+	
+	for i := 0; i < 13; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	if cache.Size() != 13 {
+		t.Errorf("Expected size to reach high-water mark of 13, got %d", cache.Size())
+	}
+	
+	// The next insert evicts back down to capacity in one batch
+	cache.Set("key13", []byte("value"), 0)
+	if cache.Size() != 10 {
+		t.Errorf("Expected size 10 after batch eviction, got %d", cache.Size())
+	}
+	for i := 0; i < 4; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("key%d", i)); exists {
+			t.Errorf("Expected key%d to be evicted", i)
+		}
+	}
+	
+	// A batch of one keeps size within capacity after every Set
+	cache.SetEvictBatch(1)
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("more%d", i), []byte("value"), 0)
+		if cache.Size() > cache.Capacity() {
+			t.Fatalf("Size %d exceeded capacity %d", cache.Size(), cache.Capacity())
+		}
+	}
+}
+
+func benchmarkCacheInsert(b *testing.B, batch int) {
+	cache := NewCache(10000)
+	cache.SetEvictBatch(batch)
+	
+	keys := make([]string, b.N)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	value := []byte("value")
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Set(keys[i], value, 0)
+	}
+}
+
+func BenchmarkCacheInsertEvictBatch1(b *testing.B) {
+	benchmarkCacheInsert(b, 1)
+}
+
+func BenchmarkCacheInsertEvictBatch64(b *testing.B) {
+	benchmarkCacheInsert(b, 64)
+}
+
+func BenchmarkCacheInsertEvictBatch512(b *testing.B) {
+	benchmarkCacheInsert(b, 512)
+}
+
+func TestCacheTTLJitter(t *testing.T) {
+	cache := NewCache(1000)
+	cache.SetTTLJitter(0.1)
+	
+	ttl := time.Hour
+	before := time.Now()
+	for i := 0; i < 500; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), ttl)
+	}
+	after := time.Now()
+	
+	// Expiry times fall within ±10% and are spread across the window
+	earliest, latest := time.Time{}, time.Time{}
+	for i := 0; i < 500; i++ {
+		entry, _ := cache.Lookup(fmt.Sprintf("key%d", i))
+		if entry.ExpiresAt.Before(before.Add(ttl*9/10)) || entry.ExpiresAt.After(after.Add(ttl*11/10)) {
+			t.Fatalf("Expiry %v outside jitter window", entry.ExpiresAt.Sub(before))
+		}
+		if earliest.IsZero() || entry.ExpiresAt.Before(earliest) {
+			earliest = entry.ExpiresAt
+		}
+		if entry.ExpiresAt.After(latest) {
+			latest = entry.ExpiresAt
+		}
+	}
+	if spread := latest.Sub(earliest); spread < ttl/10 {
+		t.Errorf("Expected expiries spread over most of the window, got %v", spread)
+	}
+	
+	// Tiny TTLs never become zero or negative
+	cache.SetTTLJitter(5)
+	for i := 0; i < 100; i++ {
+		if jittered := cache.jitteredTTL(time.Nanosecond); jittered <= 0 {
+			t.Fatalf("Expected positive TTL, got %v", jittered)
+		}
+	}
+}
+
+func TestCacheBinaryKeys(t *testing.T) {
+	cache := NewCache(10)
+	
+	keys := []string{"a\x00b", "a\x00c", "a", "\x00", "ключ", "键🔑", strings.Repeat("k", 4096)}
+	for i, key := range keys {
+		cache.Set(key, []byte(fmt.Sprintf("value%d", i)), 0)
+	}
+	for i, key := range keys {
+		value, exists := cache.Get(key)
+		if !exists || string(value) != fmt.Sprintf("value%d", i) {
+			t.Errorf("Expected %q to round-trip, got %q", key, value)
+		}
+	}
+}
+
+func TestCachePeekDoesNotPromote(t *testing.T) {
+	cache := NewCache(3)
+	cache.Set("tail", []byte("value1"), 0)
+	cache.Set("middle", []byte("value2"), 0)
+	cache.Set("head", []byte("value3"), 0)
+	
+	value, exists := cache.Peek("tail")
+	if !exists || string(value) != "value1" {
+		t.Fatalf("Expected to peek value1, got %q", value)
+	}
+	
+	// Peeking at the LRU tail does not rescue it from the next eviction
+	cache.Set("newest", []byte("value4"), 0)
+	if _, exists := cache.Peek("tail"); exists {
+		t.Error("Expected peeked tail entry to be evicted")
+	}
+	
+	// A Get on the new tail does rescue it
+	cache.Get("middle")
+	cache.Set("another", []byte("value5"), 0)
+	if _, exists := cache.Peek("middle"); !exists {
+		t.Error("Expected entry read with Get to survive eviction")
+	}
+	if _, exists := cache.Peek("head"); exists {
+		t.Error("Expected head to be evicted instead")
+	}
+}
+
+func TestCachePeekExpired(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(10, clock)
+	cache.Set("key", []byte("value"), time.Millisecond)
+	clock.Advance(2 * time.Millisecond)
+	
+	if _, exists := cache.Peek("key"); exists {
+		t.Error("Expected Peek to honor TTL expiry")
+	}
+}
+
+func TestCacheSetMany(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	
+	items := make([]KV, 50)
+	for i := range items {
+		items[i] = KV{Key: fmt.Sprintf("key%d", i), Value: []byte(fmt.Sprintf("value%d", i))}
+	}
+	items[0].TTL = 50 * time.Millisecond
+	cache.SetMany(items)
+	
+	if cache.Size() != 50 {
+		t.Errorf("Expected size 50, got %d", cache.Size())
+	}
+	for _, item := range items {
+		value, exists := cache.Get(item.Key)
+		if !exists || string(value) != string(item.Value) {
+			t.Errorf("Expected %s=%s, got %s (exists=%v)", item.Key, item.Value, value, exists)
+		}
+	}
+	
+	// Per-item TTLs are respected
+	clock.Advance(100 * time.Millisecond)
+	if _, exists := cache.Get("key0"); exists {
+		t.Error("Expected key0 to expire")
+	}
+	if _, exists := cache.Get("key1"); !exists {
+		t.Error("Expected key1 to have no expiry")
+	}
+}
+
+func TestCacheSetManyEviction(t *testing.T) {
+	cache := NewCache(10)
+	cache.Set("old", []byte("value"), 0)
+	
+	items := make([]KV, 15)
+	for i := range items {
+		items[i] = KV{Key: fmt.Sprintf("key%d", i), Value: []byte("value")}
+	}
+	cache.SetMany(items)
+	
+	if cache.Size() != 10 {
+		t.Errorf("Expected size 10 after eviction, got %d", cache.Size())
+	}
+	if _, exists := cache.Get("old"); exists {
+		t.Error("Expected old entry to be evicted")
+	}
+	for i := 5; i < 15; i++ {
+		if _, exists := cache.Get(fmt.Sprintf("key%d", i)); !exists {
+			t.Errorf("Expected key%d to be kept", i)
+		}
+	}
+}
+
+const setManyBatchSize = 256
+
+func setManyItems(n int) []KV {
+	items := make([]KV, n)
+	value := []byte("value")
+	for i := range items {
+		items[i] = KV{Key: fmt.Sprintf("key%d", i), Value: value, TTL: time.Minute}
+	}
+	return items
+}
+
+func BenchmarkCacheLoopedSet(b *testing.B) {
+	cache := NewCache(10000)
+	items := setManyItems(setManyBatchSize)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, item := range items {
+			cache.Set(item.Key, item.Value, item.TTL)
+		}
+	}
+}
+
+func BenchmarkCacheSetMany(b *testing.B) {
+	cache := NewCache(10000)
+	items := setManyItems(setManyBatchSize)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.SetMany(items)
+	}
+}
+
+func TestCacheOnEvict(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(2, clock)
+	
+	events := make(map[string]EvictionReason)
+	cache.OnEvict(func(key string, value []byte, reason EvictionReason) {
+		events[key] = reason
+	})
+	
+	cache.Set("key1", []byte("value1"), 0)
+	cache.Set("key2", []byte("value2"), 20*time.Millisecond)
+	cache.Set("key3", []byte("value3"), 0)
+	if reason, exists := events["key1"]; !exists || reason != EvictionCapacity {
+		t.Errorf("Expected key1 to be reported as evicted, got %v (exists=%v)", reason, exists)
+	}
+	
+	clock.Advance(50 * time.Millisecond)
+	cache.Cleanup()
+	if reason, exists := events["key2"]; !exists || reason != EvictionExpired {
+		t.Errorf("Expected key2 to be reported as expired, got %v (exists=%v)", reason, exists)
+	}
+	
+	// Deletes and tombstones are not evictions
+	cache.SetTombstoneTTL(10 * time.Millisecond)
+	cache.DeleteVersioned("key3", 1)
+	clock.Advance(20 * time.Millisecond)
+	cache.Cleanup()
+	if _, exists := events["key3"]; exists {
+		t.Error("Expected tombstone expiry not to be reported")
+	}
+	
+	if EvictionExpired.String() != "expired" || EvictionCapacity.String() != "evicted" {
+		t.Errorf("Unexpected reason names %q and %q", EvictionExpired, EvictionCapacity)
+	}
+}
+
+func TestCacheGetAndTouch(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(10, clock)
+	cache.Set("session", []byte("data"), 50*time.Millisecond)
+	cache.Set("permanent", []byte("data"), 0)
+	
+	values := cache.GetAndTouch([]string{"session", "missing", "permanent"}, time.Hour)
+	if len(values) != 2 || string(values["session"]) != "data" || string(values["permanent"]) != "data" {
+		t.Errorf("Expected the two present keys, got %v", values)
+	}
+	
+	// Present keys get the new TTL; absent keys are not created
+	for _, key := range []string{"session", "permanent"} {
+		entry, _ := cache.PeekEntry(key)
+		if remaining := entry.ExpiresAt.Sub(clock.Now()); remaining != time.Hour {
+			t.Errorf("Expected %s to expire in about an hour, got %v", key, remaining)
+		}
+	}
+	if _, exists := cache.Lookup("missing"); exists {
+		t.Error("Expected missing key not to be created")
+	}
+	
+	clock.Advance(100 * time.Millisecond)
+	if _, exists := cache.Get("session"); !exists {
+		t.Error("Expected touched key to outlive its original TTL")
+	}
+	
+	// A zero TTL removes the expiry
+	cache.GetAndTouch([]string{"session"}, 0)
+	if entry, _ := cache.PeekEntry("session"); !entry.ExpiresAt.IsZero() {
+		t.Errorf("Expected no expiry, got %v", entry.ExpiresAt)
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(10, clock)
+	cache.Set("key1", []byte("value1"), 0)
+	cache.Set("key2", []byte("value2"), time.Minute)
+	cache.Set("expired", []byte("value"), time.Millisecond)
+	cache.DeleteVersioned("deleted", 1)
+	clock.Advance(5 * time.Millisecond)
+	
+	snapshot := cache.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 live entries, got %d", len(snapshot))
+	}
+	if snapshot[0].Key != "key2" || snapshot[0].TTL <= 0 || snapshot[0].TTL > time.Minute {
+		t.Errorf("Expected key2 first with its remaining TTL, got %+v", snapshot[0])
+	}
+	if snapshot[1].Key != "key1" || snapshot[1].TTL != 0 {
+		t.Errorf("Expected key1 with no TTL, got %+v", snapshot[1])
+	}
+	
+	// Later mutations do not change the snapshot
+	cache.Set("key1", []byte("changed"), 0)
+	cache.Delete("key2")
+	cache.Set("key3", []byte("value3"), 0)
+	cache.Clear()
+	if len(snapshot) != 2 || string(snapshot[0].Value) != "value2" || string(snapshot[1].Value) != "value1" {
+		t.Errorf("Expected snapshot to be unaffected by mutations, got %+v", snapshot)
+	}
+}
+
+func TestCacheReplace(t *testing.T) {
+	cache := NewCache(10)
+	cache.Set("old", []byte("value"), 0)
+	cache.DeleteVersioned("deleted", 1)
+	
+	items := make([]KV, 12)
+	for i := range items {
+		items[i] = KV{Key: fmt.Sprintf("key%d", i), Value: []byte(fmt.Sprintf("value%d", i))}
+	}
+	items[11] = KV{Key: "key10", Value: []byte("newer")}
+	cache.Replace(items)
+	
+	// Only the most recent items fit, and later items win
+	if cache.Size() != 10 {
+		t.Errorf("Expected size 10, got %d", cache.Size())
+	}
+	if _, exists := cache.Get("key0"); exists {
+		t.Error("Expected the oldest item to be dropped over capacity")
+	}
+	if value, _ := cache.Get("key10"); string(value) != "newer" {
+		t.Errorf("Expected the later duplicate to win, got %s", value)
+	}
+	
+	// The previous contents, including tombstones, are gone
+	if _, exists := cache.Get("old"); exists {
+		t.Error("Expected old entry to be replaced")
+	}
+	if !cache.SetVersioned("deleted", []byte("value"), 0, 1) {
+		t.Error("Expected tombstone to be dropped by Replace")
+	}
+}
+
+func TestCacheReplaceIsAtomic(t *testing.T) {
+	const entries = 100
+	sets := make([][]KV, 2)
+	for s, prefix := range []string{"old", "new"} {
+		for i := 0; i < entries; i++ {
+			sets[s] = append(sets[s], KV{Key: fmt.Sprintf("%s%d", prefix, i), Value: []byte(prefix)})
+		}
+	}
+	
+	cache := NewCache(entries)
+	cache.Replace(sets[0])
+	
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				
+				// Every snapshot holds exactly one complete set
+				snapshot := cache.Snapshot()
+				if len(snapshot) != entries {
+					t.Errorf("Expected %d entries, got %d", entries, len(snapshot))
+					return
+				}
+				for _, item := range snapshot {
+					if string(item.Value) != string(snapshot[0].Value) {
+						t.Errorf("Expected a single set, saw %s and %s", snapshot[0].Value, item.Value)
+						return
+					}
+				}
+			}
+		}()
+	}
+	
+	for i := 0; i < 200; i++ {
+		cache.Replace(sets[i%2])
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestCacheTinyLFUScanResistance(t *testing.T) {
+	const (
+		capacity = 100
+		hotKeys  = 50
+		rounds   = 50
+		scanKeys = 200
+	)
+	
+	// hotHitRate replays a workload that reads a hot set between scans of keys seen once
+	// and returns the share of hot reads that hit
+	hotHitRate := func(policy Policy) float64 {
+		cache := NewCache(capacity)
+		cache.SetPolicy(policy)
+		
+		read := func(key string) bool {
+			if _, hit := cache.Get(key); hit {
+				return true
+			}
+			cache.Set(key, []byte("value"), 0)
+			return false
+		}
+		
+		hits, reads := 0, 0
+		scanned := 0
+		for round := 0; round < rounds; round++ {
+			for i := 0; i < hotKeys; i++ {
+				if read(fmt.Sprintf("hot%d", i)) && round > 0 {
+					hits++
+				}
+				if round > 0 {
+					reads++
+				}
+			}
+			for i := 0; i < scanKeys; i++ {
+				read(fmt.Sprintf("scan%d", scanned))
+				scanned++
+			}
+		}
+		return float64(hits) / float64(reads)
+	}
+	
+	lru := hotHitRate(PolicyLRU)
+	tinyLFU := hotHitRate(PolicyTinyLFU)
+	t.Logf("Hot set hit rate: %.2f with LRU, %.2f with TinyLFU", lru, tinyLFU)
+	
+	if lru > 0.1 {
+		t.Errorf("Expected scans to flush the hot set under LRU, hit rate %.2f", lru)
+	}
+	if tinyLFU < 0.9 {
+		t.Errorf("Expected TinyLFU to retain the hot set through scans, hit rate %.2f", tinyLFU)
+	}
+}
+
+func TestCacheTinyLFURejectsColdKeys(t *testing.T) {
+	cache := NewCache(2)
+	cache.SetPolicy(PolicyTinyLFU)
+	
+	cache.Set("a", []byte("value"), 0)
+	cache.Set("b", []byte("value"), 0)
+	for i := 0; i < 5; i++ {
+		cache.Get("a")
+		cache.Get("b")
+	}
+	
+	// A key never seen before is not worth evicting a frequently read one
+	cache.Set("cold", []byte("value"), 0)
+	if _, exists := cache.Get("cold"); exists {
+		t.Error("Expected cold key to be rejected")
+	}
+	if cache.GetStats()["rejected"].(uint64) != 1 {
+		t.Errorf("Expected 1 rejection, got %v", cache.GetStats()["rejected"])
+	}
+	
+	// Updates to cached keys are always applied
+	cache.Set("a", []byte("updated"), 0)
+	if value, _ := cache.Get("a"); string(value) != "updated" {
+		t.Errorf("Expected update to be applied, got %s", value)
+	}
+	
+	// A key read often enough is admitted in place of the least frequent entry
+	for i := 0; i < 20; i++ {
+		cache.Get("warm")
+	}
+	cache.Set("warm", []byte("value"), 0)
+	if _, exists := cache.Get("warm"); !exists {
+		t.Error("Expected frequently requested key to be admitted")
+	}
+	if cache.Size() != 2 {
+		t.Errorf("Expected size 2, got %d", cache.Size())
+	}
+	
+	// Entries nobody reads do not block new writes
+	unread := NewCache(2)
+	unread.SetPolicy(PolicyTinyLFU)
+	for _, key := range []string{"a", "b", "c"} {
+		unread.Set(key, []byte("value"), 0)
+	}
+	if _, exists := unread.Peek("c"); !exists {
+		t.Error("Expected new key to replace an unread entry")
+	}
+}
+
+func TestCacheEvictionPolicies(t *testing.T) {
+	// fill sets a, b and c in order, then reads a
+	fill := func(eviction Eviction) *Cache {
+		cache := NewCacheWithPolicy(3, eviction)
+		for _, key := range []string{"a", "b", "c"} {
+			cache.Set(key, []byte("value"), 0)
+		}
+		cache.Get("a")
+		return cache
+	}
+	
+	// LRU evicts the least recently used key, which is b after reading a
+	lru := fill(EvictLRU)
+	lru.Set("d", []byte("value"), 0)
+	if _, exists := lru.Peek("b"); exists {
+		t.Error("Expected LRU to evict b")
+	}
+	if _, exists := lru.Peek("a"); !exists {
+		t.Error("Expected LRU to keep recently read a")
+	}
+	
+	// FIFO evicts the oldest insertion even though it was read
+	fifo := fill(EvictFIFO)
+	fifo.Set("a", []byte("updated"), 0)
+	fifo.Set("d", []byte("value"), 0)
+	if _, exists := fifo.Peek("a"); exists {
+		t.Error("Expected FIFO to evict a despite the read and update")
+	}
+	for _, key := range []string{"b", "c", "d"} {
+		if _, exists := fifo.Peek(key); !exists {
+			t.Errorf("Expected FIFO to keep %s", key)
+		}
+	}
+	
+	// Random evicts exactly one key, not always the same one
+	victims := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		cache := fill(EvictRandom)
+		cache.Set("d", []byte("value"), 0)
+		if cache.Size() != 3 {
+			t.Fatalf("Expected size 3, got %d", cache.Size())
+		}
+		for _, key := range []string{"a", "b", "c"} {
+			if _, exists := cache.Peek(key); !exists {
+				victims[key] = true
+			}
+		}
+	}
+	if len(victims) < 2 {
+		t.Errorf("Expected random eviction to pick varying victims, got %v", victims)
+	}
+}
+
+func TestParseEviction(t *testing.T) {
+	for _, eviction := range []Eviction{EvictLRU, EvictFIFO, EvictRandom} {
+		parsed, err := ParseEviction(eviction.String())
+		if err != nil || parsed != eviction {
+			t.Errorf("Expected %s to parse to itself, got %v, %v", eviction, parsed, err)
+		}
+	}
+	if _, err := ParseEviction("mru"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestCacheExists(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(2, clock)
+	cache.Set("live", []byte("value"), 0)
+	cache.Set("expiring", []byte("value"), 20*time.Millisecond)
+	
+	if !cache.Exists("live") || !cache.Exists("expiring") {
+		t.Error("Expected live keys to exist")
+	}
+	if cache.Exists("missing") {
+		t.Error("Expected missing key not to exist")
+	}
+	
+	clock.Advance(40 * time.Millisecond)
+	if cache.Exists("expiring") {
+		t.Error("Expected expired key not to exist")
+	}
+	
+	cache.DeleteVersioned("live", 1)
+	if cache.Exists("live") {
+		t.Error("Expected deleted key not to exist")
+	}
+	
+	// Checks do not count as reads or promote the entry
+	lru := NewCache(2)
+	lru.Set("a", []byte("value"), 0)
+	lru.Set("b", []byte("value"), 0)
+	lru.Exists("a")
+	lru.Set("c", []byte("value"), 0)
+	if lru.Exists("a") {
+		t.Error("Expected Exists not to protect a from eviction")
+	}
+	if stats := lru.GetStats(); stats["hits"].(uint64) != 0 || stats["misses"].(uint64) != 0 {
+		t.Errorf("Expected no hits or misses, got %v and %v", stats["hits"], stats["misses"])
+	}
+}
+
+func TestCacheForEach(t *testing.T) {
+	const entries = 2500 // Spans several chunks
+	clock := newFakeClock()
+	cache := NewCacheWithClock(entries + 10, clock)
+	for i := 0; i < entries; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	cache.Set("expired", []byte("value"), time.Millisecond)
+	cache.DeleteVersioned("deleted", 1)
+	clock.Advance(5 * time.Millisecond)
+	
+	visited := make(map[string]bool)
+	cache.ForEach(context.Background(), func(key string, value []byte) bool {
+		if visited[key] {
+			t.Errorf("Visited %s twice", key)
+		}
+		visited[key] = true
+		
+		// The lock is not held while fn runs
+		cache.Get(key)
+		return true
+	})
+	if len(visited) != entries {
+		t.Errorf("Expected %d live entries visited, got %d", entries, len(visited))
+	}
+	if visited["expired"] || visited["deleted"] {
+		t.Error("Expected expired entries and tombstones to be skipped")
+	}
+	
+	// Returning false stops the iteration
+	count := 0
+	cache.ForEach(context.Background(), func(key string, value []byte) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("Expected iteration to stop after 10 entries, got %d", count)
+	}
+}
+
+func TestCacheForEachCanceled(t *testing.T) {
+	cache := NewCache(5000)
+	for i := 0; i < 5000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	count := 0
+	cache.ForEach(ctx, func(key string, value []byte) bool {
+		count++
+		if count == 1500 {
+			cancel()
+		}
+		return true
+	})
+	if count != 1500 {
+		t.Errorf("Expected iteration to stop once canceled after 1500 entries, got %d", count)
+	}
+	
+	// An already canceled context visits nothing
+	count = 0
+	cache.ForEach(ctx, func(key string, value []byte) bool {
+		count++
+		return true
+	})
+	if count != 0 {
+		t.Errorf("Expected no entries visited with a canceled context, got %d", count)
+	}
+}
+
+func TestCacheLockTiming(t *testing.T) {
+	cache := NewCache(10)
+	cache.Set("key", []byte("value"), 0)
+	if waits := cache.GetStats()["lock_waits"].(uint64); waits != 0 {
+		t.Errorf("Expected no lock waits recorded while timing is off, got %d", waits)
+	}
+	
+	cache.SetLockTiming(true)
+	
+	// Hold the lock so that a read has to wait for it, starting the wait only once the
+	// reader is running
+	cache.mu.Lock()
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		close(started)
+		cache.Get("key")
+	}()
+	<-started
+	time.Sleep(20 * time.Millisecond)
+	cache.mu.Unlock()
+	<-done
+	
+	stats := cache.GetStats()
+	if waits := stats["lock_waits"].(uint64); waits < 1 {
+		t.Errorf("Expected at least one lock wait, got %d", waits)
+	}
+	if wait := stats["lock_wait"].(time.Duration); wait < 10*time.Millisecond {
+		t.Errorf("Expected at least 10ms of lock wait, got %v", wait)
+	}
+}
+
+func TestCacheScan(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	for _, key := range []string{"user:3", "user:1", "order:1", "user:2", "users"} {
+		cache.Set(key, []byte("value-"+key), 0)
+	}
+	cache.SetVersioned("user:1", []byte("versioned"), 0, 42)
+	cache.DeleteVersioned("user:2", 1)
+	cache.Set("user:4", []byte("expiring"), 20*time.Millisecond)
+	
+	keys := func(entries []Entry) string {
+		result := make([]string, len(entries))
+		for i, entry := range entries {
+			result[i] = entry.Key
+		}
+		return strings.Join(result, ",")
+	}
+	
+	// Keys are matched by prefix and returned in order, and tombstones are skipped
+	entries := cache.Scan("user:")
+	if got := keys(entries); got != "user:1,user:3,user:4" {
+		t.Errorf("Expected user:1,user:3,user:4, got %s", got)
+	}
+	if string(entries[0].Value) != "versioned" || entries[0].Version != 42 {
+		t.Errorf("Expected user:1 with its value and version, got %q at %d", entries[0].Value, entries[0].Version)
+	}
+	if entries[0].Prev != nil || entries[0].Next != nil {
+		t.Error("Expected scanned entries to be detached from the list")
+	}
+	
+	if got := len(cache.Scan("")); got != 5 {
+		t.Errorf("Expected an empty prefix to select all 5 live entries, got %d", got)
+	}
+	if got := keys(cache.Scan("missing:")); got != "" {
+		t.Errorf("Expected no matches, got %s", got)
+	}
+	
+	clock.Advance(40 * time.Millisecond)
+	if got := keys(cache.Scan("user:")); got != "user:1,user:3" {
+		t.Errorf("Expected expired entries to be skipped, got %s", got)
+	}
+	
+	// Pages include tombstones and continue after the last key returned
+	page, more := cache.ScanPage("user:", "", 2)
+	if got := keys(page); got != "user:1,user:2" || !more || !page[1].Tombstone || page[1].Version != 1 {
+		t.Errorf("Expected user:1 and the user:2 tombstone with more to follow, got %s, %v", got, more)
+	}
+	page, more = cache.ScanPage("user:", "user:2", 2)
+	if got := keys(page); got != "user:3" || more {
+		t.Errorf("Expected the last page to hold user:3, got %s, %v", got, more)
+	}
+}
+
+func TestCacheRange(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	for _, key := range []string{"ts:03", "ts:01", "ts:05", "ts:02", "ts:04", "other"} {
+		cache.Set(key, []byte("value-"+key), 0)
+	}
+	cache.DeleteVersioned("ts:02", 1)
+	cache.Set("ts:025", []byte("expiring"), 20*time.Millisecond)
+	
+	keys := func(items []KV) string {
+		result := make([]string, len(items))
+		for i, item := range items {
+			result[i] = item.Key
+		}
+		return strings.Join(result, ",")
+	}
+	
+	// The start is inclusive, the end exclusive and tombstones are skipped
+	items := cache.Range("ts:01", "ts:04")
+	if got := keys(items); got != "ts:01,ts:025,ts:03" {
+		t.Errorf("Expected ts:01,ts:025,ts:03, got %s", got)
+	}
+	if string(items[0].Value) != "value-ts:01" || items[0].TTL != 0 {
+		t.Errorf("Expected ts:01 with its value and no TTL, got %+v", items[0])
+	}
+	if items[1].TTL <= 0 {
+		t.Errorf("Expected ts:025 to report its remaining TTL, got %v", items[1].TTL)
+	}
+	
+	if got := keys(cache.Range("ts:04", "")); got != "ts:04,ts:05" {
+		t.Errorf("Expected an empty end to be unbounded, got %s", got)
+	}
+	if got := keys(cache.Range("ts:06", "ts:09")); got != "" {
+		t.Errorf("Expected an empty range, got %s", got)
+	}
+	
+	clock.Advance(40 * time.Millisecond)
+	if got := keys(cache.Range("ts:02", "ts:03")); got != "" {
+		t.Errorf("Expected expired entries to be skipped, got %s", got)
+	}
+}
+
+func TestCacheBloomFilter(t *testing.T) {
+	const capacity = 1000
+	cache := NewCache(capacity)
+	cache.Set("before", []byte("value"), 0)
+	cache.SetBloomFilter(true)
+	
+	// Keys written while the filter is rebuilt concurrently must never go missing
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < capacity/4-1; i++ {
+				cache.Set(fmt.Sprintf("key%d-%d", w, i), []byte("value"), 0)
+			}
+		}(w)
+	}
+	for i := 0; i < 10; i++ {
+		cache.RebuildBloomFilter()
+	}
+	wg.Wait()
+	
+	present := []string{"before"}
+	for w := 0; w < 4; w++ {
+		for i := 0; i < capacity/4-1; i++ {
+			present = append(present, fmt.Sprintf("key%d-%d", w, i))
+		}
+	}
+	for _, key := range present {
+		if !cache.Exists(key) {
+			t.Fatalf("Expected present key %s to exist", key)
+		}
+		if _, exists := cache.Get(key); !exists {
+			t.Fatalf("Expected present key %s to be found", key)
+		}
+	}
+	
+	// Nearly every absent key is rejected by the filter alone
+	for i := 0; i < 1000; i++ {
+		cache.Get(fmt.Sprintf("absent%d", i))
+	}
+	stats := cache.GetStats()
+	if rejects := stats["bloom_rejects"].(uint64); rejects < 950 {
+		t.Errorf("Expected at least 950 of 1000 absent reads rejected by the filter, got %d", rejects)
+	}
+	if rate := stats["bloom_fp_rate"].(float64); rate <= 0 || rate > 0.02 {
+		t.Errorf("Expected a false positive rate near 1%% when full, got %f", rate)
+	}
+	
+	// Replacing every key saturates the filter until it is rebuilt
+	for i := 0; i < capacity; i++ {
+		cache.Set(fmt.Sprintf("new%d", i), []byte("value"), 0)
+	}
+	saturated := cache.GetStats()["bloom_fp_rate"].(float64)
+	cache.RebuildBloomFilter()
+	if rebuilt := cache.GetStats()["bloom_fp_rate"].(float64); rebuilt >= saturated {
+		t.Errorf("Expected a rebuild to lower the false positive rate, got %f from %f", rebuilt, saturated)
+	}
+	if !cache.Exists("new0") {
+		t.Error("Expected present key to exist after a rebuild")
+	}
+	
+	cache.SetBloomFilter(false)
+	if rate := cache.GetStats()["bloom_fp_rate"].(float64); rate != 0 {
+		t.Errorf("Expected no false positive rate with the filter off, got %f", rate)
+	}
+}
+
+func TestCacheEncryption(t *testing.T) {
+	if _, err := NewCacheWithEncryption(100, []byte("short")); err == nil {
+		t.Error("Expected a key that is not 16, 24 or 32 bytes to be rejected")
+	}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cache, err := NewCacheWithEncryption(100, key)
+	if err != nil {
+		t.Fatalf("NewCacheWithEncryption failed: %v", err)
+	}
+	
+	// Stored bytes are ciphertext, while reads return the plaintext
+	secret := []byte("correct horse battery staple")
+	cache.Set("secret", secret, 0)
+	stored := cache.entries["secret"].Value
+	if strings.Contains(string(stored), string(secret)) || len(stored) != len(secret)+28 {
+		t.Errorf("Expected %d bytes of nonce, ciphertext and tag, got %q", len(secret)+28, stored)
+	}
+	if got, found := cache.Get("secret"); !found || string(got) != string(secret) {
+		t.Errorf("Expected Get to return the plaintext, got %q (found=%v)", got, found)
+	}
+	if items := cache.Snapshot(); len(items) != 1 || string(items[0].Value) != string(secret) {
+		t.Errorf("Expected Snapshot to return the plaintext, got %v", items)
+	}
+	
+	// Each write gets a fresh nonce, so equal values do not give equal ciphertext
+	cache.Set("same", secret, 0)
+	if string(cache.entries["same"].Value) == string(stored) {
+		t.Error("Expected equal values to be encrypted differently")
+	}
+	
+	// A value moved to another key does not decrypt, and is a counted miss
+	cache.Set("moved", []byte("other"), 0)
+	cache.entries["moved"].Value = stored
+	if got, found := cache.Get("moved"); found || got != nil {
+		t.Errorf("Expected a value sealed under another key to be a miss, got %q (found=%v)", got, found)
+	}
+	if _, found := cache.Lookup("moved"); found {
+		t.Error("Expected Lookup of an undecryptable value to be a miss")
+	}
+	for _, item := range cache.Snapshot() {
+		if item.Key == "moved" {
+			t.Error("Expected Snapshot to leave out an undecryptable value")
+		}
+	}
+	if fails := cache.GetStats()["decrypt_fails"]; fails != uint64(3) {
+		t.Errorf("Expected 3 decryption failures, got %v", fails)
+	}
+	cache.Delete("moved")
+	
+	cache.Set("empty", []byte{}, 0)
+	if got, found := cache.Get("empty"); !found || got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty value, got %q (found=%v)", got, found)
+	}
+	
+	// Values stay encrypted off-heap and across Replace
+	cache.SetOffHeap(true)
+	entry := cache.entries["secret"]
+	if offHeap := cache.arena.read(entry.ref); strings.Contains(string(offHeap), string(secret)) {
+		t.Error("Expected ciphertext in the arena")
+	}
+	if got, _ := cache.Get("secret"); string(got) != string(secret) {
+		t.Errorf("Expected the plaintext from off-heap, got %q", got)
+	}
+	cache.SetOffHeap(false)
+	cache.Replace([]KV{{Key: "replaced", Value: secret}})
+	if strings.Contains(string(cache.entries["replaced"].Value), string(secret)) {
+		t.Error("Expected replaced values to be encrypted")
+	}
+	if got, _ := cache.Get("replaced"); string(got) != string(secret) {
+		t.Errorf("Expected the replaced plaintext, got %q", got)
+	}
+}
+
+func TestCacheOffHeap(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCacheWithClock(100, clock)
+	cache.Set("before", []byte("heap"), 0)
+	cache.SetOffHeap(true)
+	
+	var evicted []string
+	cache.OnEvict(func(key string, value []byte, reason EvictionReason) {
+		evicted = append(evicted, key+"="+string(value))
+	})
+	
+	// Values of every size class, one longer than a region and an empty one survive the move
+	large := []byte(strings.Repeat("x", 1<<arenaChunkShift+1))
+	values := map[string][]byte{"before": []byte("heap"), "empty": {}, "large": large}
+	for i := 0; i < arenaClasses; i++ {
+		values[fmt.Sprintf("class%d", i)] = []byte(strings.Repeat(fmt.Sprint(i%10), 1<<(arenaMinShift+i)))
+	}
+	for key, value := range values {
+		cache.Set(key, value, 0)
+	}
+	for key, want := range values {
+		if got, found := cache.Get(key); !found || string(got) != string(want) {
+			t.Errorf("Expected %s to hold %d bytes, got %d (found=%v)", key, len(want), len(got), found)
+		}
+	}
+	mapped := cache.GetStats()["offheap_bytes"].(uint64)
+	if mapped <= uint64(len(large)) {
+		t.Errorf("Expected values to be mapped off-heap, got %d bytes", mapped)
+	}
+	
+	// Reads return copies, safe to modify and to keep after the value is overwritten
+	value, _ := cache.Get("class0")
+	value[0] = '!'
+	cache.Set("class0", []byte("overwritten"), 0)
+	if got, _ := cache.Get("class0"); string(got) != "overwritten" || value[1] != '0' {
+		t.Errorf("Expected reads to be copies, got %q and %q", got, value)
+	}
+	
+	// Freed slots are reused and values of their own region are unmapped
+	for i := 0; i < 1000; i++ {
+		cache.Set("churn", []byte(fmt.Sprintf("value%d", i)), 0)
+		cache.Delete("churn")
+	}
+	cache.Delete("large")
+	if after := cache.GetStats()["offheap_bytes"].(uint64); after != mapped-uint64(len(large)) {
+		t.Errorf("Expected %d bytes mapped after churn, got %d", mapped-uint64(len(large)), after)
+	}
+	
+	// Listeners see evicted values, and versioned deletes free them
+	cache.Set("expiring", []byte("soon"), time.Millisecond)
+	clock.Advance(5 * time.Millisecond)
+	cache.Cleanup()
+	if len(evicted) != 1 || evicted[0] != "expiring=soon" {
+		t.Errorf("Expected the expired value to be reported, got %v", evicted)
+	}
+	cache.SetVersioned("versioned", []byte("value"), 0, 1)
+	cache.DeleteVersioned("versioned", 2)
+	if _, found := cache.Get("versioned"); found {
+		t.Error("Expected versioned key to be deleted")
+	}
+	
+	// Replace and turning the option off keep values intact
+	cache.Replace([]KV{{Key: "replaced", Value: []byte("value")}})
+	cache.SetOffHeap(false)
+	if got, found := cache.Get("replaced"); !found || string(got) != "value" {
+		t.Errorf("Expected replaced value to survive leaving the arena, got %q", got)
+	}
+	if mapped := cache.GetStats()["offheap_bytes"].(uint64); mapped != 0 {
+		t.Errorf("Expected nothing mapped once off, got %d bytes", mapped)
+	}
+}
+
+// benchmarkCacheGC times a full garbage collection with a cache holding 20000 values
+// of 4KiB, reporting the stop-the-world pauses of each collection
+func benchmarkCacheGC(b *testing.B, offHeap bool) {
+	cache := NewCache(20000)
+	cache.SetOffHeap(offHeap)
+	for i := 0; i < 20000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), make([]byte, 4096), 0)
+	}
+	runtime.GC()
+	
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "pause-ns/op")
+	b.ReportMetric(float64(after.HeapAlloc)/(1<<20), "heap-MiB")
+	runtime.KeepAlive(cache)
+}
+
+func BenchmarkCacheGCHeap(b *testing.B) {
+	benchmarkCacheGC(b, false)
+}
+
+func BenchmarkCacheGCOffHeap(b *testing.B) {
+	benchmarkCacheGC(b, true)
+}
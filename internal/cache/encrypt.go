@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// valueCipher encrypts values with AES-GCM before the cache stores them, so that a heap
+// dump or a read of the off-heap arena shows only ciphertext. Each value is sealed
+// under a fresh random nonce, stored ahead of the ciphertext, with its key as
+// additional data, so a value cannot be moved to another key without failing to
+// decrypt. Keys themselves are kept in plaintext for lookup.
+type valueCipher struct {
+	aead cipher.AEAD
+}
+
+// newValueCipher creates a cipher from an AES key of 16, 24 or 32 bytes
+func newValueCipher(key []byte) (*valueCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &valueCipher{aead: aead}, nil
+}
+
+// seal returns the nonce and ciphertext of a value stored under key
+func (v *valueCipher) seal(key string, value []byte) []byte {
+	nonceSize := v.aead.NonceSize()
+	sealed := make([]byte, nonceSize, nonceSize+len(value)+v.aead.Overhead())
+	if _, err := rand.Read(sealed); err != nil {
+		panic(fmt.Sprintf("failed to generate nonce: %v", err))
+	}
+	return v.aead.Seal(sealed, sealed, value, []byte(key))
+}
+
+// open returns the plaintext of a value sealed under key. Empty input, the value of a
+// tombstone, is returned as is. Values are only ever sealed by the cache itself, so
+// one that fails to authenticate has been corrupted in memory, or moved to another
+// key, and is reported with an error rather than returned.
+func (v *valueCipher) open(key string, sealed []byte) ([]byte, error) {
+	if len(sealed) == 0 {
+		return sealed, nil
+	}
+	nonceSize := v.aead.NonceSize()
+	if len(sealed) < nonceSize+v.aead.Overhead() {
+		return nil, fmt.Errorf("sealed value of %d bytes is too short", len(sealed))
+	}
+	value := make([]byte, 0, len(sealed)-nonceSize-v.aead.Overhead())
+	return v.aead.Open(value, sealed[:nonceSize], sealed[nonceSize:], []byte(key))
+}
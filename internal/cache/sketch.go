@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"hash/fnv"
+)
+
+const (
+	// sketchDepth is the number of counter rows; an estimate is the minimum across them
+	sketchDepth = 4
+
+	// sketchMaxCount caps each counter, as in TinyLFU's 4-bit counters
+	sketchMaxCount = 15
+
+	// sketchWidthFactor sets how many counters each row has per cache entry, keeping
+	// collisions between keys rare
+	sketchWidthFactor = 4
+
+	// sketchSampleFactor sets how many increments, per cache entry, are recorded before
+	// every counter is halved
+	sketchSampleFactor = 10
+)
+
+// frequencySketch is a count-min sketch of recent key accesses. Counters are halved
+// once enough increments have been recorded, so the frequencies decay exponentially
+// and keys that were popular long ago stop looking hot.
+type frequencySketch struct {
+	rows       [sketchDepth][]uint8
+	mask       uint32
+	additions  int
+	sampleSize int
+}
+
+// newFrequencySketch creates a sketch sized for a cache holding capacity entries
+func newFrequencySketch(capacity int) *frequencySketch {
+	width := 16
+	for width < sketchWidthFactor*capacity {
+		width *= 2
+	}
+	
+	s := &frequencySketch{
+		mask:       uint32(width - 1),
+		sampleSize: sketchSampleFactor * capacity,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// indexes returns the counter position of a key in each row, deriving the row hashes
+// from two halves of one 64-bit hash
+func (s *frequencySketch) indexes(key string) [sketchDepth]uint32 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+	var idx [sketchDepth]uint32
+	for i := range idx {
+		idx[i] = (h1 + uint32(i)*h2) & s.mask
+	}
+	return idx
+}
+
+// increment records an access to a key, halving every counter once the sample is full
+func (s *frequencySketch) increment(key string) {
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < sketchMaxCount {
+			s.rows[i][j]++
+		}
+	}
+	
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// estimate returns the approximate number of recent accesses to a key
+func (s *frequencySketch) estimate(key string) uint8 {
+	min := uint8(sketchMaxCount)
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < min {
+			min = s.rows[i][j]
+		}
+	}
+	return min
+}
+
+// reset halves every counter, ageing out old accesses
+func (s *frequencySketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+}
@@ -1,178 +1,1745 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/shard-cache/internal/cache"
 	"github.com/shard-cache/internal/ring"
 	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // Registers the gzip compressor
 	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// defaultPingTimeout bounds a Ping when no PingTimeout is configured
+const defaultPingTimeout = 500 * time.Millisecond
+
+// defaultMaxMsgSize is the largest gRPC message received or sent when no limit is configured
+const defaultMaxMsgSize = 16 << 20
+
+// defaultPingInterval is how often nodes are pinged for PreferNearestReplica when no PingInterval is configured
+const defaultPingInterval = 5 * time.Second
+
+// namespaceSeparator joins a namespace to the keys stored under it
+const namespaceSeparator = ":"
+
+// defaultConnectTimeout bounds how long an eager AddNode waits for a connection
+const defaultConnectTimeout = 5 * time.Second
+
+// defaultLocalCacheCapacity bounds the local fallback cache when no capacity is configured
+const defaultLocalCacheCapacity = 1000
+
+// defaultClientCacheCapacity bounds the local read cache when no capacity is configured
+const defaultClientCacheCapacity = 1000
+
+// defaultMaxKeyBytes matches the server's default key length limit
+const defaultMaxKeyBytes = 4096
+
+// ErrInvalidKey is returned for keys that are empty, too long or not valid UTF-8
+var ErrInvalidKey = errors.New("invalid key")
+
+// ErrStale is returned by Get along with a value served from the local fallback cache
+// because none of the key's owners could be reached
+var ErrStale = errors.New("owners unreachable, serving stale local copy")
+
+// ErrKeyNotFound is returned when the owners that were asked answered that they do not
+// hold a key, as opposed to failing to answer; reads do not fall back to a local copy
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrNoMajority is returned by GetConsistent when replicas disagree and no value is held by a majority of them
+var ErrNoMajority = errors.New("replicas disagree and no majority value exists")
+
+// ErrReplicasDisagree is returned by a ConsistencyStrict Get when the owners it read hold
+// different values or versions
+var ErrReplicasDisagree = errors.New("replicas disagree on the value")
+
+// ErrVersionMismatch is returned by DeleteIf when too few owners hold the expected version
+var ErrVersionMismatch = errors.New("key does not hold the expected version")
+
+// ErrInsufficientNodes is returned by writes when the ring has fewer owners for the key
+// than the write quorum needs; nothing is written to any of them
+var ErrInsufficientNodes = errors.New("not enough nodes for the write quorum")
+
+// Consistency selects how many owners an operation waits for
+type Consistency int
+
+const (
+	// ConsistencyQuorum reads from ReadQuorum owners and waits for WriteQuorum acknowledgments.
+	// An owner that misses the key is passed over like one that fails, so that a replica
+	// which missed a write does not hide the key; Get returns ErrKeyNotFound only if
+	// none of them holds it.
+	ConsistencyQuorum Consistency = iota
+
+	// ConsistencyOne reads from a single owner, normally the primary, and returns from writes after the
+	// first acknowledgment, trading durability for latency. A miss from that owner is
+	// returned as ErrKeyNotFound without asking another.
+	ConsistencyOne
+
+	// ConsistencyStrict makes Get read ReadQuorum owners at once and fail with
+	// ErrReplicasDisagree unless they all hold the same version of the value, or all
+	// lack the key, for callers that would rather fail than serve a stale value. It
+	// skips the client cache and the local fallback. Other operations behave as under
+	// ConsistencyQuorum.
+	ConsistencyStrict
+)
+
+// consistencyKey is the context key for per-call consistency overrides
+type consistencyKey struct{}
+
+// WithConsistency returns a context that overrides the client's consistency for calls made with it
+func WithConsistency(ctx context.Context, consistency Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, consistency)
+}
+
+// DeleteConsistency selects how many owners Delete waits for
+type DeleteConsistency int
+
+const (
+	// DeleteQuorum returns once WriteQuorum owners have deleted the key, like other writes
+	DeleteQuorum DeleteConsistency = iota
+
+	// DeleteAll waits for every owner to delete the key, so that no replica is left
+	// holding the value to resurrect it, and fails naming the owners that did not confirm
+	DeleteAll
+)
+
+// deleteConsistencyKey is the context key for per-call delete consistency overrides
+type deleteConsistencyKey struct{}
+
+// WithDeleteConsistency returns a context that overrides the client's delete consistency
+// for Deletes made with it
+func WithDeleteConsistency(ctx context.Context, consistency DeleteConsistency) context.Context {
+	return context.WithValue(ctx, deleteConsistencyKey{}, consistency)
+}
+
 // Client represents a distributed cache client
 type Client struct {
 	ring       *ring.Ring
 	logger     *zap.Logger
 	connections map[string]*grpc.ClientConn
+	dialed      map[string]*grpc.ClientConn // Dialed by SetTopology ahead of the ring change adding the node
 	connMutex   sync.RWMutex
 	
 	// Quorum settings
-	readQuorum  int
-	writeQuorum int
+	readQuorum        int
+	writeQuorum       int
+	dynamicQuorum     bool
+	replicationFactor int
+	consistency       Consistency
+	deleteConsistency DeleteConsistency
+	
+	// Hedging settings
+	hedgeTimeout      time.Duration
+	hedgeRatio        float64
+	latency           *latencyTracker
+	latencyAwareReads bool
+	
+	// Consistency checking
+	readRepair  bool
+	divergences uint64
+	clock       hybridClock // Issues write versions
+	
+	// Node reachability
+	eagerConnect   bool
+	connectTimeout time.Duration
+	pingTimeout    time.Duration
+	pingRTTs       map[string]time.Duration
+	nodeLoads      map[string]NodeLoad // As each node reported in its last answer to a ping
+	pingMutex      sync.RWMutex
+	pingInterval   time.Duration
+	preferNearest  bool
+	pingStop       chan struct{} // Closed to stop the pinger
+	pingDone       chan struct{}
+	pingStopOnce   sync.Once
+	detector       *failureDetector
+	phiThreshold   float64
+	
+	// Unary RPCs allowed in flight to each node; zero for no limit
+	maxPerNode       int
+	nodeQueueTimeout time.Duration
+	nodeLimits       map[string]*semaphore.Weighted // Created on each node's first call under the limit
+	limitMutex       sync.RWMutex
+	throttled        uint64 // RPCs refused with ErrNodeSaturated
+	
+	// Key prefix applied to every operation
+	namespace   string
+	maxKeyBytes int
+	
+	// Largest gRPC message received from or sent to a node
+	maxRecvMsgSize int
+	maxSendMsgSize int
+	compressor     string // gRPC compressor for requests; empty for none
+	
+	// Recent reads served when owners are unreachable
+	localCache *cache.Cache
+	staleReads uint64
+	
+	// Recent reads served without an RPC; nil unless ClientCacheTTL is set
+	readCache     *cache.Cache
+	readCacheTTL  time.Duration
+	readCacheHits uint64
+	readCacheGens [readCacheStripes]uint64 // Write generations, by key hash
+	
+	// Reads of the owners in progress, shared by concurrent Gets of the same key
+	flights singleflight.Group
+	
+	// Buffered writes; nil unless write-back is enabled
+	writeBack *writeBuffer
+	
+	// Tracing; nil when disabled
+	tracer trace.Tracer
+}
+
+// Config holds client configuration
+type Config struct {
+	ReadQuorum   int
+	WriteQuorum  int
+	HedgeTimeout time.Duration
+	HedgeRatio   float64
+	
+	// ReplicationFactor is how many owners every write is sent to; WriteQuorum of them
+	// must acknowledge it. It must be at least ReadQuorum and WriteQuorum, and defaults
+	// to the larger of the two.
+	ReplicationFactor int
+	
+	// DynamicQuorum replaces ReadQuorum and WriteQuorum with a majority of the owners
+	// the current membership provides for a key, floor(n/2)+1 where n is the smaller
+	// of the node count and the replication factor, so that shrinking the cluster below
+	// the configured quorum leaves the cache available. Quorums that shrink with the
+	// membership no longer overlap with those taken before, so a read may miss a write
+	// acknowledged by nodes that have since left; it is off by default.
+	DynamicQuorum bool
+	
+	// LatencyAwareReads sends each read first to the faster of two randomly picked
+	// healthy owners, judged by a moving average of their recent read latencies,
+	// instead of always to the primary. It applies to ConsistencyOne and hedged reads;
+	// the hedge goes to the next owner in ring order.
+	LatencyAwareReads bool
+	
+	// Consistency is the default for every call; override per call with WithConsistency
+	Consistency Consistency
+	
+	// DeleteConsistency is the default for Delete; override per call with
+	// WithDeleteConsistency. Under DeleteAll the per-call Consistency is ignored.
+	DeleteConsistency DeleteConsistency
+	
+	// ReadRepair rewrites the majority value to replicas found to disagree by GetConsistent,
+	// and the newest value to those found by a ConsistencyStrict Get
+	ReadRepair bool
+	
+	// PingTimeout bounds the Health call made by Ping
+	PingTimeout time.Duration
+	
+	// The client pings every node each PingInterval (5s by default), giving the failure
+	// detector heartbeats from idle nodes. PreferNearestReplica orders the owners of a
+	// key by their most recent round trip, so that reads go to the closest replica
+	// first and hedges to the next closest. Reads choose among all ReplicationFactor
+	// owners rather than the first ReadQuorum in ring order, so under a write quorum
+	// below the replication factor they may miss a recent write that has not yet
+	// reached the nearest replica. Writes still go to the canonical owners. It takes
+	// precedence over LatencyAwareReads.
+	PreferNearestReplica bool
+	PingInterval         time.Duration
+	
+	// PhiThreshold is the phi-accrual suspicion level at which a node's circuit
+	// breaker opens and reads prefer its other replicas. Responses to every RPC,
+	// including Ping, act as heartbeats, and a response much slower than the node's
+	// recent ones raises suspicion too. A suspected node is probed with a Ping at
+	// most once a second so that it is trusted again as soon as it answers
+	// promptly. Defaults to 8.
+	PhiThreshold float64
+	
+	// EagerConnect makes AddNode wait until the connection is ready, failing if the
+	// node cannot be reached within ConnectTimeout (5s by default)
+	EagerConnect   bool
+	ConnectTimeout time.Duration
+	
+	// MaxPerNodeConcurrency bounds the unary RPCs this client has in flight to each
+	// node, so that a hot key cannot flood one node with requests. A call over the
+	// limit waits up to PerNodeQueueTimeout for a slot and then fails with
+	// ErrNodeSaturated, at once if the timeout is zero; reads then move on to the key's
+	// other owners as they would after any failure. Zero, the default, leaves calls
+	// unbounded. Streams and the Health calls of Ping are not limited. Both can be
+	// changed later with SetMaxPerNodeConcurrency.
+	MaxPerNodeConcurrency int
+	PerNodeQueueTimeout   time.Duration
+	
+	// HashMode selects how keys are routed to nodes; jump hashing requires
+	// every client to add nodes in the same order
+	HashMode ring.HashMode
+	
+	// TimeRouting records how long owner selection on the ring takes, reported by
+	// GetStats as owner_lookups and owner_time, at the cost of two clock reads per lookup
+	TimeRouting bool
+	
+	// Namespace transparently prefixes every key so that several logical caches
+	// can share nodes without colliding. It must not contain ":".
+	Namespace string
+	
+	// EnableLocalFallback keeps the most recent LocalCacheCapacity read results (1000 by
+	// default) in process and serves them, flagged with ErrStale, when no owner of a key
+	// can be reached
+	EnableLocalFallback bool
+	LocalCacheCapacity  int
+	
+	// ClientCacheTTL keeps successful Get results in process for this long and serves
+	// repeated reads from them without an RPC, so reads may miss writes made by other
+	// clients for up to the TTL. Set and Delete through this client invalidate the
+	// local copy. At most ClientCacheCapacity results (1000 by default) are kept.
+	ClientCacheTTL      time.Duration
+	ClientCacheCapacity int
+	
+	// EnableTracing creates OpenTelemetry spans around operations and their RPCs and
+	// propagates them to the servers. Spans go to TracerProvider, or the global
+	// provider if it is nil.
+	EnableTracing  bool
+	TracerProvider trace.TracerProvider
+	
+	// WriteBack makes Set buffer writes in process and return immediately. A background
+	// flusher sends them to their owners in batches every WriteBackInterval (100ms by
+	// default) or once WriteBackBufferSize distinct keys (1000 by default) are waiting,
+	// sending only the newest write to each key. Buffered writes that have not been
+	// flushed are lost if the process crashes, or once five flushes have failed to
+	// deliver them, and errors from flushing are only logged; call Flush to drain the
+	// buffer and see them. Close flushes pending writes.
+	WriteBack           bool
+	WriteBackInterval   time.Duration
+	WriteBackBufferSize int
+	
+	// MaxKeyBytes is the longest key, including the namespace prefix, sent to the
+	// nodes; it should not exceed the servers' limit. Defaults to 4096.
+	MaxKeyBytes int
+	
+	// MaxRecvMsgSize and MaxSendMsgSize cap the size of a single gRPC message received
+	// from or sent to a node, 16MB by default, matching the servers' default limits.
+	// Larger limits admit bigger values and batches at the cost of buffering whole
+	// messages in memory. Calls that exceed a limit on either side fail with
+	// ResourceExhausted.
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	
+	// Compressor names the gRPC compressor applied to every request, such as "gzip",
+	// for links where bandwidth is scarcer than CPU. Servers compress their responses
+	// with the same compressor. Empty, the default, sends messages uncompressed.
+	Compressor string
+	
+	// Logger receives the client's logs. If it is nil a production logger writing
+	// JSON to stderr is created.
+	Logger *zap.Logger
+}
+
+// NewClient creates a new distributed cache client
+func NewClient(config *Config) (*Client, error) {
+	if strings.Contains(config.Namespace, namespaceSeparator) {
+		return nil, fmt.Errorf("namespace %q must not contain %q", config.Namespace, namespaceSeparator)
+	}
+	if config.ReplicationFactor > 0 && (config.ReplicationFactor < config.WriteQuorum || config.ReplicationFactor < config.ReadQuorum) {
+		return nil, fmt.Errorf("replication factor %d is smaller than the read quorum %d or write quorum %d",
+			config.ReplicationFactor, config.ReadQuorum, config.WriteQuorum)
+	}
+	if config.Compressor != "" && encoding.GetCompressor(config.Compressor) == nil {
+		return nil, fmt.Errorf("unknown compressor %q", config.Compressor)
+	}
+	
+	var err error
+	logger := config.Logger
+	if logger == nil {
+		if logger, err = zap.NewProduction(); err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+	}
+	
+	ringOptions := []ring.Option{ring.WithHashMode(config.HashMode)}
+	if config.TimeRouting {
+		ringOptions = append(ringOptions, ring.WithTiming())
+	}
+	
+	client := &Client{
+		ring:              ring.NewRing(ringOptions...),
+		logger:            logger,
+		connections:       make(map[string]*grpc.ClientConn),
+		dialed:            make(map[string]*grpc.ClientConn),
+		readQuorum:        config.ReadQuorum,
+		writeQuorum:       config.WriteQuorum,
+		dynamicQuorum:     config.DynamicQuorum,
+		replicationFactor: config.ReplicationFactor,
+		consistency:       config.Consistency,
+		deleteConsistency: config.DeleteConsistency,
+		hedgeTimeout:      config.HedgeTimeout,
+		hedgeRatio:        config.HedgeRatio,
+		latency:           newLatencyTracker(),
+		latencyAwareReads: config.LatencyAwareReads,
+		readRepair:        config.ReadRepair,
+		eagerConnect:      config.EagerConnect,
+		connectTimeout:    config.ConnectTimeout,
+		pingTimeout:       config.PingTimeout,
+		pingRTTs:          make(map[string]time.Duration),
+		nodeLoads:         make(map[string]NodeLoad),
+		pingInterval:      config.PingInterval,
+		preferNearest:     config.PreferNearestReplica,
+		detector:          newFailureDetector(),
+		phiThreshold:      config.PhiThreshold,
+		maxPerNode:        config.MaxPerNodeConcurrency,
+		nodeQueueTimeout:  config.PerNodeQueueTimeout,
+		nodeLimits:        make(map[string]*semaphore.Weighted),
+		namespace:         config.Namespace,
+		maxKeyBytes:       config.MaxKeyBytes,
+		maxRecvMsgSize:    config.MaxRecvMsgSize,
+		maxSendMsgSize:    config.MaxSendMsgSize,
+		compressor:        config.Compressor,
+	}
+	if client.pingTimeout <= 0 {
+		client.pingTimeout = defaultPingTimeout
+	}
+	if client.pingInterval <= 0 {
+		client.pingInterval = defaultPingInterval
+	}
+	if client.connectTimeout <= 0 {
+		client.connectTimeout = defaultConnectTimeout
+	}
+	if client.phiThreshold <= 0 {
+		client.phiThreshold = defaultPhiThreshold
+	}
+	if config.EnableTracing {
+		provider := config.TracerProvider
+		if provider == nil {
+			provider = otel.GetTracerProvider()
+		}
+		client.tracer = provider.Tracer(tracerName)
+	}
+	if config.EnableLocalFallback {
+		capacity := config.LocalCacheCapacity
+		if capacity <= 0 {
+			capacity = defaultLocalCacheCapacity
+		}
+		client.localCache = cache.NewCache(capacity)
+	}
+	if config.ClientCacheTTL > 0 {
+		capacity := config.ClientCacheCapacity
+		if capacity <= 0 {
+			capacity = defaultClientCacheCapacity
+		}
+		client.readCache = cache.NewCache(capacity)
+		client.readCacheTTL = config.ClientCacheTTL
+	}
+	if client.maxKeyBytes <= 0 {
+		client.maxKeyBytes = defaultMaxKeyBytes
+	}
+	if client.maxRecvMsgSize <= 0 {
+		client.maxRecvMsgSize = defaultMaxMsgSize
+	}
+	if client.maxSendMsgSize <= 0 {
+		client.maxSendMsgSize = defaultMaxMsgSize
+	}
+	if config.WriteBack {
+		client.writeBack = newWriteBuffer(config.WriteBackInterval, config.WriteBackBufferSize)
+		go client.runFlusher()
+	}
+	client.pingStop = make(chan struct{})
+	client.pingDone = make(chan struct{})
+	go client.runPinger()
+	
+	// Connections follow ring membership
+	client.ring.OnChange(client.handleTopologyChange)
+	
+	return client, nil
+}
+
+// AddNode adds a node to the client's ring. With EagerConnect it also waits for the
+// connection to become ready, and leaves the node out of the ring if it does not.
+func (c *Client) AddNode(id, addr string) error {
+	if err := c.ring.AddNode(id, addr); err != nil {
+		return err
+	}
+	
+	conn, err := c.getConnection(id)
+	if err == nil && c.eagerConnect {
+		err = c.waitForReady(conn)
+	}
+	if err != nil {
+		c.ring.RemoveNode(id)
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	return nil
+}
+
+// waitForReady blocks until a connection is ready or the connect timeout passes
+func (c *Client) waitForReady(conn *grpc.ClientConn) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout)
+	defer cancel()
+	
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection not ready after %v (last state %s)", c.connectTimeout, state)
+		}
+	}
+}
+
+// RemoveNode removes a node from the client's ring
+func (c *Client) RemoveNode(id string) {
+	c.ring.RemoveNode(id)
+}
+
+// NodeSpec names a node and its address, for SetTopology
+type NodeSpec struct {
+	ID   string
+	Addr string
+}
+
+// SetTopology replaces the client's whole ring with nodes in a single change, so that
+// no operation sees a partly reconfigured ring as it would between a series of
+// RemoveNode and AddNode calls. Connections follow the difference: nodes that left are
+// disconnected, new and moved nodes are dialed, and nodes that stay at the same
+// address keep their connection. Nodes already in the ring keep their status. New and
+// moved nodes are dialed before the ring changes, and one that cannot be connected to,
+// or with EagerConnect does not become ready, is left out of the ring and named in the
+// returned error; the rest of the topology still takes effect in the one change.
+func (c *Client) SetTopology(nodes []NodeSpec) error {
+	seen := make(map[string]bool, len(nodes))
+	for _, spec := range nodes {
+		if spec.ID == "" || spec.Addr == "" {
+			return fmt.Errorf("node ID and address must not be empty")
+		}
+		if seen[spec.ID] {
+			return fmt.Errorf("node %s is listed more than once", spec.ID)
+		}
+		seen[spec.ID] = true
+	}
+	
+	ringNodes := make([]*ring.Node, 0, len(nodes))
+	dialed := make(map[string]*grpc.ClientConn)
+	var failed []string
+	for _, spec := range nodes {
+		node := &ring.Node{ID: spec.ID, Addr: spec.Addr}
+		current, exists := c.ring.GetNode(spec.ID)
+		if exists {
+			node.Status = current.Status
+		}
+		if !exists || current.Addr != spec.Addr {
+			conn, err := c.dialNode(node)
+			if err == nil && c.eagerConnect {
+				if err = c.waitForReady(conn); err != nil {
+					conn.Close()
+				}
+			}
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s at %s (%v)", spec.ID, spec.Addr, err))
+				continue
+			}
+			dialed[spec.ID] = conn
+		}
+		ringNodes = append(ringNodes, node)
+	}
+	
+	// The ring change picks up the connections dialed for it; any left over belong to
+	// nodes a concurrent change has since moved or removed
+	c.connMutex.Lock()
+	for id, conn := range dialed {
+		c.dialed[id] = conn
+	}
+	c.connMutex.Unlock()
+	setErr := c.ring.SetNodes(ringNodes)
+	c.connMutex.Lock()
+	for id, conn := range dialed {
+		if c.dialed[id] == conn {
+			delete(c.dialed, id)
+			conn.Close()
+		}
+	}
+	c.connMutex.Unlock()
+	
+	if setErr != nil {
+		return setErr
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to connect to %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// NodeInfo describes a node in the client's ring, as returned by OwnersFor
+type NodeInfo struct {
+	ID     string
+	Addr   string
+	Status ring.NodeStatus
+}
+
+// OwnersFor returns the first n owners of a key on the client's ring, primary first,
+// for checking how keys are routed. The key is namespaced as it would be for an
+// operation but not validated. Owners of every status are listed in ring order; reads
+// skip joining owners and may be reordered by health or latency. A non-positive n
+// lists none.
+func (c *Client) OwnersFor(key string, n int) []NodeInfo {
+	owners := c.ring.Owners(c.namespacedKey(key), n)
+	infos := make([]NodeInfo, len(owners))
+	for i, owner := range owners {
+		infos[i] = NodeInfo{ID: owner.ID, Addr: owner.Addr, Status: owner.Status}
+	}
+	return infos
+}
+
+// SetNodeStatus moves a node through the membership lifecycle. A node set to
+// ring.NodeJoining right after AddNode receives writes for the keys it owns but serves
+// no reads, and so does not count toward the read quorum, until it is set back to
+// ring.NodeActive once it has filled up.
+func (c *Client) SetNodeStatus(id string, status ring.NodeStatus) error {
+	if !c.ring.SetNodeStatus(id, status) {
+		return fmt.Errorf("node %s is not in the ring", id)
+	}
+	c.logger.Info("Node status changed", zap.String("id", id), zap.Stringer("status", status))
+	return nil
+}
+
+// handleTopologyChange closes connections to nodes that left the ring and opens
+// and warms connections to nodes that joined it, or takes the ones SetTopology dialed
+func (c *Client) handleTopologyChange(added, removed []*ring.Node) {
+	for _, node := range removed {
+		c.connMutex.Lock()
+		if conn, exists := c.connections[node.ID]; exists {
+			conn.Close()
+			delete(c.connections, node.ID)
+		}
+		c.connMutex.Unlock()
+		
+		c.pingMutex.Lock()
+		delete(c.pingRTTs, node.ID)
+		delete(c.nodeLoads, node.ID)
+		c.pingMutex.Unlock()
+		c.latency.remove(node.ID)
+		c.detector.remove(node.ID)
+		c.limitMutex.Lock()
+		delete(c.nodeLimits, node.ID)
+		c.limitMutex.Unlock()
+		
+		c.logger.Info("Removed node", zap.String("id", node.ID), zap.String("addr", node.Addr))
+	}
+	
+	for _, node := range added {
+		c.connMutex.Lock()
+		conn := c.dialed[node.ID]
+		if conn != nil && conn.Target() == node.Addr {
+			delete(c.dialed, node.ID)
+		} else {
+			conn = nil
+		}
+		c.connMutex.Unlock()
+		
+		if conn == nil {
+			var err error
+			if conn, err = c.dialNode(node); err != nil {
+				c.logger.Error("Failed to connect to node",
+					zap.String("id", node.ID),
+					zap.String("addr", node.Addr),
+					zap.Error(err))
+				continue
+			}
+		}
+		
+		c.connMutex.Lock()
+		if current, exists := c.ring.GetNode(node.ID); !exists || current.Addr != node.Addr {
+			// A concurrent change moved or removed the node while it was being added
+			c.connMutex.Unlock()
+			conn.Close()
+			continue
+		}
+		if old, exists := c.connections[node.ID]; exists {
+			old.Close()
+		}
+		c.connections[node.ID] = conn
+		c.connMutex.Unlock()
+		
+		c.logger.Info("Added node", zap.String("id", node.ID), zap.String("addr", node.Addr))
+	}
+}
+
+// dialNode opens a connection to a node and starts connecting, so that the first
+// request does not pay for it
+func (c *Client) dialNode(node *ring.Node) (*grpc.ClientConn, error) {
+	callOptions := []grpc.CallOption{grpc.MaxCallRecvMsgSize(c.maxRecvMsgSize), grpc.MaxCallSendMsgSize(c.maxSendMsgSize)}
+	if c.compressor != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(c.compressor))
+	}
+	interceptors := []grpc.UnaryClientInterceptor{
+		c.unaryInterceptor(node.ID),
+		c.limitInterceptor(node.ID),
+		c.detectorInterceptor(node.ID),
+	}
+	
+	conn, err := grpc.Dial(node.Addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+		grpc.WithStreamInterceptor(c.streamInterceptor),
+		grpc.WithDefaultCallOptions(callOptions...))
+	if err != nil {
+		return nil, err
+	}
+	conn.Connect()
+	return conn, nil
+}
+
+// Ping calls the Health RPC on a node with a short deadline and returns the round-trip
+// latency. The load the node reports is kept for NodeLoad and for routing reads.
+func (c *Client) Ping(ctx context.Context, nodeID string) (time.Duration, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return 0, err
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, c.pingTimeout)
+	defer cancel()
+	
+	start := time.Now()
+	resp, err := proto.NewCacheServiceClient(conn).Health(ctx, &proto.HealthRequest{})
+	rtt := time.Since(start)
+	if err != nil {
+		return 0, fmt.Errorf("ping %s failed: %w", nodeID, err)
+	}
+	
+	c.pingMutex.Lock()
+	c.nodeLoads[nodeID] = NodeLoad{Load: resp.Load, InFlight: resp.InFlight, Shedding: resp.Shedding}
+	if resp.Healthy {
+		c.pingRTTs[nodeID] = rtt
+	}
+	c.pingMutex.Unlock()
+	
+	if !resp.Healthy {
+		return rtt, fmt.Errorf("node %s unhealthy: %s", nodeID, resp.Status)
+	}
+	return rtt, nil
+}
+
+// NodeLoad is the load a node reported in its last answer to a ping
+type NodeLoad struct {
+	Load     float64 // Cache size as a fraction of capacity
+	InFlight int64   // Requests the node was serving
+	Shedding bool    // Whether the node was rejecting requests as overloaded
+}
+
+// NodeLoad returns the load a node reported in its last answer to a ping, reporting
+// false if it has not answered one
+func (c *Client) NodeLoad(nodeID string) (NodeLoad, bool) {
+	c.pingMutex.RLock()
+	defer c.pingMutex.RUnlock()
+	load, ok := c.nodeLoads[nodeID]
+	return load, ok
+}
+
+// Entry is a single cache entry streamed from a node by Dump or returned by RangeGet
+type Entry struct {
+	Key     string
+	Value   []byte
+	TTL     time.Duration
+	Version uint64
+}
+
+// Dump streams every live entry held by a single node. Keys are returned as stored,
+// including any namespace prefix. The channel is closed when the dump completes,
+// fails or ctx is canceled; failures part way through are logged.
+func (c *Client) Dump(ctx context.Context, nodeID string) (<-chan Entry, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	stream, err := proto.NewCacheServiceClient(conn).Dump(ctx, &proto.DumpRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("dump %s failed: %w", nodeID, err)
+	}
+	
+	entries := make(chan Entry)
+	go func() {
+		defer close(entries)
+		
+		for {
+			item, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				c.logger.Warn("Dump stream failed", zap.String("node", nodeID), zap.Error(err))
+				return
+			}
+			
+			entry := Entry{Key: item.Key, Value: item.Value, Version: item.Version}
+			if item.Ttl != nil {
+				entry.TTL = item.Ttl.AsDuration()
+			}
+			
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	
+	return entries, nil
+}
+
+// Get retrieves a value using quorum reads. Concurrent Gets of the same key with the
+// same consistency share a single read of the owners and all receive its result,
+// errors included. With ClientCacheTTL, a value this client read within the TTL is
+// returned without an RPC. With EnableLocalFallback, a value this client read earlier
+// is returned together with ErrStale when no owner can be reached.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := c.startOperation(ctx, "Get", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Writes still waiting to be flushed are visible to this client
+	if c.writeBack != nil {
+		if value, found := c.writeBack.get(key); found {
+			return value, nil
+		}
+	}
+	
+	if c.readCache != nil && c.consistencyFor(ctx) != ConsistencyStrict {
+		if value, found := c.readCache.Get(key); found {
+			atomic.AddUint64(&c.readCacheHits, 1)
+			return value, nil
+		}
+	}
+	
+	var gen uint64
+	if c.readCache != nil {
+		gen = atomic.LoadUint64(c.readCacheGen(key))
+	}
+	value, err := c.coalescedGet(ctx, key)
+	if err == nil && c.readCache != nil {
+		c.fillReadCache(key, value, gen)
+	}
+	if c.localCache == nil {
+		return value, err
+	}
+	
+	switch {
+	case err == nil:
+		c.localCache.Set(key, value, 0)
+		return value, nil
+	case errors.Is(err, ErrKeyNotFound):
+		// The owners answered, so a local copy would resurrect a deleted key
+		c.localCache.Delete(key)
+		return nil, err
+	case errors.Is(err, ErrReplicasDisagree):
+		return nil, err
+	case c.consistencyFor(ctx) == ConsistencyStrict:
+		// A strict read would rather fail than serve a possibly stale copy
+		return nil, err
+	}
+	
+	if stale, found := c.localCache.Get(key); found {
+		atomic.AddUint64(&c.staleReads, 1)
+		c.logger.Warn("Serving stale local copy", zap.String("key", key), zap.Error(err))
+		return stale, ErrStale
+	}
+	return nil, err
+}
+
+// readCacheStripes is the number of write generations the read cache's keys share
+const readCacheStripes = 256
+
+// readCacheGen returns the write generation of a key's stripe
+func (c *Client) readCacheGen(key string) *uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &c.readCacheGens[h.Sum32()%readCacheStripes]
+}
+
+// fillReadCache stores a value read from the owners unless the key was written since
+// gen was taken, before the read began, so that a write finishing during the read is
+// not hidden behind the old value. A write finishing during the fill is caught by the
+// check after it, and the value is dropped again.
+func (c *Client) fillReadCache(key string, value []byte, gen uint64) {
+	stripe := c.readCacheGen(key)
+	if atomic.LoadUint64(stripe) != gen {
+		return
+	}
+	c.readCache.Set(key, value, c.readCacheTTL)
+	if atomic.LoadUint64(stripe) != gen {
+		c.readCache.Delete(key)
+	}
+}
+
+// invalidateRead drops a written key from the read cache, bumping its generation so
+// that a Get that read the owners before the write does not store the old value
+func (c *Client) invalidateRead(key string) {
+	atomic.AddUint64(c.readCacheGen(key), 1)
+	c.readCache.Delete(key)
+}
+
+// GetFrom reads a key like Get and also returns the ID of the node that answered, to
+// help track down a stale replica. It always asks the owners: writes buffered by
+// WriteBack, the client cache and the local fallback are skipped, and the read is not
+// shared with concurrent Gets. The node ID is empty when the read fails.
+func (c *Client) GetFrom(ctx context.Context, key string) ([]byte, string, error) {
+	ctx, span := c.startOperation(ctx, "GetFrom", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	value, nodeID, err := c.get(ctx, key)
+	if err == nil {
+		span.SetAttributes(attribute.String("cache.node", nodeID))
+	}
+	return value, nodeID, err
+}
+
+// flightResult is the outcome of a read shared through Client.flights
+type flightResult struct {
+	value   []byte
+	expired bool // The read ran out of its starter's deadline
+}
+
+// coalescedGet reads a stored key through c.flights, joining a read of the same key
+// and consistency already in progress. The shared read is bounded by the deadline of
+// the caller that started it but not canceled along with it, so that the others are
+// not failed by one caller giving up; each caller stops waiting when its own context
+// ends. A caller whose context outlives a shared read that failed at its starter's
+// deadline reads again on its own. Callers sharing a result get their own copy of the
+// value. Writes through the client end the sharing of reads of their key begun before
+// them.
+func (c *Client) coalescedGet(ctx context.Context, key string) ([]byte, error) {
+	results := c.flights.DoChan(flightKey(c.consistencyFor(ctx), key), func() (interface{}, error) {
+		flightCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			flightCtx, cancel = context.WithDeadline(flightCtx, deadline)
+			defer cancel()
+		}
+		value, _, err := c.get(flightCtx, key)
+		return flightResult{value: value, expired: flightCtx.Err() != nil}, err
+	})
+	
+	select {
+	case result := <-results:
+		flight := result.Val.(flightResult)
+		if result.Err != nil && flight.expired && ctx.Err() == nil {
+			value, _, err := c.get(ctx, key)
+			return value, err
+		}
+		if result.Shared && flight.value != nil {
+			return bytes.Clone(flight.value), result.Err
+		}
+		return flight.value, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flightKey returns the key under which reads of key at a consistency are shared
+func flightKey(consistency Consistency, key string) string {
+	return strconv.Itoa(int(consistency)) + ":" + key
+}
+
+// forgetFlights stops reads of a written key already in progress from being shared
+// with later Gets, which then read the owners again and see the write
+func (c *Client) forgetFlights(key string) {
+	for consistency := ConsistencyQuorum; consistency <= ConsistencyStrict; consistency++ {
+		c.flights.Forget(flightKey(consistency, key))
+	}
+}
+
+// get reads a stored key from its owners and returns the ID of the owner that answered,
+// returning ErrKeyNotFound if any owner reported the key missing and none returned it
+func (c *Client) get(ctx context.Context, key string) ([]byte, string, error) {
+	owners := c.readOwners(key, c.currentReadQuorum())
+	if len(owners) == 0 {
+		return nil, "", fmt.Errorf("no nodes available")
+	}
+	owners = c.byHealth(owners)
+	if c.latencyAwareReads && !c.preferNearest {
+		owners = c.byLatency(owners)
+	}
+	
+	// Best-effort reads only ask the first healthy owner
+	if c.consistencyFor(ctx) == ConsistencyOne {
+		value, err := c.getFromNode(ctx, owners[0].ID, key)
+		if err != nil {
+			return nil, "", err
+		}
+		return value, owners[0].ID, nil
+	}
+	if c.consistencyFor(ctx) == ConsistencyStrict {
+		return c.strictGet(ctx, owners, key)
+	}
+	
+	// Try the first healthy owner, normally the primary, hedging against the next owner if configured
+	next := 1
+	notFound := false
+	if c.hedgeTimeout > 0 && len(owners) > 1 {
+		value, nodeID, err := c.hedgedGet(ctx, owners[0].ID, owners[1].ID, key)
+		if err == nil {
+			return value, nodeID, nil
+		}
+		notFound = errors.Is(err, ErrKeyNotFound)
+		next = 2
+	} else {
+		value, err := c.getFromNode(ctx, owners[0].ID, key)
+		if err == nil {
+			return value, owners[0].ID, nil
+		}
+		notFound = errors.Is(err, ErrKeyNotFound)
+	}
+	
+	// If primary fails, try other owners
+	for i := next; i < len(owners); i++ {
+		value, err := c.getFromNode(ctx, owners[i].ID, key)
+		if err == nil {
+			return value, owners[i].ID, nil
+		}
+		notFound = notFound || errors.Is(err, ErrKeyNotFound)
+	}
+	
+	if notFound {
+		return nil, "", ErrKeyNotFound
+	}
+	return nil, "", fmt.Errorf("failed to get key from any node")
+}
+
+// strictGet reads a key from every owner given at once and returns its value only if
+// all of them answered with the same state. Disagreement is logged and, with read
+// repair, the newest versioned state is written back so that a retry can succeed.
+func (c *Client) strictGet(ctx context.Context, owners []*ring.Node, key string) ([]byte, string, error) {
+	if len(owners) < c.currentReadQuorum() {
+		return nil, "", fmt.Errorf("only %d of the %d owners a strict read needs are available", len(owners), c.currentReadQuorum())
+	}
+	
+	resps := make([]*proto.GetResponse, len(owners))
+	errs := make([]error, len(owners))
+	var wg sync.WaitGroup
+	for i, owner := range owners {
+		wg.Add(1)
+		go func(i int, nodeID string) {
+			defer wg.Done()
+			resps[i], errs[i] = c.readFromNode(ctx, nodeID, key)
+		}(i, owner.ID)
+	}
+	wg.Wait()
+	
+	for i, err := range errs {
+		if err != nil {
+			return nil, "", fmt.Errorf("strict read failed on node %s: %w", owners[i].ID, err)
+		}
+	}
+	
+	state := replicaState(resps[0])
+	newest := resps[0]
+	var divergent []string
+	for i, resp := range resps[1:] {
+		if replicaState(resp) != state {
+			divergent = append(divergent, owners[i+1].ID)
+		}
+		if resp.Version > newest.Version {
+			newest = resp
+		}
+	}
+	if len(divergent) > 0 {
+		atomic.AddUint64(&c.divergences, 1)
+		c.logger.Warn("Strict read found divergent replicas",
+			zap.String("key", key),
+			zap.String("node", owners[0].ID),
+			zap.Strings("divergent_nodes", divergent))
+		
+		if c.readRepair && newest.Version > 0 {
+			var stale []string
+			for i, resp := range resps {
+				if replicaState(resp) != replicaState(newest) {
+					stale = append(stale, owners[i].ID)
+				}
+			}
+			c.repair(ctx, key, newest, stale)
+		}
+		return nil, "", ErrReplicasDisagree
+	}
+	
+	if !resps[0].Found {
+		return nil, "", ErrKeyNotFound
+	}
+	return resps[0].Value, owners[0].ID, nil
+}
+
+// Metadata describes a stored value without its payload
+type Metadata struct {
+	Version   uint64
+	Size      int64
+	CreatedAt time.Time
+	ExpiresAt time.Time // Zero if the value never expires
+}
+
+// GetMeta returns a key's metadata from the first owner that holds it, without
+// transferring the value
+func (c *Client) GetMeta(ctx context.Context, key string) (*Metadata, error) {
+	ctx, span := c.startOperation(ctx, "GetMeta", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return nil, err
+	}
+	
+	owners := c.readOwners(key, c.currentReadQuorum())
+	if len(owners) == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	owners = c.byHealth(owners)
+	
+	lastErr := fmt.Errorf("failed to get key from any node")
+	for _, owner := range owners {
+		conn, err := c.getConnection(owner.ID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		
+		resp, err := proto.NewCacheServiceClient(conn).Get(ctx, &proto.GetRequest{Key: key, MetaOnly: true})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !resp.Found {
+			lastErr = ErrKeyNotFound
+			continue
+		}
+		
+		meta := &Metadata{
+			Version:   resp.Version,
+			Size:      resp.Size,
+			CreatedAt: resp.CreatedAt.AsTime(),
+		}
+		if resp.ExpiresAt != nil {
+			meta.ExpiresAt = resp.ExpiresAt.AsTime()
+		}
+		return meta, nil
+	}
+	
+	return nil, lastErr
+}
+
+// Exists reports whether a key is present without transferring its value. Owners are
+// asked in turn and the key exists if any of them holds it; an error is returned only
+// if no owner could be asked. Writes still buffered by WriteBack count as present.
+func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
+	ctx, span := c.startOperation(ctx, "Exists", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return false, err
+	}
+	
+	if c.writeBack != nil {
+		if _, found := c.writeBack.get(key); found {
+			return true, nil
+		}
+	}
+	
+	owners := c.readOwners(key, c.currentReadQuorum())
+	if len(owners) == 0 {
+		return false, fmt.Errorf("no nodes available")
+	}
+	owners = c.byHealth(owners)
+	
+	answered := false
+	lastErr := fmt.Errorf("failed to check key on any node")
+	for _, owner := range owners {
+		conn, err := c.getConnection(owner.ID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		
+		resp, err := proto.NewCacheServiceClient(conn).Exists(ctx, &proto.ExistsRequest{Key: key})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.Exists {
+			return true, nil
+		}
+		answered = true
+	}
+	
+	if answered {
+		return false, nil
+	}
+	return false, lastErr
+}
+
+// GetConsistent reads a key from every replica and returns the resolved value, along with
+// the IDs of replicas that disagreed with it. Replicas holding versioned entries or
+// tombstones resolve to the newest version; unversioned entries resolve to the state held
+// by a majority of the replicas that responded. Divergence is logged and, when read repair
+// is enabled, the resolved state is written back to the divergent replicas with the
+// expiry it has on the replica it was read from.
+func (c *Client) GetConsistent(ctx context.Context, key string) ([]byte, []string, error) {
+	ctx, span := c.startOperation(ctx, "GetConsistent", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	owners := c.readOwners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return nil, nil, fmt.Errorf("no nodes available")
+	}
+	
+	type replicaRead struct {
+		nodeID string
+		resp   *proto.GetResponse
+		err    error
+	}
+	
+	// Read from all replicas concurrently
+	results := make(chan replicaRead, len(owners))
+	for _, owner := range owners {
+		go func(owner *ring.Node) {
+			resp, err := c.readFromNode(ctx, owner.ID, key)
+			results <- replicaRead{nodeID: owner.ID, resp: resp, err: err}
+		}(owner)
+	}
+	
+	// Tally the state reported by each reachable replica
+	votes := make(map[string]int)
+	states := make(map[string]string)
+	responses := make(map[string]*proto.GetResponse)
+	var newest *proto.GetResponse
+	for i := 0; i < len(owners); i++ {
+		r := <-results
+		if r.err != nil {
+			continue
+		}
+		
+		state := replicaState(r.resp)
+		votes[state]++
+		states[r.nodeID] = state
+		responses[state] = r.resp
+		if newest == nil || r.resp.Version > newest.Version {
+			newest = r.resp
+		}
+	}
+	
+	if len(states) == 0 {
+		return nil, nil, fmt.Errorf("failed to get key from any node")
+	}
+	
+	var winner string
+	if newest.Version > 0 {
+		winner = replicaState(newest)
+	} else {
+		winnerVotes := 0
+		for state, count := range votes {
+			if count > winnerVotes {
+				winner, winnerVotes = state, count
+			}
+		}
+		if winnerVotes*2 <= len(states) {
+			atomic.AddUint64(&c.divergences, 1)
+			c.logger.Warn("Replicas diverged with no majority",
+				zap.String("key", key),
+				zap.Int("replicas", len(states)))
+			return nil, nil, ErrNoMajority
+		}
+	}
+	
+	var divergent []string
+	for nodeID, state := range states {
+		if state != winner {
+			divergent = append(divergent, nodeID)
+		}
+	}
+	
+	resolved := responses[winner]
+	if len(divergent) > 0 {
+		atomic.AddUint64(&c.divergences, 1)
+		c.logger.Warn("Replica divergence detected",
+			zap.String("key", key),
+			zap.Strings("divergent_nodes", divergent))
+		
+		if c.readRepair {
+			c.repair(ctx, key, resolved, divergent)
+		}
+	}
+	
+	if !resolved.Found {
+		return nil, divergent, ErrKeyNotFound
+	}
+	
+	return resolved.Value, divergent, nil
+}
+
+// replicaState encodes a replica's answer so that equal answers compare equal
+func replicaState(resp *proto.GetResponse) string {
+	switch {
+	case resp.Found:
+		return fmt.Sprintf("v%d:%s", resp.Version, resp.Value)
+	case resp.Tombstone:
+		return fmt.Sprintf("t%d", resp.Version)
+	default:
+		return ""
+	}
+}
+
+// repair writes the resolved state back to divergent replicas at its original version
+func (c *Client) repair(ctx context.Context, key string, resolved *proto.GetResponse, nodeIDs []string) {
+	// Keep the resolved value's remaining lifetime rather than making it permanent
+	var ttl time.Duration
+	if resolved.Found && resolved.ExpiresAt != nil {
+		ttl = time.Until(resolved.ExpiresAt.AsTime())
+		if ttl <= 0 {
+			return
+		}
+	}
 	
-	// Hedging settings
-	hedgeTimeout time.Duration
-	hedgeRatio   float64
+	for _, nodeID := range nodeIDs {
+		var err error
+		if resolved.Found {
+			err = c.setToNode(ctx, nodeID, key, resolved.Value, ttl, resolved.Version)
+		} else {
+			err = c.deleteFromNode(ctx, nodeID, key, resolved.Version)
+		}
+		if err != nil {
+			c.logger.Warn("Read repair failed",
+				zap.String("key", key),
+				zap.String("node", nodeID),
+				zap.Error(err))
+		}
+	}
 }
 
-// Config holds client configuration
-type Config struct {
-	ReadQuorum   int
-	WriteQuorum  int
-	HedgeTimeout time.Duration
-	HedgeRatio   float64
+// consistencyFor returns the consistency for a call, preferring a per-call override
+func (c *Client) consistencyFor(ctx context.Context) Consistency {
+	if consistency, ok := ctx.Value(consistencyKey{}).(Consistency); ok {
+		return consistency
+	}
+	return c.consistency
 }
 
-// NewClient creates a new distributed cache client
-func NewClient(config *Config) (*Client, error) {
-	logger, err := zap.NewProduction()
+// requiredWrites returns how many owners must acknowledge a write before it succeeds
+func (c *Client) requiredWrites(ctx context.Context) int {
+	if c.consistencyFor(ctx) == ConsistencyOne {
+		return 1
+	}
+	return c.currentWriteQuorum()
+}
+
+// currentReadQuorum returns how many owners reads consult: ReadQuorum, or under
+// DynamicQuorum a majority of the owners available
+func (c *Client) currentReadQuorum() int {
+	if c.dynamicQuorum {
+		return c.majorityQuorum()
+	}
+	return c.readQuorum
+}
+
+// currentWriteQuorum returns how many owners must acknowledge a write: WriteQuorum,
+// or under DynamicQuorum a majority of the owners available
+func (c *Client) currentWriteQuorum() int {
+	if c.dynamicQuorum {
+		return c.majorityQuorum()
+	}
+	return c.writeQuorum
+}
+
+// majorityQuorum returns a majority of the owners each key has with the current
+// membership
+func (c *Client) majorityQuorum() int {
+	owners := c.ring.NodeCount()
+	if replicas := c.replicaCount(); replicas < owners {
+		owners = replicas
+	}
+	return owners/2 + 1
+}
+
+// readOwners returns the owners of a key that reads may go to: the first n of its
+// replicas that are not still joining. Joining nodes are written to but skipped here,
+// so a read falls through to the next replica instead of one that may be missing the key.
+// With PreferNearestReplica the replicas are taken nearest first rather than in ring order.
+func (c *Client) readOwners(key string, n int) []*ring.Node {
+	owners := c.ring.Owners(key, c.replicaCount(), ring.NodeActive, ring.NodeLeaving)
+	if c.preferNearest {
+		owners = c.byNearest(owners)
+	}
+	if len(owners) > n {
+		owners = owners[:n]
+	}
+	return owners
+}
+
+// readOwnersBatch returns the owners readOwners would for each of keys, looking them
+// all up at once
+func (c *Client) readOwnersBatch(keys []string, n int) map[string][]*ring.Node {
+	batch := c.ring.OwnersBatch(keys, c.replicaCount(), ring.NodeActive, ring.NodeLeaving)
+	for key, owners := range batch {
+		if c.preferNearest {
+			owners = c.byNearest(owners)
+		}
+		if len(owners) > n {
+			owners = owners[:n]
+		}
+		batch[key] = owners
+	}
+	return batch
+}
+
+// replicaCount returns the number of owners that hold a copy of each key
+func (c *Client) replicaCount() int {
+	if c.replicationFactor > 0 {
+		return c.replicationFactor
+	}
+	if c.readQuorum > c.writeQuorum {
+		return c.readQuorum
+	}
+	return c.writeQuorum
+}
+
+// Set stores a value on every replica, succeeding once a quorum acknowledges it. With WriteBack it only buffers the write.
+// If the ring has fewer owners for the key than the quorum, it returns ErrInsufficientNodes without writing to any.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, span := c.startOperation(ctx, "Set", attribute.String("cache.key", key))
+	defer span.End()
+	
+	_, err := c.set(ctx, key, value, ttl)
+	return err
+}
+
+// set writes a value as Set does and returns the version it was written with
+func (c *Client) set(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, error) {
+	key, err := c.storedKey(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create logger: %w", err)
+		return 0, err
 	}
 	
-	client := &Client{
-		ring:         ring.NewRing(),
-		logger:       logger,
-		connections:  make(map[string]*grpc.ClientConn),
-		readQuorum:   config.ReadQuorum,
-		writeQuorum:  config.WriteQuorum,
-		hedgeTimeout: config.HedgeTimeout,
-		hedgeRatio:   config.HedgeRatio,
+	// Drop the local copy once the write is done, whether or not it succeeded
+	defer c.forgetFlights(key)
+	if c.readCache != nil {
+		defer c.invalidateRead(key)
 	}
 	
-	return client, nil
+	if c.writeBack != nil {
+		write := pendingWrite{value: value, version: c.nextVersion()}
+		if ttl > 0 {
+			write.expiresAt = time.Now().Add(ttl)
+		}
+		c.writeBack.add(key, write)
+		if c.localCache != nil {
+			c.localCache.Set(key, value, ttl)
+		}
+		return write.version, nil
+	}
+	
+	owners := c.ring.Owners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return 0, fmt.Errorf("%w: no nodes available", ErrInsufficientNodes)
+	}
+	
+	version := c.nextVersion()
+	err = c.writeToOwners(ctx, owners, func(ctx context.Context, nodeID string) error {
+		return c.setToNode(ctx, nodeID, key, value, ttl, version)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to write to quorum of nodes: %w", err)
+	}
+	
+	if c.localCache != nil {
+		c.localCache.Set(key, value, ttl)
+	}
+	return version, nil
 }
 
-// AddNode adds a node to the client's ring
-func (c *Client) AddNode(id, addr string) error {
-	c.ring.AddNode(id, addr)
+// Delete removes a key using quorum writes. Owners keep a versioned tombstone so that
+// replicas which missed the delete cannot resurrect the value through read repair.
+// As with Set, ErrInsufficientNodes is returned before anything is deleted if the
+// quorum cannot be reached with the owners in the ring. Under DeleteAll it waits for
+// every owner instead and fails, naming them, if any did not confirm the delete; the
+// owners that did keep it.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	ctx, span := c.startOperation(ctx, "Delete", attribute.String("cache.key", key))
+	defer span.End()
 	
-	// Create connection
-	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	key, err := c.storedKey(key)
 	if err != nil {
-		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+		return err
 	}
 	
-	c.connMutex.Lock()
-	c.connections[id] = conn
-	c.connMutex.Unlock()
+	defer c.forgetFlights(key)
+	if c.readCache != nil {
+		defer c.invalidateRead(key)
+	}
+	
+	// The delete's newer version would beat a buffered write anyway
+	if c.writeBack != nil {
+		c.writeBack.remove(key)
+	}
+	
+	owners := c.ring.Owners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return fmt.Errorf("%w: no nodes available", ErrInsufficientNodes)
+	}
 	
-	c.logger.Info("Added node", zap.String("id", id), zap.String("addr", addr))
+	version := c.nextVersion()
+	if c.deleteConsistencyFor(ctx) == DeleteAll {
+		if err := c.deleteFromAll(ctx, owners, key, version); err != nil {
+			return err
+		}
+	} else {
+		err = c.writeToOwners(ctx, owners, func(ctx context.Context, nodeID string) error {
+			return c.deleteFromNode(ctx, nodeID, key, version)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete from quorum of nodes: %w", err)
+		}
+	}
+	
+	if c.localCache != nil {
+		c.localCache.Delete(key)
+	}
 	return nil
 }
 
-// RemoveNode removes a node from the client's ring
-func (c *Client) RemoveNode(id string) {
-	c.ring.RemoveNode(id)
+// deleteConsistencyFor returns the delete consistency for a call, preferring a per-call override
+func (c *Client) deleteConsistencyFor(ctx context.Context) DeleteConsistency {
+	if consistency, ok := ctx.Value(deleteConsistencyKey{}).(DeleteConsistency); ok {
+		return consistency
+	}
+	return c.deleteConsistency
+}
+
+// deleteFromAll deletes a key from every owner concurrently and waits for all of them,
+// returning an error that names the owners that did not confirm the delete. As with
+// writeToOwners, it returns ErrInsufficientNodes without deleting from any if there
+// are fewer owners than the write quorum.
+func (c *Client) deleteFromAll(ctx context.Context, owners []*ring.Node, key string, version uint64) error {
+	if required := c.requiredWrites(ctx); len(owners) < required {
+		return fmt.Errorf("%w: only %d of the %d required owners are available", ErrInsufficientNodes, len(owners), required)
+	}
 	
-	c.connMutex.Lock()
-	if conn, exists := c.connections[id]; exists {
-		conn.Close()
-		delete(c.connections, id)
+	errs := make([]error, len(owners))
+	var wg sync.WaitGroup
+	for i, owner := range owners {
+		wg.Add(1)
+		go func(i int, nodeID string) {
+			defer wg.Done()
+			errs[i] = c.deleteFromNode(ctx, nodeID, key, version)
+		}(i, owner.ID)
 	}
-	c.connMutex.Unlock()
+	wg.Wait()
 	
-	c.logger.Info("Removed node", zap.String("id", id))
+	var failed []string
+	var lastErr error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, owners[i].ID)
+			lastErr = err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("delete not confirmed by %d of %d owners (%s): %w", len(failed), len(owners), strings.Join(failed, ", "), lastErr)
+	}
+	return nil
 }
 
-// Get retrieves a value using quorum reads
-func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
-	owners := c.ring.Owners(key, c.readQuorum)
-	if len(owners) == 0 {
-		return nil, fmt.Errorf("no nodes available")
+// DeleteIf deletes a key only where its current version equals expectedVersion, as
+// returned by GetMeta, so that a value someone else wrote since is not deleted. It
+// succeeds once the required number of owners have applied the delete and returns
+// ErrVersionMismatch if too many owners hold a different version or no value at all.
+// Owners that applied it keep the delete even when the call fails. Writes still
+// buffered by WriteBack are not considered.
+func (c *Client) DeleteIf(ctx context.Context, key string, expectedVersion uint64) error {
+	ctx, span := c.startOperation(ctx, "DeleteIf", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return err
 	}
 	
-	// Try to get from primary owner first
-	primary := owners[0]
-	value, err := c.getFromNode(ctx, primary.ID, key)
-	if err == nil {
-		return value, nil
+	defer c.forgetFlights(key)
+	if c.readCache != nil {
+		defer c.invalidateRead(key)
 	}
 	
-	// If primary fails, try other owners
-	for i := 1; i < len(owners); i++ {
-		value, err := c.getFromNode(ctx, owners[i].ID, key)
-		if err == nil {
-			return value, nil
-		}
+	owners := c.ring.Owners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return fmt.Errorf("%w: no nodes available", ErrInsufficientNodes)
+	}
+	
+	err = c.writeToOwners(ctx, owners, func(ctx context.Context, nodeID string) error {
+		return c.deleteIfOnNode(ctx, nodeID, key, expectedVersion)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from quorum of nodes: %w", err)
 	}
 	
-	return nil, fmt.Errorf("failed to get key from any node")
+	if c.localCache != nil {
+		c.localCache.Delete(key)
+	}
+	return nil
 }
 
-// Set stores a value using quorum writes
-func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	owners := c.ring.Owners(key, c.writeQuorum)
-	if len(owners) == 0 {
-		return fmt.Errorf("no nodes available")
+// GetAndDelete removes a key from every owner and returns the value it held, so that
+// a key can be consumed like a work item. Each owner replaces the key with a tombstone
+// at a new version, which also rejects a copy of the value still being replicated, and
+// answers with what it held before. Once the write quorum has answered, the newest
+// state among the answers decides: a value is returned, and a tombstone, left by a
+// delete or by another call that reached one of those owners first, is a miss. As
+// long as write quorums overlap, at most one of several concurrent calls receives the
+// value, though when they race all of them may miss. Writes still buffered by
+// WriteBack are not considered.
+func (c *Client) GetAndDelete(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, span := c.startOperation(ctx, "GetAndDelete", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return nil, false, err
 	}
 	
-	// Send to all owners concurrently
-	results := make(chan error, len(owners))
-	for _, owner := range owners {
-		go func(owner *ring.Node) {
-			results <- c.setToNode(ctx, owner.ID, key, value, ttl)
-		}(owner)
+	defer c.forgetFlights(key)
+	if c.readCache != nil {
+		defer c.invalidateRead(key)
 	}
 	
-	// Wait for quorum
-	successes := 0
-	for i := 0; i < len(owners); i++ {
-		if err := <-results; err == nil {
-			successes++
-		}
+	owners := c.ring.Owners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return nil, false, fmt.Errorf("%w: no nodes available", ErrInsufficientNodes)
 	}
 	
-	if successes >= c.writeQuorum {
+	version := c.nextVersion()
+	var mu sync.Mutex
+	var newest *proto.GetResponse
+	err = c.writeToOwners(ctx, owners, func(ctx context.Context, nodeID string) error {
+		resp, err := c.getAndDeleteFromNode(ctx, nodeID, key, version)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		if (resp.Found || resp.Tombstone) && (newest == nil || resp.Version > newest.Version) {
+			newest = resp
+		}
+		mu.Unlock()
 		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to delete from quorum of nodes: %w", err)
 	}
 	
-	return fmt.Errorf("failed to write to quorum of nodes")
+	if c.localCache != nil {
+		c.localCache.Delete(key)
+	}
+	
+	mu.Lock()
+	defer mu.Unlock()
+	if newest == nil || !newest.Found {
+		return nil, false, nil
+	}
+	return newest.Value, true, nil
 }
 
-// Delete removes a key using quorum writes
-func (c *Client) Delete(ctx context.Context, key string) error {
-	owners := c.ring.Owners(key, c.writeQuorum)
-	if len(owners) == 0 {
-		return fmt.Errorf("no nodes available")
+// writeToOwners sends a write to every owner concurrently and returns once the required
+// number have acknowledged it, leaving the rest to finish in the background. As soon as
+// too many owners have failed for the quorum to be reached it returns the last failure
+// and cancels the writes still outstanding.
+func (c *Client) writeToOwners(ctx context.Context, owners []*ring.Node, write func(ctx context.Context, nodeID string) error) error {
+	required := c.requiredWrites(ctx)
+	if len(owners) < required {
+		return fmt.Errorf("%w: only %d of the %d required owners are available", ErrInsufficientNodes, len(owners), required)
 	}
 	
-	// Send to all owners concurrently
+	ctx, cancel := context.WithCancel(ctx)
+	var outstanding sync.WaitGroup
 	results := make(chan error, len(owners))
 	for _, owner := range owners {
+		outstanding.Add(1)
 		go func(owner *ring.Node) {
-			results <- c.deleteFromNode(ctx, owner.ID, key)
+			defer outstanding.Done()
+			results <- write(ctx, owner.ID)
 		}(owner)
 	}
+	go func() {
+		outstanding.Wait()
+		cancel()
+	}()
 	
-	// Wait for quorum
-	successes := 0
+	// Wait for quorum, or the first acknowledgment for best-effort writes
+	successes, failures := 0, 0
 	for i := 0; i < len(owners); i++ {
-		if err := <-results; err == nil {
+		err := <-results
+		if err == nil {
 			successes++
+			if successes >= required {
+				return nil
+			}
+			continue
+		}
+		
+		failures++
+		if len(owners)-failures < required {
+			cancel()
+			return err
 		}
 	}
 	
-	if successes >= c.writeQuorum {
-		return nil
+	return fmt.Errorf("failed to reach quorum")
+}
+
+// storedKey namespaces and validates a caller's key
+func (c *Client) storedKey(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("%w: key must not be empty", ErrInvalidKey)
 	}
 	
-	return fmt.Errorf("failed to delete from quorum of nodes")
+	key = c.namespacedKey(key)
+	if len(key) > c.maxKeyBytes {
+		return "", fmt.Errorf("%w: key is %d bytes, longer than the %d byte limit", ErrInvalidKey, len(key), c.maxKeyBytes)
+	}
+	if !utf8.ValidString(key) {
+		return "", fmt.Errorf("%w: key must be valid UTF-8", ErrInvalidKey)
+	}
+	return key, nil
+}
+
+// namespacedKey returns the key as stored on the nodes. Namespaces cannot contain the
+// separator, so keys from different namespaces never collide; prefix operations are
+// scoped to the namespace the same way by prefixing the prefix.
+func (c *Client) namespacedKey(key string) string {
+	if c.namespace == "" {
+		return key
+	}
+	return c.namespace + namespaceSeparator + key
 }
 
 // getFromNode gets a value from a specific node
@@ -184,35 +1751,116 @@ func (c *Client) getFromNode(ctx context.Context, nodeID, key string) ([]byte, e
 	
 	client := proto.NewCacheServiceClient(conn)
 	
-	// Apply hedging if configured
-	if c.hedgeTimeout > 0 {
-		ctx, cancel := context.WithTimeout(ctx, c.hedgeTimeout)
-		defer cancel()
-		
-		// Start hedge request after a delay
-		hedgeCh := make(chan []byte, 1)
+	// A miss is still an answer from a reachable node
+	start := time.Now()
+	value, err := c.getFromNodeWithRetry(ctx, client, key)
+	if err == nil || errors.Is(err, ErrKeyNotFound) {
+		c.latency.observe(nodeID, time.Since(start))
+	}
+	
+	return value, err
+}
+
+// hedgedGet reads from the primary and, once the primary has taken longer than its
+// own recent tail latency, races a duplicate read against the backup owner. The tail
+// is the (1 - hedgeRatio) quantile of the primary's latency window, so roughly
+// hedgeRatio of reads to a steady node are hedged while a node that turns slow is
+// hedged on nearly every read. Until a node has enough samples, half the hedge
+// timeout is used as the delay.
+//
+// Both reads are bounded by the sooner of the caller's deadline and the hedge timeout.
+// If the caller's deadline leaves no more time than the hedge delay, no hedge is sent,
+// since it could not finish in time; the backup is then only asked if the primary
+// fails. It returns the ID of the node whose read succeeded.
+func (c *Client) hedgedGet(ctx context.Context, primary, backup, key string) ([]byte, string, error) {
+	delay := c.hedgeDelay(primary)
+	hedge := true
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		hedge = false
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, c.hedgeTimeout)
+	defer cancel()
+	
+	type hedgeResult struct {
+		value  []byte
+		err    error
+		backup bool
+	}
+	
+	results := make(chan hedgeResult, 2)
+	start := time.Now()
+	go func() {
+		value, err := c.getFromNode(ctx, primary, key)
+		results <- hedgeResult{value: value, err: err}
+	}()
+	
+	var hedgeTimer <-chan time.Time
+	if hedge {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+	
+	sendBackup := func() {
 		go func() {
-			time.Sleep(c.hedgeTimeout / 2)
-			if value, err := c.getFromNodeWithRetry(ctx, client, key); err == nil {
-				hedgeCh <- value
-			}
+			value, err := c.getFromNode(ctx, backup, key)
+			results <- hedgeResult{value: value, err: err, backup: true}
 		}()
-		
-		// Try primary request
-		if value, err := c.getFromNodeWithRetry(ctx, client, key); err == nil {
-			return value, nil
-		}
-		
-		// Try hedge request
+	}
+	
+	pending, backupSent, primaryDone := 1, false, false
+	var lastErr error
+	for pending > 0 {
 		select {
-		case value := <-hedgeCh:
-			return value, nil
-		case <-ctx.Done():
-			return nil, ctx.Err()
+		case <-hedgeTimer:
+			if !backupSent {
+				backupSent = true
+				pending++
+				c.latency.recordHedge(primary)
+				sendBackup()
+			}
+		case r := <-results:
+			pending--
+			if !r.backup {
+				primaryDone = true
+			}
+			if r.err == nil {
+				// The abandoned primary read is recorded at the time it lost so that the
+				// threshold adapts to a node that stays slow
+				if r.backup && !primaryDone {
+					c.latency.observe(primary, time.Since(start))
+				}
+				if r.backup {
+					return r.value, backup, nil
+				}
+				return r.value, primary, nil
+			}
+			lastErr = r.err
+			
+			// Fall back to the backup as soon as the primary fails
+			if !backupSent {
+				backupSent = true
+				pending++
+				sendBackup()
+			}
 		}
 	}
 	
-	return c.getFromNodeWithRetry(ctx, client, key)
+	return nil, "", lastErr
+}
+
+// hedgeDelay returns how long to wait on a node before hedging a read to another owner
+func (c *Client) hedgeDelay(nodeID string) time.Duration {
+	q := 1 - c.hedgeRatio
+	if c.hedgeRatio <= 0 || c.hedgeRatio >= 1 {
+		q = 0.95
+	}
+	
+	if delay, ok := c.latency.quantile(nodeID, q); ok {
+		return delay
+	}
+	return c.hedgeTimeout / 2
 }
 
 // getFromNodeWithRetry gets a value with retry logic
@@ -221,16 +1869,33 @@ func (c *Client) getFromNodeWithRetry(ctx context.Context, client proto.CacheSer
 	if err != nil {
 		return nil, err
 	}
+	c.clock.observe(resp.Version)
 	
 	if !resp.Found {
-		return nil, fmt.Errorf("key not found")
+		return nil, ErrKeyNotFound
 	}
 	
 	return resp.Value, nil
 }
 
+// readFromNode issues a single Get to a node and returns the raw response
+func (c *Client) readFromNode(ctx context.Context, nodeID, key string) (*proto.GetResponse, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	resp, err := proto.NewCacheServiceClient(conn).Get(ctx, &proto.GetRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	c.clock.observe(resp.Version)
+	
+	return resp, nil
+}
+
 // setToNode sets a value to a specific node
-func (c *Client) setToNode(ctx context.Context, nodeID, key string, value []byte, ttl time.Duration) error {
+func (c *Client) setToNode(ctx context.Context, nodeID, key string, value []byte, ttl time.Duration, version uint64) error {
 	conn, err := c.getConnection(nodeID)
 	if err != nil {
 		return err
@@ -244,15 +1909,18 @@ func (c *Client) setToNode(ctx context.Context, nodeID, key string, value []byte
 	}
 	
 	resp, err := client.Set(ctx, &proto.SetRequest{
-		Key:   key,
-		Value: value,
-		Ttl:   protoTTL,
+		Key:     key,
+		Value:   value,
+		Ttl:     protoTTL,
+		Version: version,
 	})
 	if err != nil {
 		return err
 	}
 	
 	if !resp.Success {
+		// A newer write won; catch up so the next write from this client beats it
+		c.clock.observe(resp.Version)
 		return fmt.Errorf("set operation failed")
 	}
 	
@@ -260,7 +1928,7 @@ func (c *Client) setToNode(ctx context.Context, nodeID, key string, value []byte
 }
 
 // deleteFromNode deletes a key from a specific node
-func (c *Client) deleteFromNode(ctx context.Context, nodeID, key string) error {
+func (c *Client) deleteFromNode(ctx context.Context, nodeID, key string, version uint64) error {
 	conn, err := c.getConnection(nodeID)
 	if err != nil {
 		return err
@@ -268,7 +1936,7 @@ func (c *Client) deleteFromNode(ctx context.Context, nodeID, key string) error {
 	
 	client := proto.NewCacheServiceClient(conn)
 	
-	resp, err := client.Delete(ctx, &proto.DeleteRequest{Key: key})
+	resp, err := client.Delete(ctx, &proto.DeleteRequest{Key: key, Version: version})
 	if err != nil {
 		return err
 	}
@@ -280,6 +1948,45 @@ func (c *Client) deleteFromNode(ctx context.Context, nodeID, key string) error {
 	return nil
 }
 
+// getAndDeleteFromNode pops a key on a specific node at version, returning what it held
+func (c *Client) getAndDeleteFromNode(ctx context.Context, nodeID, key string, version uint64) (*proto.GetResponse, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	client := proto.NewCacheServiceClient(conn)
+	return client.GetAndDelete(ctx, &proto.GetAndDeleteRequest{Key: key, Version: version})
+}
+
+// deleteIfOnNode deletes a key from a specific node if it holds the expected version
+func (c *Client) deleteIfOnNode(ctx context.Context, nodeID, key string, expectedVersion uint64) error {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return err
+	}
+	
+	resp, err := proto.NewCacheServiceClient(conn).Delete(ctx, &proto.DeleteRequest{
+		Key:              key,
+		IfVersionMatches: true,
+		ExpectedVersion:  expectedVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Deleted {
+		return ErrVersionMismatch
+	}
+	
+	return nil
+}
+
+// nextVersion returns a write version from the client's hybrid logical clock, above
+// every version this client has written or read
+func (c *Client) nextVersion() uint64 {
+	return c.clock.now()
+}
+
 // getConnection gets or creates a connection to a node
 func (c *Client) getConnection(nodeID string) (*grpc.ClientConn, error) {
 	c.connMutex.RLock()
@@ -293,8 +2000,25 @@ func (c *Client) getConnection(nodeID string) (*grpc.ClientConn, error) {
 	return nil, fmt.Errorf("no connection to node %s", nodeID)
 }
 
-// Close closes all connections
+// Close flushes any buffered writes and closes all connections. It returns the
+// error from the final flush, if any.
 func (c *Client) Close() error {
+	if c.pingStop != nil {
+		c.pingStopOnce.Do(func() {
+			close(c.pingStop)
+		})
+		<-c.pingDone
+	}
+	
+	var flushErr error
+	if c.writeBack != nil {
+		c.writeBack.stopFlusher()
+		
+		ctx, cancel := context.WithTimeout(context.Background(), writeBackFlushTimeout)
+		flushErr = c.Flush(ctx)
+		cancel()
+	}
+	
 	c.connMutex.Lock()
 	defer c.connMutex.Unlock()
 	
@@ -303,7 +2027,7 @@ func (c *Client) Close() error {
 		delete(c.connections, id)
 	}
 	
-	return nil
+	return flushErr
 }
 
 // GetStats returns client statistics
@@ -311,12 +2035,153 @@ func (c *Client) GetStats() map[string]interface{} {
 	c.connMutex.RLock()
 	defer c.connMutex.RUnlock()
 	
+	lookups, lookupTime := c.ring.OwnersTiming()
 	return map[string]interface{}{
 		"nodes":         c.ring.NodeCount(),
 		"connections":   len(c.connections),
 		"read_quorum":   c.readQuorum,
 		"write_quorum":  c.writeQuorum,
+		"replicas":      c.replicaCount(),
 		"hedge_timeout": c.hedgeTimeout,
 		"hedge_ratio":   c.hedgeRatio,
+		"read_repair":   c.readRepair,
+		"divergences":   atomic.LoadUint64(&c.divergences),
+		"ping_rtts":     c.pingSnapshot(),
+		"hedges":        c.latency.hedgeCounts(),
+		"phi":           c.detector.snapshot(time.Now()),
+		"namespace":     c.namespace,
+		"stale_reads":   atomic.LoadUint64(&c.staleReads),
+		"cached_reads":  atomic.LoadUint64(&c.readCacheHits),
+		"write_backlog": c.pendingWrites(),
+		"write_dropped": c.droppedWrites(),
+		"owner_lookups": lookups,
+		"owner_time":    lookupTime,
+		"throttled":     atomic.LoadUint64(&c.throttled),
+	}
+}
+
+// NodeStats holds the Stats reported by a single node, or the error that prevented it
+type NodeStats struct {
+	Size           int64
+	Capacity       int64
+	Load           float64
+	InFlight       int64
+	RequestsTotal  uint64
+	RequestRateEMA float64
+	CPUEMA         float64
+	Hits           uint64
+	Misses         uint64
+	Err            error
+}
+
+// StatsSummary merges the stats of every node that responded
+type StatsSummary struct {
+	Nodes       int
+	Failed      int
+	TotalSize   int64
+	AverageLoad float64
+	Hits        uint64
+	Misses      uint64
+}
+
+// ClusterStats calls the Stats RPC on every node concurrently. Nodes that fail to
+// respond are reported through NodeStats.Err; an error is returned only if no node
+// responded. Use Summarize to merge the results.
+func (c *Client) ClusterStats(ctx context.Context) (map[string]NodeStats, error) {
+	ctx, span := c.startOperation(ctx, "ClusterStats")
+	defer span.End()
+	
+	nodes := c.ring.GetNodes()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	
+	type nodeResult struct {
+		nodeID string
+		stats  NodeStats
+	}
+	
+	results := make(chan nodeResult, len(nodes))
+	for _, node := range nodes {
+		go func(node *ring.Node) {
+			results <- nodeResult{nodeID: node.ID, stats: c.statsFromNode(ctx, node.ID)}
+		}(node)
+	}
+	
+	stats := make(map[string]NodeStats, len(nodes))
+	failed := 0
+	for i := 0; i < len(nodes); i++ {
+		r := <-results
+		stats[r.nodeID] = r.stats
+		if r.stats.Err != nil {
+			failed++
+		}
+	}
+	
+	if failed == len(nodes) {
+		return stats, fmt.Errorf("failed to get stats from any node")
+	}
+	
+	return stats, nil
+}
+
+// Summarize merges per-node stats into cluster totals, skipping nodes that failed
+func Summarize(stats map[string]NodeStats) StatsSummary {
+	var summary StatsSummary
+	var totalLoad float64
+	for _, node := range stats {
+		if node.Err != nil {
+			summary.Failed++
+			continue
+		}
+		summary.Nodes++
+		summary.TotalSize += node.Size
+		summary.Hits += node.Hits
+		summary.Misses += node.Misses
+		totalLoad += node.Load
+	}
+	if summary.Nodes > 0 {
+		summary.AverageLoad = totalLoad / float64(summary.Nodes)
+	}
+	return summary
+}
+
+// statsFromNode fetches the stats of a single node
+func (c *Client) statsFromNode(ctx context.Context, nodeID string) NodeStats {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return NodeStats{Err: err}
+	}
+	
+	resp, err := proto.NewCacheServiceClient(conn).Stats(ctx, &proto.StatsRequest{})
+	if err != nil {
+		return NodeStats{Err: fmt.Errorf("stats %s failed: %w", nodeID, err)}
+	}
+	
+	stats := NodeStats{
+		Size:           resp.CacheSize,
+		Capacity:       resp.CacheCapacity,
+		InFlight:       resp.InFlight,
+		RequestsTotal:  resp.RequestsTotal,
+		RequestRateEMA: resp.RequestRateEma,
+		CPUEMA:         resp.CpuEma,
+		Hits:           resp.Hits,
+		Misses:         resp.Misses,
+	}
+	if resp.CacheCapacity > 0 {
+		stats.Load = float64(resp.CacheSize) / float64(resp.CacheCapacity)
+	}
+	return stats
+}
+
+// pingSnapshot copies the most recent ping latency of each node
+func (c *Client) pingSnapshot() map[string]time.Duration {
+	c.pingMutex.RLock()
+	defer c.pingMutex.RUnlock()
+	
+	rtts := make(map[string]time.Duration, len(c.pingRTTs))
+	for id, rtt := range c.pingRTTs {
+		rtts[id] = rtt
 	}
+	return rtts
 } 
\ No newline at end of file
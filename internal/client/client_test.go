@@ -0,0 +1,459 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shard-cache/internal/ring"
+	"github.com/shard-cache/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestClientConnectionsFollowRing(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	if err := c.AddNode("node1", "localhost:8081"); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	first, err := c.getConnection("node1")
+	if err != nil {
+		t.Fatalf("Expected connection after AddNode: %v", err)
+	}
+	
+	// Moving a node to a new address replaces its connection
+	if err := c.AddNode("node1", "localhost:9091"); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	second, err := c.getConnection("node1")
+	if err != nil {
+		t.Fatalf("Expected connection after address change: %v", err)
+	}
+	if first == second || second.Target() != "localhost:9091" {
+		t.Errorf("Expected a new connection to localhost:9091, got %s", second.Target())
+	}
+	
+	// Nodes removed from the ring directly are disconnected too
+	c.ring.RemoveNode("node1")
+	if _, err := c.getConnection("node1"); err == nil {
+		t.Error("Expected connection to be closed after removal")
+	}
+}
+
+func TestClientSetTopology(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	for i := 0; i < 3; i++ {
+		if err := c.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:808%d", i)); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	c.SetNodeStatus("node1", ring.NodeJoining)
+	before := make(map[string]*grpc.ClientConn)
+	for id, conn := range c.connections {
+		before[id] = conn
+	}
+	
+	// node0 leaves, node1 stays, node2 moves and node3 joins
+	err = c.SetTopology([]NodeSpec{
+		{ID: "node1", Addr: "localhost:8081"},
+		{ID: "node2", Addr: "localhost:9092"},
+		{ID: "node3", Addr: "localhost:8083"},
+	})
+	if err != nil {
+		t.Fatalf("SetTopology failed: %v", err)
+	}
+	
+	if len(c.connections) != 3 || c.ring.NodeCount() != 3 {
+		t.Fatalf("Expected 3 nodes and 3 connections, got %d and %d", c.ring.NodeCount(), len(c.connections))
+	}
+	if _, exists := c.connections["node0"]; exists || before["node0"].GetState() != connectivity.Shutdown {
+		t.Error("Expected the connection to node0 to be closed")
+	}
+	if c.connections["node1"] != before["node1"] {
+		t.Error("Expected node1 to keep its connection")
+	}
+	if node, _ := c.ring.GetNode("node1"); node.Status != ring.NodeJoining {
+		t.Errorf("Expected node1 to keep its status, got %s", node.Status)
+	}
+	if conn := c.connections["node2"]; conn == before["node2"] || conn.Target() != "localhost:9092" || before["node2"].GetState() != connectivity.Shutdown {
+		t.Error("Expected node2's connection to be replaced by one to its new address")
+	}
+	if conn := c.connections["node3"]; conn == nil || conn.Target() != "localhost:8083" {
+		t.Error("Expected a connection to node3")
+	}
+	
+	// Invalid topologies are refused before anything changes
+	if err := c.SetTopology([]NodeSpec{{ID: "node1", Addr: "a"}, {ID: "node1", Addr: "b"}}); err == nil {
+		t.Error("Expected a duplicate node ID to be rejected")
+	}
+	if c.ring.NodeCount() != 3 {
+		t.Errorf("Expected a rejected topology to leave the ring alone, got %d nodes", c.ring.NodeCount())
+	}
+}
+
+func TestClientInjectedLogger(t *testing.T) {
+	logger := zap.NewNop()
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1, Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	if c.logger != logger {
+		t.Error("Expected the client to use the injected logger")
+	}
+}
+
+func TestClientOwnersFor(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1, Namespace: "users"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	for i := 0; i < 5; i++ {
+		if err := c.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:808%d", i)); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	c.SetNodeStatus("node3", ring.NodeLeaving)
+	
+	for _, key := range []string{"alice", "bob", "carol"} {
+		for n := 1; n <= 6; n++ {
+			infos := c.OwnersFor(key, n)
+			owners := c.ring.Owners(c.namespacedKey(key), n)
+			if len(infos) != len(owners) {
+				t.Fatalf("Expected %d owners of %s, got %d", len(owners), key, len(infos))
+			}
+			for i, owner := range owners {
+				want := NodeInfo{ID: owner.ID, Addr: owner.Addr, Status: owner.Status}
+				if infos[i] != want {
+					t.Errorf("Expected owner %d of %s to be %+v, got %+v", i, key, want, infos[i])
+				}
+			}
+		}
+	}
+	
+	for _, n := range []int{0, -1} {
+		if infos := c.OwnersFor("alice", n); len(infos) != 0 {
+			t.Errorf("Expected no owners for n %d, got %v", n, infos)
+		}
+	}
+	
+	// Routing follows topology changes
+	primary := c.OwnersFor("alice", 1)[0].ID
+	c.RemoveNode(primary)
+	infos := c.OwnersFor("alice", 5)
+	if len(infos) != 4 {
+		t.Fatalf("Expected the 4 remaining nodes, got %v", infos)
+	}
+	for _, info := range infos {
+		if info.ID == primary {
+			t.Errorf("Expected removed %s not to own alice, got %v", primary, infos)
+		}
+	}
+}
+
+func TestClientEagerConnect(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	go srv.Serve(lis)
+	defer srv.Stop()
+	
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1, EagerConnect: true, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	// The connection is ready as soon as AddNode returns, before any request
+	if err := c.AddNode("node1", lis.Addr().String()); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	conn, err := c.getConnection("node1")
+	if err != nil {
+		t.Fatalf("Expected connection: %v", err)
+	}
+	if state := conn.GetState(); state != connectivity.Ready {
+		t.Errorf("Expected connection to be ready, got %s", state)
+	}
+	
+	// Unreachable nodes are reported and left out of the ring
+	closed, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := closed.Addr().String()
+	closed.Close()
+	
+	c.connectTimeout = 200 * time.Millisecond
+	if err := c.AddNode("node2", addr); err == nil {
+		t.Error("Expected AddNode to fail for an unreachable node")
+	}
+	if c.ring.NodeCount() != 1 {
+		t.Errorf("Expected unreachable node to be left out of the ring, got %d nodes", c.ring.NodeCount())
+	}
+	if _, err := c.getConnection("node2"); err == nil {
+		t.Error("Expected no connection to the unreachable node")
+	}
+	
+	// SetTopology dials before it changes the ring, so an unreachable node never joins
+	changes := 0
+	c.ring.OnChange(func(added, removed []*ring.Node) { changes++ })
+	err = c.SetTopology([]NodeSpec{{ID: "node1", Addr: lis.Addr().String()}, {ID: "node3", Addr: addr}})
+	if err == nil || !strings.Contains(err.Error(), "node3") {
+		t.Errorf("Expected SetTopology to report node3, got %v", err)
+	}
+	if changes != 0 || c.ring.NodeCount() != 1 {
+		t.Errorf("Expected the ring to be left as it was, got %d changes and %d nodes", changes, c.ring.NodeCount())
+	}
+	if current, _ := c.getConnection("node1"); current != conn {
+		t.Error("Expected node1 to keep its connection")
+	}
+	if _, err := c.getConnection("node3"); err == nil || len(c.dialed) != 0 {
+		t.Error("Expected no connection to the unreachable node")
+	}
+}
+
+func TestClientDuplicateAddNode(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	var conns []*grpc.ClientConn
+	closed := func() int {
+		count := 0
+		for _, conn := range conns {
+			if conn.GetState() == connectivity.Shutdown {
+				count++
+			}
+		}
+		return count
+	}
+	add := func(addr string) {
+		t.Helper()
+		if err := c.AddNode("node1", addr); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+		conn, err := c.getConnection("node1")
+		if err != nil {
+			t.Fatalf("Expected connection: %v", err)
+		}
+		if len(conns) == 0 || conns[len(conns)-1] != conn {
+			conns = append(conns, conn)
+		}
+	}
+	
+	// Adding the same node at the same address keeps its connection
+	add("localhost:8081")
+	add("localhost:8081")
+	if len(conns) != 1 || closed() != 0 {
+		t.Errorf("Expected duplicate AddNode to reuse the connection, %d closed", closed())
+	}
+	
+	// Each address change closes the connection it replaces
+	add("localhost:9091")
+	add("localhost:9092")
+	if len(conns) != 3 || closed() != 2 {
+		t.Errorf("Expected the 2 replaced connections of 3 to be closed, got %d of %d", closed(), len(conns))
+	}
+	if len(c.connections) != 1 || c.ring.NodeCount() != 1 {
+		t.Errorf("Expected one node and connection, got %d nodes and %d connections", c.ring.NodeCount(), len(c.connections))
+	}
+}
+
+// blockingCacheServer answers every Get with a hit, holding each one until release is
+// closed, if it is set
+type blockingCacheServer struct {
+	proto.UnimplementedCacheServiceServer
+	release  chan struct{}
+	inFlight atomic.Int32
+}
+
+func (s *blockingCacheServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+		}
+	}
+	return &proto.GetResponse{Found: true, Value: []byte("value")}, nil
+}
+
+// startBlockingServer serves s until the test ends and returns its address
+func startBlockingServer(t *testing.T, s *blockingCacheServer) string {
+	t.Helper()
+	
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	proto.RegisterCacheServiceServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestClientMaxPerNodeConcurrency(t *testing.T) {
+	slow := &blockingCacheServer{release: make(chan struct{})}
+	fast := &blockingCacheServer{}
+	c, err := NewClient(&Config{ReadQuorum: 2, WriteQuorum: 1, MaxPerNodeConcurrency: 2})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	if err := c.AddNode("slow", startBlockingServer(t, slow)); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := c.AddNode("fast", startBlockingServer(t, fast)); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	ctx := context.Background()
+	
+	// Fill the slow node's limit
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.getFromNode(ctx, "slow", "key")
+		}()
+	}
+	for slow.inFlight.Load() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	// Excess requests to it fail at once without reaching it, while the other node is unaffected
+	start := time.Now()
+	if _, err := c.getFromNode(ctx, "slow", "key"); !errors.Is(err, ErrNodeSaturated) {
+		t.Errorf("Expected ErrNodeSaturated, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected the excess request to fail fast, took %v", elapsed)
+	}
+	if slow.inFlight.Load() != 2 {
+		t.Errorf("Expected the excess request not to reach the node, %d in flight", slow.inFlight.Load())
+	}
+	for i := 0; i < 5; i++ {
+		if value, err := c.getFromNode(ctx, "fast", "key"); err != nil || string(value) != "value" {
+			t.Fatalf("Expected the other node to serve reads, got %q (%v)", value, err)
+		}
+	}
+	
+	// Reads whose primary is saturated are served by another owner
+	key := ""
+	for i := 0; key == ""; i++ {
+		if candidate := fmt.Sprintf("key%d", i); c.ring.Owners(candidate, 1)[0].ID == "slow" {
+			key = candidate
+		}
+	}
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "value" {
+		t.Errorf("Expected the read to fall back to the other owner, got %q (%v)", value, err)
+	}
+	if throttled := c.GetStats()["throttled"].(uint64); throttled != 2 {
+		t.Errorf("Expected 2 throttled requests, got %d", throttled)
+	}
+	
+	// Pings still reach the saturated node and are not throttled
+	if _, err := c.Ping(ctx, "slow"); err == nil || errors.Is(err, ErrNodeSaturated) {
+		t.Errorf("Expected the ping to reach the node, which does not implement Health, got %v", err)
+	}
+	if throttled := c.GetStats()["throttled"].(uint64); throttled != 2 {
+		t.Errorf("Expected pings not to be counted as throttled, got %d", throttled)
+	}
+	
+	// A new limit applies to later calls, leaving those in flight their slots
+	c.SetMaxPerNodeConcurrency(1, time.Second)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.getFromNode(ctx, "slow", "key")
+	}()
+	for slow.inFlight.Load() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	// With a queue timeout, excess requests wait for a slot to be released
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.getFromNode(ctx, "slow", "key")
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(slow.release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the queued request to succeed once a slot freed, got %v", err)
+	}
+	wg.Wait()
+}
+
+func TestClientReadCacheFill(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1, ClientCacheTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	gen := atomic.LoadUint64(c.readCacheGen("key"))
+	c.fillReadCache("key", []byte("value"), gen)
+	if value, found := c.readCache.Get("key"); !found || string(value) != "value" {
+		t.Errorf("Expected an unwritten key to be cached, got %q, %v", value, found)
+	}
+	
+	// A read that began before a write finished must not store its older value
+	gen = atomic.LoadUint64(c.readCacheGen("key"))
+	c.invalidateRead("key")
+	c.fillReadCache("key", []byte("old"), gen)
+	if value, found := c.readCache.Get("key"); found {
+		t.Errorf("Expected the read overtaken by a write not to be cached, got %q", value)
+	}
+}
+
+func TestClientForgetFlights(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	// A read begun before a write is not shared with reads after it
+	release := make(chan struct{})
+	before := c.flights.DoChan(flightKey(ConsistencyQuorum, "key"), func() (interface{}, error) {
+		<-release
+		return "old", nil
+	})
+	c.forgetFlights("key")
+	after := c.flights.DoChan(flightKey(ConsistencyQuorum, "key"), func() (interface{}, error) {
+		return "new", nil
+	})
+	if result := <-after; result.Val != "new" || result.Shared {
+		t.Errorf("Expected a fresh read after the write, got %v, shared %v", result.Val, result.Shared)
+	}
+	close(release)
+	<-before
+}
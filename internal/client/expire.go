@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Expire gives a key a new TTL, counted from when each owner applies it, without
+// rewriting its value. It succeeds once a quorum of owners has answered and reports
+// whether any of them held the key; keys that are absent are not created. Writes
+// still buffered by WriteBack are not considered.
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ctx, span := c.startOperation(ctx, "Expire", attribute.String("cache.key", key))
+	defer span.End()
+	
+	if ttl <= 0 {
+		return false, fmt.Errorf("ttl must be positive; use Persist to remove the expiry")
+	}
+	return c.updateExpiry(ctx, key, ttl, func(ctx context.Context, client proto.CacheServiceClient, key string) (bool, error) {
+		resp, err := client.Expire(ctx, &proto.ExpireRequest{Key: key, Ttl: durationpb.New(ttl)})
+		if err != nil {
+			return false, err
+		}
+		return resp.Updated, nil
+	})
+}
+
+// Persist removes a key's TTL so that it no longer expires, as Expire would set one.
+// It reports whether any owner in the quorum held the key.
+func (c *Client) Persist(ctx context.Context, key string) (bool, error) {
+	ctx, span := c.startOperation(ctx, "Persist", attribute.String("cache.key", key))
+	defer span.End()
+	
+	return c.updateExpiry(ctx, key, 0, func(ctx context.Context, client proto.CacheServiceClient, key string) (bool, error) {
+		resp, err := client.Persist(ctx, &proto.PersistRequest{Key: key})
+		if err != nil {
+			return false, err
+		}
+		return resp.Updated, nil
+	})
+}
+
+// updateExpiry sends an expiry change to every owner of a key over the write quorum.
+// The local cache keeps its copy under the new TTL, zero for none, while the read
+// cache drops its copy so that it is re-read with its own freshness limit.
+func (c *Client) updateExpiry(ctx context.Context, key string, ttl time.Duration, update func(ctx context.Context, client proto.CacheServiceClient, key string) (bool, error)) (bool, error) {
+	key, err := c.storedKey(key)
+	if err != nil {
+		return false, err
+	}
+	
+	owners := c.ring.Owners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return false, fmt.Errorf("%w: no nodes available", ErrInsufficientNodes)
+	}
+	
+	var updated atomic.Bool
+	err = c.writeToOwners(ctx, owners, func(ctx context.Context, nodeID string) error {
+		conn, err := c.getConnection(nodeID)
+		if err != nil {
+			return err
+		}
+		held, err := update(ctx, proto.NewCacheServiceClient(conn), key)
+		if err != nil {
+			return err
+		}
+		if held {
+			updated.Store(true)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update expiry on quorum of nodes: %w", err)
+	}
+	
+	c.forgetFlights(key)
+	if c.readCache != nil {
+		c.invalidateRead(key)
+	}
+	if c.localCache != nil {
+		c.localCache.Touch(key, ttl)
+	}
+	return updated.Load(), nil
+}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shard-cache/internal/ring"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultPhiThreshold is the suspicion level at which a node's breaker opens
+	defaultPhiThreshold = 8.0
+
+	// heartbeatWindow is the number of recent inter-arrival intervals kept per node
+	heartbeatWindow = 100
+
+	// minHeartbeatStdDev stops regular traffic from making small gaps look suspicious
+	minHeartbeatStdDev = 50 * time.Millisecond
+
+	// firstHeartbeatEstimate seeds the interval history of a node seen only once
+	firstHeartbeatEstimate = 500 * time.Millisecond
+
+	// probeInterval is the least time between probes of a suspected node
+	probeInterval = time.Second
+)
+
+// failureDetector is a phi-accrual failure detector. Every response from a node is
+// treated as a heartbeat, and phi measures how unlikely the time since the last one
+// is given the recent intervals between them: phi of 1 means a 10% chance that the
+// node is still fine, 2 means 1%, and so on. The latency of the last response is
+// judged the same way against the recent latencies, and the node's phi is the higher
+// of the two, so that a node turning slow is suspected before it stops answering.
+type failureDetector struct {
+	mu    sync.Mutex
+	nodes map[string]*heartbeatHistory
+}
+
+// heartbeatHistory holds the arrival intervals and response latencies for a single
+// node in ring buffers
+type heartbeatHistory struct {
+	intervals []time.Duration
+	next      int
+	last      time.Time
+	
+	latencies   []time.Duration
+	nextLatency int
+	lastLatency time.Duration
+	probedAt    time.Time // When the node was last probed while suspected
+}
+
+// newFailureDetector creates an empty failure detector
+func newFailureDetector() *failureDetector {
+	return &failureDetector{
+		nodes: make(map[string]*heartbeatHistory),
+	}
+}
+
+// heartbeat records that a node responded at the given time
+func (d *failureDetector) heartbeat(nodeID string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	h, exists := d.nodes[nodeID]
+	if !exists {
+		// Seed the history so that phi is meaningful from the second heartbeat on
+		h = &heartbeatHistory{intervals: make([]time.Duration, 0, heartbeatWindow)}
+		h.intervals = append(h.intervals,
+			firstHeartbeatEstimate-firstHeartbeatEstimate/4,
+			firstHeartbeatEstimate+firstHeartbeatEstimate/4)
+		h.last = now
+		d.nodes[nodeID] = h
+		return
+	}
+	
+	interval := now.Sub(h.last)
+	h.last = now
+	if len(h.intervals) < heartbeatWindow {
+		h.intervals = append(h.intervals, interval)
+		return
+	}
+	h.intervals[h.next] = interval
+	h.next = (h.next + 1) % heartbeatWindow
+}
+
+// observeLatency records how long a node took to answer; the latest sample is the one
+// judged, so a slow response stops counting against the node once it answers promptly
+func (d *failureDetector) observeLatency(nodeID string, latency time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	h, exists := d.nodes[nodeID]
+	if !exists {
+		return
+	}
+	// The latest sample joins the history only once the next arrives, so that an
+	// outlier is judged against the samples before it
+	previous := h.lastLatency
+	h.lastLatency = latency
+	if previous == 0 {
+		return
+	}
+	if len(h.latencies) < heartbeatWindow {
+		h.latencies = append(h.latencies, previous)
+		return
+	}
+	h.latencies[h.nextLatency] = previous
+	h.nextLatency = (h.nextLatency + 1) % heartbeatWindow
+}
+
+// startProbe reports whether a suspected node is due a probe, recording it as probed
+func (d *failureDetector) startProbe(nodeID string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	h, exists := d.nodes[nodeID]
+	if !exists || now.Sub(h.probedAt) < probeInterval {
+		return false
+	}
+	h.probedAt = now
+	return true
+}
+
+// phi returns the suspicion level of a node at the given time; nodes that have
+// never responded are not suspected
+func (d *failureDetector) phi(nodeID string, now time.Time) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	h, exists := d.nodes[nodeID]
+	if !exists {
+		return 0
+	}
+	return h.phi(now)
+}
+
+// phi returns the higher of the suspicion levels of the time since the last heartbeat
+// and of the last response's latency
+func (h *heartbeatHistory) phi(now time.Time) float64 {
+	level := suspicion(h.intervals, now.Sub(h.last))
+	if len(h.latencies) > 1 {
+		level = math.Max(level, suspicion(h.latencies, h.lastLatency))
+	}
+	return level
+}
+
+// suspicion computes how unlikely an observed duration is from a normal distribution
+// fitted to the samples, using the logistic approximation of its tail
+func suspicion(samples []time.Duration, observed time.Duration) float64 {
+	var sum float64
+	for _, sample := range samples {
+		sum += float64(sample)
+	}
+	mean := sum / float64(len(samples))
+	
+	var variance float64
+	for _, sample := range samples {
+		diff := float64(sample) - mean
+		variance += diff * diff
+	}
+	stdDev := math.Max(math.Sqrt(variance/float64(len(samples))), float64(minHeartbeatStdDev))
+	
+	y := (float64(observed) - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if y > 0 {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// snapshot returns the current suspicion level of every node that has responded
+func (d *failureDetector) snapshot(now time.Time) map[string]float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	levels := make(map[string]float64, len(d.nodes))
+	for id, h := range d.nodes {
+		levels[id] = h.phi(now)
+	}
+	return levels
+}
+
+// remove forgets a node's heartbeats
+func (d *failureDetector) remove(nodeID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.nodes, nodeID)
+}
+
+// detectorInterceptor feeds the failure detector with the responses from one node and
+// their latencies. Any answer from the node counts as a heartbeat, including errors it
+// returned; transport failures, timeouts and cancellations do not.
+func (c *Client) detectorInterceptor(nodeID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		default:
+			now := time.Now()
+			c.detector.heartbeat(nodeID, now)
+			c.detector.observeLatency(nodeID, now.Sub(start))
+		}
+		return err
+	}
+}
+
+// breakerOpen reports whether a node is suspected of having failed. A suspected node
+// is probed in the background, as a half-open breaker would let one call through, so
+// that it is trusted again once it answers even though reads are steered away from it.
+func (c *Client) breakerOpen(nodeID string) bool {
+	now := time.Now()
+	if c.detector.phi(nodeID, now) < c.phiThreshold {
+		return false
+	}
+	if c.detector.startProbe(nodeID, now) {
+		go c.Ping(context.Background(), nodeID)
+	}
+	return true
+}
+
+// byHealth moves owners whose breaker is open behind the others, and behind those
+// owners whose last ping reported them shedding load, keeping ring order otherwise,
+// so reads go to a healthy owner with capacity first but still reach the others
+// when nothing else is left
+func (c *Client) byHealth(owners []*ring.Node) []*ring.Node {
+	rank := make(map[string]int, len(owners))
+	c.pingMutex.RLock()
+	for _, owner := range owners {
+		if c.nodeLoads[owner.ID].Shedding {
+			rank[owner.ID] = 1
+		}
+	}
+	c.pingMutex.RUnlock()
+	for _, owner := range owners {
+		if c.breakerOpen(owner.ID) {
+			rank[owner.ID] = 2
+		}
+	}
+	sort.SliceStable(owners, func(i, j int) bool {
+		return rank[owners[i].ID] < rank[owners[j].ID]
+	})
+	return owners
+}
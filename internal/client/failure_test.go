@@ -0,0 +1,137 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shard-cache/internal/ring"
+)
+
+func TestFailureDetectorPhiRises(t *testing.T) {
+	detector := newFailureDetector()
+	
+	if phi := detector.phi("node1", time.Now()); phi != 0 {
+		t.Errorf("Expected no suspicion for an unknown node, got %f", phi)
+	}
+	
+	// Regular heartbeats every 100ms with a little jitter
+	now := time.Now()
+	for i := 0; i < heartbeatWindow; i++ {
+		now = now.Add(100*time.Millisecond + time.Duration(i%5)*time.Millisecond)
+		detector.heartbeat("node1", now)
+	}
+	
+	// Suspicion grows the longer the next heartbeat is delayed
+	var last float64
+	for _, delay := range []time.Duration{50 * time.Millisecond, 150 * time.Millisecond, 250 * time.Millisecond, 400 * time.Millisecond} {
+		phi := detector.phi("node1", now.Add(delay))
+		if phi <= last {
+			t.Errorf("Expected phi to rise after %v, got %f (previous %f)", delay, phi, last)
+		}
+		last = phi
+	}
+	
+	if phi := detector.phi("node1", now.Add(50*time.Millisecond)); phi >= 1 {
+		t.Errorf("Expected low suspicion before the usual interval, got %f", phi)
+	}
+	if phi := detector.phi("node1", now.Add(time.Second)); phi < defaultPhiThreshold {
+		t.Errorf("Expected phi above %v after a long silence, got %f", defaultPhiThreshold, phi)
+	}
+	
+	// A heartbeat clears the suspicion
+	now = now.Add(time.Second)
+	detector.heartbeat("node1", now)
+	if phi := detector.phi("node1", now); phi >= 1 {
+		t.Errorf("Expected suspicion to clear after a heartbeat, got %f", phi)
+	}
+	
+	if levels := detector.snapshot(now); len(levels) != 1 {
+		t.Errorf("Expected one node in snapshot, got %d", len(levels))
+	}
+	detector.remove("node1")
+	if phi := detector.phi("node1", now.Add(time.Hour)); phi != 0 {
+		t.Errorf("Expected heartbeats to be dropped after remove, got %f", phi)
+	}
+}
+
+func TestClientByHealthPrefersUnsuspectedOwners(t *testing.T) {
+	c := &Client{detector: newFailureDetector(), phiThreshold: defaultPhiThreshold, nodeLoads: make(map[string]NodeLoad)}
+	
+	// node0 went silent a while ago, node1 just responded and node2 was never contacted
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		c.detector.heartbeat("node0", now.Add(-time.Hour+time.Duration(i)*10*time.Millisecond))
+		c.detector.heartbeat("node1", now.Add(time.Duration(i-20)*10*time.Millisecond))
+	}
+	
+	owners := c.byHealth([]*ring.Node{{ID: "node0"}, {ID: "node1"}, {ID: "node2"}})
+	var order []string
+	for _, owner := range owners {
+		order = append(order, owner.ID)
+	}
+	if order[0] != "node1" || order[1] != "node2" || order[2] != "node0" {
+		t.Errorf("Expected suspected node0 to be tried last, got %v", order)
+	}
+	
+	// A node that reported shedding load goes behind the others that did not
+	c.nodeLoads["node1"] = NodeLoad{Load: 0.5, Shedding: true}
+	order = order[:0]
+	for _, owner := range c.byHealth([]*ring.Node{{ID: "node0"}, {ID: "node1"}, {ID: "node2"}}) {
+		order = append(order, owner.ID)
+	}
+	if order[0] != "node2" || order[1] != "node1" || order[2] != "node0" {
+		t.Errorf("Expected shedding node1 after node2 and before suspected node0, got %v", order)
+	}
+}
+
+func TestFailureDetectorSlowResponses(t *testing.T) {
+	detector := newFailureDetector()
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		now = now.Add(100 * time.Millisecond)
+		detector.heartbeat("node1", now)
+		detector.observeLatency("node1", 5*time.Millisecond)
+	}
+	if phi := detector.phi("node1", now); phi >= 1 {
+		t.Errorf("Expected no suspicion of a prompt node, got %f", phi)
+	}
+	
+	// A response far slower than usual raises suspicion until a prompt one follows
+	detector.heartbeat("node1", now)
+	detector.observeLatency("node1", time.Second)
+	if phi := detector.phi("node1", now); phi < defaultPhiThreshold {
+		t.Errorf("Expected phi above %v after a slow response, got %f", defaultPhiThreshold, phi)
+	}
+	detector.observeLatency("node1", 5*time.Millisecond)
+	if phi := detector.phi("node1", now); phi >= 1 {
+		t.Errorf("Expected a prompt response to clear the suspicion, got %f", phi)
+	}
+}
+
+func TestClientProbesSuspectedNode(t *testing.T) {
+	c, err := NewClient(&Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	if err := c.AddNode("node1", startBlockingServer(t, &blockingCacheServer{})); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	
+	// The node went quiet an hour ago, so nothing but a probe would reach it
+	c.detector.remove("node1")
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		c.detector.heartbeat("node1", now.Add(-time.Hour+time.Duration(i)*10*time.Millisecond))
+	}
+	if !c.breakerOpen("node1") {
+		t.Fatal("Expected the quiet node to be suspected")
+	}
+	
+	// The probe's answer clears the suspicion
+	for deadline := time.Now().Add(time.Second); c.breakerOpen("node1"); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the probe to clear the suspicion of a node that answers")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// hlcLogicalBits is how many low bits of a timestamp hold the logical counter. The
+// remaining bits hold the wall clock in nanoseconds rounded down to 2^16ns (about
+// 65µs), so timestamps stay comparable with plain wall clock versions.
+const hlcLogicalBits = 16
+
+// hlcLogicalMask selects the logical counter of a timestamp
+const hlcLogicalMask = 1<<hlcLogicalBits - 1
+
+// hlcMaxDrift bounds how far ahead of the wall clock an observed timestamp may be.
+// Beyond it the timestamp is taken for a corrupt or runaway version rather than a
+// clock running ahead, and is not followed, so that one bad version cannot push
+// every later write far into the future.
+const hlcMaxDrift = time.Minute
+
+// hybridClock issues hybrid logical clock timestamps, used as write versions. Each
+// timestamp is above both the wall clock and every timestamp issued or observed
+// before, so a write made after reading another's result always wins last-write-wins
+// resolution, however far behind this client's clock is. Within one wall clock tick,
+// or while behind an observed timestamp, the logical counter orders writes; if it
+// overflows it carries into the wall clock part, which remains correct.
+type hybridClock struct {
+	last uint64
+}
+
+// now returns a timestamp above every one issued or observed so far
+func (h *hybridClock) now() uint64 {
+	for {
+		last := atomic.LoadUint64(&h.last)
+		next := uint64(time.Now().UnixNano()) &^ hlcLogicalMask
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapUint64(&h.last, last, next) {
+			return next
+		}
+	}
+}
+
+// observe advances the clock past a timestamp seen on a node, unless it is more than
+// hlcMaxDrift ahead of the wall clock
+func (h *hybridClock) observe(timestamp uint64) {
+	if timestamp > uint64(time.Now().Add(hlcMaxDrift).UnixNano()) {
+		return
+	}
+	for {
+		last := atomic.LoadUint64(&h.last)
+		if timestamp <= last || atomic.CompareAndSwapUint64(&h.last, last, timestamp) {
+			return
+		}
+	}
+}
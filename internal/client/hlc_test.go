@@ -0,0 +1,43 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHybridClock(t *testing.T) {
+	var clock hybridClock
+	
+	// Timestamps follow the wall clock and strictly increase
+	before := uint64(time.Now().UnixNano()) &^ hlcLogicalMask
+	first := clock.now()
+	if first < before {
+		t.Errorf("Expected a timestamp at or after the wall clock %d, got %d", before, first)
+	}
+	if second := clock.now(); second <= first {
+		t.Errorf("Expected %d to follow %d", second, first)
+	}
+	
+	// A timestamp from a clock half a minute ahead pulls this one forward, with the
+	// logical counter ordering writes until the wall clock catches up
+	ahead := uint64(time.Now().Add(30*time.Second).UnixNano()) &^ hlcLogicalMask
+	clock.observe(ahead)
+	if next := clock.now(); next != ahead+1 {
+		t.Errorf("Expected %d after observing %d, got %d", ahead+1, ahead, next)
+	}
+	if next := clock.now(); next != ahead+2 {
+		t.Errorf("Expected the logical counter to advance to %d, got %d", ahead+2, next)
+	}
+	
+	// Older timestamps are ignored
+	clock.observe(first)
+	if next := clock.now(); next != ahead+3 {
+		t.Errorf("Expected an older timestamp to be ignored, got %d", next)
+	}
+	
+	// So are timestamps further ahead than the wall clock can plausibly drift
+	clock.observe(uint64(time.Now().Add(time.Hour).UnixNano()))
+	if next := clock.now(); next != ahead+4 {
+		t.Errorf("Expected a timestamp an hour ahead to be ignored, got %d", next)
+	}
+}
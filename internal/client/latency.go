@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shard-cache/internal/ring"
+)
+
+const (
+	// latencyWindow is the number of recent samples kept per node
+	latencyWindow = 128
+
+	// minLatencySamples is how many samples a node needs before its quantiles are used
+	minLatencySamples = 16
+
+	// latencyEWMAAlpha weights each new sample in a node's moving average latency
+	latencyEWMAAlpha = 0.3
+
+	// latencyExploreRatio is the share of latency-aware reads sent to a random owner, so
+	// that a node avoided for being slow is sampled again once it recovers
+	latencyExploreRatio = 0.05
+)
+
+// latencyTracker keeps a rolling window of read latencies and hedge counts per node
+type latencyTracker struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeLatency
+}
+
+// nodeLatency holds the samples for a single node in a ring buffer
+type nodeLatency struct {
+	samples []time.Duration
+	next    int
+	hedges  uint64
+	ewma    float64 // Exponentially weighted moving average in nanoseconds
+}
+
+// newLatencyTracker creates an empty latency tracker
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{
+		nodes: make(map[string]*nodeLatency),
+	}
+}
+
+// node returns the stats for a node, creating them if needed; the caller must hold the lock
+func (t *latencyTracker) node(nodeID string) *nodeLatency {
+	n, exists := t.nodes[nodeID]
+	if !exists {
+		n = &nodeLatency{samples: make([]time.Duration, 0, latencyWindow)}
+		t.nodes[nodeID] = n
+	}
+	return n
+}
+
+// observe records a latency sample for a node, replacing the oldest once the window is full
+func (t *latencyTracker) observe(nodeID string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	n := t.node(nodeID)
+	if len(n.samples) == 0 {
+		n.ewma = float64(d)
+	} else {
+		n.ewma += latencyEWMAAlpha * (float64(d) - n.ewma)
+	}
+	
+	if len(n.samples) < latencyWindow {
+		n.samples = append(n.samples, d)
+		return
+	}
+	n.samples[n.next] = d
+	n.next = (n.next + 1) % latencyWindow
+}
+
+// quantile returns the q-th quantile of a node's recent latencies, or false if
+// there are too few samples to be meaningful
+func (t *latencyTracker) quantile(nodeID string, q float64) (time.Duration, bool) {
+	t.mu.Lock()
+	n, exists := t.nodes[nodeID]
+	if !exists || len(n.samples) < minLatencySamples {
+		t.mu.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, len(n.samples))
+	copy(sorted, n.samples)
+	t.mu.Unlock()
+	
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	
+	idx := int(q * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx], true
+}
+
+// average returns a node's exponentially weighted moving average latency, or false if
+// it has no samples
+func (t *latencyTracker) average(nodeID string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	n, exists := t.nodes[nodeID]
+	if !exists || len(n.samples) == 0 {
+		return 0, false
+	}
+	return time.Duration(n.ewma), true
+}
+
+// fasterThan reports whether node a has a lower moving average latency than node b;
+// a node without samples is treated as the fastest
+func (t *latencyTracker) fasterThan(a, b string) bool {
+	avgA, okA := t.average(a)
+	avgB, okB := t.average(b)
+	if !okA || !okB {
+		return !okA && okB
+	}
+	return avgA < avgB
+}
+
+// recordHedge counts a hedge fired because a node was slower than usual
+func (t *latencyTracker) recordHedge(nodeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.node(nodeID).hedges++
+}
+
+// hedgeCounts returns the number of hedges fired against each node
+func (t *latencyTracker) hedgeCounts() map[string]uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	counts := make(map[string]uint64, len(t.nodes))
+	for id, n := range t.nodes {
+		counts[id] = n.hedges
+	}
+	return counts
+}
+
+// remove forgets a node's samples
+func (t *latencyTracker) remove(nodeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.nodes, nodeID)
+}
+
+// byLatency moves the read target to the front of owners, choosing with the power of
+// two choices: two healthy owners are picked at random and the one with the lower
+// moving average latency wins. Owners without samples win so that they get measured,
+// and ties keep ring order. Suspected owners, which byHealth has already moved last,
+// are never chosen.
+func (c *Client) byLatency(owners []*ring.Node) []*ring.Node {
+	healthy := 0
+	for healthy < len(owners) && !c.breakerOpen(owners[healthy].ID) {
+		healthy++
+	}
+	if healthy < 2 {
+		return owners
+	}
+	
+	var chosen int
+	if rand.Float64() < latencyExploreRatio {
+		chosen = rand.Intn(healthy)
+	} else {
+		i, j := rand.Intn(healthy), rand.Intn(healthy-1)
+		if j >= i {
+			j++
+		}
+		if i > j {
+			i, j = j, i
+		}
+		chosen = i
+		if c.latency.fasterThan(owners[j].ID, owners[i].ID) {
+			chosen = j
+		}
+	}
+	
+	// Shift the owners ahead of the chosen one back, keeping their order
+	target := owners[chosen]
+	copy(owners[1:chosen+1], owners[:chosen])
+	owners[0] = target
+	return owners
+}
+
+// runPinger pings every node once and then every ping interval, until Close stops it.
+// The answers are heartbeats for the failure detector, so that an idle node is not
+// suspected, and the round trips are what byNearest orders owners by.
+func (c *Client) runPinger() {
+	defer close(c.pingDone)
+	
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+	
+	for {
+		c.pingAll()
+		select {
+		case <-c.pingStop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pingAll pings every node in the ring concurrently. Failures are left to the failure
+// detector, and the node keeps its last measured round trip.
+func (c *Client) pingAll() {
+	var wg sync.WaitGroup
+	for _, node := range c.ring.GetNodes() {
+		wg.Add(1)
+		go func(nodeID string) {
+			defer wg.Done()
+			c.Ping(context.Background(), nodeID)
+		}(node.ID)
+	}
+	wg.Wait()
+}
+
+// byNearest orders owners by their most recent ping round trip, nearest first. Owners
+// that have not answered a ping yet go last, and ties keep ring order.
+func (c *Client) byNearest(owners []*ring.Node) []*ring.Node {
+	c.pingMutex.RLock()
+	rtts := make(map[string]time.Duration, len(owners))
+	for _, owner := range owners {
+		if rtt, ok := c.pingRTTs[owner.ID]; ok {
+			rtts[owner.ID] = rtt
+		}
+	}
+	c.pingMutex.RUnlock()
+	
+	sort.SliceStable(owners, func(i, j int) bool {
+		a, aok := rtts[owners[i].ID]
+		b, bok := rtts[owners[j].ID]
+		if aok != bok {
+			return aok
+		}
+		return a < b
+	})
+	return owners
+}
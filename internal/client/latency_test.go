@@ -0,0 +1,111 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shard-cache/internal/ring"
+)
+
+func TestLatencyTrackerQuantile(t *testing.T) {
+	tracker := newLatencyTracker()
+	
+	// Too few samples are not trusted
+	for i := 0; i < minLatencySamples-1; i++ {
+		tracker.observe("node1", time.Millisecond)
+	}
+	if _, ok := tracker.quantile("node1", 0.95); ok {
+		t.Error("Expected no quantile before minimum samples")
+	}
+	
+	tracker = newLatencyTracker()
+	for i := 1; i <= 100; i++ {
+		tracker.observe("node1", time.Duration(i)*time.Millisecond)
+	}
+	
+	p95, ok := tracker.quantile("node1", 0.95)
+	if !ok {
+		t.Fatal("Expected quantile with enough samples")
+	}
+	if p95 != 96*time.Millisecond {
+		t.Errorf("Expected p95 of 96ms, got %v", p95)
+	}
+	
+	if _, ok := tracker.quantile("unknown", 0.95); ok {
+		t.Error("Expected no quantile for unknown node")
+	}
+}
+
+func TestLatencyTrackerWindow(t *testing.T) {
+	tracker := newLatencyTracker()
+	
+	// Fill the window with slow samples, then overwrite it entirely with fast ones
+	for i := 0; i < latencyWindow; i++ {
+		tracker.observe("node1", time.Second)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		tracker.observe("node1", time.Millisecond)
+	}
+	
+	p99, _ := tracker.quantile("node1", 0.99)
+	if p99 != time.Millisecond {
+		t.Errorf("Expected old samples to roll out of the window, got p99 %v", p99)
+	}
+	
+	tracker.recordHedge("node1")
+	tracker.recordHedge("node1")
+	if counts := tracker.hedgeCounts(); counts["node1"] != 2 {
+		t.Errorf("Expected 2 hedges, got %d", counts["node1"])
+	}
+	
+	tracker.remove("node1")
+	if _, ok := tracker.quantile("node1", 0.5); ok {
+		t.Error("Expected samples to be dropped after remove")
+	}
+}
+
+func TestLatencyTrackerAverage(t *testing.T) {
+	tracker := newLatencyTracker()
+	if _, ok := tracker.average("node1"); ok {
+		t.Error("Expected no average without samples")
+	}
+	
+	// The first sample seeds the average and later ones pull it towards them
+	tracker.observe("node1", 10*time.Millisecond)
+	if avg, _ := tracker.average("node1"); avg != 10*time.Millisecond {
+		t.Errorf("Expected average of 10ms after one sample, got %v", avg)
+	}
+	for i := 0; i < 20; i++ {
+		tracker.observe("node1", time.Millisecond)
+	}
+	if avg, _ := tracker.average("node1"); avg > 1100*time.Microsecond {
+		t.Errorf("Expected average to approach 1ms, got %v", avg)
+	}
+	
+	tracker.observe("node2", 5*time.Millisecond)
+	if !tracker.fasterThan("node1", "node2") || tracker.fasterThan("node2", "node1") {
+		t.Error("Expected node1 to be faster than node2")
+	}
+	if !tracker.fasterThan("node3", "node1") {
+		t.Error("Expected a node without samples to be treated as fastest")
+	}
+}
+
+func TestClientByNearestOrdersByPingRTT(t *testing.T) {
+	c := &Client{pingRTTs: map[string]time.Duration{
+		"node0": 30 * time.Millisecond,
+		"node2": 5 * time.Millisecond,
+		"node3": 30 * time.Millisecond,
+	}}
+	
+	// node1 has not answered a ping, and node0 and node3 tie
+	owners := c.byNearest([]*ring.Node{{ID: "node0"}, {ID: "node1"}, {ID: "node2"}, {ID: "node3"}})
+	var order []string
+	for _, owner := range owners {
+		order = append(order, owner.ID)
+	}
+	if strings.Join(order, ",") != "node2,node0,node3,node1" {
+		t.Errorf("Expected owners nearest first with unmeasured ones last, got %v", order)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/shard-cache/proto"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc"
+)
+
+// ErrNodeSaturated is returned for an RPC that found MaxPerNodeConcurrency calls
+// already in flight to its node and no slot freed within PerNodeQueueTimeout
+var ErrNodeSaturated = errors.New("too many requests in flight to node")
+
+// limitInterceptor bounds the unary RPCs in flight on one node's connection. It runs
+// ahead of the failure detector, so a call refused here is not taken for a response.
+// Health calls are not limited, so that pings still reach a node this client keeps
+// busy, and are never counted as throttled.
+func (c *Client) limitInterceptor(nodeID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if method == proto.CacheService_Health_FullMethodName {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		sem, queueTimeout := c.nodeSlots(nodeID)
+		if sem == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if err := acquireNodeSlot(ctx, sem, queueTimeout); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			atomic.AddUint64(&c.throttled, 1)
+			return fmt.Errorf("%w %s", ErrNodeSaturated, nodeID)
+		}
+		defer sem.Release(1)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// SetMaxPerNodeConcurrency changes MaxPerNodeConcurrency and PerNodeQueueTimeout on a
+// running client. Calls already in flight keep the slots they hold under the old
+// limit, and are not counted against the new one.
+func (c *Client) SetMaxPerNodeConcurrency(limit int, queueTimeout time.Duration) {
+	c.limitMutex.Lock()
+	defer c.limitMutex.Unlock()
+	c.maxPerNode = limit
+	c.nodeQueueTimeout = queueTimeout
+	c.nodeLimits = make(map[string]*semaphore.Weighted)
+}
+
+// nodeSlots returns the semaphore bounding the calls in flight to a node, creating it
+// on first use, and the queue timeout; the semaphore is nil when calls are unbounded
+func (c *Client) nodeSlots(nodeID string) (*semaphore.Weighted, time.Duration) {
+	c.limitMutex.RLock()
+	sem, limit, queueTimeout := c.nodeLimits[nodeID], c.maxPerNode, c.nodeQueueTimeout
+	c.limitMutex.RUnlock()
+	if sem != nil || limit <= 0 {
+		return sem, queueTimeout
+	}
+	
+	c.limitMutex.Lock()
+	defer c.limitMutex.Unlock()
+	if c.maxPerNode <= 0 {
+		return nil, c.nodeQueueTimeout
+	}
+	if sem = c.nodeLimits[nodeID]; sem == nil {
+		sem = semaphore.NewWeighted(int64(c.maxPerNode))
+		c.nodeLimits[nodeID] = sem
+	}
+	return sem, c.nodeQueueTimeout
+}
+
+// acquireNodeSlot takes a slot, waiting up to queueTimeout for one to be released if
+// they are all in use
+func acquireNodeSlot(ctx context.Context, sem *semaphore.Weighted, queueTimeout time.Duration) error {
+	if sem.TryAcquire(1) {
+		return nil
+	}
+	if queueTimeout <= 0 {
+		return ErrNodeSaturated
+	}
+	
+	waitCtx, cancel := context.WithTimeout(ctx, queueTimeout)
+	defer cancel()
+	return sem.Acquire(waitCtx, 1)
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadSpec describes a synthetic load for RunLoad
+type LoadSpec struct {
+	Workers   int           // Concurrent workers; defaults to 10
+	Duration  time.Duration // How long to run; zero runs until the context ends
+	Keys      int           // Distinct keys each worker uses; defaults to 10
+	ReadRatio float64       // Fraction of operations that are Gets, the rest being Sets; zero issues only Sets
+	Pause     time.Duration // Delay between a worker's operations
+}
+
+// LoadResult totals the operations of a RunLoad run
+type LoadResult struct {
+	Operations int64 // Gets and Sets completed, successful or not
+	Reads      int64
+	Writes     int64
+	Misses     int64 // Gets of keys that were not found
+	Errors     int64 // Operations that failed, misses excluded
+	Elapsed    time.Duration
+	Latency    LatencyStats
+}
+
+// LatencyStats summarizes the latencies of a run's operations. Min, Mean and Max are
+// exact; the percentiles are estimated from a sample of up to loadSampleSize latencies
+// per worker.
+type LatencyStats struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// String returns the result on one line, for logging
+func (r LoadResult) String() string {
+	return fmt.Sprintf("%d operations (%d reads, %d writes, %d misses, %d errors) in %v; latency min %v mean %v p50 %v p99 %v max %v",
+		r.Operations, r.Reads, r.Writes, r.Misses, r.Errors, r.Elapsed,
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P99, r.Latency.Max)
+}
+
+// loadSampleSize is how many latencies each worker keeps for the percentiles, so that
+// a long run's memory stays bounded
+const loadSampleSize = 10000
+
+// loadWorker is one worker's share of a LoadResult
+type loadWorker struct {
+	reads, writes, misses, errors int64
+	latencies                     latencySample
+}
+
+// latencySample totals a set of latencies and keeps a uniform random sample of at most
+// loadSampleSize of them, by reservoir sampling
+type latencySample struct {
+	count    int64
+	total    time.Duration
+	min, max time.Duration
+	samples  []time.Duration
+}
+
+// add records a latency
+func (s *latencySample) add(d time.Duration) {
+	if s.count == 0 || d < s.min {
+		s.min = d
+	}
+	if d > s.max {
+		s.max = d
+	}
+	s.count++
+	s.total += d
+	
+	if len(s.samples) < loadSampleSize {
+		s.samples = append(s.samples, d)
+	} else if i := rand.Int63n(s.count); i < loadSampleSize {
+		s.samples[i] = d
+	}
+}
+
+// merge adds the latencies recorded in other
+func (s *latencySample) merge(other latencySample) {
+	if other.count == 0 {
+		return
+	}
+	if s.count == 0 || other.min < s.min {
+		s.min = other.min
+	}
+	if other.max > s.max {
+		s.max = other.max
+	}
+	s.count += other.count
+	s.total += other.total
+	s.samples = append(s.samples, other.samples...)
+}
+
+// RunLoad drives the client with a mix of Gets and Sets from a pool of workers until
+// the spec's duration has passed or ctx ends, and returns the operation counts and
+// latencies. Each worker picks keys at random from its own set, so that workers do
+// not contend for keys. Operations cut short by the end of the run are not counted.
+func (c *Client) RunLoad(ctx context.Context, spec LoadSpec) LoadResult {
+	if spec.Workers <= 0 {
+		spec.Workers = 10
+	}
+	if spec.Keys <= 0 {
+		spec.Keys = 10
+	}
+	if spec.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Duration)
+		defer cancel()
+	}
+	
+	workers := make([]loadWorker, spec.Workers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range workers {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			c.runLoadWorker(ctx, spec, id, &workers[id])
+		}(i)
+	}
+	wg.Wait()
+	
+	result := LoadResult{Elapsed: time.Since(start)}
+	var latencies latencySample
+	for _, w := range workers {
+		result.Reads += w.reads
+		result.Writes += w.writes
+		result.Misses += w.misses
+		result.Errors += w.errors
+		latencies.merge(w.latencies)
+	}
+	result.Operations = result.Reads + result.Writes
+	result.Latency = latencyStats(latencies)
+	return result
+}
+
+// runLoadWorker issues operations until ctx ends, recording them in w
+func (c *Client) runLoadWorker(ctx context.Context, spec LoadSpec, id int, w *loadWorker) {
+	for ops := 0; ctx.Err() == nil; ops++ {
+		key := fmt.Sprintf("key-%d-%d", id, rand.Intn(spec.Keys))
+		read := rand.Float64() < spec.ReadRatio
+		
+		start := time.Now()
+		var err error
+		if read {
+			_, err = c.Get(ctx, key)
+		} else {
+			err = c.Set(ctx, key, []byte(fmt.Sprintf("value-%d-%d", id, ops)), 0)
+		}
+		elapsed := time.Since(start)
+		if ctx.Err() != nil {
+			return
+		}
+		
+		if read {
+			w.reads++
+		} else {
+			w.writes++
+		}
+		switch {
+		case errors.Is(err, ErrKeyNotFound):
+			w.misses++
+		case err != nil:
+			w.errors++
+		}
+		w.latencies.add(elapsed)
+		
+		if spec.Pause > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(spec.Pause):
+			}
+		}
+	}
+}
+
+// latencyStats summarizes a latency sample, whose samples it sorts
+func latencyStats(latencies latencySample) LatencyStats {
+	if latencies.count == 0 {
+		return LatencyStats{}
+	}
+	samples := latencies.samples
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	
+	quantile := func(q float64) time.Duration {
+		return samples[int(q*float64(len(samples)-1))]
+	}
+	return LatencyStats{
+		Min:  latencies.min,
+		Mean: latencies.total / time.Duration(latencies.count),
+		P50:  quantile(0.5),
+		P99:  quantile(0.99),
+		Max:  latencies.max,
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencySample(t *testing.T) {
+	var sample latencySample
+	if stats := latencyStats(sample); stats != (LatencyStats{}) {
+		t.Errorf("Expected zero stats for no latencies, got %+v", stats)
+	}
+	
+	// The sample stays bounded while the totals stay exact
+	n := 3 * loadSampleSize
+	for i := 1; i <= n; i++ {
+		sample.add(time.Duration(i) * time.Microsecond)
+	}
+	if len(sample.samples) != loadSampleSize {
+		t.Errorf("Expected %d samples kept, got %d", loadSampleSize, len(sample.samples))
+	}
+	
+	var other latencySample
+	other.add(time.Nanosecond)
+	sample.merge(other)
+	
+	stats := latencyStats(sample)
+	if stats.Min != time.Nanosecond || stats.Max != time.Duration(n)*time.Microsecond {
+		t.Errorf("Expected exact min and max, got %+v", stats)
+	}
+	if want := time.Duration(n+1) * time.Microsecond / 2; stats.Mean < want-time.Microsecond || stats.Mean > want {
+		t.Errorf("Expected a mean of about %v, got %v", want, stats.Mean)
+	}
+	
+	// Percentiles come from a uniform sample, so they land near the true ones
+	if p50 := time.Duration(n/2) * time.Microsecond; stats.P50 < p50*9/10 || stats.P50 > p50*11/10 {
+		t.Errorf("Expected a p50 near %v, got %v", p50, stats.P50)
+	}
+	if stats.P99 < stats.P50 || stats.P99 > stats.Max {
+		t.Errorf("Expected p99 between p50 and max, got %+v", stats)
+	}
+}
@@ -0,0 +1,479 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shard-cache/internal/ring"
+	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// MGetResult is the outcome of reading a single key with MGet
+type MGetResult struct {
+	Key   string
+	Value []byte
+	Found bool
+	Err   error // Set if none of the key's owners could be reached
+}
+
+// MGet reads several keys using one BatchGet per node. Keys are grouped by their
+// primary owner, so a healthy cluster serves the whole call with one RPC for each
+// distinct primary. Keys whose node fails are regrouped by their next owner and
+// retried, again one RPC per node, until every owner has been tried. An owner that
+// answers is authoritative: a key it does not hold is reported as not found rather
+// than asked of the other owners.
+//
+// Results are returned in the order of keys. Keys that could not be fetched have Err
+// set; an error is returned only if a key is invalid, there are no nodes or no key
+// could be fetched at all.
+func (c *Client) MGet(ctx context.Context, keys []string) ([]MGetResult, error) {
+	ctx, span := c.startOperation(ctx, "MGet", attribute.Int("cache.keys", len(keys)))
+	defer span.End()
+	
+	results := make([]MGetResult, len(keys))
+	positions := make(map[string][]int) // Stored key to its indexes in results
+	var unread []string
+	for i, key := range keys {
+		results[i].Key = key
+		
+		stored, err := c.storedKey(key)
+		if err != nil {
+			return nil, err
+		}
+		
+		// Writes still waiting to be flushed are visible to this client
+		if c.writeBack != nil {
+			if value, found := c.writeBack.get(stored); found {
+				results[i].Value, results[i].Found = value, true
+				continue
+			}
+		}
+		
+		if _, seen := positions[stored]; !seen {
+			unread = append(unread, stored)
+		}
+		positions[stored] = append(positions[stored], i)
+	}
+	
+	remaining := make(map[string][]*ring.Node, len(unread)) // Stored key to the owners left to try
+	for key, owners := range c.readOwnersBatch(unread, c.currentReadQuorum()) {
+		if len(owners) == 0 {
+			return nil, fmt.Errorf("no nodes available")
+		}
+		remaining[key] = c.byHealth(owners)
+	}
+	if len(remaining) < len(unread) {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	
+	type batchResult struct {
+		keys      []string
+		responses []*proto.GetResponse
+		err       error
+	}
+	
+	failed := 0
+	for len(remaining) > 0 {
+		// Group the keys still unread by the next owner to try
+		batches := make(map[string][]string)
+		for key, owners := range remaining {
+			batches[owners[0].ID] = append(batches[owners[0].ID], key)
+		}
+		
+		batchResults := make(chan batchResult, len(batches))
+		for nodeID, batch := range batches {
+			go func(nodeID string, batch []string) {
+				responses, err := c.batchGetFromNode(ctx, nodeID, batch)
+				batchResults <- batchResult{keys: batch, responses: responses, err: err}
+			}(nodeID, batch)
+		}
+		
+		for i := 0; i < len(batches); i++ {
+			r := <-batchResults
+			for j, key := range r.keys {
+				if r.err == nil {
+					for _, pos := range positions[key] {
+						results[pos].Value = r.responses[j].Value
+						results[pos].Found = r.responses[j].Found
+					}
+					delete(remaining, key)
+					continue
+				}
+				
+				// Fall back to the next owner, if any
+				if owners := remaining[key][1:]; len(owners) > 0 {
+					remaining[key] = owners
+					continue
+				}
+				for _, pos := range positions[key] {
+					results[pos].Err = fmt.Errorf("failed to get key from any node: %w", r.err)
+					failed++
+				}
+				delete(remaining, key)
+			}
+		}
+	}
+	
+	if len(keys) > 0 && failed == len(keys) {
+		return results, fmt.Errorf("failed to get any key from any node")
+	}
+	
+	return results, nil
+}
+
+// batchGetFromNode reads several keys from a specific node, returning one response per key
+func (c *Client) batchGetFromNode(ctx context.Context, nodeID string, keys []string) ([]*proto.GetResponse, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	resp, err := proto.NewCacheServiceClient(conn).BatchGet(ctx, &proto.BatchGetRequest{Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(keys) {
+		return nil, fmt.Errorf("node %s returned %d results for %d keys", nodeID, len(resp.Results), len(keys))
+	}
+	for _, result := range resp.Results {
+		c.clock.observe(result.Version)
+	}
+	
+	return resp.Results, nil
+}
+
+// GetAndTouch reads several keys and resets the TTL of those present to ttl from now
+// on every owner that holds them, for sliding expiration; a ttl of zero removes their
+// expiry. Keys that are absent are reported as not found and are not created. One
+// GetAndTouch RPC is sent to each node that owns any of the keys. A key is found if
+// any of its owners holds it, and has Err set if none of its owners could be reached.
+// Writes still buffered by WriteBack are neither read nor touched.
+//
+// Results are returned in the order of keys; errors are returned as by MGet.
+func (c *Client) GetAndTouch(ctx context.Context, keys []string, ttl time.Duration) ([]MGetResult, error) {
+	ctx, span := c.startOperation(ctx, "GetAndTouch", attribute.Int("cache.keys", len(keys)))
+	defer span.End()
+	
+	if ttl < 0 {
+		return nil, fmt.Errorf("ttl must not be negative")
+	}
+	
+	results := make([]MGetResult, len(keys))
+	storedKeys := make([]string, len(keys))
+	positions := make(map[string][]int)
+	for i, key := range keys {
+		results[i].Key = key
+		
+		stored, err := c.storedKey(key)
+		if err != nil {
+			return nil, err
+		}
+		storedKeys[i] = stored
+		positions[stored] = append(positions[stored], i)
+	}
+	
+	owners := c.ring.OwnersBatch(storedKeys, c.replicaCount())
+	batches := make(map[string][]string) // Node ID to the stored keys it owns
+	for stored := range positions {
+		if len(owners[stored]) == 0 {
+			return nil, fmt.Errorf("no nodes available")
+		}
+		for _, owner := range owners[stored] {
+			batches[owner.ID] = append(batches[owner.ID], stored)
+		}
+	}
+	
+	type batchResult struct {
+		keys      []string
+		responses []*proto.GetResponse
+		err       error
+	}
+	
+	batchResults := make(chan batchResult, len(batches))
+	for nodeID, batch := range batches {
+		go func(nodeID string, batch []string) {
+			responses, err := c.touchOnNode(ctx, nodeID, batch, ttl)
+			batchResults <- batchResult{keys: batch, responses: responses, err: err}
+		}(nodeID, batch)
+	}
+	
+	reached := make(map[string]bool, len(positions))
+	lastErr := make(map[string]error)
+	for i := 0; i < len(batches); i++ {
+		r := <-batchResults
+		for j, key := range r.keys {
+			if r.err != nil {
+				lastErr[key] = r.err
+				continue
+			}
+			reached[key] = true
+			if r.responses[j].Found {
+				for _, pos := range positions[key] {
+					results[pos].Value = r.responses[j].Value
+					results[pos].Found = true
+				}
+			}
+		}
+	}
+	
+	failed := 0
+	for key, indexes := range positions {
+		if reached[key] {
+			continue
+		}
+		for _, pos := range indexes {
+			results[pos].Err = fmt.Errorf("failed to touch key on any node: %w", lastErr[key])
+			failed++
+		}
+	}
+	if len(keys) > 0 && failed == len(keys) {
+		return results, fmt.Errorf("failed to touch any key on any node")
+	}
+	
+	return results, nil
+}
+
+// touchOnNode reads and touches several keys on a specific node, returning one response per key
+func (c *Client) touchOnNode(ctx context.Context, nodeID string, keys []string, ttl time.Duration) ([]*proto.GetResponse, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	req := &proto.GetAndTouchRequest{Keys: keys}
+	if ttl > 0 {
+		req.Ttl = durationpb.New(ttl)
+	}
+	resp, err := proto.NewCacheServiceClient(conn).GetAndTouch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(keys) {
+		return nil, fmt.Errorf("node %s returned %d results for %d keys", nodeID, len(resp.Results), len(keys))
+	}
+	
+	return resp.Results, nil
+}
+
+// RangeGet returns every live entry whose key sorts within [startKey, endKey), in key
+// order. Keys are spread across the ring, so every node is asked with one RangeGet
+// RPC and the results are merged. Nodes keep no sorted index: each scans its whole
+// keyspace, making a call O(n) in the number of entries held rather than in the size
+// of the range. A key held by several owners is taken from the first of its owners in
+// ring order that returned it, as Get would read it.
+//
+// Keys are returned without the namespace prefix and versions are not reported. An
+// error is returned if any node fails, since the keys it holds could be missing.
+// Writes still buffered by WriteBack are not included.
+func (c *Client) RangeGet(ctx context.Context, startKey, endKey string) ([]Entry, error) {
+	ctx, span := c.startOperation(ctx, "RangeGet",
+		attribute.String("cache.start_key", startKey), attribute.String("cache.end_key", endKey))
+	defer span.End()
+	
+	start, err := c.storedKey(startKey)
+	if err != nil {
+		return nil, err
+	}
+	end, err := c.storedKey(endKey)
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("end key %q must sort after start key %q", endKey, startKey)
+	}
+	
+	nodes := c.ring.GetNodes()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	
+	type rangeResult struct {
+		nodeID  string
+		entries []*proto.Entry
+		err     error
+	}
+	
+	results := make(chan rangeResult, len(nodes))
+	for _, node := range nodes {
+		go func(nodeID string) {
+			entries, err := c.rangeFromNode(ctx, nodeID, start, end)
+			results <- rangeResult{nodeID: nodeID, entries: entries, err: err}
+		}(node.ID)
+	}
+	
+	copies := make(map[string]map[string]*proto.Entry) // Stored key to the entry held by each node
+	var rangeErr error
+	for i := 0; i < len(nodes); i++ {
+		r := <-results
+		if r.err != nil {
+			rangeErr = fmt.Errorf("failed to read range from node %s: %w", r.nodeID, r.err)
+			continue
+		}
+		for _, entry := range r.entries {
+			if copies[entry.Key] == nil {
+				copies[entry.Key] = make(map[string]*proto.Entry)
+			}
+			copies[entry.Key][r.nodeID] = entry
+		}
+	}
+	if rangeErr != nil {
+		return nil, rangeErr
+	}
+	
+	prefix := c.namespacedKey("")
+	merged := make([]Entry, 0, len(copies))
+	for key, held := range copies {
+		var chosen *proto.Entry
+		for _, owner := range c.readOwners(key, c.replicaCount()) {
+			if entry, exists := held[owner.ID]; exists {
+				chosen = entry
+				break
+			}
+		}
+		if chosen == nil {
+			// Only joining nodes, or nodes that no longer own the key, hold it
+			for _, entry := range held {
+				chosen = entry
+				break
+			}
+		}
+		
+		entry := Entry{Key: strings.TrimPrefix(key, prefix), Value: chosen.Value}
+		if chosen.Ttl != nil {
+			entry.TTL = chosen.Ttl.AsDuration()
+		}
+		merged = append(merged, entry)
+	}
+	
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Key < merged[j].Key
+	})
+	return merged, nil
+}
+
+// rangeFromNode reads the entries within a range of stored keys from a specific node
+func (c *Client) rangeFromNode(ctx context.Context, nodeID, startKey, endKey string) ([]*proto.Entry, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	resp, err := proto.NewCacheServiceClient(conn).RangeGet(ctx, &proto.RangeGetRequest{StartKey: startKey, EndKey: endKey})
+	if err != nil {
+		return nil, err
+	}
+	
+	return resp.Entries, nil
+}
+
+// ScanPrefix returns the value of every live key that starts with prefix, across the
+// whole cluster; an empty prefix returns every key in the namespace. This is an
+// expensive operation: every node is scanned with Scan RPCs a page at a time, each of
+// them scanning the node's whole keyspace, and the matching entries are all held in memory,
+// so it suits occasional listings rather than the request path. A key held by several
+// nodes is taken from the copy with the newest version, or on a tie from the first of
+// its owners in ring order, and left out if that copy is a tombstone.
+//
+// Keys are returned without the namespace prefix. An error is returned if any node
+// fails, since the keys it holds could be missing. Writes still buffered by WriteBack
+// are not included.
+func (c *Client) ScanPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
+	ctx, span := c.startOperation(ctx, "ScanPrefix", attribute.String("cache.prefix", prefix))
+	defer span.End()
+	
+	nodes := c.ring.GetNodes()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	
+	type scanResult struct {
+		nodeID  string
+		entries []*proto.Entry
+		err     error
+	}
+	
+	stored := c.namespacedKey(prefix)
+	results := make(chan scanResult, len(nodes))
+	for _, node := range nodes {
+		go func(nodeID string) {
+			entries, err := c.scanNode(ctx, nodeID, stored)
+			results <- scanResult{nodeID: nodeID, entries: entries, err: err}
+		}(node.ID)
+	}
+	
+	copies := make(map[string]map[string]*proto.Entry) // Stored key to the entry held by each node
+	var scanErr error
+	for i := 0; i < len(nodes); i++ {
+		r := <-results
+		if r.err != nil {
+			scanErr = fmt.Errorf("failed to scan node %s: %w", r.nodeID, r.err)
+			continue
+		}
+		for _, entry := range r.entries {
+			if copies[entry.Key] == nil {
+				copies[entry.Key] = make(map[string]*proto.Entry)
+			}
+			copies[entry.Key][r.nodeID] = entry
+		}
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	
+	namespace := c.namespacedKey("")
+	merged := make(map[string][]byte, len(copies))
+	for key, held := range copies {
+		var chosen *proto.Entry
+		// A tombstone beats a value of its version, as DeleteIf keeps the version deleted
+		consider := func(entry *proto.Entry) {
+			if chosen == nil || entry.Version > chosen.Version ||
+				entry.Version == chosen.Version && entry.Tombstone && !chosen.Tombstone {
+				chosen = entry
+			}
+		}
+		for _, owner := range c.readOwners(key, c.replicaCount()) {
+			if entry, exists := held[owner.ID]; exists {
+				consider(entry)
+			}
+		}
+		// Copies on nodes that no longer own the key win only if they are newer
+		for _, entry := range held {
+			consider(entry)
+		}
+		// A key deleted since a replica's copy was written is not listed
+		if !chosen.Tombstone {
+			merged[strings.TrimPrefix(key, namespace)] = chosen.Value
+		}
+	}
+	return merged, nil
+}
+
+// scanNode reads the entries under a prefix of stored keys from a specific node
+func (c *Client) scanNode(ctx context.Context, nodeID, prefix string) ([]*proto.Entry, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	var entries []*proto.Entry
+	req := &proto.ScanRequest{Prefix: prefix}
+	for {
+		resp, err := proto.NewCacheServiceClient(conn).Scan(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range resp.Entries {
+			c.clock.observe(entry.Version)
+		}
+		entries = append(entries, resp.Entries...)
+		if resp.Next == "" {
+			return entries, nil
+		}
+		req.After = resp.Next
+	}
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MigrateResult counts the entries moved by Migrate
+type MigrateResult struct {
+	Migrated int64 // Entries the destination applied
+	Skipped  int64 // Entries that had expired, or that the destination refused
+}
+
+// Migrate copies every live entry of one node to another, for moving a node's contents
+// to a replacement. Entries are streamed from the source with Dump and written to the
+// destination over a single SetStream as they arrive, so the whole dump is never held
+// in memory. Each keeps its version, so the destination refuses it if it already
+// holds a newer one, and its remaining TTL as of the dump, so it expires on the
+// destination when it would have on the source. Entries that expire on the source
+// while the dump runs are left out of it; any reported with no time left are skipped
+// rather than written without expiry. Keys are copied as stored, whatever their
+// namespace, and are left on the source. An error is returned with the counts so far
+// if either stream fails.
+func (c *Client) Migrate(ctx context.Context, fromNodeID, toNodeID string) (MigrateResult, error) {
+	ctx, span := c.startOperation(ctx, "Migrate",
+		attribute.String("cache.from_node", fromNodeID),
+		attribute.String("cache.to_node", toNodeID))
+	defer span.End()
+	
+	if fromNodeID == toNodeID {
+		return MigrateResult{}, fmt.Errorf("cannot migrate node %s to itself", fromNodeID)
+	}
+	sourceConn, err := c.getConnection(fromNodeID)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+	destConn, err := c.getConnection(toNodeID)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+	
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	
+	dump, err := proto.NewCacheServiceClient(sourceConn).Dump(ctx, &proto.DumpRequest{})
+	if err != nil {
+		return MigrateResult{}, fmt.Errorf("dump %s failed: %w", fromNodeID, err)
+	}
+	stream, err := proto.NewCacheServiceClient(destConn).SetStream(ctx)
+	if err != nil {
+		return MigrateResult{}, fmt.Errorf("failed to open stream to node %s: %w", toNodeID, err)
+	}
+	
+	var result MigrateResult
+	for {
+		entry, err := dump.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("dump %s failed: %w", fromNodeID, err)
+		}
+		
+		// A zero TTL would store the entry without expiry, so one that ran out is dropped
+		req := &proto.SetRequest{Key: entry.Key, Value: entry.Value, Version: entry.Version}
+		if entry.Ttl != nil {
+			if remaining := entry.Ttl.AsDuration(); remaining <= 0 {
+				result.Skipped++
+				continue
+			}
+			req.Ttl = entry.Ttl
+		}
+		if err := stream.Send(req); err != nil {
+			// Send fails with io.EOF once the destination has ended the stream; its
+			// status is the reason
+			if err == io.EOF {
+				if _, recvErr := stream.CloseAndRecv(); recvErr != nil {
+					err = recvErr
+				}
+			}
+			return result, fmt.Errorf("failed to send to node %s: %w", toNodeID, err)
+		}
+	}
+	
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		return result, fmt.Errorf("stream to node %s failed: %w", toNodeID, err)
+	}
+	result.Migrated = summary.Succeeded
+	result.Skipped += summary.Failed
+	
+	span.SetAttributes(
+		attribute.Int64("cache.migrated", result.Migrated),
+		attribute.Int64("cache.skipped", result.Skipped))
+	return result, nil
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RebalanceResult counts the work done by Rebalance
+type RebalanceResult struct {
+	Keys    int // Keys found on at least one node
+	Copied  int // Copies written to owners that lacked the newest value
+	Removed int // Copies deleted from nodes that no longer own their key
+	Failed  int // Keys left in place because an owner could not be written
+}
+
+// heldCopy is the state of a key on one node, as read by Rebalance
+type heldCopy struct {
+	value   []byte
+	ttl     time.Duration
+	version uint64
+}
+
+// Rebalance moves keys to the owners the ring now assigns them, so that after nodes
+// join or leave the data follows without waiting for reads and writes to move it.
+// keys lists the keys to move; nil moves every key held by any node, found with Dump
+// and limited to the client's namespace. Every node is asked for the keys, and the
+// newest copy found is written, at its version and remaining TTL, to each current
+// owner that does not already hold it. Once every owner holds the key it is deleted
+// from the nodes that no longer own it; a key that could not be written to some
+// owner is left in place and counted as failed, so that no copy is lost.
+//
+// Tombstones and writes still buffered by WriteBack are not moved. An error is
+// returned, before anything is moved, if any node cannot be read, and after the
+// moves if any key failed.
+func (c *Client) Rebalance(ctx context.Context, keys []string) (RebalanceResult, error) {
+	ctx, span := c.startOperation(ctx, "Rebalance", attribute.Int("cache.keys", len(keys)))
+	defer span.End()
+	
+	stored := make([]string, len(keys))
+	for i, key := range keys {
+		var err error
+		if stored[i], err = c.storedKey(key); err != nil {
+			return RebalanceResult{}, err
+		}
+	}
+	
+	nodes := c.ring.GetNodes()
+	if len(nodes) == 0 {
+		return RebalanceResult{}, fmt.Errorf("no nodes available")
+	}
+	
+	type holdings struct {
+		nodeID string
+		copies map[string]heldCopy
+		err    error
+	}
+	
+	results := make(chan holdings, len(nodes))
+	for _, node := range nodes {
+		go func(nodeID string) {
+			var copies map[string]heldCopy
+			var err error
+			if keys == nil {
+				copies, err = c.dumpHoldings(ctx, nodeID)
+			} else {
+				copies, err = c.keyHoldings(ctx, nodeID, stored)
+			}
+			results <- holdings{nodeID: nodeID, copies: copies, err: err}
+		}(node.ID)
+	}
+	
+	held := make(map[string]map[string]heldCopy) // Stored key to the copy on each node
+	var readErr error
+	for i := 0; i < len(nodes); i++ {
+		r := <-results
+		if r.err != nil {
+			readErr = fmt.Errorf("failed to read keys from node %s: %w", r.nodeID, r.err)
+			continue
+		}
+		for key, state := range r.copies {
+			if held[key] == nil {
+				held[key] = make(map[string]heldCopy)
+			}
+			held[key][r.nodeID] = state
+		}
+	}
+	if readErr != nil {
+		return RebalanceResult{}, readErr
+	}
+	
+	result := RebalanceResult{Keys: len(held)}
+	var lastErr error
+	for key, copies := range held {
+		copied, removed, err := c.rebalanceKey(ctx, key, copies)
+		result.Copied += copied
+		result.Removed += removed
+		if err != nil {
+			result.Failed++
+			lastErr = err
+		}
+	}
+	
+	if lastErr != nil {
+		return result, fmt.Errorf("failed to rebalance %d of %d keys: %w", result.Failed, result.Keys, lastErr)
+	}
+	return result, nil
+}
+
+// rebalanceKey writes the newest copy of a key to every owner lacking it, then deletes
+// it from the nodes that are not owners, returning the copies written and deleted. A
+// node's copy is only deleted if it still holds the version read from it, so that a
+// write it received since is kept.
+func (c *Client) rebalanceKey(ctx context.Context, key string, copies map[string]heldCopy) (int, int, error) {
+	owners := c.ring.Owners(key, c.replicaCount())
+	isOwner := make(map[string]bool, len(owners))
+	
+	// Take the newest copy, preferring one on an owner among copies of the same version
+	var newest heldCopy
+	found := false
+	for _, owner := range owners {
+		isOwner[owner.ID] = true
+		if state, exists := copies[owner.ID]; exists && (!found || state.version > newest.version) {
+			newest, found = state, true
+		}
+	}
+	for _, state := range copies {
+		if !found || state.version > newest.version {
+			newest, found = state, true
+		}
+	}
+	
+	copied := 0
+	for _, owner := range owners {
+		if state, exists := copies[owner.ID]; exists && state.version == newest.version {
+			continue
+		}
+		if err := c.setToNode(ctx, owner.ID, key, newest.value, newest.ttl, newest.version); err != nil {
+			return copied, 0, fmt.Errorf("failed to copy %q to node %s: %w", key, owner.ID, err)
+		}
+		copied++
+	}
+	
+	removed := 0
+	for nodeID := range copies {
+		if isOwner[nodeID] {
+			continue
+		}
+		err := c.deleteIfOnNode(ctx, nodeID, key, copies[nodeID].version)
+		if errors.Is(err, ErrVersionMismatch) {
+			continue
+		}
+		if err != nil {
+			return copied, removed, fmt.Errorf("failed to remove %q from node %s: %w", key, nodeID, err)
+		}
+		removed++
+	}
+	
+	return copied, removed, nil
+}
+
+// dumpHoldings reads every live entry in the client's namespace from a node. It reads
+// the Dump stream itself rather than through Client.Dump, so that a dump failing part
+// way through is an error rather than a short result.
+func (c *Client) dumpHoldings(ctx context.Context, nodeID string) (map[string]heldCopy, error) {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	
+	stream, err := proto.NewCacheServiceClient(conn).Dump(ctx, &proto.DumpRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("dump %s failed: %w", nodeID, err)
+	}
+	
+	prefix := c.namespacedKey("")
+	copies := make(map[string]heldCopy)
+	for {
+		item, err := stream.Recv()
+		if err == io.EOF {
+			return copies, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dump %s failed: %w", nodeID, err)
+		}
+		if !strings.HasPrefix(item.Key, prefix) {
+			continue
+		}
+		
+		held := heldCopy{value: item.Value, version: item.Version}
+		if item.Ttl != nil {
+			held.ttl = item.Ttl.AsDuration()
+		}
+		copies[item.Key] = held
+	}
+}
+
+// keyHoldings reads the live entries among keys from a node with one BatchGet
+func (c *Client) keyHoldings(ctx context.Context, nodeID string, keys []string) (map[string]heldCopy, error) {
+	copies := make(map[string]heldCopy)
+	if len(keys) == 0 {
+		return copies, nil
+	}
+	
+	responses, err := c.batchGetFromNode(ctx, nodeID, keys)
+	if err != nil {
+		return nil, err
+	}
+	
+	now := time.Now()
+	for i, resp := range responses {
+		if !resp.Found {
+			continue
+		}
+		state := heldCopy{value: resp.Value, version: resp.Version}
+		if resp.ExpiresAt != nil {
+			if state.ttl = resp.ExpiresAt.AsTime().Sub(now); state.ttl <= 0 {
+				continue
+			}
+		}
+		copies[keys[i]] = state
+	}
+	
+	return copies, nil
+}
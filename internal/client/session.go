@@ -0,0 +1,169 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrSessionBehind is returned by session reads when every owner that answered holds
+// an older version of the key than the session last wrote
+var ErrSessionBehind = errors.New("no replica has caught up with the session's write")
+
+// maxSessionTokens bounds the keys a session remembers its writes of. Beyond it the
+// tokens of the keys written longest ago are dropped, and those keys are read as usual.
+const maxSessionTokens = 10000
+
+// Session gives a sequence of operations read-your-writes consistency: a Get through
+// the session never returns a value older than the session's last Set of the key,
+// whichever replica serves it. It is safe for concurrent use.
+type Session struct {
+	client *Client
+	
+	mu     sync.Mutex
+	tokens map[string]*list.Element // Of *sessionToken, by key
+	order  *list.List               // Tokens, the key written longest ago first
+}
+
+// sessionToken is the last version a session wrote of a key
+type sessionToken struct {
+	key       string
+	version   uint64
+	expiresAt time.Time // Zero if the write does not expire
+}
+
+// NewSession starts a session with no writes
+func (c *Client) NewSession() *Session {
+	return &Session{client: c, tokens: make(map[string]*list.Element), order: list.New()}
+}
+
+// Set writes a value as Client.Set does and returns the version token it was written
+// with. The session remembers the token so that its later reads of the key see this
+// write; it can also be passed to GetAtLeast by other clients.
+func (s *Session) Set(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, error) {
+	ctx, span := s.client.startOperation(ctx, "SessionSet", attribute.String("cache.key", key))
+	defer span.End()
+	
+	version, err := s.client.set(ctx, key, value, ttl)
+	if err != nil {
+		return 0, err
+	}
+	
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, exists := s.tokens[key]; exists {
+		token := elem.Value.(*sessionToken)
+		if version > token.version {
+			token.version, token.expiresAt = version, expiresAt
+			s.order.MoveToBack(elem)
+		}
+		return version, nil
+	}
+	s.tokens[key] = s.order.PushBack(&sessionToken{key: key, version: version, expiresAt: expiresAt})
+	for s.order.Len() > maxSessionTokens {
+		oldest := s.order.Front()
+		s.order.Remove(oldest)
+		delete(s.tokens, oldest.Value.(*sessionToken).key)
+	}
+	return version, nil
+}
+
+// Token returns the version of the session's last write of key, or zero if it wrote
+// none, or the write has expired, or its token was dropped to make room for others
+func (s *Session) Token(key string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	elem, exists := s.tokens[key]
+	if !exists {
+		return 0
+	}
+	token := elem.Value.(*sessionToken)
+	if !token.expiresAt.IsZero() && time.Now().After(token.expiresAt) {
+		s.order.Remove(elem)
+		delete(s.tokens, key)
+		return 0
+	}
+	return token.version
+}
+
+// Get reads a key as GetAtLeast does with the session's token for it. Keys the session
+// has not written are read with a plain Get.
+func (s *Session) Get(ctx context.Context, key string) ([]byte, error) {
+	token := s.Token(key)
+	if token == 0 {
+		return s.client.Get(ctx, key)
+	}
+	return s.client.GetAtLeast(ctx, key, token)
+}
+
+// GetAtLeast reads a key from the first owner holding the version token or a newer
+// version, so that a value written with that token is never read back older. Owners
+// that are behind are passed over for the next one, in health order, until one has
+// caught up; since the write reached a quorum of owners, one of those normally has.
+// A delete newer than the token is a miss, and so is the key missing from every owner,
+// as it is once the write has expired or been evicted. If no owner answering has caught
+// up, it returns ErrSessionBehind rather than an older value. The client cache is not
+// used.
+func (c *Client) GetAtLeast(ctx context.Context, key string, token uint64) ([]byte, error) {
+	ctx, span := c.startOperation(ctx, "GetAtLeast", attribute.String("cache.key", key))
+	defer span.End()
+	
+	key, err := c.storedKey(key)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Buffered writes are this client's newest
+	if c.writeBack != nil {
+		if value, found := c.writeBack.get(key); found {
+			return value, nil
+		}
+	}
+	
+	owners := c.byHealth(c.readOwners(key, c.replicaCount()))
+	if len(owners) == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	
+	behind := false
+	absent := 0
+	lastErr := fmt.Errorf("failed to get key from any node")
+	for _, owner := range owners {
+		resp, err := c.readFromNode(ctx, owner.ID, key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.Version < token {
+			// An owner lacking the key may just not have received the write yet
+			if !resp.Found && !resp.Tombstone {
+				absent++
+			}
+			behind = true
+			continue
+		}
+		if !resp.Found {
+			return nil, ErrKeyNotFound
+		}
+		return resp.Value, nil
+	}
+	
+	if absent == len(owners) {
+		return nil, ErrKeyNotFound
+	}
+	if behind {
+		return nil, ErrSessionBehind
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// SetSummary counts the writes of a Stream that the nodes applied and refused. A
+// write is counted once for every owner it was sent to.
+type SetSummary struct {
+	Succeeded int64
+	Failed    int64
+}
+
+// Stream sends writes to their owners over one SetStream RPC per node, opened when
+// the first write for that node is sent, avoiding a round trip per write. Nodes
+// report only totals, so writes are not acknowledged individually: CloseAndRecv
+// returns the summary once every stream has finished. A Stream is not safe for
+// concurrent use, and CloseAndRecv must be called to release its streams.
+type Stream struct {
+	client  *Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	span    trace.Span
+	streams map[string]proto.CacheService_SetStreamClient
+}
+
+// SetStream starts a bulk write to the cluster, for warming the cache from a data
+// pipeline. Writes go straight to the nodes, bypassing WriteBack, and each is given
+// a version as Set would give it. Canceling ctx aborts every node's stream.
+func (c *Client) SetStream(ctx context.Context) (*Stream, error) {
+	if c.ring.NodeCount() == 0 {
+		return nil, fmt.Errorf("no nodes available")
+	}
+	
+	ctx, span := c.startOperation(ctx, "SetStream")
+	ctx, cancel := context.WithCancel(ctx)
+	return &Stream{
+		client:  c,
+		ctx:     ctx,
+		cancel:  cancel,
+		span:    span,
+		streams: make(map[string]proto.CacheService_SetStreamClient),
+	}, nil
+}
+
+// Send queues a write of value under key to every owner of the key. An error means
+// the write could not be sent to some owner; the nodes' own verdicts are only known
+// from CloseAndRecv.
+func (s *Stream) Send(key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
+	
+	c := s.client
+	key, err := c.storedKey(key)
+	if err != nil {
+		return err
+	}
+	c.forgetFlights(key)
+	if c.readCache != nil {
+		c.invalidateRead(key)
+	}
+	
+	owners := c.ring.Owners(key, c.replicaCount())
+	if len(owners) == 0 {
+		return fmt.Errorf("no nodes available")
+	}
+	
+	req := &proto.SetRequest{Key: key, Value: value, Version: c.nextVersion()}
+	if ttl > 0 {
+		req.Ttl = durationpb.New(ttl)
+	}
+	for _, owner := range owners {
+		stream, err := s.streamTo(owner.ID)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("failed to send to node %s: %w", owner.ID, err)
+		}
+	}
+	
+	return nil
+}
+
+// streamTo returns the stream to a node, opening it on first use
+func (s *Stream) streamTo(nodeID string) (proto.CacheService_SetStreamClient, error) {
+	if stream, exists := s.streams[nodeID]; exists {
+		return stream, nil
+	}
+	
+	conn, err := s.client.getConnection(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := proto.NewCacheServiceClient(conn).SetStream(s.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream to node %s: %w", nodeID, err)
+	}
+	
+	s.streams[nodeID] = stream
+	return stream, nil
+}
+
+// CloseAndRecv finishes every node's stream and adds up their summaries. If a node's
+// stream failed, its writes are missing from the summary and an error is returned
+// along with the totals of the other nodes.
+func (s *Stream) CloseAndRecv() (SetSummary, error) {
+	defer s.span.End()
+	defer s.cancel()
+	
+	var summary SetSummary
+	var closeErr error
+	for nodeID, stream := range s.streams {
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			closeErr = fmt.Errorf("stream to node %s failed: %w", nodeID, err)
+			continue
+		}
+		summary.Succeeded += resp.Succeeded
+		summary.Failed += resp.Failed
+	}
+	
+	return summary, closeErr
+}
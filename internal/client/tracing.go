@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the gRPC metadata key carrying the request ID; servers log it
+// and echo it back in the response trailer
+const RequestIDHeader = "x-request-id"
+
+// tracerName identifies spans created by the client
+const tracerName = "github.com/shard-cache/internal/client"
+
+// tracePropagator carries span context to the servers in gRPC metadata
+var tracePropagator = propagation.TraceContext{}
+
+// WithRequestID returns a context whose calls carry the given request ID instead of a generated one
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, requestID)
+}
+
+// ensureRequestID attaches a new request ID to the context unless it already has one, so
+// that every RPC in an operation's fan-out shares the same ID
+func ensureRequestID(ctx context.Context) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(RequestIDHeader)) > 0 {
+		return ctx
+	}
+	return WithRequestID(ctx, newRequestID())
+}
+
+// newRequestID returns a random 128-bit hex request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startOperation prepares the context for a client operation, attaching a request ID
+// and, when tracing is enabled, starting a span that parents the operation's RPCs
+func (c *Client) startOperation(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = ensureRequestID(ctx)
+	if c.tracer == nil {
+		return ctx, noop.Span{}
+	}
+	return c.tracer.Start(ctx, "cache.client."+operation, trace.WithAttributes(attrs...))
+}
+
+// unaryInterceptor attaches the request ID to every RPC to one node and, when tracing
+// is enabled, wraps it in a client span naming the node, whose context is propagated
+// to the server. The span is a child of the operation's span, so each RPC of a quorum
+// fan-out shows up as a sibling under the operation.
+func (c *Client) unaryInterceptor(nodeID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = ensureRequestID(ctx)
+		if c.tracer == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		
+		ctx, span := c.tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("net.peer.name", cc.Target()),
+				attribute.String("cache.node", nodeID)))
+		defer span.End()
+		
+		err := invoker(injectSpanContext(ctx), method, req, reply, cc, opts...)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		return err
+	}
+}
+
+// streamInterceptor attaches the request ID to streaming RPCs
+func (c *Client) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = ensureRequestID(ctx)
+	if c.tracer != nil {
+		ctx = injectSpanContext(ctx)
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// injectSpanContext writes the current span context into the outgoing metadata
+func injectSpanContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tracePropagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// metadataCarrier adapts gRPC metadata to the OpenTelemetry propagation API
+type metadataCarrier metadata.MD
+
+// Get returns the first value for a key
+func (m metadataCarrier) Get(key string) string {
+	if values := metadata.MD(m).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values for a key
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+// Keys lists the keys present in the metadata
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,327 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shard-cache/proto"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// defaultWriteBackInterval is how often buffered writes are flushed when no interval is configured
+	defaultWriteBackInterval = 100 * time.Millisecond
+
+	// defaultWriteBackBufferSize is how many distinct keys may be buffered before a flush is triggered
+	defaultWriteBackBufferSize = 1000
+
+	// writeBackFlushTimeout bounds a background flush and the final flush made by Close
+	writeBackFlushTimeout = 5 * time.Second
+
+	// writeBackBatchSize is the most writes a flush sends a node in one SetBatch call
+	writeBackBatchSize = 500
+
+	// writeBackMaxAttempts is how many flushes a write may miss quorum in before it is dropped
+	writeBackMaxAttempts = 5
+)
+
+// pendingWrite is a buffered Set waiting to be flushed
+type pendingWrite struct {
+	value     []byte
+	expiresAt time.Time // Zero if the value never expires
+	version   uint64
+	attempts  int // Flushes that failed to deliver it
+}
+
+// writeBuffer holds writes made in write-back mode until they are flushed. Repeated
+// writes to a key replace each other, so only the newest is sent.
+type writeBuffer struct {
+	mu         sync.Mutex
+	pending    map[string]pendingWrite
+	flushing   map[string]pendingWrite // Taken by the flush in progress
+	maxSize    int
+	interval   time.Duration
+	flushMutex sync.Mutex // Serializes flushes so older batches never overtake newer ones
+	dropped    uint64     // Writes given up after writeBackMaxAttempts failed flushes
+	full       chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	done       chan struct{}
+}
+
+// newWriteBuffer creates an empty write buffer; the flusher is started by the client
+func newWriteBuffer(interval time.Duration, maxSize int) *writeBuffer {
+	if interval <= 0 {
+		interval = defaultWriteBackInterval
+	}
+	if maxSize <= 0 {
+		maxSize = defaultWriteBackBufferSize
+	}
+	return &writeBuffer{
+		pending:  make(map[string]pendingWrite),
+		maxSize:  maxSize,
+		interval: interval,
+		full:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// add buffers a write, replacing any pending write to the same key, and wakes the
+// flusher once the buffer is full
+func (b *writeBuffer) add(key string, write pendingWrite) {
+	b.mu.Lock()
+	b.pending[key] = write
+	full := len(b.pending) >= b.maxSize
+	b.mu.Unlock()
+	
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// finish ends a flush, putting back the writes that failed unless the key was written
+// again or deleted since, or the write has now failed writeBackMaxAttempts times. It
+// returns the number of writes dropped.
+func (b *writeBuffer) finish(failed map[string]pendingWrite) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	dropped := 0
+	for key, write := range failed {
+		if _, flushing := b.flushing[key]; !flushing {
+			continue
+		}
+		if _, exists := b.pending[key]; exists {
+			continue
+		}
+		write.attempts++
+		if write.attempts >= writeBackMaxAttempts {
+			dropped++
+			continue
+		}
+		b.pending[key] = write
+	}
+	b.flushing = nil
+	b.dropped += uint64(dropped)
+	return dropped
+}
+
+// get returns the pending value for a key, if it is buffered and has not expired
+func (b *writeBuffer) get(key string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	write, exists := b.pending[key]
+	if !exists {
+		write, exists = b.flushing[key]
+	}
+	if !exists || (!write.expiresAt.IsZero() && time.Now().After(write.expiresAt)) {
+		return nil, false
+	}
+	return write.value, true
+}
+
+// remove drops a pending write, including one being flushed
+func (b *writeBuffer) remove(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, key)
+	delete(b.flushing, key)
+}
+
+// take starts a flush, moving every pending write aside and returning a copy of them.
+// The caller must call finish once the flush is done.
+func (b *writeBuffer) take() map[string]pendingWrite {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	b.flushing = b.pending
+	b.pending = make(map[string]pendingWrite)
+	
+	writes := make(map[string]pendingWrite, len(b.flushing))
+	for key, write := range b.flushing {
+		writes[key] = write
+	}
+	return writes
+}
+
+// size returns the number of buffered keys, including those being flushed
+func (b *writeBuffer) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending) + len(b.flushing)
+}
+
+// droppedWrites returns the number of writes given up after failing to flush
+func (b *writeBuffer) droppedWrites() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// stopFlusher stops the background flusher and waits for it to exit
+func (b *writeBuffer) stopFlusher() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+}
+
+// pendingWrites returns the number of writes waiting to be flushed
+func (c *Client) pendingWrites() int {
+	if c.writeBack == nil {
+		return 0
+	}
+	return c.writeBack.size()
+}
+
+// droppedWrites returns the number of buffered writes given up after failed flushes
+func (c *Client) droppedWrites() uint64 {
+	if c.writeBack == nil {
+		return 0
+	}
+	return c.writeBack.droppedWrites()
+}
+
+// runFlusher flushes the write buffer every interval and whenever it fills up,
+// until the buffer is stopped
+func (c *Client) runFlusher() {
+	defer close(c.writeBack.done)
+	
+	ticker := time.NewTicker(c.writeBack.interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-c.writeBack.stop:
+			return
+		case <-ticker.C:
+		case <-c.writeBack.full:
+		}
+		
+		ctx, cancel := context.WithTimeout(context.Background(), writeBackFlushTimeout)
+		if err := c.Flush(ctx); err != nil {
+			c.logger.Warn("Write-back flush failed", zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// Flush sends every buffered write to its owners, batching the writes for each node
+// into SetBatch calls of up to writeBackBatchSize writes. Writes that do not reach a
+// quorum of owners stay buffered for the next flush, unless they have now failed
+// writeBackMaxAttempts flushes, when they are dropped and logged. Flush is a no-op
+// unless WriteBack is enabled.
+func (c *Client) Flush(ctx context.Context) error {
+	if c.writeBack == nil {
+		return nil
+	}
+	
+	c.writeBack.flushMutex.Lock()
+	defer c.writeBack.flushMutex.Unlock()
+	
+	writes := c.writeBack.take()
+	if len(writes) == 0 {
+		c.writeBack.finish(nil)
+		return nil
+	}
+	
+	keys := make([]string, 0, len(writes))
+	for key := range writes {
+		keys = append(keys, key)
+	}
+	owners := c.ring.OwnersBatch(keys, c.replicaCount())
+	
+	// Group the writes by owning node, dropping any that expired while buffered
+	now := time.Now()
+	batches := make(map[string][]*proto.SetRequest)
+	owned := make(map[string][]string)
+	for key, write := range writes {
+		var ttl time.Duration
+		if !write.expiresAt.IsZero() {
+			ttl = write.expiresAt.Sub(now)
+			if ttl <= 0 {
+				delete(writes, key)
+				continue
+			}
+		}
+		
+		item := &proto.SetRequest{Key: key, Value: write.value, Version: write.version}
+		if ttl > 0 {
+			item.Ttl = durationpb.New(ttl)
+		}
+		for _, owner := range owners[key] {
+			batches[owner.ID] = append(batches[owner.ID], item)
+			owned[owner.ID] = append(owned[owner.ID], key)
+		}
+	}
+	
+	type batchResult struct {
+		nodeID string
+		keys   []string
+		err    error
+	}
+	
+	// Each node is sent its batches in turn, and the nodes in parallel
+	results := make(chan batchResult)
+	for nodeID, items := range batches {
+		go func(nodeID string, items []*proto.SetRequest) {
+			keys := owned[nodeID]
+			for start := 0; start < len(items); start += writeBackBatchSize {
+				end := min(start+writeBackBatchSize, len(items))
+				err := c.setBatchToNode(ctx, nodeID, items[start:end])
+				results <- batchResult{nodeID: nodeID, keys: keys[start:end], err: err}
+			}
+		}(nodeID, items)
+	}
+	
+	sent := 0
+	for _, items := range batches {
+		sent += (len(items) + writeBackBatchSize - 1) / writeBackBatchSize
+	}
+	acks := make(map[string]int, len(writes))
+	for i := 0; i < sent; i++ {
+		r := <-results
+		if r.err != nil {
+			c.logger.Warn("Write-back batch failed", zap.String("node", r.nodeID), zap.Int("writes", len(r.keys)), zap.Error(r.err))
+			continue
+		}
+		for _, key := range r.keys {
+			acks[key]++
+		}
+	}
+	
+	// Keep writes that missed quorum for the next flush
+	required := c.requiredWrites(ctx)
+	for key := range writes {
+		if acks[key] >= required {
+			delete(writes, key)
+		}
+	}
+	if dropped := c.writeBack.finish(writes); dropped > 0 {
+		c.logger.Error("Dropped buffered writes that repeatedly failed to flush", zap.Int("writes", dropped), zap.Int("attempts", writeBackMaxAttempts))
+	}
+	if len(writes) > 0 {
+		return fmt.Errorf("failed to flush %d buffered writes to a quorum of nodes", len(writes))
+	}
+	
+	return nil
+}
+
+// setBatchToNode sends a batch of writes to a specific node
+func (c *Client) setBatchToNode(ctx context.Context, nodeID string, items []*proto.SetRequest) error {
+	conn, err := c.getConnection(nodeID)
+	if err != nil {
+		return err
+	}
+	
+	_, err = proto.NewCacheServiceClient(conn).SetBatch(ctx, &proto.SetBatchRequest{Items: items})
+	return err
+}
@@ -3,41 +3,314 @@ package ring
 import (
 	"crypto/md5"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Node represents a cache node in the ring
 type Node struct {
-	ID   string
-	Addr string
+	ID     string
+	Addr   string
+	Status NodeStatus
 }
 
-// Ring implements consistent hashing using rendezvous hashing
+// NodeStatus is a node's stage in the membership lifecycle. Every node keeps its
+// place in the hashing whatever its status; callers use it to decide which owners
+// of a key to read from or write to.
+type NodeStatus int
+
+const (
+	// NodeActive nodes serve reads and writes; nodes are active when added
+	NodeActive NodeStatus = iota
+
+	// NodeJoining nodes are written to so that they fill up, but are not read from
+	// until they are made active, since they may not hold keys written before they joined
+	NodeJoining
+
+	// NodeLeaving nodes still serve reads and writes while they are drained ahead of
+	// their removal, but callers may prefer other owners
+	NodeLeaving
+)
+
+// String returns the status's name
+func (s NodeStatus) String() string {
+	switch s {
+	case NodeActive:
+		return "active"
+	case NodeJoining:
+		return "joining"
+	case NodeLeaving:
+		return "leaving"
+	default:
+		return fmt.Sprintf("NodeStatus(%d)", int(s))
+	}
+}
+
+// HashMode selects how the ring maps keys to nodes
+type HashMode int
+
+const (
+	// HashModeRendezvous scores every node per key. Lookups are O(N) but any
+	// node can join or leave while only moving the keys it owns.
+	HashModeRendezvous HashMode = iota
+
+	// HashModeJump uses jump consistent hashing over the nodes in the order
+	// they were added. Lookups are O(1) but the ordering must be stable: all
+	// clients have to add nodes in the same order, and only removing the most
+	// recently added node moves the minimum number of keys. Removing any other
+	// node shifts every node after it and remaps their keys.
+	HashModeJump
+)
+
+// Option configures a Ring
+type Option func(*Ring)
+
+// WithHashMode selects the hashing mode used by Owners
+func WithHashMode(mode HashMode) Option {
+	return func(r *Ring) {
+		r.mode = mode
+	}
+}
+
+// WithTiming makes Owners and OwnersBatch record how long each lookup takes, for OwnersTiming
+func WithTiming() Option {
+	return func(r *Ring) {
+		r.timing = true
+	}
+}
+
+// ChangeListener is notified with the nodes added to and removed from the ring.
+// A node whose address changes is reported as removed and then added.
+type ChangeListener func(added, removed []*Node)
+
+// Ring implements consistent hashing using rendezvous or jump hashing
 type Ring struct {
-	mu    sync.RWMutex
-	nodes map[string]*Node
+	mu        sync.RWMutex
+	nodes     map[string]*Node
+	order     []*Node
+	mode      HashMode
+	listeners []ChangeListener
+	timing    bool
+	lookups   uint64 // Owners calls timed since the ring was created
+	lookupNs  uint64 // Total time spent in those calls
 }
 
-// NewRing creates a new ring
-func NewRing() *Ring {
-	return &Ring{
+// NewRing creates a new ring, using rendezvous hashing unless configured otherwise
+func NewRing(opts ...Option) *Ring {
+	r := &Ring{
 		nodes: make(map[string]*Node),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-// AddNode adds a node to the ring
-func (r *Ring) AddNode(id, addr string) {
+// OnChange registers a listener that is called after every change to the ring's
+// membership. Listeners run synchronously on the goroutine that changed the ring,
+// outside the ring's lock, so they may call back into the ring.
+func (r *Ring) OnChange(listener ChangeListener) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.nodes[id] = &Node{ID: id, Addr: addr}
+	r.listeners = append(r.listeners, listener)
+}
+
+// AddNode adds a node to the ring. Re-adding an existing node updates its
+// address without changing its position in the jump hash ordering. Nodes without an
+// ID or address are rejected.
+func (r *Ring) AddNode(id, addr string) error {
+	if id == "" {
+		return fmt.Errorf("node ID must not be empty")
+	}
+	if addr == "" {
+		return fmt.Errorf("address of node %s must not be empty", id)
+	}
+	
+	r.mu.Lock()
+	
+	node := &Node{ID: id, Addr: addr}
+	var removed []*Node
+	if old, exists := r.nodes[id]; exists {
+		if old.Addr == addr {
+			r.mu.Unlock()
+			return nil
+		}
+		node.Status = old.Status
+		removed = []*Node{old}
+		for i := range r.order {
+			if r.order[i].ID == id {
+				r.order[i] = node
+				break
+			}
+		}
+	} else {
+		r.order = append(r.order, node)
+	}
+	r.nodes[id] = node
+	listeners := r.listeners
+	r.mu.Unlock()
+	
+	notify(listeners, []*Node{node}, removed)
+	return nil
 }
 
 // RemoveNode removes a node from the ring
 func (r *Ring) RemoveNode(id string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	
+	old, exists := r.nodes[id]
+	if !exists {
+		r.mu.Unlock()
+		return
+	}
 	delete(r.nodes, id)
+	for i, node := range r.order {
+		if node.ID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	listeners := r.listeners
+	r.mu.Unlock()
+	
+	notify(listeners, nil, []*Node{old})
+}
+
+// SetNodes replaces the whole membership of the ring with nodes in one change, so that
+// lookups never see a partly reconfigured ring, and reports the difference to change
+// listeners as a single call. Nodes are copied, with the status they are given. Nodes
+// that stay keep their position in the jump hash ordering and new ones follow in the
+// order given. A node whose ID appears more than once is taken from its last entry.
+// As with AddNode, nodes without an ID or address are rejected, and then the ring is
+// left unchanged.
+func (r *Ring) SetNodes(nodes []*Node) error {
+	for _, node := range nodes {
+		if node.ID == "" {
+			return fmt.Errorf("node ID must not be empty")
+		}
+		if node.Addr == "" {
+			return fmt.Errorf("address of node %s must not be empty", node.ID)
+		}
+	}
+	
+	next := make(map[string]*Node, len(nodes))
+	var ids []string // In the order first given
+	for _, node := range nodes {
+		if _, seen := next[node.ID]; !seen {
+			ids = append(ids, node.ID)
+		}
+		copied := *node
+		next[node.ID] = &copied
+	}
+	
+	r.mu.Lock()
+	
+	var added, removed []*Node
+	order := make([]*Node, 0, len(next))
+	for _, old := range r.order {
+		node, stays := next[old.ID]
+		if !stays {
+			removed = append(removed, old)
+			continue
+		}
+		if node.Addr != old.Addr {
+			removed = append(removed, old)
+			added = append(added, node)
+		}
+		order = append(order, node)
+	}
+	for _, id := range ids {
+		if _, exists := r.nodes[id]; !exists {
+			added = append(added, next[id])
+			order = append(order, next[id])
+		}
+	}
+	r.nodes = next
+	r.order = order
+	listeners := r.listeners
+	r.mu.Unlock()
+	
+	if len(added) > 0 || len(removed) > 0 {
+		notify(listeners, added, removed)
+	}
+	return nil
+}
+
+// SetNodeStatus changes a node's status, reporting whether the node is in the ring.
+// Status changes do not move keys and are not reported to change listeners.
+func (r *Ring) SetNodeStatus(id string, status NodeStatus) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	old, exists := r.nodes[id]
+	if !exists {
+		return false
+	}
+	
+	// Replace the node rather than modifying it, since callers may hold the old one
+	node := *old
+	node.Status = status
+	r.nodes[id] = &node
+	for i := range r.order {
+		if r.order[i].ID == id {
+			r.order[i] = &node
+			break
+		}
+	}
+	return true
+}
+
+// Validate checks the ring's invariants: every node has an ID, an address and a known
+// status, no ID appears twice, and the jump hash ordering holds exactly the nodes in
+// the ring. It returns every violation found, joined, or nil for a well-formed ring.
+func (r *Ring) Validate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	var errs []error
+	seen := make(map[string]bool, len(r.order))
+	for i, node := range r.order {
+		switch {
+		case node.ID == "":
+			errs = append(errs, fmt.Errorf("node %d in the ordering has no ID", i))
+		case seen[node.ID]:
+			// The first occurrence has been checked already
+			errs = append(errs, fmt.Errorf("node %s appears more than once in the ordering", node.ID))
+			continue
+		case r.nodes[node.ID] != node:
+			errs = append(errs, fmt.Errorf("node %s in the ordering does not match the ring's membership", node.ID))
+		}
+		seen[node.ID] = true
+		
+		if node.Addr == "" {
+			errs = append(errs, fmt.Errorf("node %q has no address", node.ID))
+		}
+		if node.Status < NodeActive || node.Status > NodeLeaving {
+			errs = append(errs, fmt.Errorf("node %q has unknown status %v", node.ID, node.Status))
+		}
+	}
+	for id := range r.nodes {
+		if !seen[id] {
+			errs = append(errs, fmt.Errorf("node %s is missing from the ordering", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notify calls each listener with a membership change
+func notify(listeners []ChangeListener, added, removed []*Node) {
+	for _, listener := range listeners {
+		listener(added, removed)
+	}
+}
+
+// Mode returns the hashing mode used by the ring
+func (r *Ring) Mode() HashMode {
+	return r.mode
 }
 
 // GetNodes returns all nodes in the ring
@@ -52,12 +325,47 @@ func (r *Ring) GetNodes() []*Node {
 	return nodes
 }
 
-// Owners returns the top N nodes responsible for a key using rendezvous hashing
-func (r *Ring) Owners(key string, n int) []*Node {
+// GetNode returns the node with the given ID
+func (r *Ring) GetNode(id string) (*Node, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	if len(r.nodes) == 0 {
+	node, exists := r.nodes[id]
+	return node, exists
+}
+
+// Owners returns the top N nodes responsible for a key. If statuses are given, owners
+// with any other status are left out of the result, which may then hold fewer than N
+// nodes; the remaining owners are not replaced by nodes further down the order, since
+// those do not hold the key.
+func (r *Ring) Owners(key string, n int, statuses ...NodeStatus) []*Node {
+	if r.timing {
+		defer r.recordLookup(time.Now())
+	}
+	
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	owners := r.owners(key, n)
+	if len(statuses) == 0 {
+		return owners
+	}
+	
+	filtered := owners[:0]
+	for _, owner := range owners {
+		for _, status := range statuses {
+			if owner.Status == status {
+				filtered = append(filtered, owner)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// owners returns the top N nodes responsible for a key; the caller must hold the lock
+func (r *Ring) owners(key string, n int) []*Node {
+	if len(r.nodes) == 0 || n <= 0 {
 		return nil
 	}
 	
@@ -65,12 +373,11 @@ func (r *Ring) Owners(key string, n int) []*Node {
 		n = len(r.nodes)
 	}
 	
-	// Calculate hash scores for all nodes
-	type nodeScore struct {
-		node  *Node
-		score uint64
+	if r.mode == HashModeJump {
+		return r.jumpOwners(key, n)
 	}
 	
+	// Calculate hash scores for all nodes
 	scores := make([]nodeScore, 0, len(r.nodes))
 	for _, node := range r.nodes {
 		score := r.hash(key + node.ID)
@@ -91,12 +398,191 @@ func (r *Ring) Owners(key string, n int) []*Node {
 	return result
 }
 
-// hash computes a hash for rendezvous hashing
+// nodeScore is a node's rendezvous hashing score for a key
+type nodeScore struct {
+	node  *Node
+	score uint64
+}
+
+// OwnersBatch returns the top N nodes responsible for each of keys, the same nodes in
+// the same order as Owners, keyed by key. The ring is locked once for the whole batch,
+// each node's ID is converted for hashing once rather than per key, and only the top N
+// scores are kept instead of sorting every node, so routing thousands of keys costs
+// far less than calling Owners for each of them. Statuses filter the owners as they do
+// for Owners. Keys are left out of the result if the ring is empty or n is not positive.
+func (r *Ring) OwnersBatch(keys []string, n int, statuses ...NodeStatus) map[string][]*Node {
+	if r.timing {
+		defer r.recordLookup(time.Now())
+	}
+	
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	result := r.ownersBatch(keys, n)
+	if len(statuses) == 0 {
+		return result
+	}
+	for key, owners := range result {
+		filtered := owners[:0]
+		for _, owner := range owners {
+			for _, status := range statuses {
+				if owner.Status == status {
+					filtered = append(filtered, owner)
+					break
+				}
+			}
+		}
+		result[key] = filtered
+	}
+	return result
+}
+
+// ownersBatch implements OwnersBatch without the status filter; the caller must hold
+// the lock
+func (r *Ring) ownersBatch(keys []string, n int) map[string][]*Node {
+	result := make(map[string][]*Node, len(keys))
+	if len(r.nodes) == 0 || n <= 0 {
+		return result
+	}
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	
+	if r.mode == HashModeJump {
+		for _, key := range keys {
+			if _, seen := result[key]; !seen {
+				result[key] = r.jumpOwners(key, n)
+			}
+		}
+		return result
+	}
+	
+	nodes := make([]*Node, 0, len(r.nodes))
+	seeds := make([][]byte, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		nodes = append(nodes, node)
+		seeds = append(seeds, []byte(node.ID))
+	}
+	
+	owners := make([]*Node, 0, len(keys)*n)
+	top := make([]nodeScore, 0, n)
+	buf := make([]byte, 0, 64)
+	for _, key := range keys {
+		if _, seen := result[key]; seen {
+			continue
+		}
+		
+		// Keep the n highest scores, highest first, by insertion
+		top = top[:0]
+		for i, node := range nodes {
+			buf = append(append(buf[:0], key...), seeds[i]...)
+			score := hashBytes(buf)
+			if len(top) == n && score <= top[n-1].score {
+				continue
+			}
+			if len(top) < n {
+				top = append(top, nodeScore{})
+			}
+			j := len(top) - 1
+			for ; j > 0 && top[j-1].score < score; j-- {
+				top[j] = top[j-1]
+			}
+			top[j] = nodeScore{node: node, score: score}
+		}
+		
+		start := len(owners)
+		for _, s := range top {
+			owners = append(owners, s.node)
+		}
+		result[key] = owners[start:len(owners):len(owners)]
+	}
+	return result
+}
+
+// recordLookup adds the time since start to the Owners timings
+func (r *Ring) recordLookup(start time.Time) {
+	atomic.AddUint64(&r.lookupNs, uint64(time.Since(start)))
+	atomic.AddUint64(&r.lookups, 1)
+}
+
+// OwnersTiming returns the number of Owners and OwnersBatch calls timed and the total time they took,
+// including waiting for the ring's lock. Both are zero unless the ring was created
+// WithTiming.
+func (r *Ring) OwnersTiming() (lookups uint64, total time.Duration) {
+	return atomic.LoadUint64(&r.lookups), time.Duration(atomic.LoadUint64(&r.lookupNs))
+}
+
+// jumpOwners picks the primary with jump hashing and takes the following
+// nodes in the ordering as replicas; the caller must hold the lock
+func (r *Ring) jumpOwners(key string, n int) []*Node {
+	start := jumpHash(r.hash(key), len(r.order))
+	
+	result := make([]*Node, n)
+	for i := 0; i < n; i++ {
+		result[i] = r.order[(start+i)%len(r.order)]
+	}
+	return result
+}
+
+// jumpHash implements Lamping and Veach's jump consistent hash, mapping a key
+// to a bucket in [0, buckets)
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// hash computes a hash for key placement
 func (r *Ring) hash(input string) uint64 {
-	h := md5.Sum([]byte(input))
+	return hashBytes([]byte(input))
+}
+
+// hashBytes returns the first 8 bytes of the MD5 hash of b as a big-endian integer,
+// the hash behind every score and position on the ring
+func hashBytes(b []byte) uint64 {
+	h := md5.Sum(b)
 	return binary.BigEndian.Uint64(h[:8])
 }
 
+// LoadDistribution returns how many of the sample keys each node owns as primary.
+// Every node in the ring is included, with zero if it owns none of the keys.
+func (r *Ring) LoadDistribution(sampleKeys []string) map[string]int {
+	load := make(map[string]int)
+	for _, node := range r.GetNodes() {
+		load[node.ID] = 0
+	}
+	
+	for _, key := range sampleKeys {
+		if owners := r.Owners(key, 1); len(owners) > 0 {
+			load[owners[0].ID]++
+		}
+	}
+	return load
+}
+
+// ImbalanceRatio returns the most sample keys owned by any node divided by the mean
+// per node. A perfectly balanced ring gives 1; it returns 0 for an empty ring or no
+// keys. With few sample keys per node the ratio also reflects sampling noise.
+func (r *Ring) ImbalanceRatio(sampleKeys []string) float64 {
+	load := r.LoadDistribution(sampleKeys)
+	if len(load) == 0 || len(sampleKeys) == 0 {
+		return 0
+	}
+	
+	max := 0
+	for _, count := range load {
+		if count > max {
+			max = count
+		}
+	}
+	mean := float64(len(sampleKeys)) / float64(len(load))
+	return float64(max) / mean
+}
+
 // NodeCount returns the number of nodes in the ring
 func (r *Ring) NodeCount() int {
 	r.mu.RLock()
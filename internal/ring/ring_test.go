@@ -2,8 +2,9 @@ package ring
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
-	"time"
 )
 
 func TestRingAddRemoveNode(t *testing.T) {
@@ -164,4 +165,532 @@ func TestRingOwnerDistribution(t *testing.T) {
 			t.Errorf("Node %s got no keys", nodeID)
 		}
 	}
-} 
\ No newline at end of file
+}
+
+func TestRingJumpHashOwners(t *testing.T) {
+	ring := NewRing(WithHashMode(HashModeJump))
+	ring.AddNode("node1", "localhost:8081")
+	ring.AddNode("node2", "localhost:8082")
+	ring.AddNode("node3", "localhost:8083")
+	
+	owners := ring.Owners("test-key", 3)
+	if len(owners) != 3 {
+		t.Fatalf("Expected 3 owners, got %d", len(owners))
+	}
+	
+	seen := make(map[string]bool)
+	for _, owner := range owners {
+		if seen[owner.ID] {
+			t.Errorf("Duplicate owner found: %s", owner.ID)
+		}
+		seen[owner.ID] = true
+	}
+	
+	// Re-adding a node must not move it in the ordering
+	ring.AddNode("node1", "localhost:9091")
+	again := ring.Owners("test-key", 3)
+	for i := range owners {
+		if owners[i].ID != again[i].ID {
+			t.Errorf("Owner mapping changed after re-adding node: %s vs %s", owners[i].ID, again[i].ID)
+		}
+	}
+}
+
+func TestRingJumpHashRemoveLast(t *testing.T) {
+	ring := NewRing(WithHashMode(HashModeJump))
+	for i := 0; i < 5; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		before[key] = ring.Owners(key, 1)[0].ID
+	}
+	
+	// Removing the last node only moves the keys it owned
+	ring.RemoveNode("node4")
+	for key, owner := range before {
+		now := ring.Owners(key, 1)[0].ID
+		if owner != "node4" && now != owner {
+			t.Errorf("Key %s moved from %s to %s", key, owner, now)
+		}
+	}
+}
+
+func TestRingModeDistribution(t *testing.T) {
+	const nodes = 8
+	const keys = 16000
+	
+	for _, tc := range []struct {
+		name string
+		mode HashMode
+	}{
+		{"rendezvous", HashModeRendezvous},
+		{"jump", HashModeJump},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ring := NewRing(WithHashMode(tc.mode))
+			for i := 0; i < nodes; i++ {
+				ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+			}
+			
+			distribution := make(map[string]int)
+			for i := 0; i < keys; i++ {
+				distribution[ring.Owners(fmt.Sprintf("key%d", i), 1)[0].ID]++
+			}
+			
+			// Every node should be within 15% of an even share
+			expected := keys / nodes
+			for i := 0; i < nodes; i++ {
+				id := fmt.Sprintf("node%d", i)
+				count := distribution[id]
+				if count < expected*85/100 || count > expected*115/100 {
+					t.Errorf("Node %s got %d keys, expected about %d", id, count, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRingOnChange(t *testing.T) {
+	ring := NewRing()
+	
+	type change struct {
+		added   []string
+		removed []string
+	}
+	var changes []change
+	ids := func(nodes []*Node) []string {
+		var result []string
+		for _, node := range nodes {
+			result = append(result, node.ID+"@"+node.Addr)
+		}
+		return result
+	}
+	ring.OnChange(func(added, removed []*Node) {
+		changes = append(changes, change{added: ids(added), removed: ids(removed)})
+		
+		// Listeners run outside the lock and may read the ring
+		ring.NodeCount()
+	})
+	
+	ring.AddNode("node1", "localhost:8081")
+	ring.AddNode("node2", "localhost:8082")
+	ring.AddNode("node2", "localhost:8082")
+	ring.AddNode("node2", "localhost:9092")
+	ring.RemoveNode("node1")
+	ring.RemoveNode("missing")
+	
+	expected := []change{
+		{added: []string{"node1@localhost:8081"}},
+		{added: []string{"node2@localhost:8082"}},
+		{added: []string{"node2@localhost:9092"}, removed: []string{"node2@localhost:8082"}},
+		{removed: []string{"node1@localhost:8081"}},
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("Expected changes %v, got %v", expected, changes)
+	}
+}
+
+func TestRingBinaryKeys(t *testing.T) {
+	ring := NewRing()
+	for i := 0; i < 5; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	
+	// Keys differing only after a null byte must hash independently and stably
+	keys := []string{"a\x00b", "a\x00c", "a", "\x00", "ключ", "键🔑"}
+	hashes := make(map[uint64]string)
+	for _, key := range keys {
+		hash := ring.hash(key)
+		if other, exists := hashes[hash]; exists {
+			t.Errorf("Keys %q and %q hash the same", key, other)
+		}
+		hashes[hash] = key
+		
+		if ring.Owners(key, 1)[0].ID != ring.Owners(key, 1)[0].ID {
+			t.Errorf("Owner of %q is not stable", key)
+		}
+	}
+}
+
+func sampleKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("sample-%d", i)
+	}
+	return keys
+}
+
+func TestRingLoadDistribution(t *testing.T) {
+	ring := NewRing()
+	if ratio := ring.ImbalanceRatio(sampleKeys(100)); ratio != 0 {
+		t.Errorf("Expected ratio 0 for an empty ring, got %f", ratio)
+	}
+	
+	ring.AddNode("node1", "localhost:8081")
+	ring.AddNode("node2", "localhost:8082")
+	ring.AddNode("node3", "localhost:8083")
+	
+	keys := sampleKeys(3000)
+	load := ring.LoadDistribution(keys)
+	total := 0
+	for nodeID, count := range load {
+		if count == 0 {
+			t.Errorf("Node %s got no keys", nodeID)
+		}
+		total += count
+	}
+	if len(load) != 3 || total != len(keys) {
+		t.Errorf("Expected 3 nodes owning %d keys, got %d nodes owning %d", len(keys), len(load), total)
+	}
+	
+	// Keys all owned by one node are maximally skewed
+	var skewed []string
+	for _, key := range keys {
+		if ring.Owners(key, 1)[0].ID == "node1" {
+			skewed = append(skewed, key)
+		}
+	}
+	if ratio := ring.ImbalanceRatio(skewed); ratio != 3 {
+		t.Errorf("Expected ratio 3 when one node owns every key, got %f", ratio)
+	}
+	
+	// Nodes owning no sample keys are still reported
+	ring.AddNode("node4", "localhost:8084")
+	if count, exists := ring.LoadDistribution(nil)["node4"]; !exists || count != 0 {
+		t.Errorf("Expected node4 with no keys, got %d (exists=%v)", count, exists)
+	}
+}
+
+func TestRingImbalanceRatio(t *testing.T) {
+	// Sampling noise shrinks as each node is given more sample keys
+	ring := NewRing()
+	for i := 0; i < 8; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	last := ring.ImbalanceRatio(sampleKeys(100))
+	for _, n := range []int{1000, 10000, 100000} {
+		ratio := ring.ImbalanceRatio(sampleKeys(n))
+		if ratio >= last {
+			t.Errorf("Expected ratio to fall with %d sample keys, got %f (previous %f)", n, ratio, last)
+		}
+		last = ratio
+	}
+	
+	// With a fixed sample, rendezvous hashing stays close to balanced as nodes are
+	// added, though fewer keys per node means more noise
+	keys := sampleKeys(10000)
+	ring = NewRing()
+	for i := 1; i <= 32; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+		if i&(i-1) != 0 {
+			continue // Check at powers of two
+		}
+		if ratio := ring.ImbalanceRatio(keys); ratio < 1 || ratio > 1.2 {
+			t.Errorf("Expected ratio within 20%% of balanced with %d nodes, got %f", i, ratio)
+		}
+	}
+}
+
+func TestRingOwnersTiming(t *testing.T) {
+	untimed := NewRing()
+	timed := NewRing(WithTiming())
+	for i := 0; i < 8; i++ {
+		untimed.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+		timed.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		untimed.Owners(key, 3)
+		timed.Owners(key, 3)
+	}
+	
+	if lookups, total := untimed.OwnersTiming(); lookups != 0 || total != 0 {
+		t.Errorf("Expected no timings without WithTiming, got %d lookups in %v", lookups, total)
+	}
+	lookups, total := timed.OwnersTiming()
+	if lookups != 100 {
+		t.Errorf("Expected 100 timed lookups, got %d", lookups)
+	}
+	if total <= 0 {
+		t.Errorf("Expected a positive total lookup time, got %v", total)
+	}
+}
+
+func TestRingNodeStatus(t *testing.T) {
+	ring := NewRing()
+	for i := 0; i < 3; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("addr%d", i))
+	}
+	
+	if ring.SetNodeStatus("missing", NodeJoining) {
+		t.Error("Expected SetNodeStatus to report a missing node")
+	}
+	
+	// Find a key whose replicas include node1, then make node1 joining
+	var key string
+	var owners []*Node
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key%d", i)
+		owners = ring.Owners(key, 2)
+		if owners[0].ID == "node1" {
+			break
+		}
+	}
+	before := ring.Owners(key, 2)
+	if !ring.SetNodeStatus("node1", NodeJoining) {
+		t.Fatal("Expected SetNodeStatus to find node1")
+	}
+	if before[0].Status != NodeActive {
+		t.Error("Expected nodes handed out before the change to keep their status")
+	}
+	
+	// Writers still see node1 as the primary
+	writers := ring.Owners(key, 2)
+	if writers[0].ID != "node1" || writers[0].Status != NodeJoining || writers[1].ID != owners[1].ID {
+		t.Errorf("Expected node1 to stay the joining primary, got %v", writers)
+	}
+	
+	// Readers skip it without pulling in a node outside the replicas
+	readers := ring.Owners(key, 2, NodeActive, NodeLeaving)
+	if len(readers) != 1 || readers[0].ID != owners[1].ID {
+		t.Errorf("Expected only %s as a read owner, got %v", owners[1].ID, readers)
+	}
+	
+	// Changing the address keeps the status
+	ring.AddNode("node1", "moved")
+	if node, _ := ring.GetNode("node1"); node.Status != NodeJoining {
+		t.Errorf("Expected node1 to stay joining after moving, got %s", node.Status)
+	}
+	
+	ring.SetNodeStatus("node1", NodeActive)
+	if readers := ring.Owners(key, 2, NodeActive); len(readers) != 2 || readers[0].ID != "node1" {
+		t.Errorf("Expected node1 to serve reads once active, got %v", readers)
+	}
+}
+
+func TestRingSetNodes(t *testing.T) {
+	ring := NewRing(WithHashMode(HashModeJump))
+	for i := 0; i < 3; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("addr%d", i))
+	}
+	
+	var changes int
+	var added, removed []string
+	ring.OnChange(func(a, r []*Node) {
+		changes++
+		for _, node := range a {
+			added = append(added, node.ID+"@"+node.Addr)
+		}
+		for _, node := range r {
+			removed = append(removed, node.ID+"@"+node.Addr)
+		}
+	})
+	
+	// node0 leaves, node1 stays, node2 moves and node3 joins, all at once
+	given := []*Node{
+		{ID: "node3", Addr: "addr3"},
+		{ID: "node2", Addr: "moved", Status: NodeJoining},
+		{ID: "node1", Addr: "addr1"},
+	}
+	if err := ring.SetNodes(given); err != nil {
+		t.Fatalf("SetNodes failed: %v", err)
+	}
+	given[0].Addr = "changed"
+	
+	if changes != 1 {
+		t.Fatalf("Expected one change notification, got %d", changes)
+	}
+	if fmt.Sprint(added) != "[node2@moved node3@addr3]" || fmt.Sprint(removed) != "[node0@addr0 node2@addr2]" {
+		t.Errorf("Expected node2 and node3 added and node0 and node2 removed, got %v and %v", added, removed)
+	}
+	if node, _ := ring.GetNode("node3"); node.Addr != "addr3" {
+		t.Errorf("Expected the ring to copy the nodes given, got %s", node.Addr)
+	}
+	if node, _ := ring.GetNode("node2"); node.Status != NodeJoining {
+		t.Errorf("Expected node2 to take the status given, got %s", node.Status)
+	}
+	
+	// Remaining nodes keep their jump hash positions and new ones follow
+	var order []string
+	for _, node := range ring.order {
+		order = append(order, node.ID)
+	}
+	if fmt.Sprint(order) != "[node1 node2 node3]" {
+		t.Errorf("Expected jump order [node1 node2 node3], got %v", order)
+	}
+	
+	// Setting the same membership again changes nothing
+	ring.SetNodes(ring.GetNodes())
+	if changes != 1 {
+		t.Errorf("Expected no notification for an unchanged membership, got %d", changes-1)
+	}
+	
+	ring.SetNodes(nil)
+	if ring.NodeCount() != 0 || len(ring.Owners("key", 1)) != 0 {
+		t.Errorf("Expected an empty ring, got %d nodes", ring.NodeCount())
+	}
+}
+
+func TestRingValidate(t *testing.T) {
+	ring := NewRing(WithHashMode(HashModeJump))
+	if err := ring.Validate(); err != nil {
+		t.Errorf("Expected an empty ring to be valid, got %v", err)
+	}
+	
+	// Nodes without an ID or address are rejected and leave the ring unchanged
+	if err := ring.AddNode("", "addr0"); err == nil {
+		t.Error("Expected a node with an empty ID to be rejected")
+	}
+	if err := ring.AddNode("node0", ""); err == nil {
+		t.Error("Expected a node with an empty address to be rejected")
+	}
+	if err := ring.SetNodes([]*Node{{ID: "node0", Addr: "addr0"}, {ID: "", Addr: "addr1"}}); err == nil {
+		t.Error("Expected SetNodes to reject a node with an empty ID")
+	}
+	if err := ring.SetNodes([]*Node{{ID: "node0", Addr: "addr0"}, {ID: "node1"}}); err == nil {
+		t.Error("Expected SetNodes to reject a node with an empty address")
+	}
+	if ring.NodeCount() != 0 {
+		t.Errorf("Expected rejected nodes not to be added, got %d nodes", ring.NodeCount())
+	}
+	
+	// A ring built through its methods is well-formed after concurrent changes
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id := fmt.Sprintf("node%d", (i+j)%10)
+				ring.AddNode(id, fmt.Sprintf("addr%d-%d", i, j))
+				ring.SetNodeStatus(id, NodeStatus(j%3))
+				if j%7 == 0 {
+					ring.RemoveNode(id)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := ring.Validate(); err != nil {
+		t.Errorf("Expected a valid ring, got %v", err)
+	}
+	
+	// Broken invariants are all reported, each node once
+	ring = NewRing(WithHashMode(HashModeJump))
+	ring.SetNodes([]*Node{{ID: "node0", Addr: "addr0"}, {ID: "node1", Addr: "addr1"}})
+	ring.order = append(ring.order, ring.nodes["node0"], &Node{ID: "stray", Addr: "addr"})
+	ring.nodes["node1"].Addr = ""
+	ring.nodes["node1"].Status = NodeStatus(9)
+	err := ring.Validate()
+	if err == nil {
+		t.Fatal("Expected a broken ring to fail validation")
+	}
+	want := strings.Join([]string{
+		`node "node1" has no address`,
+		fmt.Sprintf(`node "node1" has unknown status %v`, NodeStatus(9)),
+		"node node0 appears more than once in the ordering",
+		"node stray in the ordering does not match the ring's membership",
+	}, "\n")
+	if err.Error() != want {
+		t.Errorf("Expected the errors\n%s\ngot\n%v", want, err)
+	}
+}
+
+func TestRingOwnersBatch(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	keys = append(keys, keys[0]) // Repeated keys are routed once
+	
+	for _, mode := range []HashMode{HashModeRendezvous, HashModeJump} {
+		ring := NewRing(WithHashMode(mode))
+		for i := 0; i < 7; i++ {
+			ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+		}
+		
+		for _, n := range []int{1, 3, 10} {
+			batch := ring.OwnersBatch(keys, n)
+			if len(batch) != 1000 {
+				t.Fatalf("mode %d: expected 1000 keys, got %d", mode, len(batch))
+			}
+			for _, key := range keys {
+				want, got := ring.Owners(key, n), batch[key]
+				if len(got) != len(want) {
+					t.Fatalf("mode %d: expected %d owners of %s, got %d", mode, len(want), key, len(got))
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("mode %d: owner %d of %s is %s, Owners gives %s", mode, i, key, got[i].ID, want[i].ID)
+					}
+				}
+			}
+		}
+	}
+	
+	if batch := NewRing().OwnersBatch(keys, 3); len(batch) != 0 {
+		t.Errorf("Expected no owners on an empty ring, got %d keys", len(batch))
+	}
+	
+	// Statuses filter the owners as for Owners, and timed rings count each batch
+	ring := NewRing(WithTiming())
+	for i := 0; i < 5; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	ring.SetNodeStatus("node2", NodeJoining)
+	batch := ring.OwnersBatch(keys, 3, NodeActive)
+	for _, key := range keys {
+		want, got := ring.Owners(key, 3, NodeActive), batch[key]
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d active owners of %s, got %d", len(want), key, len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Active owner %d of %s is %s, Owners gives %s", i, key, got[i].ID, want[i].ID)
+			}
+		}
+	}
+	if lookups, _ := ring.OwnersTiming(); lookups != uint64(len(keys)+1) {
+		t.Errorf("Expected the batch and %d Owners calls timed, got %d", len(keys), lookups)
+	}
+}
+
+// benchmarkOwnersKeys returns the keys routed by the Owners benchmarks, on a ring of
+// 10 nodes
+func benchmarkOwnersKeys() (*Ring, []string) {
+	ring := NewRing()
+	for i := 0; i < 10; i++ {
+		ring.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%08d", i)
+	}
+	return ring, keys
+}
+
+// BenchmarkRingOwnersPerKey routes 10k keys with one Owners call each
+func BenchmarkRingOwnersPerKey(b *testing.B) {
+	ring, keys := benchmarkOwnersKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			ring.Owners(key, 3)
+		}
+	}
+}
+
+// BenchmarkRingOwnersBatch routes the same 10k keys with one OwnersBatch call
+func BenchmarkRingOwnersBatch(b *testing.B) {
+	ring, keys := benchmarkOwnersKeys()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ring.OwnersBatch(keys, 3)
+	}
+}
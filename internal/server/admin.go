@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+
+	"github.com/shard-cache/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Resize implements the Resize admin RPC
+func (s *Server) Resize(ctx context.Context, req *proto.ResizeRequest) (*proto.ResizeResponse, error) {
+	evicted, err := s.cache.Resize(int(req.Capacity))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	
+	s.logger.Info("Cache resized",
+		zap.Int64("capacity", req.Capacity),
+		zap.Int("evicted", evicted))
+	
+	return &proto.ResizeResponse{
+		Capacity: req.Capacity,
+		Evicted:  int64(evicted),
+	}, nil
+}
+
+// SetMode implements the SetMode admin RPC
+func (s *Server) SetMode(ctx context.Context, req *proto.SetModeRequest) (*proto.SetModeResponse, error) {
+	mode, err := parseMode(req.Mode)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	
+	previous := s.setMode(mode)
+	s.logger.Info("Serving mode changed",
+		zap.String("mode", string(mode)),
+		zap.String("previous", string(previous)))
+	
+	return &proto.SetModeResponse{
+		Mode:     string(mode),
+		Previous: string(previous),
+	}, nil
+}
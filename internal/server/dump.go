@@ -0,0 +1,46 @@
+package server
+
+import (
+	"time"
+
+	"github.com/shard-cache/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Dump implements the Dump RPC. The key list is snapshotted up front and each
+// entry is then read on its own, so the cache lock is never held while sending.
+// Entries deleted or expired after the snapshot are skipped.
+func (s *Server) Dump(req *proto.DumpRequest, stream proto.CacheService_DumpServer) error {
+	ctx := stream.Context()
+	
+	for _, key := range s.cache.Keys() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		
+		entry, exists := s.cache.PeekEntry(key)
+		if !exists {
+			continue
+		}
+		
+		var ttl *durationpb.Duration
+		if !entry.ExpiresAt.IsZero() {
+			remaining := time.Until(entry.ExpiresAt)
+			if remaining <= 0 {
+				continue
+			}
+			ttl = durationpb.New(remaining)
+		}
+		
+		if err := stream.Send(&proto.Entry{
+			Key:     entry.Key,
+			Value:   entry.Value,
+			Ttl:     ttl,
+			Version: entry.Version,
+		}); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
@@ -1,16 +1,43 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shard-cache/internal/cache"
 	"github.com/shard-cache/internal/client"
+	"github.com/shard-cache/internal/ring"
 	"github.com/shard-cache/proto"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // TestE2EQuorumLogic tests the complete distributed cache with quorum logic
@@ -238,6 +265,9 @@ func TestE2EQuorumLogic(t *testing.T) {
 		if server.grpcServer != nil {
 			server.grpcServer.Stop()
 		}
+		if server.httpServer != nil {
+			server.httpServer.Close()
+		}
 	}
 }
 
@@ -333,4 +363,4486 @@ func TestE2ESingleNode(t *testing.T) {
 	if server.grpcServer != nil {
 		server.grpcServer.Stop()
 	}
-} 
\ No newline at end of file
+}
+
+// freePort returns a TCP port that is currently free on localhost
+func freePort(t *testing.T) int {
+	t.Helper()
+	
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer lis.Close()
+	
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+// startTestServer starts an embedded server on free ports and stops it when the test ends
+func startTestServer(t *testing.T, configure func(*Config)) *Server {
+	t.Helper()
+	
+	config := &Config{
+		GRPCPort:      freePort(t),
+		HTTPPort:      freePort(t),
+		CacheCapacity: 1000,
+		MaxConcurrent: 100,
+		CPUThreshold:  0.9,
+		CPUWindow:     10 * time.Second,
+	}
+	if configure != nil {
+		configure(config)
+	}
+	
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if err := server.startGRPCServer(); err != nil {
+		t.Fatalf("Failed to start gRPC server: %v", err)
+	}
+	if err := server.startHTTPServer(); err != nil {
+		t.Fatalf("Failed to start HTTP server: %v", err)
+	}
+	
+	t.Cleanup(func() {
+		server.stopBackground()
+		server.grpcServer.Stop()
+		server.httpServer.Close()
+		server.wg.Wait()
+	})
+	
+	return server
+}
+
+// grpcAddr returns the address a test server accepts gRPC connections on
+func grpcAddr(s *Server) string {
+	return fmt.Sprintf("localhost:%d", s.config.GRPCPort)
+}
+
+// newTestClient creates a client with one node per server, named node0..nodeN
+func newTestClient(t *testing.T, config *client.Config, servers ...*Server) *client.Client {
+	t.Helper()
+	
+	c, err := client.NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	
+	for i, s := range servers {
+		if err := c.AddNode(fmt.Sprintf("node%d", i), grpcAddr(s)); err != nil {
+			t.Fatalf("Failed to add node%d: %v", i, err)
+		}
+	}
+	
+	return c
+}
+
+// dialTestServer opens a direct gRPC client to a single test server
+func dialTestServer(t *testing.T, s *Server) proto.CacheServiceClient {
+	t.Helper()
+	
+	conn, err := grpc.Dial(grpcAddr(s), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	
+	return proto.NewCacheServiceClient(conn)
+}
+
+// TestE2EGetConsistent tests majority reads and read repair over divergent replicas
+func TestE2EGetConsistent(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:  3,
+		WriteQuorum: 3,
+		ReadRepair:  true,
+	}, servers...)
+	
+	ctx := context.Background()
+	key := "divergent-key"
+	
+	// Write diverging values directly to each replica
+	values := []string{"majority", "majority", "stale"}
+	for i, s := range servers {
+		if _, err := dialTestServer(t, s).Set(ctx, &proto.SetRequest{Key: key, Value: []byte(values[i])}); err != nil {
+			t.Fatalf("Direct set to node%d failed: %v", i, err)
+		}
+	}
+	
+	value, divergent, err := c.GetConsistent(ctx, key)
+	if err != nil {
+		t.Fatalf("GetConsistent failed: %v", err)
+	}
+	if string(value) != "majority" {
+		t.Errorf("Expected majority value, got %s", string(value))
+	}
+	if len(divergent) != 1 || divergent[0] != "node2" {
+		t.Errorf("Expected node2 to be reported as divergent, got %v", divergent)
+	}
+	if stats := c.GetStats(); stats["divergences"] != uint64(1) {
+		t.Errorf("Expected 1 divergence in stats, got %v", stats["divergences"])
+	}
+	
+	// Read repair should have fixed the stale replica
+	resp, err := dialTestServer(t, servers[2]).Get(ctx, &proto.GetRequest{Key: key})
+	if err != nil {
+		t.Fatalf("Direct get failed: %v", err)
+	}
+	if string(resp.Value) != "majority" {
+		t.Errorf("Expected stale replica to be repaired, got %s", string(resp.Value))
+	}
+	
+	_, divergent, err = c.GetConsistent(ctx, key)
+	if err != nil {
+		t.Fatalf("GetConsistent after repair failed: %v", err)
+	}
+	if len(divergent) != 0 {
+		t.Errorf("Expected no divergence after repair, got %v", divergent)
+	}
+	
+	// With no value held by a majority the read must fail
+	for i, v := range []string{"a", "b", "c"} {
+		if _, err := dialTestServer(t, servers[i]).Set(ctx, &proto.SetRequest{Key: "split", Value: []byte(v)}); err != nil {
+			t.Fatalf("Direct set to node%d failed: %v", i, err)
+		}
+	}
+	if _, _, err := c.GetConsistent(ctx, "split"); !errors.Is(err, client.ErrNoMajority) {
+		t.Errorf("Expected ErrNoMajority, got %v", err)
+	}
+}
+
+// TestE2EPing tests round-trip latency measurement against an embedded server
+func TestE2EPing(t *testing.T) {
+	server := startTestServer(t, nil)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	
+	ctx := context.Background()
+	rtt, err := c.Ping(ctx, "node0")
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if rtt <= 0 || rtt > time.Second {
+		t.Errorf("Expected a reasonable local round trip, got %v", rtt)
+	}
+	
+	rtts := c.GetStats()["ping_rtts"].(map[string]time.Duration)
+	if rtts["node0"] != rtt {
+		t.Errorf("Expected recorded RTT %v, got %v", rtt, rtts["node0"])
+	}
+	
+	if _, err := c.Ping(ctx, "missing"); err == nil {
+		t.Error("Expected ping to unknown node to fail")
+	}
+	
+	// A stopped node must fail within the ping deadline
+	server.grpcServer.Stop()
+	start := time.Now()
+	if _, err := c.Ping(ctx, "node0"); err == nil {
+		t.Error("Expected ping to stopped node to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Ping to stopped node took %v", elapsed)
+	}
+}
+
+// TestE2EWarmupFile tests that entries from a warmup file are served right after start
+func TestE2EWarmupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warmup.jsonl")
+	lines := []string{
+		`{"key":"warm1","value":"` + base64.StdEncoding.EncodeToString([]byte("value1")) + `"}`,
+		`{"key":"warm2","value":"` + base64.StdEncoding.EncodeToString([]byte("value2")) + `","expires_at":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`,
+		`{"key":"expired","value":"` + base64.StdEncoding.EncodeToString([]byte("old")) + `","expires_at":"` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `"}`,
+		`not json`,
+		`{"value":"` + base64.StdEncoding.EncodeToString([]byte("no-key")) + `"}`,
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatalf("Failed to write warmup file: %v", err)
+	}
+	
+	server := startTestServer(t, func(config *Config) {
+		config.WarmupFile = path
+	})
+	
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	for key, want := range map[string]string{"warm1": "value1", "warm2": "value2"} {
+		resp, err := grpcClient.Get(ctx, &proto.GetRequest{Key: key})
+		if err != nil {
+			t.Fatalf("Get %s failed: %v", key, err)
+		}
+		if !resp.Found || string(resp.Value) != want {
+			t.Errorf("Expected %s=%s after warmup, got found=%v value=%s", key, want, resp.Found, string(resp.Value))
+		}
+	}
+	
+	resp, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "expired"})
+	if err != nil {
+		t.Fatalf("Get expired failed: %v", err)
+	}
+	if resp.Found {
+		t.Error("Expected expired warmup entry to be skipped")
+	}
+	
+	if size := server.cache.Size(); size != 2 {
+		t.Errorf("Expected 2 warmed entries, got %d", size)
+	}
+}
+
+// TestE2ESnapshotFormats tests that a snapshot saved in each format loads back with
+// its keys and TTLs, and that snapshots from a newer format version are rejected
+func TestE2ESnapshotFormats(t *testing.T) {
+	for _, format := range []SnapshotFormat{SnapshotBinary, SnapshotJSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot")
+			source := startTestServer(t, func(config *Config) {
+				config.SnapshotFormat = format
+			})
+			source.cache.Set("persistent", []byte("forever"), 0)
+			source.cache.Set("expiring", []byte("soon"), time.Hour)
+			source.cache.Set("binary", []byte{0, '\n', 0xff}, 0)
+			source.cache.Set("empty", []byte{}, 0)
+			
+			if saved, err := source.SaveSnapshot(path); err != nil || saved != 4 {
+				t.Fatalf("SaveSnapshot = %d, %v; want 4 entries", saved, err)
+			}
+			
+			restored := startTestServer(t, func(config *Config) {
+				config.WarmupFile = path
+			})
+			for key, want := range map[string]string{"persistent": "forever", "expiring": "soon", "binary": "\x00\n\xff", "empty": ""} {
+				value, found := restored.cache.Get(key)
+				if !found || string(value) != want {
+					t.Errorf("Expected %s=%q after restore, got found=%v value=%q", key, want, found, value)
+				}
+			}
+			for _, item := range restored.cache.Snapshot() {
+				switch item.Key {
+				case "expiring":
+					if item.TTL <= 59*time.Minute || item.TTL > time.Hour {
+						t.Errorf("Expected expiring to keep its hour TTL, got %v", item.TTL)
+					}
+				default:
+					if item.TTL != 0 {
+						t.Errorf("Expected %s to have no TTL, got %v", item.Key, item.TTL)
+					}
+				}
+			}
+		})
+	}
+	
+	path := filepath.Join(t.TempDir(), "future")
+	if err := os.WriteFile(path, []byte(snapshotMagic+"\x09\x00\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+	_, err := NewServer(&Config{
+		GRPCPort:      freePort(t),
+		HTTPPort:      freePort(t),
+		CacheCapacity: 1000,
+		MaxConcurrent: 100,
+		CPUThreshold:  0.9,
+		CPUWindow:     10 * time.Second,
+		WarmupFile:    path,
+	})
+	if err == nil || !strings.Contains(err.Error(), "version 9") {
+		t.Errorf("Expected a snapshot from version 9 to be rejected, got %v", err)
+	}
+}
+
+// TestE2EWarmupFailureStopsPublisher tests that a server failing to start on a bad
+// warmup file stops its event publisher, publishing the evictions the warmup caused
+func TestE2EWarmupFailureStopsPublisher(t *testing.T) {
+	source := startTestServer(t, nil)
+	for _, key := range []string{"a", "b", "c"} {
+		source.cache.Set(key, []byte("value"), 0)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot")
+	if _, err := source.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-3], 0o644); err != nil {
+		t.Fatalf("Failed to truncate snapshot: %v", err)
+	}
+	
+	sink := &fakeSink{}
+	_, err = NewServer(&Config{
+		GRPCPort:           freePort(t),
+		HTTPPort:           freePort(t),
+		CacheCapacity:      1,
+		MaxConcurrent:      100,
+		CPUThreshold:       0.9,
+		CPUWindow:          10 * time.Second,
+		EventSink:          sink,
+		EventFlushInterval: time.Hour,
+		WarmupFile:         path,
+	})
+	if err == nil {
+		t.Fatal("Expected a truncated warmup file to fail the start")
+	}
+	sink.mu.Lock()
+	published := len(sink.events)
+	sink.mu.Unlock()
+	if published != 1 {
+		t.Errorf("Expected the publisher to flush the warmup's eviction as it stopped, got %d events", published)
+	}
+}
+
+// TestE2EHTTPFailureStopsGRPC tests that a server failing to listen for HTTP stops
+// the gRPC server it had already started, releasing its port
+func TestE2EHTTPFailureStopsGRPC(t *testing.T) {
+	occupied, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to occupy a port: %v", err)
+	}
+	defer occupied.Close()
+	
+	config := &Config{
+		GRPCPort:      freePort(t),
+		HTTPPort:      occupied.Addr().(*net.TCPAddr).Port,
+		CacheCapacity: 1000,
+		MaxConcurrent: 100,
+		CPUThreshold:  0.9,
+		CPUWindow:     10 * time.Second,
+	}
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.abortStart()
+	
+	if err := server.Start(); err == nil {
+		t.Fatal("Expected the start to fail with the HTTP port in use")
+	}
+	lis, err := net.Listen("tcp", config.GRPCListenAddr())
+	if err != nil {
+		t.Fatalf("Expected the gRPC port to be released, got %v", err)
+	}
+	lis.Close()
+}
+
+// TestE2EPreload tests bulk-loading a node over the Preload stream
+func TestE2EPreload(t *testing.T) {
+	server := startTestServer(t, nil)
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	stream, err := grpcClient.Preload(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open preload stream: %v", err)
+	}
+	
+	for i := 0; i < 10; i++ {
+		item := &proto.PreloadItem{
+			Key:   fmt.Sprintf("preload-%d", i),
+			Value: []byte(fmt.Sprintf("value-%d", i)),
+		}
+		if err := stream.Send(item); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	
+	// Malformed and expired items are skipped
+	if err := stream.Send(&proto.PreloadItem{Value: []byte("no-key")}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := stream.Send(&proto.PreloadItem{Key: "expired", Ttl: durationpb.New(-time.Second)}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv failed: %v", err)
+	}
+	if resp.Loaded != 10 || resp.Skipped != 2 {
+		t.Errorf("Expected 10 loaded and 2 skipped, got %d and %d", resp.Loaded, resp.Skipped)
+	}
+	
+	for i := 0; i < 10; i++ {
+		getResp, err := grpcClient.Get(ctx, &proto.GetRequest{Key: fmt.Sprintf("preload-%d", i)})
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if !getResp.Found || string(getResp.Value) != fmt.Sprintf("value-%d", i) {
+			t.Errorf("Expected preload-%d to be present", i)
+		}
+	}
+}
+
+// restartTestServer stops a test server's gRPC listener and starts it again on the same port,
+// keeping its cache contents, then waits until the client can reach it
+func restartTestServer(t *testing.T, s *Server, c *client.Client, nodeID string) {
+	t.Helper()
+	
+	s.grpcServer.Stop()
+	if err := s.startGRPCServer(); err != nil {
+		t.Fatalf("Failed to restart gRPC server: %v", err)
+	}
+	
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := c.Ping(context.Background(), nodeID); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Node %s unreachable after restart", nodeID)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestE2ETombstonePreventsResurrection tests that a replica which missed a delete
+// does not bring the value back through read repair
+func TestE2ETombstonePreventsResurrection(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:  2,
+		WriteQuorum: 1,
+		ReadRepair:  true,
+	}, servers...)
+	
+	ctx := context.Background()
+	key := "resurrect-test"
+	
+	// Writes go to the primary only; find the secondary replica
+	r := ring.NewRing()
+	r.AddNode("node0", grpcAddr(servers[0]))
+	r.AddNode("node1", grpcAddr(servers[1]))
+	secondaryID := r.Owners(key, 2)[1].ID
+	secondary := servers[0]
+	if secondaryID == "node1" {
+		secondary = servers[1]
+	}
+	
+	// Read repair copies the value to the secondary
+	if err := c.Set(ctx, key, []byte("old-value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, _, err := c.GetConsistent(ctx, key); err != nil {
+		t.Fatalf("GetConsistent failed: %v", err)
+	}
+	if _, found := secondary.cache.Get(key); !found {
+		t.Fatal("Expected secondary to be repaired with the value")
+	}
+	
+	// The secondary is down while the key is deleted
+	secondary.grpcServer.Stop()
+	if err := c.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	
+	// It comes back still holding the old value
+	restartTestServer(t, secondary, c, secondaryID)
+	if _, found := secondary.cache.Get(key); !found {
+		t.Fatal("Expected secondary to still hold the value it missed the delete for")
+	}
+	
+	// The tombstone is newer, so the read resolves to deleted and repairs the secondary
+	_, divergent, err := c.GetConsistent(ctx, key)
+	if err == nil {
+		t.Fatal("Expected key to resolve as deleted")
+	}
+	if len(divergent) != 1 || divergent[0] != secondaryID {
+		t.Errorf("Expected %s to be divergent, got %v", secondaryID, divergent)
+	}
+	
+	resp, err := dialTestServer(t, secondary).Get(ctx, &proto.GetRequest{Key: key})
+	if err != nil {
+		t.Fatalf("Direct get failed: %v", err)
+	}
+	if resp.Found || !resp.Tombstone {
+		t.Errorf("Expected secondary to be repaired with a tombstone, got %+v", resp)
+	}
+	
+	for _, s := range servers {
+		if _, found := s.cache.Get(key); found {
+			t.Error("Expected value not to be resurrected")
+		}
+	}
+}
+
+// TestE2EHybridClockLastWriteWins tests that writes resolve by hybrid logical clock:
+// a delayed older write is rejected, and a client whose clock is behind still wins
+// once it has seen the newer write
+func TestE2EHybridClockLastWriteWins(t *testing.T) {
+	server := startTestServer(t, nil)
+	grpcClient := dialTestServer(t, server)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	ctx := context.Background()
+	
+	set := func(value string, version uint64) *proto.SetResponse {
+		t.Helper()
+		resp, err := grpcClient.Set(ctx, &proto.SetRequest{Key: "key", Value: []byte(value), Version: version})
+		if err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		return resp
+	}
+	get := func() string {
+		t.Helper()
+		value, err := c.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		return string(value)
+	}
+	
+	// A writer whose clock runs half a minute ahead, followed by one of its earlier
+	// writes arriving late
+	ahead := uint64(time.Now().Add(30 * time.Second).UnixNano())
+	set("ahead", ahead)
+	if resp := set("delayed", ahead-1); resp.Success || resp.Version != ahead {
+		t.Errorf("Expected the delayed write to be rejected in favor of version %d, got %+v", ahead, resp)
+	}
+	if value := get(); value != "ahead" {
+		t.Errorf("Expected the logically latest value, got %q", value)
+	}
+	
+	// Having read the newer write, the client's next write follows it
+	if err := c.Set(ctx, "key", []byte("latest"), 0); err != nil {
+		t.Fatalf("Expected a write after reading the newer one to win, got %v", err)
+	}
+	if resp := set("ahead", ahead); resp.Success {
+		t.Error("Expected a replayed older write to be rejected")
+	}
+	if value := get(); value != "latest" {
+		t.Errorf("Expected the client's write to persist, got %q", value)
+	}
+}
+
+// slowServer wraps a test server and delays every Get, and separately every Set and
+// Delete, by a configurable amount
+type slowServer struct {
+	*Server
+	delay          int64 // nanoseconds, accessed atomically
+	writeDelay     int64 // nanoseconds, accessed atomically
+	startedWrites  int64 // accessed atomically
+	canceledWrites int64 // writes abandoned because the caller canceled, accessed atomically
+	gets           int64 // accessed atomically
+	pingDelay      int64 // nanoseconds added to Health, accessed atomically
+}
+
+// Get delays before delegating to the wrapped server
+func (s *slowServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	atomic.AddInt64(&s.gets, 1)
+	time.Sleep(time.Duration(atomic.LoadInt64(&s.delay)))
+	return s.Server.Get(ctx, req)
+}
+
+// Health delays before delegating to the wrapped server, simulating a distant node
+func (s *slowServer) Health(ctx context.Context, req *proto.HealthRequest) (*proto.HealthResponse, error) {
+	time.Sleep(time.Duration(atomic.LoadInt64(&s.pingDelay)))
+	return s.Server.Health(ctx, req)
+}
+
+// delayWrite waits out the write delay, returning an error if the caller cancels first
+func (s *slowServer) delayWrite(ctx context.Context) error {
+	atomic.AddInt64(&s.startedWrites, 1)
+	select {
+	case <-time.After(time.Duration(atomic.LoadInt64(&s.writeDelay))):
+		return nil
+	case <-ctx.Done():
+		atomic.AddInt64(&s.canceledWrites, 1)
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// Set delays before delegating to the wrapped server
+func (s *slowServer) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResponse, error) {
+	if err := s.delayWrite(ctx); err != nil {
+		return nil, err
+	}
+	return s.Server.Set(ctx, req)
+}
+
+// Delete delays before delegating to the wrapped server
+func (s *slowServer) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	if err := s.delayWrite(ctx); err != nil {
+		return nil, err
+	}
+	return s.Server.Delete(ctx, req)
+}
+
+// startSlowServer serves a slowServer on a free port and returns it with its address
+func startSlowServer(t *testing.T) (*slowServer, string) {
+	t.Helper()
+	
+	slow := &slowServer{Server: startTestServer(t, nil)}
+	
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	proto.RegisterCacheServiceServer(grpcServer, slow)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+	
+	return slow, lis.Addr().String()
+}
+
+// keyOwnedBy returns a key whose primary owner among nodeIDs is the given node
+func keyOwnedBy(t *testing.T, primary string, nodeIDs ...string) string {
+	t.Helper()
+	
+	r := ring.NewRing()
+	for _, id := range nodeIDs {
+		r.AddNode(id, id)
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if r.Owners(key, 1)[0].ID == primary {
+			return key
+		}
+	}
+	t.Fatalf("No key found with primary %s", primary)
+	return ""
+}
+
+// TestE2EGetFrom tests that GetFrom reports the owner that served each read, including
+// a stale replica and the backup owner once the primary is down
+func TestE2EGetFrom(t *testing.T) {
+	servers := []*Server{startTestServer(t, nil), startTestServer(t, nil), startTestServer(t, nil)}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	key := keyOwnedBy(t, "node1", "node0", "node1", "node2")
+	r := ring.NewRing()
+	for i := range servers {
+		r.AddNode(fmt.Sprintf("node%d", i), fmt.Sprintf("localhost:%d", 8080+i))
+	}
+	backup := r.Owners(key, 2)[1].ID
+	
+	if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	// getFrom reads the key and checks that exactly the reported node served it
+	getFrom := func(want string) []byte {
+		t.Helper()
+		before := make([]uint64, len(servers))
+		for i, s := range servers {
+			before[i] = atomic.LoadUint64(&s.requestsTotal)
+		}
+		value, nodeID, err := c.GetFrom(ctx, key)
+		if err != nil {
+			t.Fatalf("GetFrom failed: %v", err)
+		}
+		if nodeID != want {
+			t.Errorf("Expected the read to be served by %s, got %s", want, nodeID)
+		}
+		for i, s := range servers {
+			id := fmt.Sprintf("node%d", i)
+			if served := atomic.LoadUint64(&s.requestsTotal) - before[i]; (served > 0) != (id == nodeID) {
+				t.Errorf("Expected only %s to receive the read, %s received %d requests", nodeID, id, served)
+			}
+		}
+		return value
+	}
+	
+	if value := getFrom("node1"); string(value) != "value" {
+		t.Errorf("Expected value, got %q", value)
+	}
+	
+	// A primary that missed a write is identified by its ID
+	servers[1].cache.Set(key, []byte("stale"), 0)
+	if value := getFrom("node1"); string(value) != "stale" {
+		t.Errorf("Expected the stale value from node1, got %q", value)
+	}
+	
+	// With the primary down, the backup owner answers
+	servers[1].grpcServer.Stop()
+	if value := getFrom(backup); string(value) != "value" {
+		t.Errorf("Expected value from %s, got %q", backup, value)
+	}
+	
+	if _, nodeID, err := c.GetFrom(ctx, "missing"); err == nil || nodeID != "" {
+		t.Errorf("Expected an error and no node ID for a missing key, got %q (%v)", nodeID, err)
+	}
+}
+
+// TestE2EGetCoalescing tests that concurrent Gets of one key share a single RPC, that
+// its error reaches every caller, and that reads at different consistencies are not merged
+func TestE2EGetCoalescing(t *testing.T) {
+	slow, slowAddr := startSlowServer(t)
+	atomic.StoreInt64(&slow.delay, int64(100*time.Millisecond))
+	slow.cache.Set("hot", []byte("value"), 0)
+	
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err := c.AddNode("slow", slowAddr); err != nil {
+		t.Fatalf("Failed to add slow node: %v", err)
+	}
+	ctx := context.Background()
+	
+	readAll := func(ctx context.Context, key string, n int) []error {
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				value, err := c.Get(ctx, key)
+				if err == nil && string(value) != "value" {
+					err = fmt.Errorf("got %q", value)
+				}
+				errs[i] = err
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+	
+	for i, err := range readAll(ctx, "hot", 50) {
+		if err != nil {
+			t.Fatalf("Get %d failed: %v", i, err)
+		}
+	}
+	if gets := atomic.LoadInt64(&slow.gets); gets != 1 {
+		t.Errorf("Expected 50 concurrent Gets to send 1 RPC, got %d", gets)
+	}
+	
+	// A miss is reported to every caller sharing the read
+	for i, err := range readAll(ctx, "missing", 20) {
+		if err == nil {
+			t.Errorf("Expected Get %d of a missing key to fail", i)
+		}
+	}
+	if gets := atomic.LoadInt64(&slow.gets); gets != 2 {
+		t.Errorf("Expected the concurrent misses to send 1 RPC, got %d in total", gets-1)
+	}
+	
+	// Reads at another consistency level get a read of their own
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		readAll(client.WithConsistency(ctx, client.ConsistencyOne), "hot", 10)
+	}()
+	readAll(ctx, "hot", 10)
+	wg.Wait()
+	if gets := atomic.LoadInt64(&slow.gets); gets != 4 {
+		t.Errorf("Expected one RPC per consistency level, got %d", gets-2)
+	}
+}
+
+// TestE2EAdaptiveHedging tests that a node that turns slow gets hedged more often
+func TestE2EAdaptiveHedging(t *testing.T) {
+	fast := startTestServer(t, nil)
+	slow, slowAddr := startSlowServer(t)
+	
+	c, err := client.NewClient(&client.Config{
+		ReadQuorum:   2,
+		WriteQuorum:  2,
+		HedgeTimeout: 500 * time.Millisecond,
+		HedgeRatio:   0.05,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	
+	if err := c.AddNode("fast", grpcAddr(fast)); err != nil {
+		t.Fatalf("Failed to add fast node: %v", err)
+	}
+	if err := c.AddNode("slow", slowAddr); err != nil {
+		t.Fatalf("Failed to add slow node: %v", err)
+	}
+	
+	ctx := context.Background()
+	key := keyOwnedBy(t, "slow", "fast", "slow")
+	if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	hedges := func() uint64 {
+		return c.GetStats()["hedges"].(map[string]uint64)["slow"]
+	}
+	
+	read := func(n int) {
+		for i := 0; i < n; i++ {
+			value, err := c.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if string(value) != "value" {
+				t.Fatalf("Expected value, got %s", string(value))
+			}
+		}
+	}
+	
+	const fastReads, slowReads = 60, 20
+	read(fastReads)
+	fastHedges := hedges()
+	
+	// Slow the primary down well past its learned tail latency
+	atomic.StoreInt64(&slow.delay, int64(20*time.Millisecond))
+	read(slowReads)
+	slowHedges := hedges() - fastHedges
+	
+	fastRate := float64(fastHedges) / fastReads
+	slowRate := float64(slowHedges) / slowReads
+	t.Logf("Hedge rate: %.2f while fast, %.2f while slow", fastRate, slowRate)
+	
+	if slowRate <= fastRate {
+		t.Errorf("Expected hedge rate to increase when the node slowed, got %.2f -> %.2f", fastRate, slowRate)
+	}
+	if slowRate < 0.5 {
+		t.Errorf("Expected most reads to a slow node to be hedged, got %.2f", slowRate)
+	}
+}
+
+func TestE2EHedgingRespectsDeadline(t *testing.T) {
+	fast := startTestServer(t, nil)
+	slow, slowAddr := startSlowServer(t)
+	
+	// Without latency samples the hedge delay is half the timeout, 250ms
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2, HedgeTimeout: 500 * time.Millisecond}, fast)
+	if err := c.AddNode("slow", slowAddr); err != nil {
+		t.Fatalf("Failed to add slow node: %v", err)
+	}
+	
+	key := keyOwnedBy(t, "slow", "node0", "slow")
+	if err := c.Set(context.Background(), key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	atomic.StoreInt64(&slow.delay, int64(400*time.Millisecond))
+	
+	hedges := func() uint64 {
+		return c.GetStats()["hedges"].(map[string]uint64)["slow"]
+	}
+	
+	// A deadline sooner than the hedge delay gets no hedge and is not overrun
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Get(ctx, key); err == nil {
+		t.Error("Expected the read to fail at the caller's deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected the read to end at the 100ms deadline, took %v", elapsed)
+	}
+	if n := hedges(); n != 0 {
+		t.Errorf("Expected no hedge under a short deadline, got %d", n)
+	}
+	
+	// A deadline with room for the hedge still gets one
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "value" {
+		t.Errorf("Expected the hedge to return value, got %s, %v", string(value), err)
+	}
+	if n := hedges(); n != 1 {
+		t.Errorf("Expected one hedge with a long deadline, got %d", n)
+	}
+}
+
+// TestE2ELatencyAwareReads tests that latency-aware reads skew away from a slow primary
+func TestE2ELatencyAwareReads(t *testing.T) {
+	fast := startTestServer(t, nil)
+	slow, slowAddr := startSlowServer(t)
+	atomic.StoreInt64(&slow.delay, int64(10*time.Millisecond))
+	
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2, LatencyAwareReads: true}, fast)
+	if err := c.AddNode("slow", slowAddr); err != nil {
+		t.Fatalf("Failed to add slow node: %v", err)
+	}
+	
+	ctx := client.WithConsistency(context.Background(), client.ConsistencyOne)
+	key := keyOwnedBy(t, "slow", "node0", "slow")
+	if err := c.Set(context.Background(), key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	const reads = 100
+	before := atomic.LoadUint64(&fast.requestsTotal)
+	for i := 0; i < reads; i++ {
+		value, err := c.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if string(value) != "value" {
+			t.Fatalf("Expected value, got %s", string(value))
+		}
+	}
+	
+	fastReads := atomic.LoadUint64(&fast.requestsTotal) - before
+	slowReads := atomic.LoadInt64(&slow.gets)
+	if fastReads+uint64(slowReads) != reads {
+		t.Fatalf("Expected %d reads in total, fast served %d and slow %d", reads, fastReads, slowReads)
+	}
+	if fastReads < reads*8/10 {
+		t.Errorf("Expected most reads to go to the fast owner, fast served %d and slow %d", fastReads, slowReads)
+	}
+}
+
+// TestE2EPreferNearestReplica tests that reads go to the owner with the lowest ping
+// round trip and follow it when latencies change, while writes reach every owner
+func TestE2EPreferNearestReplica(t *testing.T) {
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:           1,
+		WriteQuorum:          3,
+		PreferNearestReplica: true,
+		PingInterval:         20 * time.Millisecond,
+	})
+	
+	delays := map[string]time.Duration{"far": 40 * time.Millisecond, "near": 0, "mid": 20 * time.Millisecond}
+	nodes := make(map[string]*slowServer)
+	for _, id := range []string{"far", "near", "mid"} {
+		slow, addr := startSlowServer(t)
+		atomic.StoreInt64(&slow.pingDelay, int64(delays[id]))
+		nodes[id] = slow
+		if err := c.AddNode(id, addr); err != nil {
+			t.Fatalf("Failed to add node %s: %v", id, err)
+		}
+	}
+	
+	ctx := context.Background()
+	if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for id, node := range nodes {
+		if _, found := node.cache.Get("key"); !found {
+			t.Errorf("Expected the write to reach %s", id)
+		}
+	}
+	
+	// waitForNearest waits until the pinger has measured id as the nearest node
+	waitForNearest := func(id string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			rtts := c.GetStats()["ping_rtts"].(map[string]time.Duration)
+			nearest := len(rtts) == len(nodes)
+			for other, rtt := range rtts {
+				if other != id && rtt <= rtts[id] {
+					nearest = false
+				}
+			}
+			if nearest {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %s to be measured nearest", id)
+	}
+	
+	// readFrom reads the key repeatedly and checks that only the given node served it
+	readFrom := func(id string) {
+		t.Helper()
+		before := make(map[string]int64)
+		for other, node := range nodes {
+			before[other] = atomic.LoadInt64(&node.gets)
+		}
+		const reads = 20
+		for i := 0; i < reads; i++ {
+			value, err := c.Get(ctx, "key")
+			if err != nil || string(value) != "value" {
+				t.Fatalf("Get failed: %q, %v", value, err)
+			}
+		}
+		for other, node := range nodes {
+			want := int64(0)
+			if other == id {
+				want = reads
+			}
+			if got := atomic.LoadInt64(&node.gets) - before[other]; got != want {
+				t.Errorf("Expected %s to serve %d reads, got %d", other, want, got)
+			}
+		}
+	}
+	
+	waitForNearest("near")
+	readFrom("near")
+	
+	// Reads move to a new nearest node once the pings measure it
+	atomic.StoreInt64(&nodes["near"].pingDelay, int64(40*time.Millisecond))
+	atomic.StoreInt64(&nodes["far"].pingDelay, 0)
+	waitForNearest("far")
+	readFrom("far")
+}
+
+// TestE2EAdminResize tests resizing a running node through the admin service
+func TestE2EAdminResize(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.CacheCapacity = 10
+		config.EnableAdmin = true
+	})
+	
+	conn, err := grpc.Dial(grpcAddr(server), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	admin := proto.NewAdminServiceClient(conn)
+	
+	for i := 0; i < 10; i++ {
+		server.cache.Set(fmt.Sprintf("key-%d", i), []byte("value"), 0)
+	}
+	
+	ctx := context.Background()
+	resp, err := admin.Resize(ctx, &proto.ResizeRequest{Capacity: 4})
+	if err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if resp.Evicted != 6 || resp.Capacity != 4 {
+		t.Errorf("Expected 6 evicted at capacity 4, got %d at %d", resp.Evicted, resp.Capacity)
+	}
+	if server.cache.Size() != 4 {
+		t.Errorf("Expected 4 entries after resize, got %d", server.cache.Size())
+	}
+	
+	_, err = admin.Resize(ctx, &proto.ResizeRequest{Capacity: 0})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for zero capacity, got %v", err)
+	}
+	
+	// Admin service is not exposed unless enabled
+	plain := startTestServer(t, nil)
+	conn2, err := grpc.Dial(grpcAddr(plain), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn2.Close()
+	_, err = proto.NewAdminServiceClient(conn2).Resize(ctx, &proto.ResizeRequest{Capacity: 4})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected Unimplemented without admin enabled, got %v", err)
+	}
+}
+
+// TestE2ESetLimits tests changing the concurrency limit of a running node through the admin service
+func TestE2ESetLimits(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.EnableAdmin = true
+	})
+	grpcClient := dialTestServer(t, server)
+	
+	conn, err := grpc.Dial(grpcAddr(server), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	admin := proto.NewAdminServiceClient(conn)
+	
+	ctx := context.Background()
+	resp, err := admin.SetLimits(ctx, &proto.SetLimitsRequest{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+	if resp.MaxConcurrent != 1 || resp.CpuThreshold != 0.9 {
+		t.Errorf("Expected max concurrent 1 with the CPU threshold unchanged, got %d and %v", resp.MaxConcurrent, resp.CpuThreshold)
+	}
+	
+	// Hold the only slot with a request that blocks until released
+	release := make(chan struct{})
+	held := make(chan error, 1)
+	go func() {
+		_, err := server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"},
+			&grpc.UnaryServerInfo{FullMethod: "/cache.CacheService/Get"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				<-release
+				return &proto.GetResponse{}, nil
+			})
+		held <- err
+	}()
+	for atomic.LoadInt64(&server.inFlight) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	_, err = grpcClient.Get(ctx, &proto.GetRequest{Key: "key"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable beyond the new limit, got %v", err)
+	}
+	close(release)
+	if err := <-held; err != nil {
+		t.Errorf("Expected the held request to succeed, got %v", err)
+	}
+	
+	if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"}); err != nil {
+		t.Errorf("Expected Get to succeed once the slot is free, got %v", err)
+	}
+	
+	_, err = admin.SetLimits(ctx, &proto.SetLimitsRequest{MaxConcurrent: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a negative limit, got %v", err)
+	}
+}
+
+// TestE2EMaxRequestDuration tests that a handler running past MaxRequestDuration is
+// canceled and its caller gets DeadlineExceeded
+func TestE2EMaxRequestDuration(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.MaxRequestDuration = 50 * time.Millisecond
+	})
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/cache.CacheService/Get"}
+	
+	// A slow handler has its context canceled when the cap fires
+	canceled := make(chan error, 1)
+	start := time.Now()
+	_, err := server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"}, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return nil, ctx.Err()
+		})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the cap to fire after 50ms, took %v", elapsed)
+	}
+	select {
+	case err := <-canceled:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected the handler's context to hit its deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the handler's context to be canceled")
+	}
+	
+	// A handler that ignores its context still returns at the cap, keeping its slot until it finishes
+	release := make(chan struct{})
+	_, err = server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"}, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			<-release
+			return &proto.GetResponse{}, nil
+		})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded from a handler ignoring its context, got %v", err)
+	}
+	if inFlight := atomic.LoadInt64(&server.inFlight); inFlight != 1 {
+		t.Errorf("Expected the runaway handler to stay in flight, got %d", inFlight)
+	}
+	close(release)
+	for atomic.LoadInt64(&server.inFlight) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	// Fast requests are unaffected
+	if _, err := grpcClient.Set(ctx, &proto.SetRequest{Key: "key", Value: []byte("value")}); err != nil {
+		t.Errorf("Expected Set to succeed, got %v", err)
+	}
+}
+
+// TestE2EQueueTimeout tests that a burst over MaxConcurrent waits for a slot within
+// QueueTimeout instead of being rejected, and is rejected once the wait runs out
+func TestE2EQueueTimeout(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.MaxConcurrent = 2
+		config.QueueTimeout = 500 * time.Millisecond
+	})
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/cache.CacheService/Get"}
+	slow := func(d time.Duration) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			time.Sleep(d)
+			return &proto.GetResponse{}, nil
+		}
+	}
+	
+	// A burst of eight short requests against two slots is admitted in turn
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"}, info, slow(20*time.Millisecond))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected every request in the burst to be admitted, got %v", err)
+		}
+	}
+	if rejected := atomic.LoadUint64(&server.backpressureRejectedTotal); rejected != 0 {
+		t.Errorf("Expected no rejections, got %d", rejected)
+	}
+	
+	// Requests that cannot get a slot within the timeout are rejected
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"}, info,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					<-release
+					return &proto.GetResponse{}, nil
+				})
+		}()
+	}
+	for atomic.LoadInt64(&server.inFlight) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	_, err := server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"}, info, slow(0))
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable after the queue wait, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the request to wait about 500ms before rejection, took %v", elapsed)
+	}
+	
+	// A caller whose deadline passes while queued gets DeadlineExceeded, and one that
+	// cancels gets Canceled; neither is counted as backpressure
+	deadlineCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = server.unaryInterceptor(deadlineCtx, &proto.GetRequest{Key: "key"}, info, slow(0))
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded once the caller's deadline passes, got %v", err)
+	}
+	cancelCtx, cancelNow := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancelNow)
+	_, err = server.unaryInterceptor(cancelCtx, &proto.GetRequest{Key: "key"}, info, slow(0))
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("Expected Canceled once the caller cancels, got %v", err)
+	}
+	if rejected := atomic.LoadUint64(&server.backpressureRejectedTotal); rejected != 1 {
+		t.Errorf("Expected one rejection, got %d", rejected)
+	}
+	
+	// With the queue full, a request is rejected without waiting
+	server.config.MaxQueued = 1
+	queuedCtx, cancelQueued := context.WithCancel(ctx)
+	defer cancelQueued()
+	queuedErr := make(chan error, 1)
+	go func() {
+		_, err := server.unaryInterceptor(queuedCtx, &proto.GetRequest{Key: "key"}, info, slow(0))
+		queuedErr <- err
+	}()
+	for atomic.LoadInt64(&server.queued) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	start = time.Now()
+	_, err = server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key"}, info, slow(0))
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable with the queue full, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected a full queue to reject at once, took %v", elapsed)
+	}
+	if rejected := atomic.LoadUint64(&server.backpressureRejectedTotal); rejected != 2 {
+		t.Errorf("Expected two rejections, got %d", rejected)
+	}
+	cancelQueued()
+	if err := <-queuedErr; status.Code(err) != codes.Canceled {
+		t.Errorf("Expected the queued request to be canceled, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+}
+
+// TestE2ESlowThreshold tests that requests whose handler runs past SlowThreshold are
+// logged as warnings with their method, key and duration, and faster ones are not
+func TestE2ESlowThreshold(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.SlowThreshold = 50 * time.Millisecond
+	})
+	core, logs := observer.New(zap.WarnLevel)
+	server.logger = zap.New(core)
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: proto.CacheService_Get_FullMethodName}
+	slow := func(d time.Duration) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			time.Sleep(d)
+			return &proto.GetResponse{}, nil
+		}
+	}
+	
+	for i := 0; i < 5; i++ {
+		if _, err := server.unaryInterceptor(ctx, &proto.GetRequest{Key: "fast"}, info, slow(0)); err != nil {
+			t.Fatalf("Fast request failed: %v", err)
+		}
+	}
+	if _, err := server.unaryInterceptor(ctx, &proto.GetRequest{Key: "slow"}, info, slow(100*time.Millisecond)); err != nil {
+		t.Fatalf("Slow request failed: %v", err)
+	}
+	
+	entries := logs.FilterMessage("Slow request").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one slow request warning, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["method"] != proto.CacheService_Get_FullMethodName || fields["key"] != "slow" {
+		t.Errorf("Expected the slow Get of key slow, got %v", fields)
+	}
+	if duration, _ := fields["duration"].(time.Duration); duration < 100*time.Millisecond {
+		t.Errorf("Expected a duration of at least 100ms, got %v", fields["duration"])
+	}
+}
+
+// TestReloadConfig tests that a reload applies the runtime limits and ignores other settings
+func TestReloadConfig(t *testing.T) {
+	var server *Server
+	server = startTestServer(t, func(config *Config) {
+		config.ReloadConfig = func() (*Config, error) {
+			reloaded := *server.config
+			reloaded.GRPCPort++
+			reloaded.MaxConcurrent = 7
+			reloaded.CPUThreshold = 0.5
+			reloaded.CleanupInterval = 10 * time.Millisecond
+			return &reloaded, nil
+		}
+	})
+	port := server.config.GRPCPort
+	
+	// Cleanup starts out disabled
+	server.cache.Set("expiring", []byte("value"), time.Millisecond)
+	
+	server.reloadFromSource()
+	if limit := server.concurrencyLimit(); limit != 7 {
+		t.Errorf("Expected max concurrent 7 after reload, got %d", limit)
+	}
+	if limits := server.runtimeLimits(); limits.CPUThreshold != 0.5 || limits.CleanupInterval != 10*time.Millisecond {
+		t.Errorf("Expected reloaded CPU threshold and cleanup interval, got %v and %v", limits.CPUThreshold, limits.CleanupInterval)
+	}
+	if server.config.GRPCPort != port {
+		t.Errorf("Expected the gRPC port to be left at %d, got %d", port, server.config.GRPCPort)
+	}
+	
+	deadline := time.Now().Add(time.Second)
+	for server.cache.Size() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if server.cache.Size() != 0 {
+		t.Error("Expected the reloaded cleanup interval to purge the expired entry")
+	}
+}
+
+// TestE2EReadOnlyMode tests that read-only and draining nodes reject writes but keep serving reads
+func TestE2EReadOnlyMode(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.EnableAdmin = true
+	})
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	conn, err := grpc.Dial(grpcAddr(server), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	admin := proto.NewAdminServiceClient(conn)
+	
+	if _, err := grpcClient.Set(ctx, &proto.SetRequest{Key: "key", Value: []byte("value")}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	resp, err := admin.SetMode(ctx, &proto.SetModeRequest{Mode: "read-only"})
+	if err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	if resp.Previous != "normal" || resp.Mode != "read-only" {
+		t.Errorf("Expected normal -> read-only, got %s -> %s", resp.Previous, resp.Mode)
+	}
+	
+	_, err = grpcClient.Set(ctx, &proto.SetRequest{Key: "key", Value: []byte("new-value")})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for Set, got %v", err)
+	}
+	_, err = grpcClient.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for Delete, got %v", err)
+	}
+	
+	getResp, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !getResp.Found || string(getResp.Value) != "value" {
+		t.Errorf("Expected original value to still be served, got %q", getResp.Value)
+	}
+	
+	health, err := grpcClient.Health(ctx, &proto.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if !health.Healthy || health.Mode != "read-only" {
+		t.Errorf("Expected healthy read-only node, got healthy=%v mode=%s", health.Healthy, health.Mode)
+	}
+	
+	// Draining nodes also report themselves unhealthy
+	if _, err := admin.SetMode(ctx, &proto.SetModeRequest{Mode: "draining"}); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	health, err = grpcClient.Health(ctx, &proto.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if health.Healthy || health.Mode != "draining" {
+		t.Errorf("Expected unhealthy draining node, got healthy=%v mode=%s", health.Healthy, health.Mode)
+	}
+	
+	_, err = admin.SetMode(ctx, &proto.SetModeRequest{Mode: "bogus"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for unknown mode, got %v", err)
+	}
+	
+	// Writes resume once back in normal mode
+	if _, err := admin.SetMode(ctx, &proto.SetModeRequest{Mode: "normal"}); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	if _, err := grpcClient.Set(ctx, &proto.SetRequest{Key: "key", Value: []byte("new-value")}); err != nil {
+		t.Errorf("Expected Set to succeed in normal mode, got %v", err)
+	}
+}
+
+// TestE2EHealthLoad tests that Health reports the node's load, requests in flight and
+// shedding state, and keeps answering while the node rejects other requests
+func TestE2EHealthLoad(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.CacheCapacity = 10
+		config.MaxConcurrent = 3
+	})
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	health, err := grpcClient.Health(ctx, &proto.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if health.Load != 0 || health.Shedding || health.InFlight != 0 {
+		t.Errorf("Expected an idle node, got load=%v shedding=%v in_flight=%d", health.Load, health.Shedding, health.InFlight)
+	}
+	
+	// Half fill the cache and take every concurrency slot
+	for i := 0; i < 5; i++ {
+		server.cache.Set(fmt.Sprintf("key%d", i), []byte("value"), 0)
+	}
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	info := &grpc.UnaryServerInfo{FullMethod: proto.CacheService_Get_FullMethodName}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.unaryInterceptor(ctx, &proto.GetRequest{Key: "key0"}, info,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					<-release
+					return &proto.GetResponse{}, nil
+				})
+		}()
+	}
+	for atomic.LoadInt64(&server.inFlight) != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	
+	if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key0"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected a saturated node to reject Get, got %v", err)
+	}
+	health, err = grpcClient.Health(ctx, &proto.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Expected Health to answer while saturated, got %v", err)
+	}
+	if health.Load != 0.5 || health.InFlight != 3 || health.Shedding {
+		t.Errorf("Expected load 0.5 with 3 in flight, got load=%v shedding=%v in_flight=%d", health.Load, health.Shedding, health.InFlight)
+	}
+	close(release)
+	wg.Wait()
+	
+	// CPU over the threshold sheds load, which Health reports rather than suffers
+	server.recordLoad(1, time.Second)
+	if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key0"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected a shedding node to reject Get, got %v", err)
+	}
+	health, err = grpcClient.Health(ctx, &proto.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Expected Health to answer while shedding, got %v", err)
+	}
+	if !health.Shedding || health.InFlight != 0 || !health.Healthy {
+		t.Errorf("Expected a healthy node shedding load, got healthy=%v shedding=%v in_flight=%d", health.Healthy, health.Shedding, health.InFlight)
+	}
+	
+	server.recordLoad(0, time.Second)
+	if health, err = grpcClient.Health(ctx, &proto.HealthRequest{}); err != nil || health.Shedding {
+		t.Errorf("Expected shedding to stop once CPU falls, got %v (%v)", health, err)
+	}
+	
+	// Health calls are counted and timed like other requests
+	before := atomic.LoadUint64(&server.requestsTotal)
+	if _, err := grpcClient.Health(ctx, &proto.HealthRequest{}); err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if requests := atomic.LoadUint64(&server.requestsTotal) - before; requests != 1 {
+		t.Errorf("Expected Health to be counted as a request, got %d", requests)
+	}
+	recorder := httptest.NewRecorder()
+	server.metrics.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics/prometheus", nil))
+	if !strings.Contains(recorder.Body.String(), `method="/cache.CacheService/Health"`) {
+		t.Error("Expected Health to be timed in the request duration histogram")
+	}
+}
+
+// TestE2EClientAvoidsSheddingNode tests that the client reads the load a node reports
+// to its pings and routes reads away from a node shedding load
+func TestE2EClientAvoidsSheddingNode(t *testing.T) {
+	servers := []*Server{startTestServer(t, nil), startTestServer(t, nil)}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	key := keyOwnedBy(t, "node0", "node0", "node1")
+	if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	servers[0].recordLoad(1, time.Second)
+	if _, err := c.Ping(ctx, "node0"); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if load, ok := c.NodeLoad("node0"); !ok || !load.Shedding {
+		t.Fatalf("Expected node0 to be reported shedding, got %+v (%v)", load, ok)
+	}
+	
+	before := atomic.LoadUint64(&servers[0].loadShedTotal)
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "value" {
+		t.Fatalf("Expected the read to succeed, got %q (%v)", value, err)
+	}
+	if shed := atomic.LoadUint64(&servers[0].loadShedTotal) - before; shed != 0 {
+		t.Errorf("Expected the read to skip the shedding primary, got %d rejections", shed)
+	}
+	
+	servers[0].recordLoad(0, time.Second)
+	if _, err := c.Ping(ctx, "node0"); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if load, _ := c.NodeLoad("node0"); load.Shedding {
+		t.Error("Expected node0 to stop being reported shedding")
+	}
+}
+
+// TestE2EStandardHealth tests that the grpc.health.v1 service follows draining and shutdown
+func TestE2EStandardHealth(t *testing.T) {
+	server := startTestServer(t, nil)
+	
+	conn, err := grpc.Dial(grpcAddr(server), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	healthClient := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+	
+	check := func(want healthpb.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		for _, service := range []string{"", "cache.CacheService"} {
+			resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatalf("Check(%q) failed: %v", service, err)
+			}
+			if resp.Status != want {
+				t.Errorf("Expected %q to be %v, got %v", service, want, resp.Status)
+			}
+		}
+	}
+	
+	check(healthpb.HealthCheckResponse_SERVING)
+	server.setMode(ModeDraining)
+	check(healthpb.HealthCheckResponse_NOT_SERVING)
+	server.setMode(ModeNormal)
+	check(healthpb.HealthCheckResponse_SERVING)
+	
+	// Watchers see the node stop serving as soon as shutdown begins
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := healthClient.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if resp, err := stream.Recv(); err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Expected SERVING before shutdown, got %v (%v)", resp, err)
+	}
+	
+	done := make(chan struct{})
+	go func() {
+		server.shutdown()
+		close(done)
+	}()
+	if resp, err := stream.Recv(); err != nil || resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Expected NOT_SERVING during shutdown, got %v (%v)", resp, err)
+	}
+	
+	// The open watch holds the graceful stop until it ends
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for shutdown")
+	}
+}
+
+// TestE2ENamespaceIsolation tests that namespaced clients sharing a node do not see each other's keys
+func TestE2ENamespaceIsolation(t *testing.T) {
+	server := startTestServer(t, nil)
+	ctx := context.Background()
+	
+	orders := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1, Namespace: "orders"}, server)
+	users := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1, Namespace: "users"}, server)
+	
+	if err := orders.Set(ctx, "id-1", []byte("order"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := users.Set(ctx, "id-1", []byte("user"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	value, err := orders.Get(ctx, "id-1")
+	if err != nil || string(value) != "order" {
+		t.Errorf("Expected orders to read its own value, got %q (%v)", value, err)
+	}
+	value, err = users.Get(ctx, "id-1")
+	if err != nil || string(value) != "user" {
+		t.Errorf("Expected users to read its own value, got %q (%v)", value, err)
+	}
+	
+	// Keys are prefixed on the node, not stored under the raw key
+	if _, exists := server.cache.Get("id-1"); exists {
+		t.Error("Expected raw key to be absent on the node")
+	}
+	if server.cache.Size() != 2 {
+		t.Errorf("Expected 2 entries on the node, got %d", server.cache.Size())
+	}
+	
+	// Deleting in one namespace leaves the other untouched
+	if err := orders.Delete(ctx, "id-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := orders.Get(ctx, "id-1"); err == nil {
+		t.Error("Expected deleted key to be gone from orders")
+	}
+	if value, err := users.Get(ctx, "id-1"); err != nil || string(value) != "user" {
+		t.Errorf("Expected users value to survive, got %q (%v)", value, err)
+	}
+	
+	if _, err := client.NewClient(&client.Config{Namespace: "bad:ns"}); err == nil {
+		t.Error("Expected namespace containing the separator to be rejected")
+	}
+}
+
+// TestLoadEMA tests that the smoothed request rate rises with a spike and decays afterwards
+func TestLoadEMA(t *testing.T) {
+	s := &Server{config: &Config{}, cache: cache.NewCache(10), cpuWindow: 10 * time.Second, emaAlpha: 0.5}
+	
+	// Synthetic spike of 1000 requests in one second
+	atomic.AddUint64(&s.requestsTotal, 1000)
+	s.recordLoad(0.8, time.Second)
+	
+	cpu, rate := s.loadAverages()
+	if rate != 500 || cpu != 0.4 {
+		t.Fatalf("Expected rate 500 and cpu 0.4 after spike, got %v and %v", rate, cpu)
+	}
+	
+	// The averages decay while the node is idle
+	previous := rate
+	for i := 0; i < 10; i++ {
+		s.recordLoad(0, time.Second)
+		_, rate = s.loadAverages()
+		if rate >= previous {
+			t.Fatalf("Expected rate to decay, got %v after %v", rate, previous)
+		}
+		previous = rate
+	}
+	if rate > 1 {
+		t.Errorf("Expected rate to have mostly decayed, got %v", rate)
+	}
+}
+
+// TestEvictionRateWarning tests that a cache evicting faster than the threshold logs
+// a warning at most once per interval
+func TestEvictionRateWarning(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	s := &Server{
+		config: &Config{
+			CacheCapacity:        10,
+			EvictionWarnRate:     100,
+			EvictionWarnInterval: 200 * time.Millisecond,
+		},
+		cache:     cache.NewCache(10),
+		logger:    zap.New(core),
+		cpuWindow: 10 * time.Second,
+		emaAlpha:  1,
+	}
+	
+	next := 0
+	evict := func(n int) {
+		for i := 0; i < n; i++ {
+			s.cache.Set(fmt.Sprintf("key-%d", next), []byte("value"), 0)
+			next++
+		}
+	}
+	warnings := func() int {
+		return logs.FilterMessageSnippet("evicting rapidly").Len()
+	}
+	
+	// Sustained eviction far over the threshold warns once within the interval
+	evict(10)
+	for i := 0; i < 5; i++ {
+		evict(1000)
+		s.recordLoad(0, time.Second)
+	}
+	if n := warnings(); n != 1 {
+		t.Fatalf("Expected one warning within the interval, got %d", n)
+	}
+	if rate := s.evictionRate(); rate != 1000 {
+		t.Errorf("Expected eviction rate of 1000/s, got %v", rate)
+	}
+	if evictions := s.cache.StatsSnapshot().Evictions; evictions != 5000 {
+		t.Errorf("Expected 5000 evictions counted, got %v", evictions)
+	}
+	
+	// Once the interval has passed the next sample over the threshold warns again
+	time.Sleep(250 * time.Millisecond)
+	evict(1000)
+	s.recordLoad(0, time.Second)
+	s.recordLoad(0, time.Second)
+	if n := warnings(); n != 2 {
+		t.Fatalf("Expected a second warning after the interval, got %d", n)
+	}
+	
+	// A rate under the threshold does not warn
+	time.Sleep(250 * time.Millisecond)
+	evict(50)
+	s.recordLoad(0, time.Second)
+	if n := warnings(); n != 2 {
+		t.Errorf("Expected no warning under the threshold, got %d warnings", n)
+	}
+}
+
+// TestPinnedCacheWarning tests that a cache whose capacity is all pinned is warned
+// about once per interval
+func TestPinnedCacheWarning(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	s := &Server{
+		config:    &Config{EvictionWarnInterval: time.Hour},
+		cache:     cache.NewCache(2),
+		logger:    zap.New(core),
+		cpuWindow: 10 * time.Second,
+		emaAlpha:  1,
+	}
+	warnings := func() int {
+		return logs.FilterMessageSnippet("Pinned entries fill the cache").Len()
+	}
+	
+	s.cache.Set("a", []byte("value"), 0)
+	s.cache.Set("b", []byte("value"), 0)
+	s.cache.Pin("a")
+	s.recordLoad(0, time.Second)
+	if n := warnings(); n != 0 {
+		t.Fatalf("Expected no warning while an entry is evictable, got %d", n)
+	}
+	
+	s.cache.Pin("b")
+	s.recordLoad(0, time.Second)
+	s.recordLoad(0, time.Second)
+	if n := warnings(); n != 1 {
+		t.Errorf("Expected one warning within the interval once fully pinned, got %d", n)
+	}
+}
+
+// TestStatsHistory tests that sampled cache statistics fill the history buffer, wrap
+// around once it is full, and are served oldest first by /metrics/history
+func TestStatsHistory(t *testing.T) {
+	s := &Server{cache: cache.NewCache(2), history: newStatsHistory(4)}
+	start := time.Unix(1000, 0)
+	
+	// Each tick adds one key, reads it once and misses once, evicting from the third on
+	tick := func(i int) {
+		key := fmt.Sprintf("key%d", i)
+		s.cache.Set(key, []byte("value"), 0)
+		s.cache.Get(key)
+		s.cache.Get("missing")
+		s.sampleStats(start.Add(time.Duration(i) * time.Second))
+	}
+	
+	for i := 0; i < 3; i++ {
+		tick(i)
+	}
+	samples := s.history.snapshot()
+	if len(samples) != 3 {
+		t.Fatalf("Expected 3 samples before the buffer fills, got %d", len(samples))
+	}
+	if samples[0].Size != 1 || samples[2].Size != 2 || samples[1].Evictions != 0 || samples[2].Evictions != 1 {
+		t.Errorf("Unexpected samples %+v", samples)
+	}
+	
+	for i := 3; i < 7; i++ {
+		tick(i)
+	}
+	samples = s.history.snapshot()
+	if len(samples) != 4 {
+		t.Fatalf("Expected the buffer to hold 4 samples, got %d", len(samples))
+	}
+	for i, sample := range samples {
+		if want := start.Add(time.Duration(i+3) * time.Second); !sample.Time.Equal(want) {
+			t.Errorf("Expected sample %d from %v, got %v", i, want, sample.Time)
+		}
+		if sample.Hits != 1 || sample.Misses != 1 || sample.HitRatio != 0.5 || sample.Evictions != 1 {
+			t.Errorf("Expected per-interval counts in sample %d, got %+v", i, sample)
+		}
+	}
+	
+	recorder := httptest.NewRecorder()
+	s.statsHistoryHandler(recorder, httptest.NewRequest(http.MethodGet, "/metrics/history", nil))
+	var served []statsSample
+	if err := json.NewDecoder(recorder.Body).Decode(&served); err != nil {
+		t.Fatalf("Failed to decode history: %v", err)
+	}
+	if len(served) != 4 || !served[0].Time.Equal(samples[0].Time) || served[3].Size != 2 {
+		t.Errorf("Expected the served history to match, got %+v", served)
+	}
+	
+	// Counters reset between ticks count from zero rather than wrapping around
+	s.cache.ResetStats()
+	tick(7)
+	samples = s.history.snapshot()
+	if last := samples[len(samples)-1]; last.Hits != 1 || last.Misses != 1 || last.Evictions != 1 {
+		t.Errorf("Expected the counts since the reset, got %+v", last)
+	}
+}
+
+// fetchMetrics reads and decodes a test server's /metrics endpoint
+func fetchMetrics(t *testing.T, s *Server) map[string]interface{} {
+	t.Helper()
+	
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/metrics", s.config.HTTPPort))
+	if err != nil {
+		t.Fatalf("Metrics request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	
+	var metrics map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&metrics); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	return metrics
+}
+
+func TestE2EInternalTimings(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.LockTiming = true
+	})
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1, TimeRouting: true}, server)
+	ctx := context.Background()
+	
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	
+	stats := c.GetStats()
+	if lookups := stats["owner_lookups"].(uint64); lookups < 20 {
+		t.Errorf("Expected at least 20 timed owner lookups, got %d", lookups)
+	}
+	if total := stats["owner_time"].(time.Duration); total <= 0 {
+		t.Errorf("Expected a positive owner lookup time, got %v", total)
+	}
+	
+	metrics := fetchMetrics(t, server)
+	if waits := metrics["cache_lock_waits"].(float64); waits < 20 {
+		t.Errorf("Expected at least 20 timed cache lock waits, got %v", waits)
+	}
+	if wait := metrics["cache_lock_wait_ns"].(float64); wait <= 0 {
+		t.Errorf("Expected a positive cache lock wait, got %v", wait)
+	}
+}
+
+// TestE2ETenantMetrics tests the per-tenant breakdown in /metrics, with tenants beyond
+// MaxTenants and keys without a prefix counted as other
+func TestE2ETenantMetrics(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.TenantLabel = TenantPrefix(":")
+		config.MaxTenants = 2
+	})
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	for _, key := range []string{"acme:1", "globex:1", "initech:1", "unprefixed"} {
+		if _, err := grpcClient.Set(ctx, &proto.SetRequest{Key: key, Value: []byte("value")}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	for _, key := range []string{"acme:1", "acme:2", "globex:1", "initech:1", "unprefixed"} {
+		if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: key}); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	
+	tenants := fetchMetrics(t, server)["tenants"].(map[string]interface{})
+	if len(tenants) != 3 {
+		t.Errorf("Expected 3 tenant labels, got %v", tenants)
+	}
+	expected := map[string]map[string]float64{
+		"acme":   {"requests": 3, "hits": 1, "misses": 1},
+		"globex": {"requests": 2, "hits": 1, "misses": 0},
+		"other":  {"requests": 4, "hits": 2, "misses": 0},
+	}
+	for label, want := range expected {
+		got, ok := tenants[label].(map[string]interface{})
+		if !ok {
+			t.Errorf("Expected metrics for tenant %q", label)
+			continue
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("Expected %s %s to be %v, got %v", label, name, value, got[name])
+			}
+		}
+	}
+}
+
+// TestE2EStats tests the Stats RPC and the smoothed load in /metrics
+func TestE2EStats(t *testing.T) {
+	server := startTestServer(t, nil)
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	for i := 0; i < 5; i++ {
+		if _, err := grpcClient.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%d", i), Value: []byte("value")}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	server.recordLoad(0.5, time.Second)
+	
+	stats, err := grpcClient.Stats(ctx, &proto.StatsRequest{})
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.CacheSize != 5 || stats.CacheCapacity != 1000 {
+		t.Errorf("Expected 5 of 1000 entries, got %d of %d", stats.CacheSize, stats.CacheCapacity)
+	}
+	if stats.RequestsTotal < 6 {
+		t.Errorf("Expected at least 6 requests counted, got %d", stats.RequestsTotal)
+	}
+	if stats.RequestRateEma <= 0 || stats.CpuEma <= 0 {
+		t.Errorf("Expected positive load averages, got rate %v cpu %v", stats.RequestRateEma, stats.CpuEma)
+	}
+	
+	metrics := fetchMetrics(t, server)
+	for _, field := range []string{"request_rate_ema", "cpu_ema", "requests_total"} {
+		if _, exists := metrics[field]; !exists {
+			t.Errorf("Expected %s in metrics", field)
+		}
+	}
+}
+
+// TestE2EDump tests streaming every live entry out of a node
+func TestE2EDump(t *testing.T) {
+	server := startTestServer(t, nil)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	ctx := context.Background()
+	
+	for i := 0; i < 50; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)), time.Hour); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	
+	// Expired entries and tombstones are not dumped
+	server.cache.Set("expired", []byte("value"), time.Nanosecond)
+	if err := c.Delete(ctx, "key-0"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	
+	entries, err := c.Dump(ctx, "node0")
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	
+	seen := make(map[string]bool)
+	for entry := range entries {
+		if seen[entry.Key] {
+			t.Errorf("Key %s dumped twice", entry.Key)
+		}
+		seen[entry.Key] = true
+		
+		if entry.Key == "expired" || entry.Key == "key-0" {
+			t.Errorf("Expected %s to be skipped", entry.Key)
+		}
+		if entry.TTL <= 0 || entry.TTL > time.Hour {
+			t.Errorf("Expected remaining TTL within an hour for %s, got %v", entry.Key, entry.TTL)
+		}
+		if entry.Version == 0 {
+			t.Errorf("Expected version for %s", entry.Key)
+		}
+	}
+	if len(seen) != 49 {
+		t.Errorf("Expected 49 dumped entries, got %d", len(seen))
+	}
+	
+	if _, err := c.Dump(ctx, "missing"); err == nil {
+		t.Error("Expected error dumping an unknown node")
+	}
+}
+
+// TestE2EClusterStats tests aggregating stats across nodes, with one node unreachable
+func TestE2EClusterStats(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, func(config *Config) { config.CacheCapacity = 100 }),
+		startTestServer(t, func(config *Config) { config.CacheCapacity = 200 }),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, servers...)
+	ctx := context.Background()
+	
+	servers[0].cache.Set("a", []byte("value"), 0)
+	servers[0].cache.Set("b", []byte("value"), 0)
+	servers[1].cache.Set("c", []byte("value"), 0)
+	servers[0].cache.Get("a")
+	servers[1].cache.Get("missing")
+	
+	// A node nobody listens on reports an error without failing the call
+	if err := c.AddNode("down", fmt.Sprintf("localhost:%d", freePort(t))); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	
+	stats, err := c.ClusterStats(ctx)
+	if err != nil {
+		t.Fatalf("ClusterStats failed: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("Expected stats for 3 nodes, got %d", len(stats))
+	}
+	if stats["down"].Err == nil {
+		t.Error("Expected an error for the unreachable node")
+	}
+	if stats["node0"].Size != 2 || stats["node1"].Capacity != 200 {
+		t.Errorf("Unexpected per-node stats: %+v %+v", stats["node0"], stats["node1"])
+	}
+	
+	summary := client.Summarize(stats)
+	if summary.Nodes != 2 || summary.Failed != 1 {
+		t.Errorf("Expected 2 responding and 1 failed node, got %d and %d", summary.Nodes, summary.Failed)
+	}
+	if summary.TotalSize != 3 || summary.Hits != 1 || summary.Misses != 1 {
+		t.Errorf("Expected size 3 with 1 hit and 1 miss, got %+v", summary)
+	}
+	if want := (0.02 + 0.005) / 2; summary.AverageLoad != want {
+		t.Errorf("Expected average load %v, got %v", want, summary.AverageLoad)
+	}
+}
+
+// TestE2EMessageSizeLimits tests that values just under the configured gRPC message
+// limits are stored while larger ones fail with ResourceExhausted on either side
+func TestE2EMessageSizeLimits(t *testing.T) {
+	const limit = 1 << 20
+	server := startTestServer(t, func(config *Config) {
+		config.MaxRecvMsgSize = limit
+	})
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	ctx := context.Background()
+	
+	// A value leaving room for the rest of the request fits
+	near := bytes.Repeat([]byte("v"), limit-1024)
+	if err := c.Set(ctx, "near", near, 0); err != nil {
+		t.Fatalf("Expected a value under the limit to be stored, got %v", err)
+	}
+	if value, err := c.Get(ctx, "near"); err != nil || !bytes.Equal(value, near) {
+		t.Fatalf("Expected the value under the limit back, got %d bytes (%v)", len(value), err)
+	}
+	
+	// The server rejects a request over its limit
+	over := bytes.Repeat([]byte("v"), limit+1)
+	if err := c.Set(ctx, "over", over, 0); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted from the server, got %v", err)
+	}
+	if _, found := server.cache.Get("over"); found {
+		t.Error("Expected the value over the limit not to be stored")
+	}
+	
+	// The client refuses to send past its own limit
+	small := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1, MaxSendMsgSize: limit / 2}, server)
+	before := atomic.LoadUint64(&server.requestsTotal)
+	if err := small.Set(ctx, "over", near, 0); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted from the client, got %v", err)
+	}
+	if requests := atomic.LoadUint64(&server.requestsTotal) - before; requests != 0 {
+		t.Errorf("Expected the oversized request not to reach the server, got %d requests", requests)
+	}
+}
+
+// TestE2EDynamicQuorum tests that under DynamicQuorum writes keep succeeding once the
+// cluster shrinks below the configured quorum, where a fixed quorum fails
+func TestE2EDynamicQuorum(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	fixed := newTestClient(t, &client.Config{ReadQuorum: 3, WriteQuorum: 3}, servers...)
+	dynamic := newTestClient(t, &client.Config{ReadQuorum: 3, WriteQuorum: 3, DynamicQuorum: true}, servers...)
+	ctx := context.Background()
+	
+	if err := dynamic.Set(ctx, "key", []byte("v1"), 0); err != nil {
+		t.Fatalf("Set failed with 3 nodes: %v", err)
+	}
+	
+	// Shrink from 3 to 2 nodes
+	servers[2].grpcServer.Stop()
+	fixed.RemoveNode("node2")
+	dynamic.RemoveNode("node2")
+	
+	if err := fixed.Set(ctx, "key", []byte("v2"), 0); !errors.Is(err, client.ErrInsufficientNodes) {
+		t.Errorf("Expected a fixed quorum of 3 to fail on 2 nodes, got %v", err)
+	}
+	if err := dynamic.Set(ctx, "key", []byte("v2"), 0); err != nil {
+		t.Fatalf("Expected dynamic quorum Set to succeed on 2 nodes, got %v", err)
+	}
+	value, err := dynamic.Get(ctx, "key")
+	if err != nil || string(value) != "v2" {
+		t.Errorf("Expected v2 from the remaining nodes, got %q, %v", value, err)
+	}
+	for _, s := range servers[:2] {
+		if value, found := s.cache.Get("key"); !found || string(value) != "v2" {
+			t.Errorf("Expected both remaining nodes to hold v2, got %q", value)
+		}
+	}
+}
+
+// TestE2ECompression tests that a client configured with the gzip compressor talks to
+// the server compressed both ways, sending far fewer bytes for a compressible value
+func TestE2ECompression(t *testing.T) {
+	server := startTestServer(t, nil)
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("shard-cache "), 1<<16)
+	
+	if _, err := client.NewClient(&client.Config{ReadQuorum: 1, WriteQuorum: 1, Compressor: "zstd"}); err == nil {
+		t.Error("Expected an unregistered compressor to be rejected")
+	}
+	
+	// Route one client through a proxy per compressor to count the bytes on the wire
+	wire := make(map[string]*proxyCounts)
+	for _, compressor := range []string{"", "gzip"} {
+		addr, counts := countingProxy(t, grpcAddr(server))
+		c, err := client.NewClient(&client.Config{ReadQuorum: 1, WriteQuorum: 1, Compressor: compressor})
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		if err := c.AddNode("node0", addr); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+		
+		key := "large-" + compressor
+		if err := c.Set(ctx, key, value, 0); err != nil {
+			t.Fatalf("Set with compressor %q failed: %v", compressor, err)
+		}
+		got, err := c.Get(ctx, key)
+		if err != nil || !bytes.Equal(got, value) {
+			t.Fatalf("Get with compressor %q returned %d bytes, %v", compressor, len(got), err)
+		}
+		wire[compressor] = counts
+	}
+	
+	plain, gzipped := wire[""], wire["gzip"]
+	t.Logf("Sent %d bytes plain and %d gzipped, received %d plain and %d gzipped",
+		plain.sent.Load(), gzipped.sent.Load(), plain.received.Load(), gzipped.received.Load())
+	if plain.sent.Load() < int64(len(value)) || plain.received.Load() < int64(len(value)) {
+		t.Errorf("Expected the uncompressed value to cross the wire both ways, sent %d and received %d",
+			plain.sent.Load(), plain.received.Load())
+	}
+	if gzipped.sent.Load()*10 > plain.sent.Load() || gzipped.received.Load()*10 > plain.received.Load() {
+		t.Errorf("Expected gzip to cut the bytes on the wire tenfold, sent %d and received %d",
+			gzipped.sent.Load(), gzipped.received.Load())
+	}
+}
+
+// proxyCounts holds the bytes a countingProxy has forwarded in each direction
+type proxyCounts struct {
+	sent     atomic.Int64 // From the client to the server
+	received atomic.Int64 // From the server to the client
+}
+
+// countingWriter counts the bytes written through it. Bytes are counted before they
+// are passed on, so the count is complete by the time the peer can read them.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+// Write counts p and writes it to the underlying writer
+func (c countingWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return c.w.Write(p)
+}
+
+// countingProxy forwards TCP connections to addr until the test ends, counting the
+// bytes that pass in each direction, and returns the address it listens on
+func countingProxy(t *testing.T, addr string) (string, *proxyCounts) {
+	t.Helper()
+	
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	
+	counts := &proxyCounts{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			go func() {
+				io.Copy(countingWriter{upstream, &counts.sent}, conn)
+				upstream.Close()
+			}()
+			go func() {
+				io.Copy(countingWriter{conn, &counts.received}, upstream)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String(), counts
+}
+
+// TestE2EPrometheusRequestDuration tests that unary RPCs are observed in the request
+// duration histogram by method, and that it is served in the Prometheus format
+func TestE2EPrometheusRequestDuration(t *testing.T) {
+	server := startTestServer(t, nil)
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	if _, err := grpcClient.Set(ctx, &proto.SetRequest{Key: "key", Value: []byte("value")}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"}); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/metrics/prometheus", server.config.HTTPPort))
+	if err != nil {
+		t.Fatalf("GET /metrics/prometheus failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	metrics := string(body)
+	
+	for _, want := range []string{
+		`shardcache_request_duration_seconds_count{method="/cache.CacheService/Get"} 3`,
+		`shardcache_request_duration_seconds_count{method="/cache.CacheService/Set"} 1`,
+		`shardcache_request_duration_seconds_bucket{method="/cache.CacheService/Get",le="10"} 3`,
+		`shardcache_request_duration_seconds_bucket{method="/cache.CacheService/Get",le="0.0001"}`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("Expected the metrics to contain %s, got:\n%s", want, metrics)
+		}
+	}
+	
+	// Requests rejected before reaching their handler are not observed
+	server.setMode(ModeReadOnly)
+	grpcClient.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+	if count := testutil.CollectAndCount(server.metrics.duration); count != 2 {
+		t.Errorf("Expected histograms for Get and Set only, got %d", count)
+	}
+}
+
+// TestE2ELogLevel tests that the log level can be read and changed over HTTP on an
+// admin server, and that the endpoint is absent otherwise
+func TestE2ELogLevel(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.EnableAdmin = true
+	})
+	url := fmt.Sprintf("http://localhost:%d/loglevel", server.config.HTTPPort)
+	
+	level := func() string {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GET /loglevel failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body struct{ Level string }
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode level: %v", err)
+		}
+		return body.Level
+	}
+	if got := level(); got != "info" {
+		t.Errorf("Expected the production level info, got %q", got)
+	}
+	if server.logger.Core().Enabled(zap.DebugLevel) {
+		t.Fatal("Expected debug logs to be disabled by default")
+	}
+	
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(`{"level":"debug"}`))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /loglevel failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 from PUT /loglevel, got %d", resp.StatusCode)
+	}
+	if got := level(); got != "debug" {
+		t.Errorf("Expected level debug after PUT, got %q", got)
+	}
+	if server.logger.Check(zap.DebugLevel, "debug") == nil {
+		t.Error("Expected debug logs to be emitted after switching to debug")
+	}
+	
+	// Without EnableAdmin, or with an injected logger, there is no endpoint
+	for _, configure := range []func(*Config){
+		nil,
+		func(config *Config) {
+			config.EnableAdmin = true
+			config.Logger = zap.NewNop()
+		},
+	} {
+		other := startTestServer(t, configure)
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%d/loglevel", other.config.HTTPPort))
+		if err != nil {
+			t.Fatalf("GET /loglevel failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 without a settable level, got %d", resp.StatusCode)
+		}
+	}
+}
+
+// TestE2EInjectedLogger tests that a server logs to the logger in its config instead
+// of creating its own
+func TestE2EInjectedLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	server := startTestServer(t, func(config *Config) {
+		config.Logger = zap.New(core)
+	})
+	
+	if err := server.Reload(server.config); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if logs.FilterMessage("Configuration reloaded").Len() != 1 {
+		t.Errorf("Expected the reload to be logged to the injected logger, got %v", logs.All())
+	}
+}
+
+// TestE2EListenAddr tests that a server bound to loopback serves local connections but
+// not those to the host's other addresses, and that invalid addresses are rejected
+func TestE2EListenAddr(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.GRPCAddr = fmt.Sprintf("127.0.0.1:%d", config.GRPCPort)
+		config.HTTPAddr = fmt.Sprintf("127.0.0.1:%d", config.HTTPPort)
+	})
+	
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err := c.AddNode("node0", server.config.GRPCAddr); err != nil {
+		t.Fatalf("Failed to add node: %v", err)
+	}
+	if err := c.Set(context.Background(), "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Expected Set over loopback to succeed, got %v", err)
+	}
+	resp, err := http.Get("http://" + server.config.HTTPAddr + "/health")
+	if err != nil {
+		t.Fatalf("Expected /health over loopback to answer, got %v", err)
+	}
+	resp.Body.Close()
+	
+	// Neither port accepts connections on a non-loopback address
+	var external net.IP
+	addrs, _ := net.InterfaceAddrs()
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+			external = ipnet.IP
+			break
+		}
+	}
+	if external == nil {
+		t.Log("No non-loopback address to check against")
+	} else {
+		for _, port := range []int{server.config.GRPCPort, server.config.HTTPPort} {
+			addr := net.JoinHostPort(external.String(), strconv.Itoa(port))
+			if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+				conn.Close()
+				t.Errorf("Expected %s to refuse connections", addr)
+			}
+		}
+	}
+	
+	for _, addr := range []string{"127.0.0.1", "127.0.0.1:http-ish", "127.0.0.1:70000", "no-such-host.invalid:8080"} {
+		if _, err := NewServer(&Config{GRPCAddr: addr, CacheCapacity: 10, MaxConcurrent: 10}); err == nil {
+			t.Errorf("Expected gRPC address %q to be rejected", addr)
+		}
+	}
+}
+
+// TestE2EBinarySafeKeys tests that keys with null bytes and multibyte UTF-8 round-trip
+// and that empty, oversized and invalid UTF-8 keys are rejected by client and server
+func TestE2EBinarySafeKeys(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.MaxKeyBytes = 64
+	})
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	keys := []string{"a\x00b", "a\x00c", "\x00", "ключ", "键🔑", "a"}
+	for i, key := range keys {
+		if err := c.Set(ctx, key, []byte(fmt.Sprintf("value-%d", i)), 0); err != nil {
+			t.Fatalf("Set %q failed: %v", key, err)
+		}
+	}
+	for i, key := range keys {
+		value, err := c.Get(ctx, key)
+		if err != nil || string(value) != fmt.Sprintf("value-%d", i) {
+			t.Errorf("Expected %q to round-trip, got %q (%v)", key, value, err)
+		}
+	}
+	
+	// Empty values are present, not missing
+	if err := c.Set(ctx, "empty-value", nil, 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, err := c.Get(ctx, "empty-value"); err != nil || len(value) != 0 {
+		t.Errorf("Expected empty value to be found, got %q (%v)", value, err)
+	}
+	
+	// The client rejects invalid keys before sending them
+	for _, key := range []string{"", "\xff\xfe"} {
+		if err := c.Set(ctx, key, []byte("value"), 0); !errors.Is(err, client.ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+	
+	// The server enforces its own limit
+	long := strings.Repeat("k", 65)
+	for name, call := range map[string]func() error{
+		"get": func() error {
+			_, err := grpcClient.Get(ctx, &proto.GetRequest{Key: long})
+			return err
+		},
+		"set": func() error {
+			_, err := grpcClient.Set(ctx, &proto.SetRequest{Key: long, Value: []byte("value")})
+			return err
+		},
+		"delete": func() error {
+			_, err := grpcClient.Delete(ctx, &proto.DeleteRequest{Key: ""})
+			return err
+		},
+	} {
+		if err := call(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %s, got %v", name, err)
+		}
+	}
+	if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: strings.Repeat("k", 64)}); err != nil {
+		t.Errorf("Expected key at the limit to be accepted, got %v", err)
+	}
+}
+
+// TestE2ERejectionMetrics tests that backpressure and load shedding rejections are counted
+func TestE2ERejectionMetrics(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.MaxConcurrent = 2
+	})
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	// Saturate the semaphore so every request is rejected
+	if !server.semaphore.TryAcquire(2) {
+		t.Fatal("Failed to saturate semaphore")
+	}
+	for i := 0; i < 3; i++ {
+		_, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"})
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("Expected Unavailable while saturated, got %v", err)
+		}
+	}
+	server.semaphore.Release(2)
+	
+	metrics := fetchMetrics(t, server)
+	if metrics["backpressure_rejected_total"] != float64(3) {
+		t.Errorf("Expected 3 backpressure rejections, got %v", metrics["backpressure_rejected_total"])
+	}
+	if metrics["load_shed_total"] != float64(0) {
+		t.Errorf("Expected no load shedding, got %v", metrics["load_shed_total"])
+	}
+	if _, exists := metrics["semaphore_utilization"]; !exists {
+		t.Error("Expected semaphore_utilization in metrics")
+	}
+	
+	// Overloaded CPU sheds load before the semaphore is tried
+	server.cpuMutex.Lock()
+	server.cpuThreshold = -1
+	server.cpuMutex.Unlock()
+	server.recordLoad(1, time.Second)
+	if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable while shedding, got %v", err)
+	}
+	
+	metrics = fetchMetrics(t, server)
+	if metrics["load_shed_total"] != float64(1) || metrics["backpressure_rejected_total"] != float64(3) {
+		t.Errorf("Expected 1 shed and 3 rejected, got %v and %v", metrics["load_shed_total"], metrics["backpressure_rejected_total"])
+	}
+}
+
+// TestE2EConsistencyOne tests that best-effort calls succeed with only the primary reachable
+func TestE2EConsistencyOne(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	config := &client.Config{ReadQuorum: 2, WriteQuorum: 2}
+	c := newTestClient(t, config, servers...)
+	ctx := context.Background()
+	
+	// Take every node but the primary down
+	key := keyOwnedBy(t, "node0", "node0", "node1", "node2")
+	servers[1].grpcServer.Stop()
+	servers[2].grpcServer.Stop()
+	
+	if err := c.Set(ctx, key, []byte("value"), 0); err == nil {
+		t.Error("Expected quorum write to fail with one node up")
+	}
+	
+	one := client.WithConsistency(ctx, client.ConsistencyOne)
+	if err := c.Set(one, key, []byte("value"), 0); err != nil {
+		t.Fatalf("Expected best-effort write to succeed, got %v", err)
+	}
+	value, err := c.Get(one, key)
+	if err != nil || string(value) != "value" {
+		t.Errorf("Expected best-effort read of value, got %q (%v)", value, err)
+	}
+	
+	// The same behavior can be selected for every call
+	config.Consistency = client.ConsistencyOne
+	global := newTestClient(t, config, servers...)
+	if err := global.Delete(ctx, key); err != nil {
+		t.Errorf("Expected best-effort delete to succeed, got %v", err)
+	}
+	if _, err := global.Get(ctx, key); err == nil {
+		t.Error("Expected key to be deleted")
+	}
+}
+
+// TestE2EConsistencyStrict tests that a strict read fails when the owners it reads
+// disagree, while a default read returns the primary's newer value
+func TestE2EConsistencyStrict(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	config := &client.Config{ReadQuorum: 2, WriteQuorum: 2}
+	c := newTestClient(t, config, servers...)
+	ctx := context.Background()
+	strict := client.WithConsistency(ctx, client.ConsistencyStrict)
+	
+	key := keyOwnedBy(t, "node0", "node0", "node1", "node2")
+	if err := c.Set(ctx, key, []byte("fresh"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, err := c.Get(strict, key); err != nil || string(value) != "fresh" {
+		t.Fatalf("Expected a strict read of agreeing replicas to succeed, got %q (%v)", value, err)
+	}
+	
+	// The primary falls behind with an older version
+	servers[0].cache.Delete(key)
+	servers[0].cache.SetVersioned(key, []byte("stale"), 0, 1)
+	
+	if _, err := c.Get(strict, key); !errors.Is(err, client.ErrReplicasDisagree) {
+		t.Errorf("Expected ErrReplicasDisagree, got %v", err)
+	}
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "stale" {
+		t.Errorf("Expected the default read to serve the primary's stale value, got %q (%v)", value, err)
+	}
+	
+	// With read repair the failed strict read fixes the replica, so a retry succeeds
+	config.ReadRepair = true
+	repairing := newTestClient(t, config, servers...)
+	if _, err := repairing.Get(strict, key); !errors.Is(err, client.ErrReplicasDisagree) {
+		t.Errorf("Expected ErrReplicasDisagree before the repair, got %v", err)
+	}
+	if value, err := repairing.Get(strict, key); err != nil || string(value) != "fresh" {
+		t.Errorf("Expected the strict read to succeed after the repair, got %q (%v)", value, err)
+	}
+	
+	// A key no owner holds is a miss, not a disagreement
+	if _, err := c.Get(strict, "missing"); err == nil || errors.Is(err, client.ErrReplicasDisagree) {
+		t.Errorf("Expected a miss for an absent key, got %v", err)
+	}
+}
+
+// TestE2ESession tests that session reads never return a value older than the
+// session's last write, even when the replica read first has fallen behind
+func TestE2ESession(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2, ReplicationFactor: 2}, servers...)
+	ctx := context.Background()
+	session := c.NewSession()
+	
+	key := keyOwnedBy(t, "node0", "node0", "node1", "node2")
+	if _, err := session.Set(ctx, key, []byte("old"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	token, err := session.Set(ctx, key, []byte("new"), 0)
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if session.Token(key) != token {
+		t.Errorf("Expected the session to track token %d, got %d", token, session.Token(key))
+	}
+	
+	// The primary falls behind, as if it had missed the write
+	servers[0].cache.Delete(key)
+	servers[0].cache.SetVersioned(key, []byte("old"), 0, token-1)
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "old" {
+		t.Fatalf("Expected a plain read of the primary to be stale, got %q (%v)", value, err)
+	}
+	
+	for i := 0; i < 10; i++ {
+		if value, err := session.Get(ctx, key); err != nil || string(value) != "new" {
+			t.Fatalf("Expected the session to read its write, got %q (%v)", value, err)
+		}
+	}
+	
+	// The token also works from another client
+	other := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2, ReplicationFactor: 2}, servers...)
+	if value, err := other.GetAtLeast(ctx, key, token); err != nil || string(value) != "new" {
+		t.Errorf("Expected GetAtLeast to skip the stale primary, got %q (%v)", value, err)
+	}
+	
+	// With every owner behind, the read fails instead of returning the older value
+	for _, s := range servers[1:] {
+		if s.cache.Exists(key) {
+			s.cache.Delete(key)
+			s.cache.SetVersioned(key, []byte("old"), 0, token-1)
+		}
+	}
+	if value, err := session.Get(ctx, key); !errors.Is(err, client.ErrSessionBehind) {
+		t.Errorf("Expected ErrSessionBehind, got %q (%v)", value, err)
+	}
+	
+	// A key gone from every owner, as if evicted, is a miss rather than behind
+	for _, s := range servers {
+		s.cache.Delete(key)
+	}
+	if value, err := session.Get(ctx, key); !errors.Is(err, client.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for an evicted key, got %q (%v)", value, err)
+	}
+	
+	// The token of a write is dropped once the write expires
+	if _, err := session.Set(ctx, "short", []byte("value"), 50*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if token := session.Token("short"); token != 0 {
+		t.Errorf("Expected the expired write's token to be dropped, got %d", token)
+	}
+	if _, err := session.Get(ctx, "short"); !errors.Is(err, client.ErrKeyNotFound) {
+		t.Errorf("Expected the expired key to miss, got %v", err)
+	}
+	
+	// Keys the session has not written are read as usual
+	if err := c.Set(ctx, "unwritten", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, err := session.Get(ctx, "unwritten"); err != nil || string(value) != "value" {
+		t.Errorf("Expected a plain read of an unwritten key, got %q (%v)", value, err)
+	}
+}
+
+// TestE2EKeyNotFound tests that a miss from a reachable owner is reported as
+// ErrKeyNotFound, is final under ConsistencyOne and moves on to the next owner under
+// quorum reads, while unreachable owners give a different error
+func TestE2EKeyNotFound(t *testing.T) {
+	servers := []*Server{startTestServer(t, nil), startTestServer(t, nil)}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	one := client.WithConsistency(ctx, client.ConsistencyOne)
+	
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, client.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for a key no owner holds, got %v", err)
+	}
+	if _, err := c.Get(one, "missing"); !errors.Is(err, client.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound under ConsistencyOne, got %v", err)
+	}
+	
+	// The primary misses a key that the other owner holds
+	key := keyOwnedBy(t, "node0", "node0", "node1")
+	if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	servers[0].cache.Delete(key)
+	if _, err := c.Get(one, key); !errors.Is(err, client.ErrKeyNotFound) {
+		t.Errorf("Expected the primary's miss to be final under ConsistencyOne, got %v", err)
+	}
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "value" {
+		t.Errorf("Expected a quorum read to go on to the other owner, got %q (%v)", value, err)
+	}
+	
+	// Owners that cannot be reached are not a miss
+	for _, s := range servers {
+		s.grpcServer.Stop()
+	}
+	timeout, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := c.Get(timeout, "missing"); err == nil || errors.Is(err, client.ErrKeyNotFound) {
+		t.Errorf("Expected a transport error from stopped owners, got %v", err)
+	}
+}
+
+// TestE2ERunLoad tests that a short RunLoad against real servers completes a mix of
+// reads and writes and reports their latencies
+func TestE2ERunLoad(t *testing.T) {
+	servers := []*Server{startTestServer(t, nil), startTestServer(t, nil)}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2}, servers...)
+	
+	result := c.RunLoad(context.Background(), client.LoadSpec{
+		Workers:   4,
+		Duration:  200 * time.Millisecond,
+		Keys:      5,
+		ReadRatio: 0.5,
+	})
+	if result.Reads == 0 || result.Writes == 0 || result.Operations != result.Reads+result.Writes {
+		t.Fatalf("Expected both reads and writes, got %v", result)
+	}
+	if result.Errors != 0 {
+		t.Errorf("Expected no errors, got %v", result)
+	}
+	lat := result.Latency
+	if lat.Min <= 0 || lat.Min > lat.P50 || lat.P50 > lat.P99 || lat.P99 > lat.Max || lat.Mean < lat.Min || lat.Mean > lat.Max {
+		t.Errorf("Expected ordered, non-zero latency stats, got %+v", lat)
+	}
+	if result.Elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the run to last its duration, took %v", result.Elapsed)
+	}
+}
+
+// TestE2ELocalFallback tests serving a previously read key from the local fallback once every owner is down
+func TestE2ELocalFallback(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:          2,
+		WriteQuorum:         2,
+		EnableLocalFallback: true,
+		LocalCacheCapacity:  10,
+	}, servers...)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "read-key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, err := c.Get(ctx, "read-key"); err != nil || string(value) != "value" {
+		t.Fatalf("Get failed: %q (%v)", value, err)
+	}
+	
+	// A key deleted behind the client's back is not resurrected while owners are up
+	if err := c.Set(ctx, "deleted-key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for _, s := range servers {
+		s.cache.Delete("deleted-key")
+	}
+	if _, err := c.Get(ctx, "deleted-key"); err == nil || errors.Is(err, client.ErrStale) {
+		t.Errorf("Expected a plain miss for a deleted key, got %v", err)
+	}
+	
+	for _, s := range servers {
+		s.grpcServer.Stop()
+	}
+	
+	value, err := c.Get(ctx, "read-key")
+	if !errors.Is(err, client.ErrStale) || string(value) != "value" {
+		t.Errorf("Expected stale value from the local fallback, got %q (%v)", value, err)
+	}
+	strict := client.WithConsistency(ctx, client.ConsistencyStrict)
+	if value, err := c.Get(strict, "read-key"); err == nil || errors.Is(err, client.ErrStale) || value != nil {
+		t.Errorf("Expected a strict read to fail rather than use the local fallback, got %q (%v)", value, err)
+	}
+	if _, err := c.Get(ctx, "never-read"); err == nil || errors.Is(err, client.ErrStale) {
+		t.Errorf("Expected an error for a key never read, got %v", err)
+	}
+	if _, err := c.Get(ctx, "deleted-key"); errors.Is(err, client.ErrStale) {
+		t.Error("Expected deleted key to have been dropped from the local fallback")
+	}
+	if stale := c.GetStats()["stale_reads"]; stale != uint64(1) {
+		t.Errorf("Expected 1 stale read, got %v", stale)
+	}
+}
+
+// TestE2EClientCache tests that reads within ClientCacheTTL are served without an RPC
+func TestE2EClientCache(t *testing.T) {
+	server := startTestServer(t, nil)
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:     1,
+		WriteQuorum:    1,
+		ClientCacheTTL: 100 * time.Millisecond,
+	}, server)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	read := func(want string) {
+		t.Helper()
+		value, err := c.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if string(value) != want {
+			t.Errorf("Expected %s, got %s", want, string(value))
+		}
+	}
+	
+	read("value")
+	before := atomic.LoadUint64(&server.requestsTotal)
+	read("value")
+	if requests := atomic.LoadUint64(&server.requestsTotal) - before; requests != 0 {
+		t.Errorf("Expected the second read to be served locally, it made %d requests", requests)
+	}
+	if cached := c.GetStats()["cached_reads"]; cached != uint64(1) {
+		t.Errorf("Expected 1 cached read, got %v", cached)
+	}
+	
+	// Writes through the client invalidate the local copy
+	if err := c.Set(ctx, "key", []byte("new-value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	read("new-value")
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Get(ctx, "key"); err == nil {
+		t.Error("Expected the deleted key to miss")
+	}
+	
+	// Writes from elsewhere are seen once the TTL lapses
+	if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	read("value")
+	server.cache.Set("key", []byte("changed"), 0)
+	read("value")
+	time.Sleep(150 * time.Millisecond)
+	read("changed")
+}
+
+// TestE2EExists tests presence checks through the client
+func TestE2EExists(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "live", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set(ctx, "expiring", []byte("value"), 50*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	check := func(key string, want bool) {
+		t.Helper()
+		exists, err := c.Exists(ctx, key)
+		if err != nil {
+			t.Fatalf("Exists failed: %v", err)
+		}
+		if exists != want {
+			t.Errorf("Expected Exists(%s) to be %v", key, want)
+		}
+	}
+	
+	check("live", true)
+	check("expiring", true)
+	check("missing", false)
+	
+	// A key held by only one owner still exists
+	servers[0].cache.Delete("live")
+	check("live", true)
+	
+	time.Sleep(100 * time.Millisecond)
+	check("expiring", false)
+	
+	for _, s := range servers {
+		s.grpcServer.Stop()
+	}
+	if _, err := c.Exists(ctx, "live"); err == nil {
+		t.Error("Expected an error with every owner down")
+	}
+}
+
+// TestE2EDeleteAll tests that deletes under DeleteAll wait for every owner and report
+// the ones that did not confirm
+func TestE2EDeleteAll(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2, ReplicationFactor: 3, DeleteConsistency: client.DeleteAll}, servers...)
+	ctx := context.Background()
+	
+	for _, key := range []string{"all", "quorum"} {
+		if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+	
+	// With every owner up the delete reaches all of them before returning
+	if err := c.Set(ctx, "healthy", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Delete(ctx, "healthy"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for i, s := range servers {
+		if _, found := s.cache.Peek("healthy"); found {
+			t.Errorf("Expected healthy to be deleted from node%d", i)
+		}
+	}
+	
+	servers[2].grpcServer.Stop()
+	
+	// The delete is applied where possible but reported as failed, naming the node
+	err := c.Delete(ctx, "all")
+	if err == nil || !strings.Contains(err.Error(), "node2") || !strings.Contains(err.Error(), "1 of 3 owners") {
+		t.Errorf("Expected Delete to report node2 as not confirming, got %v", err)
+	}
+	for i, s := range servers[:2] {
+		if _, found := s.cache.Peek("all"); found {
+			t.Errorf("Expected all to be deleted from node%d", i)
+		}
+	}
+	
+	// A quorum delete succeeds with the same node down
+	if err := c.Delete(client.WithDeleteConsistency(ctx, client.DeleteQuorum), "quorum"); err != nil {
+		t.Errorf("Expected a quorum Delete to succeed with one owner down, got %v", err)
+	}
+}
+
+// TestE2EInsufficientNodes tests that writes needing more owners than the ring has
+// fail up front with ErrInsufficientNodes rather than writing to the owners there are
+func TestE2EInsufficientNodes(t *testing.T) {
+	server := startTestServer(t, nil)
+	server.cache.Set("existing", []byte("value"), 0)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2}, server)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "key", []byte("value"), 0); !errors.Is(err, client.ErrInsufficientNodes) {
+		t.Errorf("Expected Set to fail with ErrInsufficientNodes, got %v", err)
+	}
+	if err := c.Delete(ctx, "existing"); !errors.Is(err, client.ErrInsufficientNodes) {
+		t.Errorf("Expected Delete to fail with ErrInsufficientNodes, got %v", err)
+	}
+	
+	// Nothing reached the single node
+	if _, found := server.cache.Peek("key"); found {
+		t.Error("Expected the failed Set not to write to the only node")
+	}
+	if _, found := server.cache.Peek("existing"); !found {
+		t.Error("Expected the failed Delete not to delete from the only node")
+	}
+	
+	// Deletes waiting for every owner check the quorum first too
+	if err := c.Delete(client.WithDeleteConsistency(ctx, client.DeleteAll), "existing"); !errors.Is(err, client.ErrInsufficientNodes) {
+		t.Errorf("Expected a DeleteAll Delete to fail with ErrInsufficientNodes, got %v", err)
+	}
+	if _, found := server.cache.Peek("existing"); !found {
+		t.Error("Expected the failed DeleteAll Delete not to delete from the only node")
+	}
+	
+	// A write that only needs one acknowledgment still goes through
+	if err := c.Set(client.WithConsistency(ctx, client.ConsistencyOne), "key", []byte("value"), 0); err != nil {
+		t.Errorf("Expected a ConsistencyOne Set to succeed, got %v", err)
+	}
+	
+	// With no nodes at all the error is the same
+	empty := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1})
+	if err := empty.Set(ctx, "key", []byte("value"), 0); !errors.Is(err, client.ErrInsufficientNodes) {
+		t.Errorf("Expected Set on an empty ring to fail with ErrInsufficientNodes, got %v", err)
+	}
+}
+
+// TestE2EExpirePersist tests changing and removing TTLs on every replica without
+// rewriting values
+func TestE2EExpirePersist(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	for key, ttl := range map[string]time.Duration{"extended": 100 * time.Millisecond, "shortened": time.Hour, "persisted": 100 * time.Millisecond} {
+		if err := c.Set(ctx, key, []byte("value-"+key), ttl); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+	
+	if updated, err := c.Expire(ctx, "extended", time.Hour); err != nil || !updated {
+		t.Fatalf("Expire extended = %v, %v; want updated", updated, err)
+	}
+	if updated, err := c.Expire(ctx, "shortened", 50*time.Millisecond); err != nil || !updated {
+		t.Fatalf("Expire shortened = %v, %v; want updated", updated, err)
+	}
+	if updated, err := c.Persist(ctx, "persisted"); err != nil || !updated {
+		t.Fatalf("Persist = %v, %v; want updated", updated, err)
+	}
+	if updated, err := c.Expire(ctx, "missing", time.Hour); err != nil || updated {
+		t.Errorf("Expire of a missing key = %v, %v; want not updated", updated, err)
+	}
+	if _, err := c.Expire(ctx, "extended", 0); err == nil {
+		t.Error("Expected Expire with a zero ttl to be rejected")
+	}
+	
+	time.Sleep(200 * time.Millisecond)
+	
+	// The changes reached both replicas, and the values were left as they were
+	for i, s := range servers {
+		for _, key := range []string{"extended", "persisted"} {
+			entry, found := s.cache.PeekEntry(key)
+			if !found || string(entry.Value) != "value-"+key {
+				t.Errorf("Expected %s to outlive its original TTL on node%d", key, i)
+			}
+		}
+		if entry, _ := s.cache.PeekEntry("persisted"); !entry.ExpiresAt.IsZero() {
+			t.Errorf("Expected persisted to have no expiry on node%d, got %v", i, entry.ExpiresAt)
+		}
+		if _, found := s.cache.PeekEntry("shortened"); found {
+			t.Errorf("Expected shortened to have expired on node%d", i)
+		}
+		if _, found := s.cache.PeekEntry("missing"); found {
+			t.Errorf("Expected Expire not to create missing on node%d", i)
+		}
+	}
+	
+	// Expiry changes are writes, refused by a read-only node
+	servers[1].setMode(ModeReadOnly)
+	if _, err := c.Persist(ctx, "extended"); err == nil {
+		t.Error("Expected Persist to fail without a write quorum")
+	}
+}
+
+// TestE2EPrefixTTLs tests that writes without a TTL take the default of their longest
+// matching prefix, falling back to the empty prefix
+func TestE2EPrefixTTLs(t *testing.T) {
+	server := startTestServer(t, func(config *Config) {
+		config.PrefixTTLs = map[string]time.Duration{
+			"session:":       100 * time.Millisecond,
+			"session:admin:": time.Hour,
+			"":               time.Minute,
+		}
+	})
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	for _, key := range []string{"session:1", "session:admin:1", "user:1", "explicit"} {
+		req := &proto.SetRequest{Key: key, Value: []byte("value")}
+		if key == "explicit" {
+			req.Ttl = durationpb.New(50 * time.Millisecond)
+		}
+		if _, err := grpcClient.Set(ctx, req); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	
+	remaining := func(key string) time.Duration {
+		entry, ok := server.cache.PeekEntry(key)
+		if !ok || entry.ExpiresAt.IsZero() {
+			t.Fatalf("Expected %s to be stored with an expiry", key)
+		}
+		return time.Until(entry.ExpiresAt)
+	}
+	if ttl := remaining("session:admin:1"); ttl < 59*time.Minute {
+		t.Errorf("Expected the longest prefix to give session:admin:1 an hour, got %v", ttl)
+	}
+	if ttl := remaining("user:1"); ttl < 59*time.Second || ttl > time.Minute {
+		t.Errorf("Expected the fallback to give user:1 a minute, got %v", ttl)
+	}
+	
+	// Entries expire by their prefix rule, and an explicit TTL is kept
+	time.Sleep(150 * time.Millisecond)
+	for key, live := range map[string]bool{"session:1": false, "explicit": false, "session:admin:1": true, "user:1": true} {
+		if server.cache.Exists(key) != live {
+			t.Errorf("Expected %s live to be %v", key, live)
+		}
+	}
+	
+	// Batched writes take the defaults too, and a non-positive default is rejected
+	if _, err := grpcClient.SetBatch(ctx, &proto.SetBatchRequest{Items: []*proto.SetRequest{{Key: "session:2", Value: []byte("value")}}}); err != nil {
+		t.Fatalf("SetBatch failed: %v", err)
+	}
+	if ttl := remaining("session:2"); ttl > 100*time.Millisecond {
+		t.Errorf("Expected session:2 to get the session: default, got %v", ttl)
+	}
+	
+	// Keys under a prefix cannot be made non-expiring
+	if _, err := grpcClient.Persist(ctx, &proto.PersistRequest{Key: "user:1"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected Persist under a prefix to fail with FailedPrecondition, got %v", err)
+	}
+	remaining("user:1")
+	if _, err := NewServer(&Config{CacheCapacity: 10, MaxConcurrent: 10, PrefixTTLs: map[string]time.Duration{"a:": 0}}); err == nil {
+		t.Error("Expected a zero default TTL to be rejected")
+	}
+}
+
+// TestE2ESetStream tests bulk ingestion through the client's SetStream, with every key
+// written to both replicas
+func TestE2ESetStream(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	stream, err := c.SetStream(ctx)
+	if err != nil {
+		t.Fatalf("SetStream failed: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if err := stream.Send(fmt.Sprintf("stream-%d", i), []byte(fmt.Sprintf("value-%d", i)), time.Minute); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv failed: %v", err)
+	}
+	if summary.Succeeded != 2000 || summary.Failed != 0 {
+		t.Errorf("Expected 2000 succeeded and 0 failed, got %d and %d", summary.Succeeded, summary.Failed)
+	}
+	
+	for i, s := range servers {
+		if size := s.cache.Size(); size != 1000 {
+			t.Errorf("Expected 1000 entries on node%d, got %d", i, size)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		value, err := c.Get(ctx, fmt.Sprintf("stream-%d", i))
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if string(value) != fmt.Sprintf("value-%d", i) {
+			t.Fatalf("Expected stream-%d to be present", i)
+		}
+	}
+	
+	// A node that refuses the whole stream fails it with an error
+	servers[0].setMode(ModeReadOnly)
+	stream, err = c.SetStream(ctx)
+	if err != nil {
+		t.Fatalf("SetStream failed: %v", err)
+	}
+	if err := stream.Send("refused", []byte("value"), 0); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); err == nil {
+		t.Error("Expected an error from a read-only node")
+	}
+}
+
+// TestE2EMigrate tests that Migrate copies a populated node onto an empty one with
+// the same values, versions and expiry times
+func TestE2EMigrate(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, servers...)
+	ctx := context.Background()
+	
+	source, dest := servers[0].cache, servers[1].cache
+	for i := 0; i < 100; i++ {
+		var ttl time.Duration
+		if i%2 == 0 {
+			ttl = time.Hour
+		}
+		source.SetVersioned(fmt.Sprintf("key:%03d", i), []byte(fmt.Sprintf("value-%d", i)), ttl, uint64(i+1))
+	}
+	source.Set("unversioned", []byte("value"), 0)
+	source.Set("expiring", []byte("value"), 10*time.Millisecond)
+	source.DeleteVersioned("deleted", 1)
+	dest.SetVersioned("key:000", []byte("newer"), 0, 1000)
+	time.Sleep(20 * time.Millisecond)
+	
+	result, err := c.Migrate(ctx, "node0", "node1")
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.Migrated != 100 || result.Skipped != 1 {
+		t.Errorf("Expected 100 migrated and the key the destination holds newer skipped, got %+v", result)
+	}
+	
+	// Expired entries and tombstones stay behind, and the newer copy is kept
+	for _, key := range []string{"expiring", "deleted"} {
+		if _, exists := dest.PeekEntry(key); exists {
+			t.Errorf("Expected %s not to be migrated", key)
+		}
+	}
+	if value, _ := dest.Get("key:000"); string(value) != "newer" {
+		t.Errorf("Expected the destination's newer key:000 to be kept, got %q", value)
+	}
+	
+	for _, want := range source.Scan("") {
+		if want.Key == "key:000" {
+			continue
+		}
+		got, exists := dest.PeekEntry(want.Key)
+		if !exists {
+			t.Errorf("Expected %s on the destination", want.Key)
+			continue
+		}
+		if string(got.Value) != string(want.Value) || got.Version != want.Version {
+			t.Errorf("Expected %s as %q at %d, got %q at %d", want.Key, want.Value, want.Version, got.Value, got.Version)
+		}
+		if got.ExpiresAt.IsZero() != want.ExpiresAt.IsZero() || got.ExpiresAt.Sub(want.ExpiresAt).Abs() > time.Second {
+			t.Errorf("Expected %s to expire at %v, got %v", want.Key, want.ExpiresAt, got.ExpiresAt)
+		}
+	}
+	
+	if _, err := c.Migrate(ctx, "node0", "node0"); err == nil {
+		t.Error("Expected migrating a node to itself to fail")
+	}
+	if _, err := c.Migrate(ctx, "node0", "missing"); err == nil {
+		t.Error("Expected migrating to an unknown node to fail")
+	}
+	
+	// A destination refusing the stream reports why, not a bare EOF
+	servers[1].setMode(ModeReadOnly)
+	if _, err := c.Migrate(ctx, "node0", "node1"); err == nil || !strings.Contains(err.Error(), "node is read-only") {
+		t.Errorf("Expected the destination's refusal, got %v", err)
+	}
+}
+
+// TestE2ERebalance tests that Rebalance moves keys onto a node added after they were written
+func TestE2ERebalance(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, servers[0])
+	ctx := context.Background()
+	
+	for i := 0; i < 50; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i)), time.Minute); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	
+	if err := c.AddNode("node1", grpcAddr(servers[1])); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	r := ring.NewRing()
+	r.AddNode("node0", grpcAddr(servers[0]))
+	r.AddNode("node1", grpcAddr(servers[1]))
+	var moved []string
+	for i := 0; i < 50; i++ {
+		if key := fmt.Sprintf("key%d", i); r.Owners(key, 1)[0].ID == "node1" {
+			moved = append(moved, key)
+		}
+	}
+	if len(moved) == 0 {
+		t.Fatal("Expected some keys to move to the new node")
+	}
+	
+	// A key list moves only the keys it names
+	result, err := c.Rebalance(ctx, moved[:1])
+	if err != nil {
+		t.Fatalf("Rebalance failed: %v", err)
+	}
+	if result != (client.RebalanceResult{Keys: 1, Copied: 1, Removed: 1}) {
+		t.Errorf("Expected one key moved, got %+v", result)
+	}
+	
+	result, err = c.Rebalance(ctx, nil)
+	if err != nil {
+		t.Fatalf("Rebalance failed: %v", err)
+	}
+	remaining := len(moved) - 1
+	if result != (client.RebalanceResult{Keys: 50, Copied: remaining, Removed: remaining}) {
+		t.Errorf("Expected %d keys moved, got %+v", remaining, result)
+	}
+	
+	for _, key := range moved {
+		if _, found := servers[1].cache.Peek(key); !found {
+			t.Errorf("Expected %s on the new owner", key)
+		}
+		if _, found := servers[0].cache.Peek(key); found {
+			t.Errorf("Expected %s removed from its old owner", key)
+		}
+		if entry, _ := servers[1].cache.PeekEntry(key); entry.ExpiresAt.IsZero() {
+			t.Errorf("Expected %s to keep its TTL", key)
+		}
+	}
+	// The old owner keeps a tombstone of each key it gave up
+	if size := servers[0].cache.Size() + servers[1].cache.Size(); size != 50+len(moved) {
+		t.Errorf("Expected %d entries across the nodes, got %d", 50+len(moved), size)
+	}
+	for i := 0; i < 50; i++ {
+		value, err := c.Get(ctx, fmt.Sprintf("key%d", i))
+		if err != nil || string(value) != fmt.Sprintf("value%d", i) {
+			t.Errorf("Expected key%d to be readable after rebalancing, got %q (%v)", i, value, err)
+		}
+	}
+	
+	// A balanced cluster has nothing to move
+	result, err = c.Rebalance(ctx, nil)
+	if err != nil || result.Copied != 0 || result.Removed != 0 {
+		t.Errorf("Expected nothing to move, got %+v (%v)", result, err)
+	}
+}
+
+// TestE2ERebalanceDumpFails tests that Rebalance fails without moving anything when a
+// node's dump breaks off part way, rather than rebalancing the keys it did receive
+func TestE2ERebalanceDumpFails(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, func(config *Config) { config.MaxSendMsgSize = 1024 }),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, servers...)
+	
+	// The value too large for node0 to send fails its dump after the others
+	for i := 0; i < 20; i++ {
+		servers[0].cache.SetVersioned(fmt.Sprintf("key%d", i), []byte("value"), 0, uint64(i+1))
+	}
+	servers[0].cache.SetVersioned("large", make([]byte, 4096), 0, 100)
+	
+	if _, err := c.Rebalance(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "dump node0 failed") {
+		t.Errorf("Expected Rebalance to report node0's failed dump, got %v", err)
+	}
+	if size := servers[1].cache.Size(); size != 0 {
+		t.Errorf("Expected nothing moved to node1, got %d entries", size)
+	}
+}
+
+func TestE2EJoiningNode(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	if err := c.SetNodeStatus("node1", ring.NodeJoining); err != nil {
+		t.Fatalf("SetNodeStatus failed: %v", err)
+	}
+	if err := c.SetNodeStatus("node9", ring.NodeJoining); err == nil {
+		t.Error("Expected an error for a node not in the ring")
+	}
+	
+	// Writes reach the joining primary
+	key := keyOwnedBy(t, "node1", "node0", "node1")
+	if err := c.Set(ctx, key, []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, _ := servers[1].cache.Peek(key); string(value) != "value" {
+		t.Errorf("Expected the joining node to receive the write, got %q", value)
+	}
+	
+	// Reads skip it and go to the active replica
+	servers[1].cache.Set(key, []byte("joining"), 0)
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "value" {
+		t.Errorf("Expected the read to skip the joining node, got %s, %v", string(value), err)
+	}
+	
+	if err := c.SetNodeStatus("node1", ring.NodeActive); err != nil {
+		t.Fatalf("SetNodeStatus failed: %v", err)
+	}
+	if value, err := c.Get(ctx, key); err != nil || string(value) != "joining" {
+		t.Errorf("Expected the read to go to the primary once active, got %s, %v", string(value), err)
+	}
+}
+
+func TestE2ERangeGet(t *testing.T) {
+	server := startTestServer(t, nil)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1, Namespace: "metrics"}, server)
+	ctx := context.Background()
+	
+	for i := 1; i <= 5; i++ {
+		key := fmt.Sprintf("cpu:%02d", i)
+		if err := c.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if err := c.Set(ctx, "mem:01", []byte("mem"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	entries, err := c.RangeGet(ctx, "cpu:02", "cpu:05")
+	if err != nil {
+		t.Fatalf("RangeGet failed: %v", err)
+	}
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+		if string(entry.Value) != entry.Key {
+			t.Errorf("Expected %s to hold its own name, got %s", entry.Key, string(entry.Value))
+		}
+	}
+	if got := strings.Join(keys, ","); got != "cpu:02,cpu:03,cpu:04" {
+		t.Errorf("Expected cpu:02,cpu:03,cpu:04, got %s", got)
+	}
+	
+	if _, err := c.RangeGet(ctx, "cpu:05", "cpu:02"); err == nil {
+		t.Error("Expected an error when the end sorts before the start")
+	}
+}
+
+func TestE2EScanPrefix(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2, Namespace: "app"}, servers...)
+	ctx := context.Background()
+	
+	want := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("user:%02d", i)
+		if err := c.Set(ctx, key, []byte("value-"+key), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		want[key] = "value-" + key
+	}
+	for i := 0; i < 5; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("order:%02d", i), []byte("order"), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	
+	// The node that does not own user:00 gets an older copy, and the node that does
+	// not own user:01 a newer one; the newest version wins either way
+	for _, s := range servers {
+		if !s.cache.Exists("app:user:00") {
+			s.cache.SetVersioned("app:user:00", []byte("stale"), 0, 1)
+		}
+		if !s.cache.Exists("app:user:01") {
+			s.cache.SetVersioned("app:user:01", []byte("newer"), 0, math.MaxUint64)
+			want["user:01"] = "newer"
+		}
+	}
+	
+	// A deleted key is not brought back by a stale copy on a node that does not own it
+	if err := c.Delete(ctx, "user:02"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	delete(want, "user:02")
+	for _, s := range servers {
+		if _, found := s.cache.Lookup("app:user:02"); !found {
+			s.cache.SetVersioned("app:user:02", []byte("stale"), 0, 1)
+		}
+	}
+	
+	got, err := c.ScanPrefix(ctx, "user:")
+	if err != nil {
+		t.Fatalf("ScanPrefix failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d keys once each, got %d", len(want), len(got))
+	}
+	for key, value := range want {
+		if string(got[key]) != value {
+			t.Errorf("Expected %s to be %q, got %q", key, value, got[key])
+		}
+	}
+	
+	// An empty prefix lists the whole namespace, and other namespaces are not included
+	other := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2, Namespace: "other"}, servers...)
+	if err := other.Set(ctx, "user:99", []byte("elsewhere"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	all, err := c.ScanPrefix(ctx, "")
+	if err != nil {
+		t.Fatalf("ScanPrefix failed: %v", err)
+	}
+	if len(all) != 24 {
+		t.Errorf("Expected the 24 live keys of the namespace, got %d", len(all))
+	}
+	
+	// A node answers a page at a time, tombstones included
+	var keys []string
+	req := &proto.ScanRequest{Prefix: "app:user:", Limit: 3}
+	for {
+		resp, err := dialTestServer(t, servers[0]).Scan(ctx, req)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if len(resp.Entries) > 3 {
+			t.Fatalf("Expected at most 3 entries a page, got %d", len(resp.Entries))
+		}
+		for _, entry := range resp.Entries {
+			keys = append(keys, entry.Key)
+		}
+		if resp.Next == "" {
+			break
+		}
+		req.After = resp.Next
+	}
+	held, _ := servers[0].cache.ScanPage("app:user:", "", 0)
+	var heldKeys []string
+	for _, entry := range held {
+		heldKeys = append(heldKeys, entry.Key)
+	}
+	if strings.Join(keys, ",") != strings.Join(heldKeys, ",") {
+		t.Errorf("Expected the node's entries %v in order, got %v", heldKeys, keys)
+	}
+}
+
+// TestE2EGetAndDelete tests that concurrent pops of a replicated key return its value
+// at most once and remove it from every owner
+func TestE2EGetAndDelete(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	config := &client.Config{ReadQuorum: 1, WriteQuorum: 2, ReplicationFactor: 3}
+	consumers := []*client.Client{
+		newTestClient(t, config, servers...),
+		newTestClient(t, config, servers...),
+	}
+	ctx := context.Background()
+	
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("job:%d", i)
+		if err := consumers[0].Set(ctx, key, []byte("payload-"+key), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		
+		var wg sync.WaitGroup
+		results := make([][]byte, len(consumers))
+		for j, consumer := range consumers {
+			wg.Add(1)
+			go func(j int, consumer *client.Client) {
+				defer wg.Done()
+				value, found, err := consumer.GetAndDelete(ctx, key)
+				if err != nil {
+					t.Errorf("GetAndDelete failed: %v", err)
+				}
+				if found {
+					results[j] = value
+				}
+			}(j, consumer)
+		}
+		wg.Wait()
+		
+		// Racing pops may both miss, but never both get the value
+		if results[0] != nil && results[1] != nil {
+			t.Fatalf("Expected at most one pop of %s to get its value, got %q and %q", key, results[0], results[1])
+		}
+		if value := append(results[0], results[1]...); len(value) > 0 && string(value) != "payload-"+key {
+			t.Errorf("Expected the popped value of %s, got %q", key, value)
+		}
+		for eventually := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			held := 0
+			for _, s := range servers {
+				if s.cache.Exists(key) {
+					held++
+				}
+			}
+			if held == 0 {
+				break
+			}
+			if time.Now().After(eventually) {
+				t.Fatalf("Expected %s to be removed from every owner, %d still hold it", key, held)
+			}
+		}
+	}
+	
+	// Pops are writes, so a read-only node rejects them
+	servers[0].setMode(ModeReadOnly)
+	_, err := dialTestServer(t, servers[0]).GetAndDelete(ctx, &proto.GetAndDeleteRequest{Key: "job:0", Version: 1})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition on a read-only node, got %v", err)
+	}
+}
+
+// TestE2EGetAndDeletePendingSet tests that a pop reaching the owners while one of them
+// still waits for the key's Set returns the value, and that the Set arriving afterwards
+// does not bring the key back to be popped again
+func TestE2EGetAndDeletePendingSet(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	config := &client.Config{ReadQuorum: 1, WriteQuorum: 2, ReplicationFactor: 3}
+	consumers := []*client.Client{
+		newTestClient(t, config, servers...),
+		newTestClient(t, config, servers...),
+	}
+	ctx := context.Background()
+	
+	// The Set has reached a quorum of owners but not the primary
+	key := keyOwnedBy(t, "node0", "node0", "node1", "node2")
+	const version = 1
+	for _, s := range servers[1:] {
+		s.cache.SetVersioned(key, []byte("payload"), 0, version)
+	}
+	
+	value, found, err := consumers[0].GetAndDelete(ctx, key)
+	if err != nil {
+		t.Fatalf("GetAndDelete failed: %v", err)
+	}
+	if !found || string(value) != "payload" {
+		t.Fatalf("Expected the pop to get the value the primary missed, got %q, %v", value, found)
+	}
+	
+	// The pending Set lands on the primary after the pop
+	resp, err := dialTestServer(t, servers[0]).Set(ctx, &proto.SetRequest{Key: key, Value: []byte("payload"), Version: version})
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if resp.Success {
+		t.Error("Expected the pop's tombstone to reject the late Set")
+	}
+	
+	for _, consumer := range consumers {
+		if value, found, err := consumer.GetAndDelete(ctx, key); err != nil || found {
+			t.Errorf("Expected the job not to be popped twice, got %q, %v, %v", value, found, err)
+		}
+	}
+}
+
+func TestE2EDeleteIf(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "key", []byte("first"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	meta, err := c.GetMeta(ctx, "key")
+	if err != nil {
+		t.Fatalf("GetMeta failed: %v", err)
+	}
+	
+	// Someone else updates the key after it was read
+	if err := c.Set(ctx, "key", []byte("second"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.DeleteIf(ctx, "key", meta.Version); !errors.Is(err, client.ErrVersionMismatch) {
+		t.Errorf("Expected ErrVersionMismatch for a stale version, got %v", err)
+	}
+	if value, err := c.Get(ctx, "key"); err != nil || string(value) != "second" {
+		t.Errorf("Expected the update to survive, got %s, %v", string(value), err)
+	}
+	
+	meta, err = c.GetMeta(ctx, "key")
+	if err != nil {
+		t.Fatalf("GetMeta failed: %v", err)
+	}
+	if err := c.DeleteIf(ctx, "key", meta.Version); err != nil {
+		t.Fatalf("DeleteIf with the current version failed: %v", err)
+	}
+	for i, s := range servers {
+		if s.cache.Exists("key") {
+			t.Errorf("Expected key to be deleted on node%d", i)
+		}
+	}
+}
+
+// TestE2EConditionalGet tests that a Get naming the current version returns not
+// modified without the value, and that any other version returns the value
+func TestE2EConditionalGet(t *testing.T) {
+	server := startTestServer(t, nil)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "key", []byte("v1"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	first, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"})
+	if err != nil || first.Version == 0 {
+		t.Fatalf("Expected a versioned value, got %v (%v)", first, err)
+	}
+	
+	resp, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key", IfVersion: first.Version})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !resp.NotModified || !resp.Found || len(resp.Value) != 0 || resp.Version != first.Version {
+		t.Errorf("Expected not modified with no value at version %d, got %v", first.Version, resp)
+	}
+	
+	resp, err = grpcClient.Get(ctx, &proto.GetRequest{Key: "key", IfVersion: first.Version - 1})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if resp.NotModified || string(resp.Value) != "v1" {
+		t.Errorf("Expected the full value for another version, got %v", resp)
+	}
+	
+	// Once the key is rewritten the old version no longer matches
+	if err := c.Set(ctx, "key", []byte("v2"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	resp, err = grpcClient.Get(ctx, &proto.GetRequest{Key: "key", IfVersion: first.Version})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if resp.NotModified || string(resp.Value) != "v2" || resp.Version <= first.Version {
+		t.Errorf("Expected the new value and version, got %v", resp)
+	}
+	
+	resp, err = grpcClient.Get(ctx, &proto.GetRequest{Key: "missing", IfVersion: first.Version})
+	if err != nil || resp.Found || resp.NotModified {
+		t.Errorf("Expected a missing key to be reported not found, got %v (%v)", resp, err)
+	}
+}
+
+// TestE2EGetMeta tests metadata-only reads through the RPC and the client
+func TestE2EGetMeta(t *testing.T) {
+	server := startTestServer(t, nil)
+	c := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 1}, server)
+	grpcClient := dialTestServer(t, server)
+	ctx := context.Background()
+	
+	before := time.Now()
+	value := make([]byte, 64*1024)
+	if err := c.Set(ctx, "large", value, time.Hour); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	resp, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "large", MetaOnly: true})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !resp.Found || len(resp.Value) != 0 {
+		t.Errorf("Expected found with no value in meta-only mode, got found=%v with %d bytes", resp.Found, len(resp.Value))
+	}
+	if resp.Size != int64(len(value)) || resp.Version == 0 {
+		t.Errorf("Expected size %d and a version, got %d and %d", len(value), resp.Size, resp.Version)
+	}
+	
+	// Regular reads carry the same metadata along with the value
+	full, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "large"})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(full.Value) != len(value) || full.Size != resp.Size {
+		t.Errorf("Expected full value with matching size, got %d bytes and size %d", len(full.Value), full.Size)
+	}
+	
+	meta, err := c.GetMeta(ctx, "large")
+	if err != nil {
+		t.Fatalf("GetMeta failed: %v", err)
+	}
+	if meta.Size != int64(len(value)) || meta.Version != resp.Version {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+	if meta.CreatedAt.Before(before.Add(-time.Second)) || meta.CreatedAt.After(time.Now()) {
+		t.Errorf("Expected created time around the write, got %v", meta.CreatedAt)
+	}
+	if remaining := time.Until(meta.ExpiresAt); remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected about an hour until expiry, got %v", remaining)
+	}
+	
+	if _, err := c.GetMeta(ctx, "missing"); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
+
+// TestE2EReadRepairKeepsTTL tests that read repair copies the resolved value's expiry
+func TestE2EReadRepairKeepsTTL(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2, ReadRepair: true}, servers...)
+	ctx := context.Background()
+	
+	servers[0].cache.SetVersioned("key", []byte("new"), time.Hour, 5)
+	servers[1].cache.SetVersioned("key", []byte("old"), 0, 3)
+	
+	if _, _, err := c.GetConsistent(ctx, "key"); err != nil {
+		t.Fatalf("GetConsistent failed: %v", err)
+	}
+	
+	entry, exists := servers[1].cache.PeekEntry("key")
+	if !exists || string(entry.Value) != "new" || entry.Version != 5 {
+		t.Fatalf("Expected stale replica to be repaired, got %+v", entry)
+	}
+	if remaining := time.Until(entry.ExpiresAt); remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected repaired value to keep about an hour of TTL, got %v", remaining)
+	}
+}
+
+// TestE2ERequestIDAndTracing tests that request IDs are logged and echoed by the server
+// and that client and server spans join the same trace
+func TestE2ERequestIDAndTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	
+	server := startTestServer(t, func(config *Config) {
+		config.EnableTracing = true
+		config.TracerProvider = provider
+	})
+	core, logs := observer.New(zap.DebugLevel)
+	server.logger = zap.New(core)
+	
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:     1,
+		WriteQuorum:    1,
+		EnableTracing:  true,
+		TracerProvider: provider,
+	}, server)
+	ctx := context.Background()
+	
+	if err := c.Set(client.WithRequestID(ctx, "req-123"), "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	// The server logs the caller's request ID
+	correlated := logs.FilterField(zap.String("request_id", "req-123")).All()
+	if len(correlated) != 1 || correlated[0].ContextMap()["method"] != proto.CacheService_Set_FullMethodName {
+		t.Errorf("Expected one Set log entry with the request ID, got %v", correlated)
+	}
+	
+	// The server span continues the client's trace
+	var clientOp, serverSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch {
+		case span.Name() == "cache.client.Set":
+			clientOp = span
+		case span.Name() == proto.CacheService_Set_FullMethodName && span.SpanKind() == trace.SpanKindServer:
+			serverSpan = span
+		}
+	}
+	if clientOp == nil || serverSpan == nil {
+		t.Fatalf("Expected client and server spans, got %d spans", len(recorder.Ended()))
+	}
+	if serverSpan.SpanContext().TraceID() != clientOp.SpanContext().TraceID() {
+		t.Error("Expected server span to join the client's trace")
+	}
+	
+	// The request ID is echoed in the trailer, and generated when the caller sends none
+	grpcClient := dialTestServer(t, server)
+	var trailer metadata.MD
+	if _, err := grpcClient.Get(metadata.AppendToOutgoingContext(ctx, RequestIDHeader, "req-456"),
+		&proto.GetRequest{Key: "key"}, grpc.Trailer(&trailer)); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if ids := trailer.Get(RequestIDHeader); len(ids) != 1 || ids[0] != "req-456" {
+		t.Errorf("Expected request ID in trailer, got %v", ids)
+	}
+	if _, err := grpcClient.Get(ctx, &proto.GetRequest{Key: "key"}, grpc.Trailer(&trailer)); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if ids := trailer.Get(RequestIDHeader); len(ids) != 1 || ids[0] == "" {
+		t.Errorf("Expected a generated request ID in trailer, got %v", ids)
+	}
+}
+
+// TestE2ETracingFanOut tests that every RPC of a quorum fan-out is traced as a child
+// of the client operation, naming its node, and continued by that node's server span
+func TestE2ETracingFanOut(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	
+	var servers []*Server
+	for i := 0; i < 3; i++ {
+		servers = append(servers, startTestServer(t, func(config *Config) {
+			config.EnableTracing = true
+			config.TracerProvider = provider
+		}))
+	}
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:     1,
+		WriteQuorum:    3,
+		EnableTracing:  true,
+		TracerProvider: provider,
+	}, servers...)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	
+	spans := recorder.Ended()
+	for _, op := range []struct{ name, method string }{
+		{"cache.client.Set", proto.CacheService_Set_FullMethodName},
+		{"cache.client.Delete", proto.CacheService_Delete_FullMethodName},
+	} {
+		var parent sdktrace.ReadOnlySpan
+		for _, span := range spans {
+			if span.Name() == op.name {
+				parent = span
+			}
+		}
+		if parent == nil {
+			t.Fatalf("Expected a %s span", op.name)
+		}
+		
+		// One client span per owner under the operation, each naming its node
+		rpcs := make(map[trace.SpanID]bool)
+		nodes := make(map[string]bool)
+		for _, span := range spans {
+			if span.Name() != op.method || span.SpanKind() != trace.SpanKindClient || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+				continue
+			}
+			rpcs[span.SpanContext().SpanID()] = true
+			for _, attr := range span.Attributes() {
+				if attr.Key == "cache.node" {
+					nodes[attr.Value.AsString()] = true
+				}
+			}
+		}
+		if len(rpcs) != 3 || len(nodes) != 3 {
+			t.Errorf("Expected %s to have 3 RPC spans on distinct nodes, got %d spans on %v", op.name, len(rpcs), nodes)
+		}
+		
+		// Each owner's server span continues its RPC span
+		continued := 0
+		for _, span := range spans {
+			if span.Name() == op.method && span.SpanKind() == trace.SpanKindServer && rpcs[span.Parent().SpanID()] {
+				if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+					t.Errorf("Expected %s server span to join the client's trace", op.method)
+				}
+				continued++
+			}
+		}
+		if continued != 3 {
+			t.Errorf("Expected 3 server spans under the %s RPCs, got %d", op.name, continued)
+		}
+	}
+	
+	// A client without tracing sends no span context, so the servers start new traces
+	untraced := newTestClient(t, &client.Config{ReadQuorum: 1, WriteQuorum: 3}, servers...)
+	before := len(recorder.Ended())
+	if err := untraced.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if len(recorder.Ended()) == before {
+		t.Fatal("Expected the servers to record spans for untraced calls")
+	}
+	for _, span := range recorder.Ended()[before:] {
+		if span.Parent().IsValid() {
+			t.Errorf("Expected untraced calls to produce root server spans, got parent on %s", span.Name())
+		}
+	}
+}
+
+// TestE2EWriteBack tests that buffered writes are coalesced per key and delivered in batches
+func TestE2EWriteBack(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{
+		ReadQuorum:        2,
+		WriteQuorum:       2,
+		WriteBack:         true,
+		WriteBackInterval: time.Hour,
+	}, servers...)
+	ctx := context.Background()
+	
+	for i := 0; i < 5; i++ {
+		if err := c.Set(ctx, "counter", []byte(fmt.Sprintf("v%d", i)), 0); err != nil {
+			t.Fatalf("Buffered set failed: %v", err)
+		}
+	}
+	if err := c.Set(ctx, "other", []byte("value"), time.Minute); err != nil {
+		t.Fatalf("Buffered set failed: %v", err)
+	}
+	
+	if backlog := c.GetStats()["write_backlog"]; backlog != 2 {
+		t.Errorf("Expected repeated writes to coalesce into 2 pending writes, got %v", backlog)
+	}
+	for i, s := range servers {
+		if atomic.LoadUint64(&s.requestsTotal) != 0 {
+			t.Errorf("Expected no requests to node%d before flushing", i)
+		}
+	}
+	
+	// Pending writes are visible to the client that made them
+	if value, err := c.Get(ctx, "counter"); err != nil || string(value) != "v4" {
+		t.Errorf("Expected buffered value v4, got %q (%v)", value, err)
+	}
+	
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	for i, s := range servers {
+		if requests := atomic.LoadUint64(&s.requestsTotal); requests != 1 {
+			t.Errorf("Expected one batch request to node%d, got %d", i, requests)
+		}
+		
+		resp, err := dialTestServer(t, s).Get(ctx, &proto.GetRequest{Key: "counter"})
+		if err != nil || string(resp.Value) != "v4" {
+			t.Errorf("Expected node%d to hold the last write v4, got %q (%v)", i, resp.GetValue(), err)
+		}
+		resp, err = dialTestServer(t, s).Get(ctx, &proto.GetRequest{Key: "other"})
+		if err != nil || resp.ExpiresAt == nil {
+			t.Errorf("Expected node%d to hold other with its TTL, got %v (%v)", i, resp, err)
+		}
+	}
+	
+	// The background flusher delivers writes without an explicit Flush
+	flushed := newTestClient(t, &client.Config{
+		ReadQuorum:        2,
+		WriteQuorum:       2,
+		WriteBack:         true,
+		WriteBackInterval: 20 * time.Millisecond,
+	}, servers...)
+	if err := flushed.Set(ctx, "eventual", []byte("value"), 0); err != nil {
+		t.Fatalf("Buffered set failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := dialTestServer(t, servers[0]).Get(ctx, &proto.GetRequest{Key: "eventual"})
+		if err == nil && resp.Found {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected background flush to deliver the write")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	
+	// Close drains whatever is still buffered
+	closing, err := client.NewClient(&client.Config{
+		ReadQuorum:        2,
+		WriteQuorum:       2,
+		WriteBack:         true,
+		WriteBackInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for i, s := range servers {
+		closing.AddNode(fmt.Sprintf("node%d", i), grpcAddr(s))
+	}
+	closing.Set(ctx, "on-close", []byte("value"), 0)
+	if err := closing.Close(); err != nil {
+		t.Fatalf("Close failed to flush: %v", err)
+	}
+	resp, err := dialTestServer(t, servers[1]).Get(ctx, &proto.GetRequest{Key: "on-close"})
+	if err != nil || !resp.Found {
+		t.Errorf("Expected Close to flush pending writes, got %v (%v)", resp, err)
+	}
+	
+	// Large backlogs are sent in bounded batches
+	before := atomic.LoadUint64(&servers[0].requestsTotal)
+	for i := 0; i < 1200; i++ {
+		c.Set(ctx, fmt.Sprintf("bulk%d", i), []byte("value"), 0)
+	}
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if requests := atomic.LoadUint64(&servers[0].requestsTotal) - before; requests != 3 {
+		t.Errorf("Expected 1200 writes to be sent in 3 batches, got %d requests", requests)
+	}
+	
+	// Writes that keep failing are dropped rather than buffered forever
+	failing := newTestClient(t, &client.Config{
+		ReadQuorum:        1,
+		WriteQuorum:       1,
+		WriteBack:         true,
+		WriteBackInterval: time.Hour,
+	})
+	failing.AddNode("down", "localhost:1")
+	failing.Set(ctx, "lost", []byte("value"), 0)
+	for i := 0; i < 5; i++ {
+		if err := failing.Flush(ctx); err == nil {
+			t.Fatal("Expected a flush to an unreachable node to fail")
+		}
+	}
+	stats := failing.GetStats()
+	if stats["write_backlog"] != 0 || stats["write_dropped"] != uint64(1) {
+		t.Errorf("Expected the write to be dropped after 5 failed flushes, got backlog %v, dropped %v", stats["write_backlog"], stats["write_dropped"])
+	}
+}
+
+// TestE2EMGet tests that MGet issues one BatchGet per primary owner and falls back to the next owner
+func TestE2EMGet(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("mget-%d", i)
+		if i%10 == 0 {
+			continue // Left unset to check not found results
+		}
+		if err := c.Set(ctx, keys[i], []byte(fmt.Sprintf("value-%d", i)), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	
+	before := make([]uint64, len(servers))
+	for i, s := range servers {
+		before[i] = atomic.LoadUint64(&s.requestsTotal)
+	}
+	
+	results, err := c.MGet(ctx, keys)
+	if err != nil {
+		t.Fatalf("MGet failed: %v", err)
+	}
+	
+	var rpcs uint64
+	for i, s := range servers {
+		rpcs += atomic.LoadUint64(&s.requestsTotal) - before[i]
+	}
+	if rpcs != 3 {
+		t.Errorf("Expected one RPC per primary owner (3), got %d", rpcs)
+	}
+	
+	for i, result := range results {
+		if result.Key != keys[i] {
+			t.Fatalf("Expected result %d for %s, got %s", i, keys[i], result.Key)
+		}
+		if result.Err != nil {
+			t.Errorf("Unexpected error for %s: %v", result.Key, result.Err)
+		}
+		if i%10 == 0 {
+			if result.Found {
+				t.Errorf("Expected %s not to be found", result.Key)
+			}
+		} else if !result.Found || string(result.Value) != fmt.Sprintf("value-%d", i) {
+			t.Errorf("Expected %s=value-%d, got %q (found=%v)", result.Key, i, result.Value, result.Found)
+		}
+	}
+	
+	// Keys owned by a stopped primary are read from their other owner
+	servers[0].grpcServer.Stop()
+	results, err = c.MGet(ctx, keys)
+	if err != nil {
+		t.Fatalf("MGet failed with one node down: %v", err)
+	}
+	for i, result := range results {
+		if i%10 != 0 && (!result.Found || result.Err != nil) {
+			t.Errorf("Expected %s to be read from its other owner, got found=%v err=%v", result.Key, result.Found, result.Err)
+		}
+	}
+	
+	// Keys with no reachable owner are reported
+	servers[1].grpcServer.Stop()
+	r := ring.NewRing()
+	for _, id := range []string{"node0", "node1", "node2"} {
+		r.AddNode(id, id)
+	}
+	var unreachable string
+	for i := 0; unreachable == ""; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if owners := r.Owners(key, 2); owners[0].ID != "node2" && owners[1].ID != "node2" {
+			unreachable = key
+		}
+	}
+	reachable := keyOwnedBy(t, "node2", "node0", "node1", "node2")
+	results, err = c.MGet(ctx, []string{unreachable, reachable})
+	if err != nil {
+		t.Fatalf("Expected a partial result, got %v", err)
+	}
+	if results[0].Err == nil {
+		t.Errorf("Expected %s to be reported as unfetched", unreachable)
+	}
+	if results[1].Err != nil {
+		t.Errorf("Expected %s to be read from node2, got %v", reachable, results[1].Err)
+	}
+	if _, err := c.MGet(ctx, []string{unreachable}); err == nil {
+		t.Error("Expected an error when no key could be fetched")
+	}
+}
+
+// fakeSink records the events published to it
+type fakeSink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (f *fakeSink) Publish(ctx context.Context, events []Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeSink) find(key string) (Event, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, event := range f.events {
+		if event.Key == key {
+			return event, true
+		}
+	}
+	return Event{}, false
+}
+
+// TestE2EExpiryEvents tests that expired and evicted keys are published to the event sink
+func TestE2EExpiryEvents(t *testing.T) {
+	sink := &fakeSink{}
+	s := startTestServer(t, func(c *Config) {
+		c.CacheCapacity = 2
+		c.CleanupInterval = 20 * time.Millisecond
+		c.EventSink = sink
+		c.EventFlushInterval = 20 * time.Millisecond
+	})
+	cc := dialTestServer(t, s)
+	ctx := context.Background()
+	
+	if _, err := cc.Set(ctx, &proto.SetRequest{Key: "short", Value: []byte("value"), Ttl: durationpb.New(50 * time.Millisecond)}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	// Nothing is published before the TTL lapses
+	time.Sleep(30 * time.Millisecond)
+	if _, found := sink.find("short"); found {
+		t.Fatal("Expected no event before the TTL lapsed")
+	}
+	
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		event, found := sink.find("short")
+		if found {
+			if event.Reason != "expired" {
+				t.Errorf("Expected reason expired, got %q", event.Reason)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected an expiry event after the TTL lapsed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	
+	// Capacity evictions are published too
+	for _, key := range []string{"a", "b", "c"} {
+		cc.Set(ctx, &proto.SetRequest{Key: key, Value: []byte("value")})
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		if event, found := sink.find("a"); found {
+			if event.Reason != "evicted" {
+				t.Errorf("Expected reason evicted, got %q", event.Reason)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected an eviction event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	
+	if published := fetchMetrics(t, s)["events_published"]; published != float64(2) {
+		t.Errorf("Expected 2 published events, got %v", published)
+	}
+}
+
+// TestWebhookSink tests that webhook sinks post events as JSON
+func TestWebhookSink(t *testing.T) {
+	received := make(chan []Event, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []Event
+		if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+			t.Errorf("Failed to decode webhook body: %v", err)
+		}
+		received <- events
+	}))
+	defer hook.Close()
+	
+	sink, err := NewEventSink(hook.URL)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	if err := sink.Publish(context.Background(), []Event{{Key: "key", Reason: "expired", Time: time.Now()}}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if events := <-received; len(events) != 1 || events[0].Key != "key" || events[0].Reason != "expired" {
+		t.Errorf("Unexpected webhook events: %+v", events)
+	}
+	
+	if _, err := NewEventSink("nats://localhost:4222"); err == nil {
+		t.Error("Expected unsupported scheme to be rejected")
+	}
+}
+
+// TestE2EGetAndTouch tests sliding expiration through GetAndTouch on every owner
+func TestE2EGetAndTouch(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2}, servers...)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "session", []byte("data"), 100*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	results, err := c.GetAndTouch(ctx, []string{"session", "missing"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GetAndTouch failed: %v", err)
+	}
+	if !results[0].Found || string(results[0].Value) != "data" {
+		t.Errorf("Expected session to be found, got %+v", results[0])
+	}
+	if results[1].Found || results[1].Err != nil {
+		t.Errorf("Expected missing to be a plain miss, got %+v", results[1])
+	}
+	
+	for i, s := range servers {
+		cc := dialTestServer(t, s)
+		resp, err := cc.Get(ctx, &proto.GetRequest{Key: "session"})
+		if err != nil || resp.ExpiresAt == nil || time.Until(resp.ExpiresAt.AsTime()) < 59*time.Minute {
+			t.Errorf("Expected node%d to extend the TTL to an hour, got %v (%v)", i, resp, err)
+		}
+		resp, err = cc.Get(ctx, &proto.GetRequest{Key: "missing"})
+		if err != nil || resp.Found || resp.Tombstone {
+			t.Errorf("Expected node%d not to create missing, got %v (%v)", i, resp, err)
+		}
+	}
+	
+	time.Sleep(150 * time.Millisecond)
+	if value, err := c.Get(ctx, "session"); err != nil || string(value) != "data" {
+		t.Errorf("Expected touched session to outlive its original TTL, got %q (%v)", value, err)
+	}
+}
+
+// TestE2EReplicationFactor tests that writes reach every replica but succeed at the write quorum
+func TestE2EReplicationFactor(t *testing.T) {
+	servers := []*Server{
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+		startTestServer(t, nil),
+	}
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2, ReplicationFactor: 3}, servers...)
+	ctx := context.Background()
+	
+	if err := c.Set(ctx, "replicated", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	
+	// The write returns at quorum, so the last replica may still be applying it
+	for i, s := range servers {
+		cc := dialTestServer(t, s)
+		deadline := time.Now().Add(time.Second)
+		for {
+			resp, err := cc.Get(ctx, &proto.GetRequest{Key: "replicated"})
+			if err == nil && resp.Found {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected node%d to receive the write", i)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	
+	// Two of three replicas are enough for a write to succeed, one is not
+	servers[2].grpcServer.Stop()
+	if err := c.Set(ctx, "replicated", []byte("updated"), 0); err != nil {
+		t.Errorf("Expected write to succeed with two replicas up, got %v", err)
+	}
+	servers[1].grpcServer.Stop()
+	if err := c.Set(ctx, "replicated", []byte("again"), 0); err == nil {
+		t.Error("Expected write to fail with one replica up")
+	}
+	
+	if _, err := client.NewClient(&client.Config{ReadQuorum: 2, WriteQuorum: 2, ReplicationFactor: 1}); err == nil {
+		t.Error("Expected a replication factor below the quorum to be rejected")
+	}
+}
+
+// TestE2EQuorumFailsFast tests that writes give up as soon as the quorum is out of reach
+func TestE2EQuorumFailsFast(t *testing.T) {
+	down := []*Server{startTestServer(t, nil), startTestServer(t, nil)}
+	for _, s := range down {
+		s.grpcServer.Stop()
+	}
+	slow, slowAddr := startSlowServer(t)
+	atomic.StoreInt64(&slow.writeDelay, int64(5*time.Second))
+	
+	c := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2, ReplicationFactor: 3}, down...)
+	if err := c.AddNode("slow", slowAddr); err != nil {
+		t.Fatalf("Failed to add slow node: %v", err)
+	}
+	ctx := context.Background()
+	
+	for _, op := range []struct {
+		name string
+		call func() error
+	}{
+		{"Set", func() error { return c.Set(ctx, "key", []byte("value"), 0) }},
+		{"Delete", func() error { return c.Delete(ctx, "key") }},
+	} {
+		start := time.Now()
+		err := op.call()
+		elapsed := time.Since(start)
+		if err == nil {
+			t.Errorf("Expected %s to fail with two of three replicas down", op.name)
+		}
+		if elapsed > time.Second {
+			t.Errorf("Expected %s to fail before the slow replica answered, took %v", op.name, elapsed)
+		}
+	}
+	
+	// Writes that reached the slow replica are canceled rather than left to finish;
+	// the others were canceled before they were sent
+	time.Sleep(200 * time.Millisecond)
+	if started, canceled := atomic.LoadInt64(&slow.startedWrites), atomic.LoadInt64(&slow.canceledWrites); started != canceled {
+		t.Errorf("Expected every write started on the slow replica to be canceled, %d of %d were", canceled, started)
+	}
+	
+	// Too few owners for the quorum fails without sending anything
+	single := newTestClient(t, &client.Config{ReadQuorum: 2, WriteQuorum: 2})
+	if err := single.AddNode("slow", slowAddr); err != nil {
+		t.Fatalf("Failed to add slow node: %v", err)
+	}
+	start := time.Now()
+	if err := single.Set(ctx, "key", []byte("value"), 0); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Expected an immediate error with one owner for a quorum of 2, got %v after %v", err, time.Since(start))
+	}
+}
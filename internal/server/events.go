@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/shard-cache/internal/cache"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultEventBufferSize is how many events may wait for the publisher before new ones are dropped
+	defaultEventBufferSize = 1024
+
+	// defaultEventFlushInterval is how often buffered events are published when no interval is configured
+	defaultEventFlushInterval = time.Second
+
+	// eventBatchSize is the most events published in one call to the sink
+	eventBatchSize = 100
+
+	// eventPublishTimeout bounds a single call to the sink
+	eventPublishTimeout = 5 * time.Second
+)
+
+// Event reports a key that left the cache because it expired or was evicted
+type Event struct {
+	Key    string    `json:"key"`
+	Reason string    `json:"reason"` // "expired" or "evicted"
+	Time   time.Time `json:"time"`
+}
+
+// EventSink receives batches of expiry and eviction events. Publish is called from a
+// single goroutine and must not retain the slice after it returns.
+type EventSink interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// WebhookSink posts each batch of events to a URL as a JSON array
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// Publish implements EventSink
+func (w *WebhookSink) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// NewEventSink creates a sink from a URL. Only http and https webhooks are supported.
+func NewEventSink(rawURL string) (EventSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event sink URL: %w", err)
+	}
+	
+	switch u.Scheme {
+	case "http", "https":
+		return &WebhookSink{URL: rawURL, Client: &http.Client{Timeout: eventPublishTimeout}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported event sink scheme %q", u.Scheme)
+	}
+}
+
+// startEventPublisher buffers the cache's expiry and eviction events and publishes
+// them to the sink in batches. Events are dropped rather than blocking the cache
+// when the buffer is full.
+func (s *Server) startEventPublisher(sink EventSink) {
+	bufferSize := s.config.EventBufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBufferSize
+	}
+	interval := s.config.EventFlushInterval
+	if interval <= 0 {
+		interval = defaultEventFlushInterval
+	}
+	
+	events := make(chan Event, bufferSize)
+	s.cache.OnEvict(func(key string, value []byte, reason cache.EvictionReason) {
+		select {
+		case events <- Event{Key: key, Reason: reason.String(), Time: time.Now()}:
+		default:
+			atomic.AddUint64(&s.eventsDropped, 1)
+		}
+	})
+	
+	ticker := time.NewTicker(interval)
+	s.wg.Add(1)
+	
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		
+		batch := make([]Event, 0, eventBatchSize)
+		for {
+			select {
+			case <-s.shutdownCh:
+				// Publish whatever is still buffered
+				for {
+					select {
+					case event := <-events:
+						batch = append(batch, event)
+						if len(batch) == eventBatchSize {
+							batch = s.publishEvents(sink, batch)
+						}
+					default:
+						s.publishEvents(sink, batch)
+						return
+					}
+				}
+			case event := <-events:
+				batch = append(batch, event)
+				if len(batch) == eventBatchSize {
+					batch = s.publishEvents(sink, batch)
+				}
+			case <-ticker.C:
+				batch = s.publishEvents(sink, batch)
+			}
+		}
+	}()
+}
+
+// publishEvents sends a batch to the sink and returns the batch emptied for reuse.
+// Events that fail to publish are dropped and counted.
+func (s *Server) publishEvents(sink EventSink, batch []Event) []Event {
+	if len(batch) == 0 {
+		return batch
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	
+	if err := sink.Publish(ctx, batch); err != nil {
+		atomic.AddUint64(&s.eventsDropped, uint64(len(batch)))
+		s.logger.Warn("Failed to publish cache events", zap.Int("events", len(batch)), zap.Error(err))
+	} else {
+		atomic.AddUint64(&s.eventsPublished, uint64(len(batch)))
+	}
+	return batch[:0]
+}
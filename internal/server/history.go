@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultStatsHistorySize is how many samples are kept when StatsHistorySize is not set
+const defaultStatsHistorySize = 60
+
+// statsSample is the cache's state at one sampling tick, with the counters covering
+// the interval since the previous tick
+type statsSample struct {
+	Time      time.Time `json:"time"`
+	Size      int       `json:"size"`
+	Hits      uint64    `json:"hits"`
+	Misses    uint64    `json:"misses"`
+	HitRatio  float64   `json:"hit_ratio"` // Zero for an interval without reads
+	Evictions uint64    `json:"evictions"`
+}
+
+// statsHistory keeps the most recent samples in a fixed ring buffer, writing over the
+// oldest once it is full
+type statsHistory struct {
+	mu      sync.Mutex
+	samples []statsSample
+	next    int // Index the next sample is written to
+	full    bool
+	
+	// Counters at the previous sample
+	lastHits      uint64
+	lastMisses    uint64
+	lastEvictions uint64
+}
+
+// newStatsHistory creates an empty history holding up to size samples
+func newStatsHistory(size int) *statsHistory {
+	if size <= 0 {
+		size = defaultStatsHistorySize
+	}
+	return &statsHistory{samples: make([]statsSample, size)}
+}
+
+// record adds a sample taken at now from the cache's size and cumulative counters
+func (h *statsHistory) record(now time.Time, size int, hits, misses, evictions uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	sample := statsSample{
+		Time:      now,
+		Size:      size,
+		Hits:      counterDelta(hits, h.lastHits),
+		Misses:    counterDelta(misses, h.lastMisses),
+		Evictions: counterDelta(evictions, h.lastEvictions),
+	}
+	if reads := sample.Hits + sample.Misses; reads > 0 {
+		sample.HitRatio = float64(sample.Hits) / float64(reads)
+	}
+	h.lastHits, h.lastMisses, h.lastEvictions = hits, misses, evictions
+	
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// counterDelta returns how far a cumulative counter has moved since last. A counter
+// below its last value has been reset, as by ResetStats, and has counted current since.
+func counterDelta(current, last uint64) uint64 {
+	if current < last {
+		return current
+	}
+	return current - last
+}
+
+// snapshot returns a copy of the samples, oldest first
+func (h *statsHistory) snapshot() []statsSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	if !h.full {
+		return append([]statsSample(nil), h.samples[:h.next]...)
+	}
+	samples := make([]statsSample, 0, len(h.samples))
+	samples = append(samples, h.samples[h.next:]...)
+	return append(samples, h.samples[:h.next]...)
+}
+
+// sampleStats records the cache's current statistics in the history
+func (s *Server) sampleStats(now time.Time) {
+	if s.history == nil {
+		return
+	}
+	stats := s.cache.StatsSnapshot()
+	s.history.record(now, stats.Size, stats.Hits, stats.Misses, stats.Evictions)
+}
+
+// statsHistoryHandler serves the recent statistics samples as a JSON array, oldest first
+func (s *Server) statsHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	samples := []statsSample{}
+	if s.history != nil {
+		samples = s.history.snapshot()
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(samples)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/shard-cache/proto"
+)
+
+// Mode controls which requests a node accepts
+type Mode string
+
+const (
+	// ModeNormal serves reads and writes
+	ModeNormal Mode = "normal"
+
+	// ModeReadOnly serves reads and rejects writes
+	ModeReadOnly Mode = "read-only"
+
+	// ModeDraining serves reads and rejects writes, and reports the node as
+	// unhealthy so clients and load balancers stop routing to it
+	ModeDraining Mode = "draining"
+)
+
+// parseMode validates a mode name
+func parseMode(name string) (Mode, error) {
+	switch mode := Mode(name); mode {
+	case ModeNormal, ModeReadOnly, ModeDraining:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q", name)
+	}
+}
+
+// acceptsWrites reports whether the mode allows writes
+func (m Mode) acceptsWrites() bool {
+	return m == ModeNormal
+}
+
+// isWriteMethod reports whether a gRPC method modifies the cache
+func isWriteMethod(fullMethod string) bool {
+	switch fullMethod {
+	case proto.CacheService_Set_FullMethodName,
+		proto.CacheService_Delete_FullMethodName,
+		proto.CacheService_GetAndDelete_FullMethodName,
+		proto.CacheService_Expire_FullMethodName,
+		proto.CacheService_Persist_FullMethodName,
+		proto.CacheService_SetBatch_FullMethodName,
+		proto.CacheService_GetAndTouch_FullMethodName,
+		proto.CacheService_Preload_FullMethodName,
+		proto.CacheService_SetStream_FullMethodName:
+		return true
+	default:
+		return false
+	}
+}
+
+// getMode returns the current serving mode
+func (s *Server) getMode() Mode {
+	s.modeMutex.RLock()
+	defer s.modeMutex.RUnlock()
+	return s.mode
+}
+
+// setMode switches the serving mode and returns the previous one
+func (s *Server) setMode(mode Mode) Mode {
+	s.modeMutex.Lock()
+	defer s.modeMutex.Unlock()
+	
+	previous := s.mode
+	s.mode = mode
+	s.updateHealthStatus(mode)
+	return previous
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// requestDurationBuckets are the upper bounds, in seconds, of the request duration
+// histogram: from 100µs, below which cache reads usually finish, to 10s
+var requestDurationBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// requestMetrics holds the server's Prometheus collectors. Each server has its own
+// registry so that several servers can run in one process.
+type requestMetrics struct {
+	registry *prometheus.Registry
+	duration *prometheus.HistogramVec
+}
+
+// newRequestMetrics creates the collectors and registers them
+func newRequestMetrics() *requestMetrics {
+	m := &requestMetrics{
+		registry: prometheus.NewRegistry(),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shardcache_request_duration_seconds",
+			Help:    "Time spent handling unary RPCs, by method.",
+			Buckets: requestDurationBuckets,
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(m.duration)
+	return m
+}
+
+// observe records how long a call to method took
+func (m *requestMetrics) observe(method string, elapsed time.Duration) {
+	m.duration.WithLabelValues(method).Observe(elapsed.Seconds())
+}
+
+// handler serves the collectors in the Prometheus text format
+func (m *requestMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
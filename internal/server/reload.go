@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/shard-cache/proto"
+	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Reload applies the runtime limits from config: MaxConcurrent, CPUThreshold and
+// CleanupInterval. Requests already in flight finish under the previous concurrency
+// limit. Every other setting needs a restart; changes to them are logged and ignored.
+func (s *Server) Reload(config *Config) error {
+	if config.MaxConcurrent <= 0 {
+		return fmt.Errorf("max concurrent must be positive, got %d", config.MaxConcurrent)
+	}
+	if config.CleanupInterval < 0 {
+		return fmt.Errorf("cleanup interval must not be negative, got %v", config.CleanupInterval)
+	}
+	
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+	
+	for _, setting := range s.restartRequired(config) {
+		s.logger.Warn("Ignoring change to a setting that requires a restart", zap.String("setting", setting))
+	}
+	
+	s.semMutex.Lock()
+	if config.MaxConcurrent != s.maxConcurrent {
+		s.semaphore = semaphore.NewWeighted(config.MaxConcurrent)
+		s.maxConcurrent = config.MaxConcurrent
+	}
+	s.semMutex.Unlock()
+	
+	s.cpuMutex.Lock()
+	s.cpuThreshold = config.CPUThreshold
+	s.cpuMutex.Unlock()
+	
+	if config.CleanupInterval != s.cleanupInterval {
+		select {
+		case s.cleanupIntervalCh <- config.CleanupInterval:
+			s.cleanupInterval = config.CleanupInterval
+		case <-s.shutdownCh:
+		}
+	}
+	
+	s.logger.Info("Configuration reloaded",
+		zap.Int64("max_concurrent", config.MaxConcurrent),
+		zap.Float64("cpu_threshold", config.CPUThreshold),
+		zap.Duration("cleanup_interval", config.CleanupInterval))
+	
+	return nil
+}
+
+// restartRequired returns the names of the settings in config that differ from the
+// running configuration but cannot be changed by Reload
+func (s *Server) restartRequired(config *Config) []string {
+	current := s.config
+	settings := []struct {
+		name    string
+		changed bool
+	}{
+		{"GRPCPort", config.GRPCPort != current.GRPCPort},
+		{"HTTPPort", config.HTTPPort != current.HTTPPort},
+		{"GRPCAddr", config.GRPCAddr != current.GRPCAddr},
+		{"HTTPAddr", config.HTTPAddr != current.HTTPAddr},
+		{"CacheCapacity", config.CacheCapacity != current.CacheCapacity},
+		{"CPUWindow", config.CPUWindow != current.CPUWindow},
+		{"TombstoneTTL", config.TombstoneTTL != current.TombstoneTTL},
+		{"EnableAdmin", config.EnableAdmin != current.EnableAdmin},
+		{"WarmupFile", config.WarmupFile != current.WarmupFile},
+		{"SnapshotFile", config.SnapshotFile != current.SnapshotFile},
+		{"SnapshotFormat", config.SnapshotFormat != current.SnapshotFormat},
+		{"EvictBatch", config.EvictBatch != current.EvictBatch},
+		{"EvictionPolicy", config.EvictionPolicy != current.EvictionPolicy},
+		{"Eviction", config.Eviction != current.Eviction},
+		{"MaxKeyBytes", config.MaxKeyBytes != current.MaxKeyBytes},
+		{"MaxRecvMsgSize", config.MaxRecvMsgSize != current.MaxRecvMsgSize},
+		{"MaxSendMsgSize", config.MaxSendMsgSize != current.MaxSendMsgSize},
+		{"TTLJitter", config.TTLJitter != current.TTLJitter},
+		{"LockTiming", config.LockTiming != current.LockTiming},
+		{"BloomFilter", config.BloomFilter != current.BloomFilter},
+		{"BloomRebuildInterval", config.BloomRebuildInterval != current.BloomRebuildInterval},
+		{"OffHeapValues", config.OffHeapValues != current.OffHeapValues},
+		{"TenantLabel", (config.TenantLabel == nil) != (current.TenantLabel == nil)},
+		{"MaxTenants", config.MaxTenants != current.MaxTenants},
+		{"MaxRequestDuration", config.MaxRequestDuration != current.MaxRequestDuration},
+		{"QueueTimeout", config.QueueTimeout != current.QueueTimeout},
+		{"MaxQueued", config.MaxQueued != current.MaxQueued},
+		{"SlowThreshold", config.SlowThreshold != current.SlowThreshold},
+		{"PrefixTTLs", !maps.Equal(config.PrefixTTLs, current.PrefixTTLs)},
+		{"EnableTracing", config.EnableTracing != current.EnableTracing},
+		{"EMAAlpha", config.EMAAlpha != current.EMAAlpha},
+		{"EvictionWarnRate", config.EvictionWarnRate != current.EvictionWarnRate},
+		{"EvictionWarnInterval", config.EvictionWarnInterval != current.EvictionWarnInterval},
+		{"StatsHistorySize", config.StatsHistorySize != current.StatsHistorySize},
+		{"EventSinkURL", config.EventSinkURL != current.EventSinkURL},
+	}
+	
+	var changed []string
+	for _, setting := range settings {
+		if setting.changed {
+			changed = append(changed, setting.name)
+		}
+	}
+	return changed
+}
+
+// reloadFromSource reloads the configuration returned by ReloadConfig, logging
+// rather than returning failures since it runs on SIGHUP
+func (s *Server) reloadFromSource() {
+	if s.config.ReloadConfig == nil {
+		s.logger.Warn("Ignoring reload signal, no configuration source is set")
+		return
+	}
+	
+	config, err := s.config.ReloadConfig()
+	if err != nil {
+		s.logger.Error("Failed to load configuration for reload", zap.Error(err))
+		return
+	}
+	if err := s.Reload(config); err != nil {
+		s.logger.Error("Failed to reload configuration", zap.Error(err))
+	}
+}
+
+// currentSemaphore returns the semaphore limiting concurrent requests
+func (s *Server) currentSemaphore() *semaphore.Weighted {
+	s.semMutex.RLock()
+	defer s.semMutex.RUnlock()
+	return s.semaphore
+}
+
+// concurrencyLimit returns the number of requests that may be served concurrently
+func (s *Server) concurrencyLimit() int64 {
+	s.semMutex.RLock()
+	defer s.semMutex.RUnlock()
+	return s.maxConcurrent
+}
+
+// runtimeLimits returns a copy of the running configuration with the limits Reload
+// may have changed
+func (s *Server) runtimeLimits() *Config {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+	
+	config := *s.config
+	config.MaxConcurrent = s.concurrencyLimit()
+	config.CleanupInterval = s.cleanupInterval
+	s.cpuMutex.RLock()
+	config.CPUThreshold = s.cpuThreshold
+	s.cpuMutex.RUnlock()
+	return &config
+}
+
+// SetLimits implements the SetLimits admin RPC
+func (s *Server) SetLimits(ctx context.Context, req *proto.SetLimitsRequest) (*proto.SetLimitsResponse, error) {
+	config := s.runtimeLimits()
+	if req.MaxConcurrent != 0 {
+		config.MaxConcurrent = req.MaxConcurrent
+	}
+	if req.CpuThreshold != 0 {
+		config.CPUThreshold = req.CpuThreshold
+	}
+	if req.CleanupInterval != nil {
+		config.CleanupInterval = req.CleanupInterval.AsDuration()
+	}
+	
+	if err := s.Reload(config); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	
+	return &proto.SetLimitsResponse{
+		MaxConcurrent:   config.MaxConcurrent,
+		CpuThreshold:    config.CPUThreshold,
+		CleanupInterval: durationpb.New(config.CleanupInterval),
+	}, nil
+}
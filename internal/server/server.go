@@ -2,47 +2,130 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/shard-cache/internal/cache"
 	"github.com/shard-cache/proto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // Accepts and answers gzip-compressed calls
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// defaultEMAAlpha is the load smoothing factor used when none is configured
+const defaultEMAAlpha = 0.2
+
+// DefaultMaxKeyBytes is the longest key accepted when MaxKeyBytes is not configured
+const DefaultMaxKeyBytes = 4096
+
+// DefaultMaxMsgSize is the largest gRPC message received or sent when no limit is configured
+const DefaultMaxMsgSize = 16 << 20
+
+// defaultBloomRebuildInterval is how often the bloom filter is rebuilt when no interval is configured
+const defaultBloomRebuildInterval = time.Minute
+
+// defaultEvictionWarnInterval is the least time between eviction rate warnings when none is configured
+const defaultEvictionWarnInterval = time.Minute
+
 // Server represents a cache server
 type Server struct {
 	proto.UnimplementedCacheServiceServer
+	proto.UnimplementedAdminServiceServer
 	
 	config     *Config
 	cache      *cache.Cache
 	logger     *zap.Logger
+	logLevel   *zap.AtomicLevel // Level of a logger the server created; nil for an injected one
 	grpcServer *grpc.Server
 	httpServer *http.Server
 	
-	// Backpressure control
-	semaphore *semaphore.Weighted
+	// Listener the gRPC server serves; closed directly if the start fails, since a
+	// Serve goroutine that has not yet begun would otherwise keep it open
+	grpcListener net.Listener
+	
+	// Backpressure control; the semaphore is replaced when MaxConcurrent is reloaded
+	semaphore     *semaphore.Weighted
+	maxConcurrent int64
+	semMutex      sync.RWMutex
+	inFlight      int64
+	queued        int64 // Requests waiting for a slot
+	
+	// Rejection counters
+	loadShedTotal             uint64
+	backpressureRejectedTotal uint64
+	
+	// Request duration histogram served at /metrics/prometheus
+	metrics *requestMetrics
 	
 	// Graceful shutdown
-	shutdownCh chan struct{}
-	wg         sync.WaitGroup
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	wg           sync.WaitGroup
+	
+	// Standard gRPC health service, reporting NOT_SERVING while draining or shutting down
+	healthServer *health.Server
+	
+	// Cleanup scheduling; the current interval, and changes to it sent to the cleanup loop
+	cleanupInterval   time.Duration
+	cleanupIntervalCh chan time.Duration
+	reloadMutex       sync.Mutex
 	
 	// Load shedding
 	cpuThreshold float64
 	cpuWindow    time.Duration
 	cpuHistory   []float64
 	cpuMutex     sync.RWMutex
+	
+	// Smoothed load, updated alongside cpuHistory
+	emaAlpha        float64
+	emaCPU          float64
+	emaRequestRate  float64
+	requestsTotal   uint64
+	lastRequests    uint64
+	emaEvictionRate float64
+	lastEvictions   uint64
+	lastEvictWarn   time.Time // When the eviction rate warning was last logged
+	lastPinWarn     time.Time // When the fully pinned warning was last logged
+	
+	// Per-tenant request counts; nil unless TenantLabel is configured
+	tenants *tenantMetrics
+	
+	// Default TTLs from PrefixTTLs, longest prefix first
+	prefixTTLs []prefixTTL
+	
+	// Cache statistics sampled every second, served by /metrics/history
+	history *statsHistory
+	
+	// Serving mode
+	mode      Mode
+	modeMutex sync.RWMutex
+	
+	// Tracing; nil when disabled
+	tracer trace.Tracer
+	
+	// Expiry and eviction notifications
+	eventsPublished uint64
+	eventsDropped   uint64
 }
 
 // Config holds server configuration
@@ -53,29 +136,292 @@ type Config struct {
 	MaxConcurrent int64
 	CPUThreshold  float64
 	CPUWindow     time.Duration
+	
+	// GRPCAddr and HTTPAddr, if set, are the host:port addresses the servers listen
+	// on, such as 127.0.0.1:8080 to accept only local connections. They take precedence
+	// over GRPCPort and HTTPPort, which listen on every interface.
+	GRPCAddr string
+	HTTPAddr string
+	
+	// CleanupInterval controls how often expired entries and tombstones are purged
+	CleanupInterval time.Duration
+	
+	// TombstoneTTL is how long versioned deletes are remembered
+	TombstoneTTL time.Duration
+	
+	// EnableAdmin registers the AdminService for runtime operator controls, and the
+	// /loglevel HTTP endpoint for reading and changing the log level unless Logger is set
+	EnableAdmin bool
+	
+	// WarmupFile optionally preloads the cache from a snapshot or a JSON lines file on startup
+	WarmupFile string
+	
+	// SnapshotFile, if set, is written with the cache contents on graceful shutdown in
+	// SnapshotFormat, so that a restarted node can load it back as its WarmupFile
+	SnapshotFile   string
+	SnapshotFormat SnapshotFormat
+	
+	// EvictBatch lets the cache exceed capacity by up to EvictBatch-1 entries before
+	// evicting back down to capacity in one pass
+	EvictBatch int
+	
+	// EvictionPolicy selects which new keys the cache admits once it is full;
+	// cache.PolicyTinyLFU protects frequently read keys from scans
+	EvictionPolicy cache.Policy
+	
+	// Eviction selects which entry the cache evicts once it is full: LRU, the
+	// default, FIFO or random
+	Eviction cache.Eviction
+	
+	// MaxKeyBytes is the longest key accepted; longer keys are rejected with
+	// InvalidArgument. Defaults to DefaultMaxKeyBytes.
+	MaxKeyBytes int
+	
+	// MaxRecvMsgSize and MaxSendMsgSize cap the size of a single gRPC message received
+	// or sent, DefaultMaxMsgSize (16MB) by default. Larger limits admit bigger values
+	// and batches, but let every request in flight hold that much memory while it is
+	// decoded; larger messages fail with ResourceExhausted.
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	
+	// TTLJitter randomly spreads each entry's TTL by up to this fraction, e.g. 0.1 for ±10%
+	TTLJitter float64
+	
+	// LockTiming records how long operations wait for the cache lock, reported by
+	// /metrics as cache_lock_waits and cache_lock_wait_ns
+	LockTiming bool
+	
+	// BloomFilter keeps a bloom filter of the cached keys so that reads and Exists
+	// calls for keys never set are answered without a lookup. It is rebuilt from the
+	// live keys every BloomRebuildInterval (one minute by default) to clear the bits
+	// of deleted and evicted keys.
+	BloomFilter          bool
+	BloomRebuildInterval time.Duration
+	
+	// OffHeapValues stores value bytes in memory-mapped regions outside the Go heap,
+	// cutting garbage collection work for large caches at the cost of a copy per read
+	OffHeapValues bool
+	
+	// EnableTracing wraps handlers in OpenTelemetry spans that join the caller's trace.
+	// Spans go to TracerProvider, or the global provider if it is nil.
+	EnableTracing  bool
+	TracerProvider trace.TracerProvider
+	
+	// EMAAlpha is the smoothing factor in (0, 1] for the load averages; higher
+	// values react faster to changes
+	EMAAlpha float64
+	
+	// EvictionWarnRate, if positive, logs a warning whenever the smoothed rate of
+	// capacity evictions exceeds this many per second, a sign that the cache is too
+	// small for its working set. The warning is logged at most once per
+	// EvictionWarnInterval (one minute by default), as is the warning logged whatever
+	// the rate while pinned entries fill the cache.
+	EvictionWarnRate     float64
+	EvictionWarnInterval time.Duration
+	
+	// StatsHistorySize is how many once-a-second samples of cache size, hit ratio and
+	// evictions /metrics/history keeps, 60 by default
+	StatsHistorySize int
+	
+	// ReloadConfig, if set, is called on SIGHUP for a new configuration, which is
+	// applied with Reload
+	ReloadConfig func() (*Config, error)
+	
+	// EventSinkURL publishes expiry and eviction events to a webhook; EventSink, if set,
+	// is used instead. Events are buffered (EventBufferSize, 1024 by default) and sent
+	// in batches every EventFlushInterval (1s by default). Expiry is noticed lazily, when
+	// an expired key is read or purged by cleanup, and events are dropped if the buffer
+	// fills or the sink fails.
+	EventSinkURL       string
+	EventSink          EventSink
+	EventBufferSize    int
+	EventFlushInterval time.Duration
+	
+	// TenantLabel, if set, derives a tenant label from each key so that /metrics breaks
+	// down requests, hits and misses by tenant; TenantPrefix labels keys by their
+	// prefix. Only the first MaxTenants labels seen (100 by default) get their own
+	// counts, and keys without a label or beyond the limit are counted as "other".
+	TenantLabel func(key string) string
+	MaxTenants  int
+	
+	// MaxRequestDuration caps how long a unary RPC may run. Once it is reached the
+	// handler's context is canceled and the caller gets DeadlineExceeded, whether or
+	// not the handler has returned. Zero, the default, leaves RPCs uncapped.
+	MaxRequestDuration time.Duration
+	
+	// QueueTimeout is how long a request waits for a concurrency slot once
+	// MaxConcurrent requests are in flight, so that brief bursts are queued rather than
+	// failed. Requests still waiting when it expires get Unavailable. Zero, the default,
+	// rejects them at once.
+	QueueTimeout time.Duration
+	
+	// MaxQueued bounds the requests waiting for a slot under QueueTimeout. Once as many
+	// are queued, a request that finds no free slot is rejected with Unavailable at once
+	// rather than joining the queue. Zero, the default, leaves the queue unbounded.
+	MaxQueued int
+	
+	// SlowThreshold logs a warning for every unary RPC whose handler runs longer,
+	// with its method, key and duration. Slow requests are always logged, unlike
+	// the debug-level log of every request. Zero, the default, disables it.
+	SlowThreshold time.Duration
+	
+	// PrefixTTLs maps key prefixes to the TTL given to writes under them that carry
+	// no TTL of their own, so expiry policy can be set per namespace on the server.
+	// The longest matching prefix wins, and an empty prefix sets the default for every
+	// other key. Under a matched prefix, writes cannot store a key without expiry,
+	// and Persist is refused.
+	PrefixTTLs map[string]time.Duration
+	
+	// Logger receives the server's logs. If it is nil a production logger writing
+	// JSON to stderr is created.
+	Logger *zap.Logger
+}
+
+// GRPCListenAddr returns the address the gRPC server listens on: GRPCAddr, or
+// GRPCPort on every interface
+func (c *Config) GRPCListenAddr() string {
+	return listenAddr(c.GRPCAddr, c.GRPCPort)
+}
+
+// HTTPListenAddr returns the address the HTTP server listens on: HTTPAddr, or
+// HTTPPort on every interface
+func (c *Config) HTTPListenAddr() string {
+	return listenAddr(c.HTTPAddr, c.HTTPPort)
+}
+
+// listenAddr returns addr, or port on every interface if addr is empty
+func listenAddr(addr string, port int) string {
+	if addr != "" {
+		return addr
+	}
+	return fmt.Sprintf(":%d", port)
+}
+
+// validateListenAddr checks that addr is a host:port address with a port number and,
+// if a host is given, an IP address or a name that resolves
+func validateListenAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("port %q is not a number from 0 to 65535", port)
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+	if _, err := net.LookupHost(host); err != nil {
+		return fmt.Errorf("host %q does not resolve: %w", host, err)
+	}
+	return nil
 }
 
 // NewServer creates a new cache server
 func NewServer(config *Config) (*Server, error) {
-	logger, err := zap.NewProduction()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create logger: %w", err)
+	if err := validateListenAddr(config.GRPCListenAddr()); err != nil {
+		return nil, fmt.Errorf("invalid gRPC address %q: %w", config.GRPCListenAddr(), err)
+	}
+	if err := validateListenAddr(config.HTTPListenAddr()); err != nil {
+		return nil, fmt.Errorf("invalid HTTP address %q: %w", config.HTTPListenAddr(), err)
+	}
+	
+	var err error
+	var logLevel *zap.AtomicLevel
+	logger := config.Logger
+	if logger == nil {
+		loggerConfig := zap.NewProductionConfig()
+		if logger, err = loggerConfig.Build(); err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+		logLevel = &loggerConfig.Level
 	}
 	
 	server := &Server{
-		config:       config,
-		cache:        cache.NewCache(config.CacheCapacity),
-		logger:       logger,
-		semaphore:    semaphore.NewWeighted(config.MaxConcurrent),
-		shutdownCh:   make(chan struct{}),
-		cpuThreshold: config.CPUThreshold,
-		cpuWindow:    config.CPUWindow,
-		cpuHistory:   make([]float64, 0),
+		config:            config,
+		cache:             cache.NewCacheWithPolicy(config.CacheCapacity, config.Eviction),
+		logger:            logger,
+		logLevel:          logLevel,
+		semaphore:         semaphore.NewWeighted(config.MaxConcurrent),
+		maxConcurrent:     config.MaxConcurrent,
+		shutdownCh:        make(chan struct{}),
+		healthServer:      health.NewServer(),
+		cleanupInterval:   config.CleanupInterval,
+		cleanupIntervalCh: make(chan time.Duration),
+		cpuThreshold:      config.CPUThreshold,
+		cpuWindow:         config.CPUWindow,
+		cpuHistory:        make([]float64, 0),
+		mode:              ModeNormal,
+		emaAlpha:          config.EMAAlpha,
+		history:           newStatsHistory(config.StatsHistorySize),
+		metrics:           newRequestMetrics(),
+	}
+	if server.prefixTTLs, err = newPrefixTTLs(config.PrefixTTLs); err != nil {
+		return nil, err
+	}
+	if server.emaAlpha <= 0 || server.emaAlpha > 1 {
+		server.emaAlpha = defaultEMAAlpha
+	}
+	server.updateHealthStatus(server.mode)
+	if config.EnableTracing {
+		provider := config.TracerProvider
+		if provider == nil {
+			provider = otel.GetTracerProvider()
+		}
+		server.tracer = provider.Tracer(tracerName)
+	}
+	
+	if config.TombstoneTTL > 0 {
+		server.cache.SetTombstoneTTL(config.TombstoneTTL)
+	}
+	if config.EvictBatch > 1 {
+		server.cache.SetEvictBatch(config.EvictBatch)
+	}
+	if config.TTLJitter > 0 {
+		server.cache.SetTTLJitter(config.TTLJitter)
+	}
+	if config.LockTiming {
+		server.cache.SetLockTiming(true)
+	}
+	if config.EvictionPolicy != cache.PolicyLRU {
+		server.cache.SetPolicy(config.EvictionPolicy)
+	}
+	if config.BloomFilter {
+		server.cache.SetBloomFilter(true)
+		server.startBloomRebuild()
+	}
+	if config.OffHeapValues {
+		server.cache.SetOffHeap(true)
+	}
+	if config.TenantLabel != nil {
+		server.tenants = newTenantMetrics(config.TenantLabel, config.MaxTenants)
+	}
+	
+	// Publish expiry and eviction events, including any caused by the warmup
+	sink := config.EventSink
+	if sink == nil && config.EventSinkURL != "" {
+		if sink, err = NewEventSink(config.EventSinkURL); err != nil {
+			server.abortStart()
+			return nil, err
+		}
+	}
+	if sink != nil {
+		server.startEventPublisher(sink)
+	}
+	
+	// Preload the cache before serving traffic
+	if config.WarmupFile != "" {
+		if _, err := server.loadWarmupFile(config.WarmupFile); err != nil {
+			server.abortStart()
+			return nil, err
+		}
 	}
 	
 	// Start CPU monitoring
 	server.startCPUMonitoring()
 	
+	// Start expired entry cleanup; it stays idle until an interval is configured
+	server.startCleanup()
+	
 	return server, nil
 }
 
@@ -88,12 +434,15 @@ func (s *Server) Start() error {
 	
 	// Start HTTP server for metrics
 	if err := s.startHTTPServer(); err != nil {
+		// Release the gRPC port rather than serve without metrics
+		s.grpcServer.Stop()
+		s.grpcListener.Close()
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
 	
 	s.logger.Info("Server started", 
-		zap.Int("grpc_port", s.config.GRPCPort),
-		zap.Int("http_port", s.config.HTTPPort))
+		zap.String("grpc_addr", s.config.GRPCListenAddr()),
+		zap.String("http_addr", s.config.HTTPListenAddr()))
 	
 	// Wait for shutdown signal
 	s.waitForShutdown()
@@ -103,15 +452,31 @@ func (s *Server) Start() error {
 
 // startGRPCServer starts the gRPC server
 func (s *Server) startGRPCServer() error {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.GRPCPort))
+	lis, err := net.Listen("tcp", s.config.GRPCListenAddr())
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
+	s.grpcListener = lis
+	
+	maxRecv, maxSend := s.config.MaxRecvMsgSize, s.config.MaxSendMsgSize
+	if maxRecv <= 0 {
+		maxRecv = DefaultMaxMsgSize
+	}
+	if maxSend <= 0 {
+		maxSend = DefaultMaxMsgSize
+	}
 	
 	s.grpcServer = grpc.NewServer(
-		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainUnaryInterceptor(s.requestInterceptor, s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamRequestInterceptor),
+		grpc.MaxRecvMsgSize(maxRecv),
+		grpc.MaxSendMsgSize(maxSend),
 	)
 	proto.RegisterCacheServiceServer(s.grpcServer, s)
+	healthpb.RegisterHealthServer(s.grpcServer, s.healthServer)
+	if s.config.EnableAdmin {
+		proto.RegisterAdminServiceServer(s.grpcServer, s)
+	}
 	
 	s.wg.Add(1)
 	go func() {
@@ -129,16 +494,27 @@ func (s *Server) startHTTPServer() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.healthHandler)
 	mux.HandleFunc("/metrics", s.metricsHandler)
+	mux.HandleFunc("/metrics/history", s.statsHistoryHandler)
+	mux.Handle("/metrics/prometheus", s.metrics.handler())
+	if s.config.EnableAdmin && s.logLevel != nil {
+		// GET reports the level and PUT {"level":"debug"} changes it
+		mux.Handle("/loglevel", s.logLevel)
+	}
+	
+	lis, err := net.Listen("tcp", s.config.HTTPListenAddr())
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
 	
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.config.HTTPPort),
+		Addr:    lis.Addr().String(),
 		Handler: mux,
 	}
 	
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.httpServer.Serve(lis); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("HTTP server failed", zap.Error(err))
 		}
 	}()
@@ -146,26 +522,173 @@ func (s *Server) startHTTPServer() error {
 	return nil
 }
 
-// unaryInterceptor provides backpressure and load shedding
+// unaryInterceptor provides backpressure, load shedding and serving mode enforcement
 func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	// Check for context cancellation early
 	if ctx.Err() != nil {
 		return nil, status.Error(codes.Canceled, "request canceled")
 	}
 	
-	// Load shedding based on CPU usage
-	if s.shouldShedLoad() {
-		return nil, status.Error(codes.Unavailable, "server overloaded")
+	// Reject empty, oversized and non UTF-8 keys
+	if keyed, ok := req.(interface{ GetKey() string }); ok {
+		if err := s.validateKey(keyed.GetKey()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if s.tenants != nil {
+			s.tenants.recordRequest(keyed.GetKey())
+		}
+	}
+	
+	// Reject writes unless the node is in normal mode
+	if mode := s.getMode(); isWriteMethod(info.FullMethod) && !mode.acceptsWrites() {
+		return nil, status.Errorf(codes.FailedPrecondition, "node is %s", mode)
 	}
 	
-	// Backpressure control
-	if !s.semaphore.TryAcquire(1) {
-		return nil, status.Error(codes.Unavailable, "too many concurrent requests")
+	// Health is answered even when the node is overloaded, reporting its load instead,
+	// so it skips shedding and backpressure; it is still counted and timed
+	release := func() {}
+	if info.FullMethod != proto.CacheService_Health_FullMethodName {
+		// Load shedding based on CPU usage
+		if s.shouldShedLoad() {
+			atomic.AddUint64(&s.loadShedTotal, 1)
+			return nil, status.Error(codes.Unavailable, "server overloaded")
+		}
+		
+		// Backpressure control
+		sem := s.currentSemaphore()
+		if err := s.acquireWithTimeout(ctx, sem); err != nil {
+			return nil, err
+		}
+		
+		atomic.AddInt64(&s.inFlight, 1)
+		release = func() {
+			atomic.AddInt64(&s.inFlight, -1)
+			sem.Release(1)
+		}
 	}
-	defer s.semaphore.Release(1)
+	atomic.AddUint64(&s.requestsTotal, 1)
 	
-	// Call the actual handler
-	return handler(ctx, req)
+	// Call the actual handler, timing it for the duration histogram and slow log
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		s.metrics.observe(info.FullMethod, elapsed)
+		if s.config.SlowThreshold > 0 && elapsed > s.config.SlowThreshold {
+			s.logSlowRequest(info.FullMethod, req, elapsed)
+		}
+	}()
+	if s.config.MaxRequestDuration <= 0 {
+		defer release()
+		return handler(ctx, req)
+	}
+	return s.handleWithTimeout(ctx, req, handler, release)
+}
+
+// logSlowRequest warns about a request that ran past SlowThreshold
+func (s *Server) logSlowRequest(method string, req interface{}, elapsed time.Duration) {
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.Duration("duration", elapsed),
+		zap.Duration("threshold", s.config.SlowThreshold),
+	}
+	if keyed, ok := req.(interface{ GetKey() string }); ok {
+		fields = append(fields, zap.String("key", keyed.GetKey()))
+	}
+	s.logger.Warn("Slow request", fields...)
+}
+
+// acquireWithTimeout takes a concurrency slot, waiting up to QueueTimeout for one to
+// be released if they are all in use and fewer than MaxQueued requests are waiting.
+// A caller whose context ends while it waits gets Canceled or DeadlineExceeded, to
+// match, and is not counted as rejected.
+func (s *Server) acquireWithTimeout(ctx context.Context, sem *semaphore.Weighted) error {
+	if sem.TryAcquire(1) {
+		return nil
+	}
+	if s.config.QueueTimeout > 0 && s.enqueue() {
+		defer atomic.AddInt64(&s.queued, -1)
+		waitCtx, cancel := context.WithTimeout(ctx, s.config.QueueTimeout)
+		defer cancel()
+		if err := sem.Acquire(waitCtx, 1); err == nil {
+			return nil
+		}
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return status.Error(codes.DeadlineExceeded, "request deadline exceeded while queued")
+		case context.Canceled:
+			return status.Error(codes.Canceled, "request canceled")
+		}
+	}
+	atomic.AddUint64(&s.backpressureRejectedTotal, 1)
+	return status.Error(codes.Unavailable, "too many concurrent requests")
+}
+
+// enqueue counts a request into the slot queue, reporting false without counting it if
+// MaxQueued requests are already waiting
+func (s *Server) enqueue() bool {
+	if s.config.MaxQueued <= 0 {
+		atomic.AddInt64(&s.queued, 1)
+		return true
+	}
+	for {
+		queued := atomic.LoadInt64(&s.queued)
+		if queued >= int64(s.config.MaxQueued) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&s.queued, queued, queued+1) {
+			return true
+		}
+	}
+}
+
+// handleWithTimeout runs a handler with its context canceled after MaxRequestDuration,
+// returning DeadlineExceeded as soon as the cap is reached even if the handler ignores
+// its context. The handler keeps its concurrency slot until it returns, when release
+// is called, so runaway handlers still count towards MaxConcurrent.
+func (s *Server) handleWithTimeout(ctx context.Context, req interface{}, handler grpc.UnaryHandler, release func()) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.MaxRequestDuration)
+	defer cancel()
+	
+	type result struct {
+		resp interface{}
+		err  error
+	}
+	
+	done := make(chan result, 1)
+	go func() {
+		defer release()
+		resp, err := handler(ctx, req)
+		done <- result{resp: resp, err: err}
+	}()
+	
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, status.Error(codes.DeadlineExceeded, "request exceeded the maximum duration")
+		}
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+}
+
+// validateKey checks that a key is non-empty, valid UTF-8 and within MaxKeyBytes.
+// Keys may contain any UTF-8 text, including null bytes.
+func (s *Server) validateKey(key string) error {
+	maxKeyBytes := s.config.MaxKeyBytes
+	if maxKeyBytes <= 0 {
+		maxKeyBytes = DefaultMaxKeyBytes
+	}
+	
+	switch {
+	case key == "":
+		return fmt.Errorf("key must not be empty")
+	case len(key) > maxKeyBytes:
+		return fmt.Errorf("key is %d bytes, longer than the %d byte limit", len(key), maxKeyBytes)
+	case !utf8.ValidString(key):
+		return fmt.Errorf("key must be valid UTF-8")
+	}
+	return nil
 }
 
 // shouldShedLoad determines if we should shed load based on CPU usage
@@ -200,15 +723,17 @@ func (s *Server) startCPUMonitoring() {
 			select {
 			case <-s.shutdownCh:
 				return
-			case <-ticker.C:
-				s.updateCPUUsage()
+			case now := <-ticker.C:
+				s.updateCPUUsage(time.Second)
+				s.sampleStats(now)
 			}
 		}
 	}()
 }
 
-// updateCPUUsage updates the CPU usage history
-func (s *Server) updateCPUUsage() {
+// updateCPUUsage updates the CPU usage history and the smoothed load averages
+// over the elapsed interval
+func (s *Server) updateCPUUsage(elapsed time.Duration) {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 	
@@ -216,8 +741,17 @@ func (s *Server) updateCPUUsage() {
 	// In a real implementation, you'd use proper CPU monitoring
 	cpuUsage := float64(runtime.NumGoroutine()) / 1000.0 // Simplified
 	
+	s.recordLoad(cpuUsage, elapsed)
+}
+
+// recordLoad adds a CPU sample to the history and folds it, along with the request
+// and eviction rates since the previous sample, into the exponential moving averages
+func (s *Server) recordLoad(cpuUsage float64, elapsed time.Duration) {
+	requests := atomic.LoadUint64(&s.requestsTotal)
+	evictions := s.cache.Evictions()
+	pinned, capacity := s.cache.Pinned(), s.cache.Capacity()
+	
 	s.cpuMutex.Lock()
-	defer s.cpuMutex.Unlock()
 	
 	s.cpuHistory = append(s.cpuHistory, cpuUsage)
 	
@@ -226,18 +760,161 @@ func (s *Server) updateCPUUsage() {
 	if len(s.cpuHistory) > windowSize {
 		s.cpuHistory = s.cpuHistory[len(s.cpuHistory)-windowSize:]
 	}
+	
+	rate := float64(requests-s.lastRequests) / elapsed.Seconds()
+	s.lastRequests = requests
+	s.emaCPU = s.emaAlpha*cpuUsage + (1-s.emaAlpha)*s.emaCPU
+	s.emaRequestRate = s.emaAlpha*rate + (1-s.emaAlpha)*s.emaRequestRate
+	
+	evictionRate := float64(evictions-s.lastEvictions) / elapsed.Seconds()
+	s.lastEvictions = evictions
+	s.emaEvictionRate = s.emaAlpha*evictionRate + (1-s.emaAlpha)*s.emaEvictionRate
+	evictionRate = s.emaEvictionRate
+	now := time.Now()
+	warn := s.evictionWarningDue(now)
+	pinWarn := pinned >= capacity && s.warningDue(&s.lastPinWarn, now)
+	s.cpuMutex.Unlock()
+	
+	if warn {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		s.logger.Warn("Cache is evicting rapidly and may be too small for its working set",
+			zap.Float64("eviction_rate", evictionRate),
+			zap.Float64("threshold", s.config.EvictionWarnRate),
+			zap.Int("cache_capacity", s.config.CacheCapacity),
+			zap.Uint64("heap_alloc", m.HeapAlloc),
+			zap.Uint32("num_gc", m.NumGC))
+	}
+	if pinWarn {
+		s.logger.Warn("Pinned entries fill the cache, so new keys are evicted as soon as they are written",
+			zap.Int("pinned", pinned),
+			zap.Int("cache_capacity", capacity))
+	}
+}
+
+// evictionWarningDue reports whether the smoothed eviction rate is over
+// EvictionWarnRate and no warning has been logged within EvictionWarnInterval,
+// recording the warning as logged if so. The caller holds cpuMutex.
+func (s *Server) evictionWarningDue(now time.Time) bool {
+	if s.config.EvictionWarnRate <= 0 || s.emaEvictionRate <= s.config.EvictionWarnRate {
+		return false
+	}
+	return s.warningDue(&s.lastEvictWarn, now)
+}
+
+// warningDue reports whether a warning last logged at *last may be logged again now,
+// at most once per EvictionWarnInterval, recording it as logged if so. The caller
+// holds cpuMutex.
+func (s *Server) warningDue(last *time.Time, now time.Time) bool {
+	interval := s.config.EvictionWarnInterval
+	if interval <= 0 {
+		interval = defaultEvictionWarnInterval
+	}
+	if !last.IsZero() && now.Sub(*last) < interval {
+		return false
+	}
+	*last = now
+	return true
+}
+
+// loadAverages returns the smoothed CPU usage and request rate
+func (s *Server) loadAverages() (float64, float64) {
+	s.cpuMutex.RLock()
+	defer s.cpuMutex.RUnlock()
+	return s.emaCPU, s.emaRequestRate
+}
+
+// evictionRate returns the smoothed rate of capacity evictions per second
+func (s *Server) evictionRate() float64 {
+	s.cpuMutex.RLock()
+	defer s.cpuMutex.RUnlock()
+	return s.emaEvictionRate
+}
+
+// startCleanup periodically removes expired entries and tombstones, following
+// changes to the cleanup interval; an interval of zero pauses cleanup
+func (s *Server) startCleanup() {
+	s.wg.Add(1)
+	
+	go func() {
+		defer s.wg.Done()
+		
+		var ticker *time.Ticker
+		var tick <-chan time.Time
+		schedule := func(interval time.Duration) {
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+			if interval > 0 {
+				ticker = time.NewTicker(interval)
+				tick = ticker.C
+			}
+		}
+		schedule(s.config.CleanupInterval)
+		defer schedule(0)
+		
+		for {
+			select {
+			case <-s.shutdownCh:
+				return
+			case interval := <-s.cleanupIntervalCh:
+				schedule(interval)
+			case <-tick:
+				if removed := s.cache.Cleanup(); removed > 0 {
+					s.logger.Debug("Removed expired entries", zap.Int("removed", removed))
+				}
+			}
+		}
+	}()
+}
+
+// startBloomRebuild periodically rebuilds the bloom filter from the live keys
+func (s *Server) startBloomRebuild() {
+	interval := s.config.BloomRebuildInterval
+	if interval <= 0 {
+		interval = defaultBloomRebuildInterval
+	}
+	
+	ticker := time.NewTicker(interval)
+	s.wg.Add(1)
+	
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		
+		for {
+			select {
+			case <-s.shutdownCh:
+				return
+			case <-ticker.C:
+				s.cache.RebuildBloomFilter()
+			}
+		}
+	}()
 }
 
 // waitForShutdown waits for shutdown signal
 func (s *Server) waitForShutdown() {
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	
-	<-sigCh
+	// SIGHUP reloads the configuration; anything else shuts down
+	for sig := range sigCh {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		s.reloadFromSource()
+	}
 	s.logger.Info("Shutdown signal received")
-	
-	// Start graceful shutdown
-	close(s.shutdownCh)
+	s.shutdown()
+}
+
+// shutdown marks the node NOT_SERVING, drains in-flight requests and waits for the
+// background goroutines to exit
+func (s *Server) shutdown() {
+	s.healthServer.Shutdown()
+	s.stopBackground()
 	
 	// Stop accepting new requests
 	if s.grpcServer != nil {
@@ -253,19 +930,65 @@ func (s *Server) waitForShutdown() {
 	// Wait for all goroutines to finish
 	s.wg.Wait()
 	
+	// Requests have drained, so the snapshot holds every acknowledged write
+	if s.config.SnapshotFile != "" {
+		if _, err := s.SaveSnapshot(s.config.SnapshotFile); err != nil {
+			s.logger.Error("Failed to save cache snapshot", zap.Error(err))
+		}
+	}
+	
 	s.logger.Info("Server shutdown complete")
 }
 
+// abortStart stops the background goroutines NewServer has started when it fails
+// part way, publishing any buffered events, and waits for them to exit
+func (s *Server) abortStart() {
+	s.stopBackground()
+	s.wg.Wait()
+}
+
+// stopBackground signals the background goroutines to exit; it is safe to call more than once
+func (s *Server) stopBackground() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdownCh)
+	})
+}
+
+// updateHealthStatus reports the serving mode through the standard health service,
+// for the server as a whole and for the cache service
+func (s *Server) updateHealthStatus(mode Mode) {
+	serving := healthpb.HealthCheckResponse_SERVING
+	if mode == ModeDraining {
+		serving = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.healthServer.SetServingStatus("", serving)
+	s.healthServer.SetServingStatus(proto.CacheService_ServiceDesc.ServiceName, serving)
+}
+
 // healthHandler handles HTTP health checks
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	mode := s.getMode()
+	
 	w.Header().Set("Content-Type", "application/json")
+	if mode == ModeDraining {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, `{"status":"draining","mode":%q}`, mode)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"healthy"}`))
+	fmt.Fprintf(w, `{"status":"healthy","mode":%q}`, mode)
 }
 
 // metricsHandler handles metrics endpoint
 func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
-	stats := s.cache.GetStats()
+	stats := s.cache.StatsSnapshot()
+	emaCPU, emaRequestRate := s.loadAverages()
+	oldest, newest, median := s.cache.AgeStats()
+	
+	tenants := []byte("{}")
+	if s.tenants != nil {
+		tenants, _ = json.Marshal(s.tenants.snapshot())
+	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -275,14 +998,67 @@ func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
 		"cache_size": %v,
 		"cache_capacity": %v,
 		"cache_load": %v,
+		"cache_hits": %v,
+		"cache_misses": %v,
+		"cache_evictions": %v,
+		"eviction_rate_ema": %v,
 		"goroutines": %d,
-		"concurrent_requests": %d
+		"concurrent_requests": %d,
+		"max_concurrent": %d,
+		"semaphore_utilization": %v,
+		"load_shed_total": %d,
+		"backpressure_rejected_total": %d,
+		"requests_total": %d,
+		"request_rate_ema": %v,
+		"cpu_ema": %v,
+		"events_published": %d,
+		"events_dropped": %d,
+		"cache_lock_waits": %v,
+		"cache_lock_wait_ns": %d,
+		"bloom_false_positive_rate": %v,
+		"bloom_rejects": %v,
+		"cache_offheap_bytes": %v,
+		"entry_age_oldest_seconds": %v,
+		"entry_age_newest_seconds": %v,
+		"entry_age_median_seconds": %v,
+		"tenants": %s
 	}`, 
-		stats["size"], 
-		stats["capacity"], 
-		stats["load"],
+		stats.Size, 
+		stats.Capacity, 
+		stats.Load,
+		stats.Hits,
+		stats.Misses,
+		stats.Evictions,
+		s.evictionRate(),
 		runtime.NumGoroutine(),
-		s.config.MaxConcurrent-s.semaphore.Available())
+		atomic.LoadInt64(&s.inFlight),
+		s.concurrencyLimit(),
+		s.semaphoreUtilization(),
+		atomic.LoadUint64(&s.loadShedTotal),
+		atomic.LoadUint64(&s.backpressureRejectedTotal),
+		atomic.LoadUint64(&s.requestsTotal),
+		emaRequestRate,
+		emaCPU,
+		atomic.LoadUint64(&s.eventsPublished),
+		atomic.LoadUint64(&s.eventsDropped),
+		stats.LockWaits,
+		stats.LockWait.Nanoseconds(),
+		stats.BloomFPRate,
+		stats.BloomRejects,
+		stats.OffHeapBytes,
+		oldest.Seconds(),
+		newest.Seconds(),
+		median.Seconds(),
+		tenants)
+}
+
+// semaphoreUtilization returns the fraction of concurrent request slots in use
+func (s *Server) semaphoreUtilization() float64 {
+	limit := s.concurrencyLimit()
+	if limit <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&s.inFlight)) / float64(limit)
 }
 
 // Get implements the Get RPC
@@ -291,12 +1067,181 @@ func (s *Server) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResp
 		return nil, status.Error(codes.Canceled, "request canceled")
 	}
 	
-	value, found := s.cache.Get(req.Key)
+	return s.lookup(req.Key, req.MetaOnly, req.IfVersion), nil
+}
+
+// Exists implements the Exists RPC
+func (s *Server) Exists(ctx context.Context, req *proto.ExistsRequest) (*proto.ExistsResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	return &proto.ExistsResponse{Exists: s.cache.Exists(req.Key)}, nil
+}
+
+// BatchGet implements the BatchGet RPC. Every key is validated before any is read.
+func (s *Server) BatchGet(ctx context.Context, req *proto.BatchGetRequest) (*proto.BatchGetResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
 	
-	return &proto.GetResponse{
-		Value: value,
-		Found: found,
-	}, nil
+	for _, key := range req.Keys {
+		if err := s.validateKey(key); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	
+	results := make([]*proto.GetResponse, len(req.Keys))
+	for i, key := range req.Keys {
+		results[i] = s.lookup(key, false, 0)
+	}
+	
+	return &proto.BatchGetResponse{Results: results}, nil
+}
+
+// GetAndTouch implements the GetAndTouch RPC
+func (s *Server) GetAndTouch(ctx context.Context, req *proto.GetAndTouchRequest) (*proto.GetAndTouchResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	for _, key := range req.Keys {
+		if err := s.validateKey(key); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	
+	var ttl time.Duration
+	if req.Ttl != nil {
+		ttl = req.Ttl.AsDuration()
+	}
+	if ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+	
+	values := s.cache.GetAndTouch(req.Keys, ttl)
+	results := make([]*proto.GetResponse, len(req.Keys))
+	for i, key := range req.Keys {
+		value, found := values[key]
+		results[i] = &proto.GetResponse{Value: value, Found: found}
+	}
+	
+	return &proto.GetAndTouchResponse{Results: results}, nil
+}
+
+// Expire implements the Expire RPC
+func (s *Server) Expire(ctx context.Context, req *proto.ExpireRequest) (*proto.ExpireResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	var ttl time.Duration
+	if req.Ttl != nil {
+		ttl = req.Ttl.AsDuration()
+	}
+	if ttl <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must be positive; use Persist to remove the expiry")
+	}
+	
+	return &proto.ExpireResponse{Updated: s.cache.Touch(req.Key, ttl)}, nil
+}
+
+// Persist implements the Persist RPC. Keys under a prefix in PrefixTTLs are refused,
+// since they cannot be stored without expiry, and a copy moved by Rebalance or Migrate
+// would take the prefix default again.
+func (s *Server) Persist(ctx context.Context, req *proto.PersistRequest) (*proto.PersistResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	if s.ttlFor(req.Key, 0) != 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "key %q is under a prefix with a default TTL and cannot be persisted", req.Key)
+	}
+	
+	return &proto.PersistResponse{Updated: s.cache.Touch(req.Key, 0)}, nil
+}
+
+// RangeGet implements the RangeGet RPC. It scans the node's whole keyspace, so its
+// cost grows with the cache size rather than the size of the range.
+func (s *Server) RangeGet(ctx context.Context, req *proto.RangeGetRequest) (*proto.RangeGetResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	items := s.cache.Range(req.StartKey, req.EndKey)
+	entries := make([]*proto.Entry, len(items))
+	for i, item := range items {
+		entries[i] = &proto.Entry{Key: item.Key, Value: item.Value}
+		if item.TTL > 0 {
+			entries[i].Ttl = durationpb.New(item.TTL)
+		}
+	}
+	
+	return &proto.RangeGetResponse{Entries: entries}, nil
+}
+
+// maxScanPage is the most entries a Scan response holds
+const maxScanPage = 1000
+
+// Scan handles prefix scans a page at a time, reporting each entry's version, and the
+// tombstones of deleted keys, so that callers merging several replicas can keep the
+// newest state of each key
+func (s *Server) Scan(ctx context.Context, req *proto.ScanRequest) (*proto.ScanResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	limit := int(req.Limit)
+	if limit <= 0 || limit > maxScanPage {
+		limit = maxScanPage
+	}
+	found, more := s.cache.ScanPage(req.Prefix, req.After, limit)
+	entries := make([]*proto.Entry, len(found))
+	now := time.Now()
+	for i, entry := range found {
+		entries[i] = &proto.Entry{Key: entry.Key, Value: entry.Value, Version: entry.Version, Tombstone: entry.Tombstone}
+		if !entry.ExpiresAt.IsZero() {
+			entries[i].Ttl = durationpb.New(entry.ExpiresAt.Sub(now))
+		}
+	}
+	
+	resp := &proto.ScanResponse{Entries: entries}
+	if more {
+		resp.Next = found[len(found)-1].Key
+	}
+	return resp, nil
+}
+
+// lookup builds the Get response for a key. The value is left out of metadata-only
+// reads and, when ifVersion is non-zero, of reads of a live key at that version.
+func (s *Server) lookup(key string, metaOnly bool, ifVersion uint64) *proto.GetResponse {
+	entry, found := s.cache.Lookup(key)
+	if s.tenants != nil {
+		s.tenants.recordLookup(key, found && !entry.Tombstone)
+	}
+	if !found {
+		return &proto.GetResponse{}
+	}
+	
+	resp := &proto.GetResponse{
+		Found:     !entry.Tombstone,
+		Version:   entry.Version,
+		Tombstone: entry.Tombstone,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+		Size:      int64(len(entry.Value)),
+	}
+	if !entry.ExpiresAt.IsZero() {
+		resp.ExpiresAt = timestamppb.New(entry.ExpiresAt)
+	}
+	
+	// Metadata-only reads skip the payload, as do conditional reads of an unchanged value
+	switch {
+	case ifVersion != 0 && resp.Found && entry.Version == ifVersion:
+		resp.NotModified = true
+	case !metaOnly:
+		resp.Value = entry.Value
+	}
+	
+	return resp
 }
 
 // Set implements the Set RPC
@@ -309,12 +1254,52 @@ func (s *Server) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResp
 	if req.Ttl != nil {
 		ttl = req.Ttl.AsDuration()
 	}
+	ttl = s.ttlFor(req.Key, ttl)
 	
-	s.cache.Set(req.Key, req.Value, ttl)
+	if req.Version == 0 {
+		s.cache.Set(req.Key, req.Value, ttl)
+		return &proto.SetResponse{Success: true}, nil
+	}
 	
-	return &proto.SetResponse{
-		Success: true,
-	}, nil
+	// Versioned writes lose to newer entries and tombstones, whose version is returned
+	// so that the writer's clock can catch up
+	if s.cache.SetVersioned(req.Key, req.Value, ttl, req.Version) {
+		return &proto.SetResponse{Success: true}, nil
+	}
+	version, _ := s.cache.Version(req.Key)
+	return &proto.SetResponse{Version: version}, nil
+}
+
+// SetBatch implements the SetBatch RPC. Every key is validated before any write is
+// applied; each item is then applied as an individual Set would be.
+func (s *Server) SetBatch(ctx context.Context, req *proto.SetBatchRequest) (*proto.SetBatchResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	for _, item := range req.Items {
+		if err := s.validateKey(item.Key); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	
+	var applied int64
+	for _, item := range req.Items {
+		var ttl time.Duration
+		if item.Ttl != nil {
+			ttl = item.Ttl.AsDuration()
+		}
+		ttl = s.ttlFor(item.Key, ttl)
+		
+		if item.Version == 0 {
+			s.cache.Set(item.Key, item.Value, ttl)
+			applied++
+		} else if s.cache.SetVersioned(item.Key, item.Value, ttl, item.Version) {
+			applied++
+		}
+	}
+	
+	return &proto.SetBatchResponse{Applied: applied}, nil
 }
 
 // Delete implements the Delete RPC
@@ -323,21 +1308,83 @@ func (s *Server) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.D
 		return nil, status.Error(codes.Canceled, "request canceled")
 	}
 	
-	deleted := s.cache.Delete(req.Key)
+	var deleted bool
+	switch {
+	case req.IfVersionMatches:
+		deleted = s.cache.DeleteIf(req.Key, req.ExpectedVersion)
+	case req.Version == 0:
+		deleted = s.cache.Delete(req.Key)
+	default:
+		deleted = s.cache.DeleteVersioned(req.Key, req.Version)
+	}
 	
 	return &proto.DeleteResponse{
 		Deleted: deleted,
 	}, nil
 }
 
+// GetAndDelete implements the GetAndDelete RPC
+func (s *Server) GetAndDelete(ctx context.Context, req *proto.GetAndDeleteRequest) (*proto.GetResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	if req.Version == 0 {
+		return nil, status.Error(codes.InvalidArgument, "a pop needs a version for its tombstone")
+	}
+	
+	// The response describes what the key held before the pop, a tombstone included
+	held, found := s.cache.GetAndDelete(req.Key, req.Version)
+	return &proto.GetResponse{
+		Value:     held.Value,
+		Found:     found,
+		Version:   held.Version,
+		Tombstone: held.Tombstone,
+		Size:      int64(len(held.Value)),
+	}, nil
+}
+
 // Health implements the Health RPC
 func (s *Server) Health(ctx context.Context, req *proto.HealthRequest) (*proto.HealthResponse, error) {
 	if ctx.Err() != nil {
 		return nil, status.Error(codes.Canceled, "request canceled")
 	}
 	
-	return &proto.HealthResponse{
-		Healthy: true,
-		Status:  "healthy",
+	// Load and shedding state let callers route away from a busy node
+	stats := s.cache.StatsSnapshot()
+	resp := &proto.HealthResponse{
+		Healthy:  true,
+		Status:   "healthy",
+		Load:     stats.Load,
+		Shedding: s.shouldShedLoad(),
+		InFlight: atomic.LoadInt64(&s.inFlight),
+	}
+	
+	mode := s.getMode()
+	if mode == ModeDraining {
+		resp.Healthy = false
+		resp.Status = "draining"
+	}
+	resp.Mode = string(mode)
+	return resp, nil
+} 
+
+// Stats implements the Stats RPC
+func (s *Server) Stats(ctx context.Context, req *proto.StatsRequest) (*proto.StatsResponse, error) {
+	if ctx.Err() != nil {
+		return nil, status.Error(codes.Canceled, "request canceled")
+	}
+	
+	stats := s.cache.StatsSnapshot()
+	emaCPU, emaRequestRate := s.loadAverages()
+	
+	return &proto.StatsResponse{
+		CacheSize:      int64(stats.Size),
+		CacheCapacity:  int64(stats.Capacity),
+		InFlight:       atomic.LoadInt64(&s.inFlight),
+		RequestsTotal:  atomic.LoadUint64(&s.requestsTotal),
+		RequestRateEma: emaRequestRate,
+		CpuEma:         emaCPU,
+		Hits:           stats.Hits,
+		Misses:         stats.Misses,
 	}, nil
-} 
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shard-cache/internal/cache"
+	"go.uber.org/zap"
+)
+
+// snapshotMagic starts every snapshot file, followed by the format version and the
+// record encoding. Files without it are read as unversioned JSON lines.
+const snapshotMagic = "SHARDCACHE"
+
+// snapshotVersion is the format version written; files with a newer version are rejected
+const snapshotVersion = 1
+
+// maxSnapshotField bounds a single key or value when reading a binary snapshot
+const maxSnapshotField = 1 << 30
+
+// errMalformedRecord marks a record that can be skipped without losing the rest of the file
+var errMalformedRecord = errors.New("malformed record")
+
+// SnapshotFormat selects how the records of a snapshot file are encoded
+type SnapshotFormat int
+
+const (
+	// SnapshotBinary encodes each record as length-prefixed key and value bytes
+	// followed by the expiry; it is the default
+	SnapshotBinary SnapshotFormat = iota
+
+	// SnapshotJSON encodes each record as a line of JSON in the warm-up file format,
+	// for inspecting snapshots by hand
+	SnapshotJSON
+)
+
+// String returns the format's name
+func (f SnapshotFormat) String() string {
+	switch f {
+	case SnapshotBinary:
+		return "binary"
+	case SnapshotJSON:
+		return "json"
+	default:
+		return fmt.Sprintf("SnapshotFormat(%d)", int(f))
+	}
+}
+
+// ParseSnapshotFormat returns the snapshot format with the given name, "binary" or "json"
+func ParseSnapshotFormat(name string) (SnapshotFormat, error) {
+	switch name {
+	case "binary":
+		return SnapshotBinary, nil
+	case "json":
+		return SnapshotJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown snapshot format %q", name)
+	}
+}
+
+// recordDecoder returns the next record of a snapshot, io.EOF after the last one, or
+// an error wrapping errMalformedRecord for a record that should be skipped
+type recordDecoder func() (warmupEntry, error)
+
+// SaveSnapshot writes every live entry in the cache to path in the configured
+// SnapshotFormat, ready to be loaded with WarmupFile. The file is written under a
+// temporary name and renamed into place, so a failed save leaves any previous
+// snapshot intact. It returns the number of entries written.
+func (s *Server) SaveSnapshot(path string) (int, error) {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp)
+	
+	items := s.cache.Snapshot()
+	w := bufio.NewWriter(f)
+	if err := writeSnapshot(w, s.config.SnapshotFormat, items, time.Now()); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	
+	s.logger.Info("Cache snapshot saved",
+		zap.String("file", path),
+		zap.String("format", s.config.SnapshotFormat.String()),
+		zap.Int("entries", len(items)))
+	
+	return len(items), nil
+}
+
+// writeSnapshot writes the header and one record per item. TTLs are stored as
+// absolute expiry times so that they keep counting down while the file is unused.
+func writeSnapshot(w io.Writer, format SnapshotFormat, items []cache.KV, now time.Time) error {
+	if format != SnapshotBinary && format != SnapshotJSON {
+		return fmt.Errorf("unknown snapshot format %v", format)
+	}
+	if _, err := fmt.Fprintf(w, "%s%c%c\n", snapshotMagic, snapshotVersion, format); err != nil {
+		return err
+	}
+	
+	buf := make([]byte, 0, 64)
+	for _, item := range items {
+		entry := warmupEntry{Key: item.Key, Value: item.Value}
+		if item.TTL > 0 {
+			entry.ExpiresAt = now.Add(item.TTL)
+		}
+		
+		if format == SnapshotJSON {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+			continue
+		}
+		
+		var expiresAt int64
+		if !entry.ExpiresAt.IsZero() {
+			expiresAt = entry.ExpiresAt.UnixNano()
+		}
+		buf = binary.AppendUvarint(buf[:0], uint64(len(entry.Key)))
+		buf = append(buf, entry.Key...)
+		buf = binary.AppendUvarint(buf, uint64(len(entry.Value)))
+		buf = append(buf, entry.Value...)
+		buf = binary.AppendVarint(buf, expiresAt)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRecordDecoder reads the header of a snapshot and returns a decoder for its
+// records. Files from a newer format version, or with an unknown encoding, are
+// rejected; files without a header are read as JSON lines.
+func newRecordDecoder(r *bufio.Reader) (recordDecoder, error) {
+	header, err := r.Peek(len(snapshotMagic) + 3)
+	if err != nil || !bytes.HasPrefix(header, []byte(snapshotMagic)) {
+		return jsonRecordDecoder(r), nil
+	}
+	
+	version, format := int(header[len(snapshotMagic)]), SnapshotFormat(header[len(snapshotMagic)+1])
+	if version > snapshotVersion || version < 1 {
+		return nil, fmt.Errorf("snapshot format version %d is not supported (newest supported is %d)", version, snapshotVersion)
+	}
+	r.Discard(len(header))
+	
+	switch format {
+	case SnapshotBinary:
+		return binaryRecordDecoder(r), nil
+	case SnapshotJSON:
+		return jsonRecordDecoder(r), nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot record encoding %d", int(format))
+	}
+}
+
+// jsonRecordDecoder decodes one JSON record per line, skipping blank lines. Lines that
+// do not parse are reported as malformed and the next line is read on the next call.
+func jsonRecordDecoder(r io.Reader) recordDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return func() (warmupEntry, error) {
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			
+			var entry warmupEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				return warmupEntry{}, fmt.Errorf("%w: %v", errMalformedRecord, err)
+			}
+			return entry, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return warmupEntry{}, err
+		}
+		return warmupEntry{}, io.EOF
+	}
+}
+
+// binaryRecordDecoder decodes length-prefixed records. A truncated or oversized
+// record cannot be skipped, so it ends the file with an error.
+func binaryRecordDecoder(r *bufio.Reader) recordDecoder {
+	field := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxSnapshotField {
+			return nil, fmt.Errorf("field of %d bytes exceeds the %d byte limit", n, maxSnapshotField)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	
+	return func() (warmupEntry, error) {
+		if _, err := r.Peek(1); err == io.EOF {
+			return warmupEntry{}, io.EOF
+		}
+		
+		key, err := field()
+		if err != nil {
+			return warmupEntry{}, truncated(err)
+		}
+		value, err := field()
+		if err != nil {
+			return warmupEntry{}, truncated(err)
+		}
+		expiresAt, err := binary.ReadVarint(r)
+		if err != nil {
+			return warmupEntry{}, truncated(err)
+		}
+		
+		entry := warmupEntry{Key: string(key), Value: value}
+		if expiresAt != 0 {
+			entry.ExpiresAt = time.Unix(0, expiresAt)
+		}
+		return entry, nil
+	}
+}
+
+// truncated reports an end of file in the middle of a record as unexpected
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// defaultMaxTenants is how many tenant labels are tracked when MaxTenants is not set
+	defaultMaxTenants = 100
+
+	// otherTenant labels keys without a tenant and tenants beyond MaxTenants
+	otherTenant = "other"
+)
+
+// TenantPrefix returns a TenantLabel function that labels a key with the text before
+// the first delimiter, so "acme:user:1" is labeled "acme" for a delimiter of ":".
+// Keys without the delimiter get no label.
+func TenantPrefix(delimiter string) func(key string) string {
+	return func(key string) string {
+		prefix, _, found := strings.Cut(key, delimiter)
+		if !found {
+			return ""
+		}
+		return prefix
+	}
+}
+
+// tenantCounters holds the request counts of one tenant label
+type tenantCounters struct {
+	requests uint64
+	hits     uint64
+	misses   uint64
+}
+
+// tenantMetrics counts requests per tenant label. Labels are admitted on first use
+// until the limit is reached; later labels share the otherTenant counters so that a
+// stream of distinct prefixes cannot grow the metrics without bound.
+type tenantMetrics struct {
+	label   func(key string) string
+	limit   int
+	mu      sync.RWMutex
+	tenants map[string]*tenantCounters
+}
+
+// newTenantMetrics creates per-tenant counters labeled by the label function
+func newTenantMetrics(label func(key string) string, limit int) *tenantMetrics {
+	if limit <= 0 {
+		limit = defaultMaxTenants
+	}
+	return &tenantMetrics{
+		label:   label,
+		limit:   limit,
+		tenants: map[string]*tenantCounters{otherTenant: {}},
+	}
+}
+
+// counters returns the counters of the tenant a key belongs to
+func (m *tenantMetrics) counters(key string) *tenantCounters {
+	label := m.label(key)
+	if label == "" {
+		label = otherTenant
+	}
+	
+	m.mu.RLock()
+	counters, exists := m.tenants[label]
+	m.mu.RUnlock()
+	if exists {
+		return counters
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if counters, exists := m.tenants[label]; exists {
+		return counters
+	}
+	// The otherTenant entry does not count towards the limit
+	if len(m.tenants) > m.limit {
+		return m.tenants[otherTenant]
+	}
+	counters = &tenantCounters{}
+	m.tenants[label] = counters
+	return counters
+}
+
+// recordRequest counts a request for a key
+func (m *tenantMetrics) recordRequest(key string) {
+	atomic.AddUint64(&m.counters(key).requests, 1)
+}
+
+// recordLookup counts a read of a key as a hit or a miss
+func (m *tenantMetrics) recordLookup(key string, hit bool) {
+	counters := m.counters(key)
+	if hit {
+		atomic.AddUint64(&counters.hits, 1)
+	} else {
+		atomic.AddUint64(&counters.misses, 1)
+	}
+}
+
+// snapshot returns the current counts of every tenant label, for /metrics
+func (m *tenantMetrics) snapshot() map[string]map[string]uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	snapshot := make(map[string]map[string]uint64, len(m.tenants))
+	for label, counters := range m.tenants {
+		snapshot[label] = map[string]uint64{
+			"requests": atomic.LoadUint64(&counters.requests),
+			"hits":     atomic.LoadUint64(&counters.hits),
+			"misses":   atomic.LoadUint64(&counters.misses),
+		}
+	}
+	return snapshot
+}
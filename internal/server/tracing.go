@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader is the gRPC metadata key carrying the request ID; it matches the client's
+const RequestIDHeader = "x-request-id"
+
+// tracerName identifies spans created by the server
+const tracerName = "github.com/shard-cache/internal/server"
+
+// tracePropagator reads the caller's span context from gRPC metadata
+var tracePropagator = propagation.TraceContext{}
+
+// requestID returns the caller's request ID, generating one if the caller sent none
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDHeader); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startSpan continues the caller's trace with a server span when tracing is enabled
+func (s *Server) startSpan(ctx context.Context, method, id string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = tracePropagator.Extract(ctx, metadataCarrier(md))
+	}
+	return s.tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("request.id", id)))
+}
+
+// endSpan records a handler's outcome on its span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// requestInterceptor runs ahead of the backpressure interceptor so that every request,
+// including rejected ones, is logged with its request ID, echoes the ID in the trailer
+// and, when tracing is enabled, is wrapped in a span joined to the caller's trace
+func (s *Server) requestInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := requestID(ctx)
+	grpc.SetTrailer(ctx, metadata.Pairs(RequestIDHeader, id))
+	
+	var span trace.Span
+	if s.tracer != nil {
+		ctx, span = s.startSpan(ctx, info.FullMethod, id)
+	}
+	
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	
+	s.logger.Debug("Handled request",
+		zap.String("method", info.FullMethod),
+		zap.String("request_id", id),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)))
+	
+	if span != nil {
+		endSpan(span, err)
+	}
+	return resp, err
+}
+
+// streamRequestInterceptor does the same for streaming RPCs
+func (s *Server) streamRequestInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := stream.Context()
+	id := requestID(ctx)
+	stream.SetTrailer(metadata.Pairs(RequestIDHeader, id))
+	
+	var span trace.Span
+	if s.tracer != nil {
+		_, span = s.startSpan(ctx, info.FullMethod, id)
+	}
+	
+	start := time.Now()
+	err := handler(srv, stream)
+	
+	s.logger.Debug("Handled stream",
+		zap.String("method", info.FullMethod),
+		zap.String("request_id", id),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)))
+	
+	if span != nil {
+		endSpan(span, err)
+	}
+	return err
+}
+
+// metadataCarrier adapts gRPC metadata to the OpenTelemetry propagation API
+type metadataCarrier metadata.MD
+
+// Get returns the first value for a key
+func (m metadataCarrier) Get(key string) string {
+	if values := metadata.MD(m).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values for a key
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+// Keys lists the keys present in the metadata
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// prefixTTL is the default TTL for writes to keys under a prefix
+type prefixTTL struct {
+	prefix string
+	ttl    time.Duration
+}
+
+// newPrefixTTLs validates the default TTLs of PrefixTTLs and orders them longest
+// prefix first, so that the first match is the longest
+func newPrefixTTLs(ttls map[string]time.Duration) ([]prefixTTL, error) {
+	var rules []prefixTTL
+	for prefix, ttl := range ttls {
+		if ttl <= 0 {
+			return nil, fmt.Errorf("default TTL for prefix %q must be positive, got %v", prefix, ttl)
+		}
+		rules = append(rules, prefixTTL{prefix: prefix, ttl: ttl})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return len(rules[i].prefix) > len(rules[j].prefix)
+	})
+	return rules, nil
+}
+
+// ttlFor returns the TTL a write to key is stored with: its own, or if it has none
+// the default of the longest matching prefix in PrefixTTLs
+func (s *Server) ttlFor(key string, ttl time.Duration) time.Duration {
+	if ttl != 0 {
+		return ttl
+	}
+	for _, rule := range s.prefixTTLs {
+		if strings.HasPrefix(key, rule.prefix) {
+			return rule.ttl
+		}
+	}
+	return 0
+}
+
+// ParsePrefixTTLs parses default TTLs given as comma-separated prefix=ttl pairs, such
+// as "session:=30m,user:=24h,=1h", where the empty prefix sets the default for every
+// other key
+func ParsePrefixTTLs(spec string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	if spec == "" {
+		return ttls, nil
+	}
+	
+	for _, pair := range strings.Split(spec, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("prefix TTL %q is not of the form prefix=ttl", pair)
+		}
+		ttl, err := time.ParseDuration(pair[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTL for prefix %q: %w", pair[:i], err)
+		}
+		ttls[pair[:i]] = ttl
+	}
+	return ttls, nil
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shard-cache/internal/cache"
+	"github.com/shard-cache/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// preloadBatchSize is how many warm-up or preload items are inserted per cache lock
+const preloadBatchSize = 256
+
+// warmupEntry is a single record of a warm-up file or snapshot. In JSON, values are
+// base64 encoded; entries without an expiry never expire.
+type warmupEntry struct {
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// loadWarmupFile preloads the cache from a snapshot or a file of JSON lines,
+// skipping malformed and already expired entries. Snapshots from an unsupported
+// format version are rejected before anything is loaded.
+func (s *Server) loadWarmupFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open warmup file: %w", err)
+	}
+	defer f.Close()
+	
+	decode, err := newRecordDecoder(bufio.NewReader(f))
+	if err != nil {
+		return 0, fmt.Errorf("invalid warmup file: %w", err)
+	}
+	
+	loaded := 0
+	skipped := 0
+	now := time.Now()
+	batch := make([]cache.KV, 0, preloadBatchSize)
+	
+	for record := 1; ; record++ {
+		entry, err := decode()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = s.validateKey(entry.Key)
+		} else if !errors.Is(err, errMalformedRecord) {
+			s.cache.SetMany(batch)
+			return loaded, fmt.Errorf("failed to read warmup file: %w", err)
+		}
+		if err != nil {
+			s.logger.Warn("Skipping malformed warmup entry", zap.Int("record", record), zap.Error(err))
+			skipped++
+			continue
+		}
+		
+		var ttl time.Duration
+		if !entry.ExpiresAt.IsZero() {
+			ttl = entry.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				s.logger.Warn("Skipping expired warmup entry", zap.Int("record", record), zap.String("key", entry.Key))
+				skipped++
+				continue
+			}
+		}
+		
+		batch = append(batch, cache.KV{Key: entry.Key, Value: entry.Value, TTL: ttl})
+		if len(batch) == preloadBatchSize {
+			s.cache.SetMany(batch)
+			batch = batch[:0]
+		}
+		loaded++
+	}
+	s.cache.SetMany(batch)
+	
+	s.logger.Info("Cache warmup complete",
+		zap.String("file", path),
+		zap.Int("loaded", loaded),
+		zap.Int("skipped", skipped))
+	
+	return loaded, nil
+}
+
+// Preload implements the Preload RPC
+func (s *Server) Preload(stream proto.CacheService_PreloadServer) error {
+	// Streams bypass the unary interceptor, so enforce the serving mode here
+	if mode := s.getMode(); !mode.acceptsWrites() {
+		return status.Errorf(codes.FailedPrecondition, "node is %s", mode)
+	}
+	
+	var loaded, skipped int64
+	batch := make([]cache.KV, 0, preloadBatchSize)
+	
+	for {
+		item, err := stream.Recv()
+		if err == io.EOF {
+			s.cache.SetMany(batch)
+			return stream.SendAndClose(&proto.PreloadResponse{
+				Loaded:  loaded,
+				Skipped: skipped,
+			})
+		}
+		if err != nil {
+			s.cache.SetMany(batch)
+			return err
+		}
+		
+		var ttl time.Duration
+		if item.Ttl != nil {
+			ttl = item.Ttl.AsDuration()
+		}
+		if s.validateKey(item.Key) != nil || ttl < 0 {
+			s.logger.Warn("Skipping invalid preload item", zap.String("key", item.Key), zap.Duration("ttl", ttl))
+			skipped++
+			continue
+		}
+		
+		batch = append(batch, cache.KV{Key: item.Key, Value: item.Value, TTL: ttl})
+		if len(batch) == preloadBatchSize {
+			s.cache.SetMany(batch)
+			batch = batch[:0]
+		}
+		loaded++
+	}
+}
+
+// SetStream implements the SetStream RPC. Unlike Preload, each write is applied as
+// soon as it arrives, exactly as a Set would be, so versioned writes lose to newer
+// entries and tombstones. Invalid writes are counted as failed rather than ending the
+// stream.
+func (s *Server) SetStream(stream proto.CacheService_SetStreamServer) error {
+	// Streams bypass the unary interceptor, so enforce the serving mode here
+	if mode := s.getMode(); !mode.acceptsWrites() {
+		return status.Errorf(codes.FailedPrecondition, "node is %s", mode)
+	}
+	
+	var succeeded, failed int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&proto.SetSummary{
+				Succeeded: succeeded,
+				Failed:    failed,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		
+		var ttl time.Duration
+		if req.Ttl != nil {
+			ttl = req.Ttl.AsDuration()
+		}
+		if s.validateKey(req.Key) != nil || ttl < 0 {
+			failed++
+			continue
+		}
+		ttl = s.ttlFor(req.Key, ttl)
+		
+		if req.Version == 0 {
+			s.cache.Set(req.Key, req.Value, ttl)
+			succeeded++
+		} else if s.cache.SetVersioned(req.Key, req.Value, ttl, req.Version) {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+}
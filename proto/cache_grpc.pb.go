@@ -19,20 +19,63 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	CacheService_Get_FullMethodName    = "/cache.CacheService/Get"
-	CacheService_Set_FullMethodName    = "/cache.CacheService/Set"
-	CacheService_Delete_FullMethodName = "/cache.CacheService/Delete"
-	CacheService_Health_FullMethodName = "/cache.CacheService/Health"
+	CacheService_Get_FullMethodName          = "/cache.CacheService/Get"
+	CacheService_Exists_FullMethodName       = "/cache.CacheService/Exists"
+	CacheService_BatchGet_FullMethodName     = "/cache.CacheService/BatchGet"
+	CacheService_GetAndTouch_FullMethodName  = "/cache.CacheService/GetAndTouch"
+	CacheService_RangeGet_FullMethodName     = "/cache.CacheService/RangeGet"
+	CacheService_Scan_FullMethodName         = "/cache.CacheService/Scan"
+	CacheService_Set_FullMethodName          = "/cache.CacheService/Set"
+	CacheService_Delete_FullMethodName       = "/cache.CacheService/Delete"
+	CacheService_GetAndDelete_FullMethodName = "/cache.CacheService/GetAndDelete"
+	CacheService_Expire_FullMethodName       = "/cache.CacheService/Expire"
+	CacheService_Persist_FullMethodName      = "/cache.CacheService/Persist"
+	CacheService_SetBatch_FullMethodName     = "/cache.CacheService/SetBatch"
+	CacheService_Health_FullMethodName       = "/cache.CacheService/Health"
+	CacheService_Preload_FullMethodName      = "/cache.CacheService/Preload"
+	CacheService_SetStream_FullMethodName    = "/cache.CacheService/SetStream"
+	CacheService_Stats_FullMethodName        = "/cache.CacheService/Stats"
+	CacheService_Dump_FullMethodName         = "/cache.CacheService/Dump"
 )
 
 // CacheServiceClient is the client API for CacheService service.
 //
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc?tab=doc#ClientConn.NewStream.
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CacheServiceClient interface {
+	// Get retrieves a value from the cache
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Exists reports whether a key is present without returning its value
+	Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error)
+	// BatchGet retrieves several values in one call
+	BatchGet(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetResponse, error)
+	// GetAndTouch retrieves several values and resets their TTLs
+	GetAndTouch(ctx context.Context, in *GetAndTouchRequest, opts ...grpc.CallOption) (*GetAndTouchResponse, error)
+	// RangeGet retrieves every entry on the node whose key sorts within a range
+	RangeGet(ctx context.Context, in *RangeGetRequest, opts ...grpc.CallOption) (*RangeGetResponse, error)
+	// Scan retrieves every entry on the node whose key starts with a prefix
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	// Set stores a value in the cache
 	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	// Delete removes a key from the cache
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// GetAndDelete replaces a key with a tombstone and returns what it held before
+	GetAndDelete(ctx context.Context, in *GetAndDeleteRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Expire gives a key a new TTL without rewriting its value
+	Expire(ctx context.Context, in *ExpireRequest, opts ...grpc.CallOption) (*ExpireResponse, error)
+	// Persist removes a key's TTL so that it no longer expires
+	Persist(ctx context.Context, in *PersistRequest, opts ...grpc.CallOption) (*PersistResponse, error)
+	// SetBatch stores several values in one call
+	SetBatch(ctx context.Context, in *SetBatchRequest, opts ...grpc.CallOption) (*SetBatchResponse, error)
+	// Health check endpoint
 	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// Preload bulk-loads entries into the cache over a single stream
+	Preload(ctx context.Context, opts ...grpc.CallOption) (CacheService_PreloadClient, error)
+	// SetStream applies a stream of writes as they arrive and summarizes them at the end
+	SetStream(ctx context.Context, opts ...grpc.CallOption) (CacheService_SetStreamClient, error)
+	// Stats reports cache usage and smoothed load for autoscaling
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Dump streams every live entry held by the node
+	Dump(ctx context.Context, in *DumpRequest, opts ...grpc.CallOption) (CacheService_DumpClient, error)
 }
 
 type cacheServiceClient struct {
@@ -52,6 +95,51 @@ func (c *cacheServiceClient) Get(ctx context.Context, in *GetRequest, opts ...gr
 	return out, nil
 }
 
+func (c *cacheServiceClient) Exists(ctx context.Context, in *ExistsRequest, opts ...grpc.CallOption) (*ExistsResponse, error) {
+	out := new(ExistsResponse)
+	err := c.cc.Invoke(ctx, CacheService_Exists_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) BatchGet(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetResponse, error) {
+	out := new(BatchGetResponse)
+	err := c.cc.Invoke(ctx, CacheService_BatchGet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) GetAndTouch(ctx context.Context, in *GetAndTouchRequest, opts ...grpc.CallOption) (*GetAndTouchResponse, error) {
+	out := new(GetAndTouchResponse)
+	err := c.cc.Invoke(ctx, CacheService_GetAndTouch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) RangeGet(ctx context.Context, in *RangeGetRequest, opts ...grpc.CallOption) (*RangeGetResponse, error) {
+	out := new(RangeGetResponse)
+	err := c.cc.Invoke(ctx, CacheService_RangeGet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, CacheService_Scan_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cacheServiceClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
 	out := new(SetResponse)
 	err := c.cc.Invoke(ctx, CacheService_Set_FullMethodName, in, out, opts...)
@@ -70,6 +158,42 @@ func (c *cacheServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts
 	return out, nil
 }
 
+func (c *cacheServiceClient) GetAndDelete(ctx context.Context, in *GetAndDeleteRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, CacheService_GetAndDelete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Expire(ctx context.Context, in *ExpireRequest, opts ...grpc.CallOption) (*ExpireResponse, error) {
+	out := new(ExpireResponse)
+	err := c.cc.Invoke(ctx, CacheService_Expire_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Persist(ctx context.Context, in *PersistRequest, opts ...grpc.CallOption) (*PersistResponse, error) {
+	out := new(PersistResponse)
+	err := c.cc.Invoke(ctx, CacheService_Persist_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) SetBatch(ctx context.Context, in *SetBatchRequest, opts ...grpc.CallOption) (*SetBatchResponse, error) {
+	out := new(SetBatchResponse)
+	err := c.cc.Invoke(ctx, CacheService_SetBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cacheServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
 	out := new(HealthResponse)
 	err := c.cc.Invoke(ctx, CacheService_Health_FullMethodName, in, out, opts...)
@@ -79,14 +203,153 @@ func (c *cacheServiceClient) Health(ctx context.Context, in *HealthRequest, opts
 	return out, nil
 }
 
+func (c *cacheServiceClient) Preload(ctx context.Context, opts ...grpc.CallOption) (CacheService_PreloadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CacheService_ServiceDesc.Streams[0], CacheService_Preload_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheServicePreloadClient{stream}
+	return x, nil
+}
+
+type CacheService_PreloadClient interface {
+	Send(*PreloadItem) error
+	CloseAndRecv() (*PreloadResponse, error)
+	grpc.ClientStream
+}
+
+type cacheServicePreloadClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheServicePreloadClient) Send(m *PreloadItem) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *cacheServicePreloadClient) CloseAndRecv() (*PreloadResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PreloadResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cacheServiceClient) SetStream(ctx context.Context, opts ...grpc.CallOption) (CacheService_SetStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CacheService_ServiceDesc.Streams[1], CacheService_SetStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheServiceSetStreamClient{stream}
+	return x, nil
+}
+
+type CacheService_SetStreamClient interface {
+	Send(*SetRequest) error
+	CloseAndRecv() (*SetSummary, error)
+	grpc.ClientStream
+}
+
+type cacheServiceSetStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheServiceSetStreamClient) Send(m *SetRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *cacheServiceSetStreamClient) CloseAndRecv() (*SetSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SetSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cacheServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, CacheService_Stats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Dump(ctx context.Context, in *DumpRequest, opts ...grpc.CallOption) (CacheService_DumpClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CacheService_ServiceDesc.Streams[2], CacheService_Dump_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheServiceDumpClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CacheService_DumpClient interface {
+	Recv() (*Entry, error)
+	grpc.ClientStream
+}
+
+type cacheServiceDumpClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheServiceDumpClient) Recv() (*Entry, error) {
+	m := new(Entry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // CacheServiceServer is the server API for CacheService service.
 // All implementations must embed UnimplementedCacheServiceServer
 // for forward compatibility
 type CacheServiceServer interface {
+	// Get retrieves a value from the cache
 	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Exists reports whether a key is present without returning its value
+	Exists(context.Context, *ExistsRequest) (*ExistsResponse, error)
+	// BatchGet retrieves several values in one call
+	BatchGet(context.Context, *BatchGetRequest) (*BatchGetResponse, error)
+	// GetAndTouch retrieves several values and resets their TTLs
+	GetAndTouch(context.Context, *GetAndTouchRequest) (*GetAndTouchResponse, error)
+	// RangeGet retrieves every entry on the node whose key sorts within a range
+	RangeGet(context.Context, *RangeGetRequest) (*RangeGetResponse, error)
+	// Scan retrieves every entry on the node whose key starts with a prefix
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
+	// Set stores a value in the cache
 	Set(context.Context, *SetRequest) (*SetResponse, error)
+	// Delete removes a key from the cache
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// GetAndDelete replaces a key with a tombstone and returns what it held before
+	GetAndDelete(context.Context, *GetAndDeleteRequest) (*GetResponse, error)
+	// Expire gives a key a new TTL without rewriting its value
+	Expire(context.Context, *ExpireRequest) (*ExpireResponse, error)
+	// Persist removes a key's TTL so that it no longer expires
+	Persist(context.Context, *PersistRequest) (*PersistResponse, error)
+	// SetBatch stores several values in one call
+	SetBatch(context.Context, *SetBatchRequest) (*SetBatchResponse, error)
+	// Health check endpoint
 	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// Preload bulk-loads entries into the cache over a single stream
+	Preload(CacheService_PreloadServer) error
+	// SetStream applies a stream of writes as they arrive and summarizes them at the end
+	SetStream(CacheService_SetStreamServer) error
+	// Stats reports cache usage and smoothed load for autoscaling
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Dump streams every live entry held by the node
+	Dump(*DumpRequest, CacheService_DumpServer) error
 	mustEmbedUnimplementedCacheServiceServer()
 }
 
@@ -97,15 +360,54 @@ type UnimplementedCacheServiceServer struct {
 func (UnimplementedCacheServiceServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
 }
+func (UnimplementedCacheServiceServer) Exists(context.Context, *ExistsRequest) (*ExistsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exists not implemented")
+}
+func (UnimplementedCacheServiceServer) BatchGet(context.Context, *BatchGetRequest) (*BatchGetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGet not implemented")
+}
+func (UnimplementedCacheServiceServer) GetAndTouch(context.Context, *GetAndTouchRequest) (*GetAndTouchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAndTouch not implemented")
+}
+func (UnimplementedCacheServiceServer) RangeGet(context.Context, *RangeGetRequest) (*RangeGetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RangeGet not implemented")
+}
+func (UnimplementedCacheServiceServer) Scan(context.Context, *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
 func (UnimplementedCacheServiceServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
 }
 func (UnimplementedCacheServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedCacheServiceServer) GetAndDelete(context.Context, *GetAndDeleteRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAndDelete not implemented")
+}
+func (UnimplementedCacheServiceServer) Expire(context.Context, *ExpireRequest) (*ExpireResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Expire not implemented")
+}
+func (UnimplementedCacheServiceServer) Persist(context.Context, *PersistRequest) (*PersistResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Persist not implemented")
+}
+func (UnimplementedCacheServiceServer) SetBatch(context.Context, *SetBatchRequest) (*SetBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBatch not implemented")
+}
 func (UnimplementedCacheServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
 }
+func (UnimplementedCacheServiceServer) Preload(CacheService_PreloadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Preload not implemented")
+}
+func (UnimplementedCacheServiceServer) SetStream(CacheService_SetStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SetStream not implemented")
+}
+func (UnimplementedCacheServiceServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedCacheServiceServer) Dump(*DumpRequest, CacheService_DumpServer) error {
+	return status.Errorf(codes.Unimplemented, "method Dump not implemented")
+}
 func (UnimplementedCacheServiceServer) mustEmbedUnimplementedCacheServiceServer() {}
 
 // UnsafeCacheServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -137,6 +439,96 @@ func _CacheService_Get_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheService_Exists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExistsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Exists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Exists_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Exists(ctx, req.(*ExistsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_BatchGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).BatchGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_BatchGet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).BatchGet(ctx, req.(*BatchGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_GetAndTouch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAndTouchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).GetAndTouch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_GetAndTouch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).GetAndTouch(ctx, req.(*GetAndTouchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_RangeGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).RangeGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_RangeGet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).RangeGet(ctx, req.(*RangeGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Scan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CacheService_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetRequest)
 	if err := dec(in); err != nil {
@@ -173,6 +565,78 @@ func _CacheService_Delete_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheService_GetAndDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAndDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).GetAndDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_GetAndDelete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).GetAndDelete(ctx, req.(*GetAndDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Expire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Expire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Expire_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Expire(ctx, req.(*ExpireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Persist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PersistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Persist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Persist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Persist(ctx, req.(*PersistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_SetBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).SetBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_SetBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).SetBatch(ctx, req.(*SetBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CacheService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(HealthRequest)
 	if err := dec(in); err != nil {
@@ -191,6 +655,97 @@ func _CacheService_Health_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheService_Preload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CacheServiceServer).Preload(&cacheServicePreloadServer{stream})
+}
+
+type CacheService_PreloadServer interface {
+	SendAndClose(*PreloadResponse) error
+	Recv() (*PreloadItem, error)
+	grpc.ServerStream
+}
+
+type cacheServicePreloadServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheServicePreloadServer) SendAndClose(m *PreloadResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *cacheServicePreloadServer) Recv() (*PreloadItem, error) {
+	m := new(PreloadItem)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _CacheService_SetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CacheServiceServer).SetStream(&cacheServiceSetStreamServer{stream})
+}
+
+type CacheService_SetStreamServer interface {
+	SendAndClose(*SetSummary) error
+	Recv() (*SetRequest, error)
+	grpc.ServerStream
+}
+
+type cacheServiceSetStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheServiceSetStreamServer) SendAndClose(m *SetSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *cacheServiceSetStreamServer) Recv() (*SetRequest, error) {
+	m := new(SetRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _CacheService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Dump_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DumpRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheServiceServer).Dump(m, &cacheServiceDumpServer{stream})
+}
+
+type CacheService_DumpServer interface {
+	Send(*Entry) error
+	grpc.ServerStream
+}
+
+type cacheServiceDumpServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheServiceDumpServer) Send(m *Entry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // CacheService_ServiceDesc is the grpc.ServiceDesc for CacheService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -202,6 +757,26 @@ var CacheService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Get",
 			Handler:    _CacheService_Get_Handler,
 		},
+		{
+			MethodName: "Exists",
+			Handler:    _CacheService_Exists_Handler,
+		},
+		{
+			MethodName: "BatchGet",
+			Handler:    _CacheService_BatchGet_Handler,
+		},
+		{
+			MethodName: "GetAndTouch",
+			Handler:    _CacheService_GetAndTouch_Handler,
+		},
+		{
+			MethodName: "RangeGet",
+			Handler:    _CacheService_RangeGet_Handler,
+		},
+		{
+			MethodName: "Scan",
+			Handler:    _CacheService_Scan_Handler,
+		},
 		{
 			MethodName: "Set",
 			Handler:    _CacheService_Set_Handler,
@@ -210,11 +785,217 @@ var CacheService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _CacheService_Delete_Handler,
 		},
+		{
+			MethodName: "GetAndDelete",
+			Handler:    _CacheService_GetAndDelete_Handler,
+		},
+		{
+			MethodName: "Expire",
+			Handler:    _CacheService_Expire_Handler,
+		},
+		{
+			MethodName: "Persist",
+			Handler:    _CacheService_Persist_Handler,
+		},
+		{
+			MethodName: "SetBatch",
+			Handler:    _CacheService_SetBatch_Handler,
+		},
 		{
 			MethodName: "Health",
 			Handler:    _CacheService_Health_Handler,
 		},
+		{
+			MethodName: "Stats",
+			Handler:    _CacheService_Stats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Preload",
+			Handler:       _CacheService_Preload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SetStream",
+			Handler:       _CacheService_SetStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Dump",
+			Handler:       _CacheService_Dump_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/cache.proto",
+}
+
+const (
+	AdminService_Resize_FullMethodName    = "/cache.AdminService/Resize"
+	AdminService_SetMode_FullMethodName   = "/cache.AdminService/SetMode"
+	AdminService_SetLimits_FullMethodName = "/cache.AdminService/SetLimits"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	// Resize changes the cache capacity at runtime
+	Resize(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*ResizeResponse, error)
+	// SetMode switches the node between normal, read-only and draining
+	SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*SetModeResponse, error)
+	// SetLimits changes the runtime limits without restarting the node
+	SetLimits(ctx context.Context, in *SetLimitsRequest, opts ...grpc.CallOption) (*SetLimitsResponse, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) Resize(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*ResizeResponse, error) {
+	out := new(ResizeResponse)
+	err := c.cc.Invoke(ctx, AdminService_Resize_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*SetModeResponse, error) {
+	out := new(SetModeResponse)
+	err := c.cc.Invoke(ctx, AdminService_SetMode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetLimits(ctx context.Context, in *SetLimitsRequest, opts ...grpc.CallOption) (*SetLimitsResponse, error) {
+	out := new(SetLimitsResponse)
+	err := c.cc.Invoke(ctx, AdminService_SetLimits_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility
+type AdminServiceServer interface {
+	// Resize changes the cache capacity at runtime
+	Resize(context.Context, *ResizeRequest) (*ResizeResponse, error)
+	// SetMode switches the node between normal, read-only and draining
+	SetMode(context.Context, *SetModeRequest) (*SetModeResponse, error)
+	// SetLimits changes the runtime limits without restarting the node
+	SetLimits(context.Context, *SetLimitsRequest) (*SetLimitsResponse, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServiceServer struct {
+}
+
+func (UnimplementedAdminServiceServer) Resize(context.Context, *ResizeRequest) (*ResizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resize not implemented")
+}
+func (UnimplementedAdminServiceServer) SetMode(context.Context, *SetModeRequest) (*SetModeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMode not implemented")
+}
+func (UnimplementedAdminServiceServer) SetLimits(context.Context, *SetLimitsRequest) (*SetLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLimits not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_Resize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Resize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Resize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Resize(ctx, req.(*ResizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetMode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetMode(ctx, req.(*SetModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLimits(ctx, req.(*SetLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cache.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Resize",
+			Handler:    _AdminService_Resize_Handler,
+		},
+		{
+			MethodName: "SetMode",
+			Handler:    _AdminService_SetMode_Handler,
+		},
+		{
+			MethodName: "SetLimits",
+			Handler:    _AdminService_SetLimits_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/cache.proto",
-} 
\ No newline at end of file
+}